@@ -1,66 +1,287 @@
-package app
-
-import (
-	"context"
-	"fmt"
-	"log"
-	"time"
-
-	"github.com/ndk123-web/fast-todo/internal/config"
-	"github.com/ndk123-web/fast-todo/internal/handler"
-	"github.com/ndk123-web/fast-todo/internal/repository"
-	"github.com/ndk123-web/fast-todo/internal/server"
-	"github.com/ndk123-web/fast-todo/internal/service"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
-)
-
-func Run() error {
-	cfg, err := config.LoadConfig()
-	if err != nil {
-		return err
-	}
-
-	// MongoDB Atlas connection
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel() // prevent from memory leak
-
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoUri))
-
-	if err != nil {
-		return fmt.Errorf("failed to connect to mongo: %v", err)
-	}
-
-	if err = client.Ping(ctx, nil); err != nil {
-		return fmt.Errorf("mongo ping failed: %v", err)
-	}
-
-	log.Println("Connected to MongoDB Atlas")
-
-	// collection that we want
-	todoCollection := client.Database("golangdb").Collection("todos")
-	userCollection := client.Database("golangdb").Collection("users")
-	goalCollection := client.Database("golangdb").Collection("goals")
-	workspaceCollection := client.Database("golangdb").Collection("workspaces")
-
-	// todorepos
-	todoRepo := repository.NewTodoRepository(todoCollection)
-	todoService := service.NewTodoService(todoRepo)
-	todoHandler := handler.NewTodoHandler(todoService)
-
-	// userrepos
-	userRepo := repository.NewUserRepository(todoCollection, userCollection)
-	userService := service.NewUserService(userRepo)
-	userHandler := handler.NewUserHandler(userService)
-
-	goalRepo := repository.NewGoalRepository(goalCollection)
-	goalService := service.NewGoalService(goalRepo)
-	goalHandler := handler.NewGoalHandler(goalService)
-
-	workspaceRepo := repository.NewWorkspaceRepository(workspaceCollection)
-	workspaceService := service.NewWorkSpaceService(workspaceRepo)
-	workspaceHandler := handler.NewWorkspaceHandler(workspaceService)
-
-	srv := server.NewServer(todoHandler, userHandler, goalHandler, workspaceHandler)
-	return srv.Start(cfg.Port)
-}
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/config"
+	"github.com/ndk123-web/fast-todo/internal/handler"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"github.com/ndk123-web/fast-todo/internal/server"
+	"github.com/ndk123-web/fast-todo/internal/service"
+	"github.com/ndk123-web/fast-todo/internal/storage"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func Run() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	// MongoDB Atlas connection
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel() // prevent from memory leak
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoUri))
+
+	if err != nil {
+		return fmt.Errorf("failed to connect to mongo: %v", err)
+	}
+
+	if err = client.Ping(ctx, nil); err != nil {
+		return fmt.Errorf("mongo ping failed: %v", err)
+	}
+
+	log.Println("Connected to MongoDB Atlas")
+
+	// collection that we want
+	todoCollection := client.Database("golangdb").Collection("todos")
+	userCollection := client.Database("golangdb").Collection("users")
+	goalCollection := client.Database("golangdb").Collection("goals")
+	workspaceCollection := client.Database("golangdb").Collection("workspaces")
+	commentCollection := client.Database("golangdb").Collection("comments")
+	attachmentCollection := client.Database("golangdb").Collection("attachments")
+	timeEntryCollection := client.Database("golangdb").Collection("timeEntries")
+	revisionCollection := client.Database("golangdb").Collection("revisions")
+	templateCollection := client.Database("golangdb").Collection("templates")
+	notificationCollection := client.Database("golangdb").Collection("notifications")
+	focusCollection := client.Database("golangdb").Collection("focusSessions")
+	checkInCollection := client.Database("golangdb").Collection("goalCheckIns")
+	categoryCollection := client.Database("golangdb").Collection("goalCategories")
+	okrScoreCollection := client.Database("golangdb").Collection("okrScores")
+	invitationCollection := client.Database("golangdb").Collection("invitations")
+	activityCollection := client.Database("golangdb").Collection("activities")
+	activityCounterCollection := client.Database("golangdb").Collection("activityCounters")
+
+	// where attachment files live
+	blobStore, err := newBlobStore(cfg)
+	if err != nil {
+		return err
+	}
+
+	// repositories
+	todoRepo := repository.NewTodoRepository(todoCollection)
+	userRepo := repository.NewUserRepository(todoCollection, userCollection)
+	goalRepo := repository.NewGoalRepository(goalCollection)
+	workspaceRepo := repository.NewWorkspaceRepository(workspaceCollection)
+	commentRepo := repository.NewCommentRepository(commentCollection)
+	attachmentRepo := repository.NewAttachmentRepository(attachmentCollection)
+	timeEntryRepo := repository.NewTimeEntryRepository(timeEntryCollection)
+	templateRepo := repository.NewTemplateRepository(templateCollection)
+	notificationRepo := repository.NewNotificationRepository(notificationCollection)
+	focusRepo := repository.NewFocusRepository(focusCollection)
+	checkInRepo := repository.NewCheckInRepository(checkInCollection)
+	categoryRepo := repository.NewCategoryRepository(categoryCollection)
+	okrScoreRepo := repository.NewOKRScoreRepository(okrScoreCollection)
+	invitationRepo := repository.NewInvitationRepository(invitationCollection)
+	activityRepo := repository.NewActivityRepository(activityCollection, activityCounterCollection)
+
+	revisionRepo := repository.NewRevisionRepository(revisionCollection)
+
+	// multi document writes (move / copy, templates)
+	transactor := repository.NewTransactor(client)
+
+	searchRepo, err := newSearchRepository(cfg, todoCollection, goalCollection, workspaceCollection)
+	if err != nil {
+		return err
+	}
+
+	analyticsRepo, err := newAnalyticsRepository(cfg, todoCollection, goalCollection, checkInCollection)
+	if err != nil {
+		return err
+	}
+
+	// one running timer per user is enforced by a unique index
+	if err := timeEntryRepo.EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("failed to create time entry indexes: %v", err)
+	}
+	if err := revisionRepo.EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("failed to create revision indexes: %v", err)
+	}
+	if err := searchRepo.EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("failed to create search indexes: %v", err)
+	}
+	if err := todoRepo.EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("failed to create todo indexes: %v", err)
+	}
+	if err := notificationRepo.EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("failed to create notification indexes: %v", err)
+	}
+	if err := focusRepo.EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("failed to create focus session indexes: %v", err)
+	}
+	if err := checkInRepo.EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("failed to create goal check-in indexes: %v", err)
+	}
+	if err := analyticsRepo.EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("failed to create analytics indexes: %v", err)
+	}
+	if err := categoryRepo.EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("failed to create goal category indexes: %v", err)
+	}
+	if err := okrScoreRepo.EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("failed to create OKR score indexes: %v", err)
+	}
+	if err := invitationRepo.EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("failed to create invitation indexes: %v", err)
+	}
+	if err := activityRepo.EnsureIndexes(ctx); err != nil {
+		return fmt.Errorf("failed to create activity indexes: %v", err)
+	}
+
+	// old todos have free-form priorities without a weight
+	if err := todoRepo.MigratePriorities(ctx); err != nil {
+		return fmt.Errorf("failed to migrate todo priorities: %v", err)
+	}
+
+	// old goals have free text categories, they are linked to managed ones
+	categoryService := service.NewCategoryService(categoryRepo, goalRepo)
+	categoryHandler := handler.NewCategoryHandler(categoryService)
+
+	if err := categoryService.MigrateGoalCategories(ctx); err != nil {
+		return fmt.Errorf("failed to migrate goal categories: %v", err)
+	}
+
+	// feed of every workspace, written by the services that change todos,
+	// goals and workspaces
+	activityService := service.NewActivityService(activityRepo, workspaceRepo, userRepo, transactor)
+	activityHandler := handler.NewActivityHandler(activityService)
+
+	// change history of todos, goals and workspaces
+	revisionService := service.NewRevisionService(revisionRepo, todoRepo, goalRepo, workspaceRepo, activityService)
+
+	// attachments (trash purge uses it for cleanup)
+	attachmentService := service.NewAttachmentService(attachmentRepo, todoRepo, workspaceRepo, blobStore, cfg.AttachmentMaxBytes)
+	attachmentHandler := handler.NewAttachmentHandler(attachmentService, cfg.AttachmentMaxBytes)
+
+	// goal, todos linked to a goal keep its progress up to date
+	goalService := service.NewGoalService(goalRepo, todoRepo, checkInRepo, categoryRepo, workspaceRepo, userRepo, revisionService, activityService)
+	goalHandler := handler.NewGoalHandler(goalService)
+	revisionHandler := handler.NewRevisionHandler(revisionService, goalService)
+
+	// todo
+	todoService := service.NewTodoService(todoRepo, commentRepo, goalRepo, workspaceRepo, revisionService, activityService, goalService)
+	todoHandler := handler.NewTodoHandler(todoService)
+
+	// keep the manual order keys short in the background
+	go todoService.RunRankRebalancer(context.Background(), cfg.RankRebalanceInterval, cfg.RankMaxLength)
+
+	// notifications, stored until the user reads them
+	notificationService := service.NewNotificationService(notificationRepo)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
+
+	// workspace invitations by email or link, also accepted at signup
+	invitationService := service.NewInvitationService(invitationRepo, workspaceRepo, userRepo, notificationService, transactor, activityService, cfg.InviteTTL)
+	invitationHandler := handler.NewInvitationHandler(invitationService)
+
+	// user
+	userService := service.NewUserService(userRepo, invitationService)
+	userHandler := handler.NewUserHandler(userService)
+
+	// quick-add reads dates in the user's timezone and creates through the todo service
+	quickAddService := service.NewQuickAddService(todoService, userRepo)
+	quickAddHandler := handler.NewQuickAddHandler(quickAddService)
+
+	// workspace
+	workspaceService := service.NewWorkSpaceService(workspaceRepo, userRepo, todoRepo, revisionService, activityService, transactor)
+	workspaceHandler := handler.NewWorkspaceHandler(workspaceService)
+
+	// comments need todos (thread owner) and users (@mentions)
+	commentService := service.NewCommentService(commentRepo, todoRepo, userRepo, workspaceRepo)
+	commentHandler := handler.NewCommentHandler(commentService)
+
+	// time tracking
+	timeService := service.NewTimeService(timeEntryRepo, todoRepo, workspaceRepo)
+	timeHandler := handler.NewTimeHandler(timeService)
+
+	// trash, deleted todos / goals / workspaces are purged in the background
+	trashService := service.NewTrashService(todoRepo, goalRepo, workspaceRepo, commentRepo, activityRepo, timeEntryRepo, focusRepo, revisionRepo, checkInRepo, attachmentService, activityService, goalService)
+	trashHandler := handler.NewTrashHandler(trashService)
+
+	go trashService.RunTrashPurger(context.Background(), cfg.TrashPurgeInterval, cfg.TrashRetention)
+
+	// search across todos, goals and workspaces
+	searchService := service.NewSearchService(searchRepo)
+	searchHandler := handler.NewSearchHandler(searchService)
+
+	// move / copy between workspaces, multi document writes run in a transaction
+	transferService := service.NewTransferService(todoRepo, goalRepo, workspaceRepo, timeEntryRepo, focusRepo, transactor, activityService, goalService)
+	transferHandler := handler.NewTransferHandler(transferService)
+
+	// todo templates with {{variables}} and relative due dates
+	templateService := service.NewTemplateService(templateRepo, todoRepo, workspaceRepo, userRepo, transactor, activityService)
+	templateHandler := handler.NewTemplateHandler(templateService)
+
+	// snoozed todos are woken in the background
+	snoozeService := service.NewSnoozeService(todoRepo, workspaceRepo, userRepo, notificationService)
+	snoozeHandler := handler.NewSnoozeHandler(snoozeService)
+
+	go snoozeService.RunSnoozeScheduler(context.Background(), cfg.SnoozeCheckInterval)
+
+	// pomodoro focus sessions on todos
+	focusService := service.NewFocusService(focusRepo, todoRepo, workspaceRepo, cfg.FocusWorkMinutes, cfg.FocusBreakMinutes)
+	focusHandler := handler.NewFocusHandler(focusService)
+
+	// daily goal check-ins with streaks
+	checkInService := service.NewCheckInService(checkInRepo, goalRepo, workspaceRepo, userRepo, transactor, activityService, goalService)
+	checkInHandler := handler.NewCheckInHandler(checkInService)
+
+	// heatmap and completion statistics
+	analyticsService := service.NewAnalyticsService(analyticsRepo)
+	analyticsHandler := handler.NewAnalyticsHandler(analyticsService)
+
+	// objectives with weighted key results, scored when their period ends
+	okrService := service.NewOKRService(goalRepo, okrScoreRepo, workspaceRepo, userRepo, goalService, activityService)
+	okrHandler := handler.NewOKRHandler(okrService)
+
+	go okrService.RunOKRScoring(context.Background(), cfg.OKRScoreInterval)
+
+	srv := server.NewServer(todoHandler, userHandler, goalHandler, workspaceHandler, commentHandler, attachmentHandler, timeHandler, revisionHandler, trashHandler, searchHandler, quickAddHandler, transferHandler, templateHandler, snoozeHandler, notificationHandler, focusHandler, checkInHandler, analyticsHandler, categoryHandler, okrHandler, invitationHandler, activityHandler)
+	return srv.Start(cfg.Port)
+}
+
+// newBlobStore picks the attachment storage from config
+func newBlobStore(cfg *config.Config) (storage.BlobStore, error) {
+	switch cfg.BlobStore {
+	case "local":
+		return storage.NewLocalBlobStore(cfg.BlobLocalDir)
+	case "s3":
+		return storage.NewS3BlobStore(storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Bucket:    cfg.S3Bucket,
+			Region:    cfg.S3Region,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+		})
+	default:
+		return nil, fmt.Errorf("unknown BLOB_STORE %q", cfg.BlobStore)
+	}
+}
+
+// newSearchRepository picks the search backend from config
+func newSearchRepository(cfg *config.Config, todoCollection *mongo.Collection, goalCollection *mongo.Collection, workspaceCollection *mongo.Collection) (repository.SearchRepository, error) {
+	switch cfg.SearchBackend {
+	case "mongo":
+		return repository.NewTextSearchRepository(todoCollection, goalCollection, workspaceCollection), nil
+	case "memory":
+		return repository.NewMemorySearchRepository(todoCollection, goalCollection, workspaceCollection), nil
+	default:
+		return nil, fmt.Errorf("unknown SEARCH_BACKEND %q", cfg.SearchBackend)
+	}
+}
+
+// newAnalyticsRepository picks the analytics backend from config
+func newAnalyticsRepository(cfg *config.Config, todoCollection *mongo.Collection, goalCollection *mongo.Collection, checkInCollection *mongo.Collection) (repository.AnalyticsRepository, error) {
+	switch cfg.AnalyticsBackend {
+	case "mongo":
+		return repository.NewAggregationAnalyticsRepository(todoCollection, goalCollection, checkInCollection), nil
+	case "memory":
+		return repository.NewMemoryAnalyticsRepository(todoCollection, goalCollection, checkInCollection), nil
+	default:
+		return nil, fmt.Errorf("unknown ANALYTICS_BACKEND %q", cfg.AnalyticsBackend)
+	}
+}
@@ -1,28 +1,114 @@
-package config
-
-import (
-	"log"
-	"os"
-
-	"github.com/joho/godotenv"
-)
-
-type Config struct {
-	MongoUri  string
-	Port      string
-	JwtSecret []byte
-}
-
-func LoadConfig() (*Config, error) {
-
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error Loading .env file")
-	}
-
-	return &Config{
-		MongoUri:  os.Getenv("MONGO_URI"),
-		Port:      os.Getenv("DEVLOPMENT_PORT"),
-		JwtSecret: []byte(os.Getenv("JWT_SECRET")),
-	}, nil
-}
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+type Config struct {
+	MongoUri  string
+	Port      string
+	JwtSecret []byte
+
+	// manual ordering of todos
+	RankMaxLength         int
+	RankRebalanceInterval time.Duration
+
+	// attachments, BlobStore is "local" or "s3"
+	BlobStore          string
+	BlobLocalDir       string
+	S3Endpoint         string
+	S3Bucket           string
+	S3Region           string
+	S3AccessKey        string
+	S3SecretKey        string
+	AttachmentMaxBytes int64
+
+	// soft deleted todos / goals / workspaces are purged after TrashRetention
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+
+	// search, "mongo" uses text indexes, "memory" an in-process index for
+	// Mongo compatible stores without $text support
+	SearchBackend string
+
+	// analytics, "mongo" uses aggregation pipelines, "memory" counts in
+	// process for stores without aggregation support
+	AnalyticsBackend string
+
+	// how often snoozed todos are checked for wake-up
+	SnoozeCheckInterval time.Duration
+
+	// how often objectives of ended periods are scored
+	OKRScoreInterval time.Duration
+
+	// how long a workspace invitation stays valid by default
+	InviteTTL time.Duration
+
+	// default lengths of a focus session
+	FocusWorkMinutes  int
+	FocusBreakMinutes int
+}
+
+func LoadConfig() (*Config, error) {
+
+	err := godotenv.Load()
+	if err != nil {
+		log.Fatal("Error Loading .env file")
+	}
+
+	return &Config{
+		MongoUri:              os.Getenv("MONGO_URI"),
+		Port:                  os.Getenv("DEVLOPMENT_PORT"),
+		JwtSecret:             []byte(os.Getenv("JWT_SECRET")),
+		RankMaxLength:         getEnvInt("RANK_MAX_LENGTH", 12),
+		RankRebalanceInterval: getEnvDuration("RANK_REBALANCE_INTERVAL", 10*time.Minute),
+		BlobStore:             getEnvString("BLOB_STORE", "local"),
+		BlobLocalDir:          getEnvString("BLOB_LOCAL_DIR", "./uploads"),
+		S3Endpoint:            os.Getenv("S3_ENDPOINT"),
+		S3Bucket:              os.Getenv("S3_BUCKET"),
+		S3Region:              getEnvString("S3_REGION", "us-east-1"),
+		S3AccessKey:           os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:           os.Getenv("S3_SECRET_KEY"),
+		AttachmentMaxBytes:    int64(getEnvInt("ATTACHMENT_MAX_BYTES", 10<<20)),
+		TrashRetention:        getEnvDuration("TRASH_RETENTION", 30*24*time.Hour),
+		TrashPurgeInterval:    getEnvDuration("TRASH_PURGE_INTERVAL", time.Hour),
+		SearchBackend:         getEnvString("SEARCH_BACKEND", "mongo"),
+		AnalyticsBackend:      getEnvString("ANALYTICS_BACKEND", "mongo"),
+		SnoozeCheckInterval:   getEnvDuration("SNOOZE_CHECK_INTERVAL", time.Minute),
+		OKRScoreInterval:      getEnvDuration("OKR_SCORE_INTERVAL", time.Hour),
+		InviteTTL:             getEnvDuration("INVITE_TTL", 7*24*time.Hour),
+		FocusWorkMinutes:      getEnvInt("FOCUS_WORK_MINUTES", 25),
+		FocusBreakMinutes:     getEnvInt("FOCUS_BREAK_MINUTES", 5),
+	}, nil
+}
+
+// getEnvString reads a string from env and falls back to def when missing
+func getEnvString(key string, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// getEnvInt reads an int from env and falls back to def when missing / invalid
+func getEnvInt(key string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// getEnvDuration reads a duration like "10m" or "1h" from env
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
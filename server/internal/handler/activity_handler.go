@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ActivityHandler interface {
+	GetFeed(w http.ResponseWriter, r *http.Request)
+}
+
+type activityHandler struct {
+	service service.ActivityService
+}
+
+// GetFeed returns the activity feed of a workspace
+// query: ?actorId=..&types=todo-completed,goal-created&limit=50
+// &before=<cursor> pages back, &since=<cursor or RFC 3339 time> polls for new ones.
+// A cursor is the seq of an activity
+func (h *activityHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	workspaceId := r.PathValue("workspaceId")
+	values := r.URL.Query()
+
+	query, err := parseActivityQuery(values.Get("actorId"), values.Get("types"), values.Get("before"), values.Get("since"))
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+	query.Limit, _ = strconv.Atoi(values.Get("limit")) // 0 means default
+
+	page, err := h.service.GetFeed(context.Background(), actorFrom(r), workspaceId, query)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": page})
+}
+
+// parseActivityQuery reads the filters and cursors of the feed, empty ones
+// are left out
+func parseActivityQuery(actorId string, types string, before string, since string) (model.ActivityQuery, error) {
+	var query model.ActivityQuery
+	var err error
+
+	if actorId != "" {
+		if query.ActorId, err = primitive.ObjectIDFromHex(actorId); err != nil {
+			return model.ActivityQuery{}, err
+		}
+	}
+	for _, activityType := range strings.Split(types, ",") {
+		if activityType = strings.TrimSpace(activityType); activityType != "" {
+			query.Types = append(query.Types, activityType)
+		}
+	}
+	if before != "" {
+		if query.Before, err = strconv.ParseInt(before, 10, 64); err != nil || query.Before < 1 {
+			return model.ActivityQuery{}, errors.New("before must be a cursor of the feed")
+		}
+	}
+	if since != "" {
+		if query.Since, err = strconv.ParseInt(since, 10, 64); err == nil {
+			if query.Since < 0 {
+				return model.ActivityQuery{}, errors.New("since must be a cursor of the feed or an RFC 3339 time")
+			}
+		} else if query.SinceTime, err = time.Parse(time.RFC3339, since); err != nil {
+			return model.ActivityQuery{}, errors.New("since must be a cursor of the feed or an RFC 3339 time")
+		}
+	}
+
+	return query, nil
+}
+
+func NewActivityHandler(service service.ActivityService) ActivityHandler {
+	return &activityHandler{
+		service: service,
+	}
+}
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+type AnalyticsHandler interface {
+	Heatmap(w http.ResponseWriter, r *http.Request)
+	GoalStats(w http.ResponseWriter, r *http.Request)
+}
+
+type analyticsHandler struct {
+	service service.AnalyticsService
+}
+
+// Heatmap returns completed todos and goal check-ins per day
+// query: ?from=2025-01-01&to=2025-12-31&tz=Europe/Berlin (to is inclusive, at most 366 days)
+func (h *analyticsHandler) Heatmap(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	values := r.URL.Query()
+	loc, err := loadLocation(values.Get("tz"))
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	from, to, err := parseDayRange(values.Get("from"), values.Get("to"), loc)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	heatmap, err := h.service.Heatmap(context.Background(), actorFrom(r), userId, from, to, loc.String())
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": heatmap})
+}
+
+// GoalStats returns completion rates per goal category and the best / worst
+// weekday, same query as Heatmap
+func (h *analyticsHandler) GoalStats(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	values := r.URL.Query()
+	loc, err := loadLocation(values.Get("tz"))
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	from, to, err := parseDayRange(values.Get("from"), values.Get("to"), loc)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	stats, err := h.service.GoalStats(context.Background(), actorFrom(r), userId, from, to, loc.String())
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": stats})
+}
+
+func NewAnalyticsHandler(service service.AnalyticsService) AnalyticsHandler {
+	return &analyticsHandler{
+		service: service,
+	}
+}
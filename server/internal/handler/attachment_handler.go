@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/ndk123-web/fast-todo/internal/service"
+	"github.com/ndk123-web/fast-todo/internal/storage"
+)
+
+type AttachmentHandler interface {
+	UploadAttachment(w http.ResponseWriter, r *http.Request)
+	DownloadAttachment(w http.ResponseWriter, r *http.Request)
+	GetTodoAttachments(w http.ResponseWriter, r *http.Request)
+	DeleteAttachment(w http.ResponseWriter, r *http.Request)
+}
+
+type attachmentHandler struct {
+	service  service.AttachmentService
+	maxBytes int64
+}
+
+// UploadAttachment expects a multipart form with the file in the "file" field
+func (h *attachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+	todoId := r.PathValue("todoId")
+
+	if userId == "" || todoId == "" {
+		json.NewEncoder(w).Encode(map[string]string{"Error": "UserId / TodoId is empty in Handler"})
+		return
+	}
+
+	// 1MB extra for the multipart headers / boundaries
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBytes+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		// only a body over the limit is too large, anything else is a bad form
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || errors.Is(err, multipart.ErrMessageTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.service.UploadAttachment(context.Background(), actorFrom(r), userId, todoId, header.Filename, file, header.Size)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": attachment})
+}
+
+// DownloadAttachment streams the file content back to the client
+func (h *attachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	attachmentId := r.PathValue("attachmentId")
+	if attachmentId == "" {
+		http.Error(w, "AttachmentId is empty", http.StatusBadRequest)
+		return
+	}
+
+	attachment, content, err := h.service.GetAttachment(context.Background(), actorFrom(r), attachmentId)
+	if errors.Is(err, storage.ErrBlobNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	// browser must not guess another type (e.g. html) from the content
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	io.Copy(w, content)
+}
+
+func (h *attachmentHandler) GetTodoAttachments(w http.ResponseWriter, r *http.Request) {
+	todoId := r.PathValue("todoId")
+
+	attachments, err := h.service.GetTodoAttachments(context.Background(), actorFrom(r), todoId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": attachments})
+}
+
+func (h *attachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+	attachmentId := r.PathValue("attachmentId")
+
+	if err := h.service.DeleteAttachment(context.Background(), actorFrom(r), userId, attachmentId); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"response": "Success Delete Attachment"})
+}
+
+func NewAttachmentHandler(service service.AttachmentService, maxBytes int64) AttachmentHandler {
+	return &attachmentHandler{
+		service:  service,
+		maxBytes: maxBytes,
+	}
+}
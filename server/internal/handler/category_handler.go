@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+type CategoryHandler interface {
+	GetCategories(w http.ResponseWriter, r *http.Request)
+	CreateCategory(w http.ResponseWriter, r *http.Request)
+	UpdateCategory(w http.ResponseWriter, r *http.Request)
+	DeleteCategory(w http.ResponseWriter, r *http.Request)
+}
+
+type categoryHandler struct {
+	service service.CategoryService
+}
+
+// categoryBody is the payload to create or update a category
+type categoryBody struct {
+	Name  string `json:"name"`
+	Color string `json:"color"` // #RRGGBB, optional
+	Icon  string `json:"icon"`  // optional
+}
+
+func (h *categoryHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	categories, err := h.service.GetCategories(context.Background(), actorFrom(r), userId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": categories})
+}
+
+func (h *categoryHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	var reqBody categoryBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	category, err := h.service.CreateCategory(context.Background(), actorFrom(r), userId, model.GoalCategory{
+		Name:  reqBody.Name,
+		Color: reqBody.Color,
+		Icon:  reqBody.Icon,
+	})
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": category})
+}
+
+// UpdateCategory replaces name, color and icon, a new name shows on its goals
+func (h *categoryHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	categoryId := r.PathValue("categoryId")
+
+	var reqBody categoryBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	category, err := h.service.UpdateCategory(context.Background(), actorFrom(r), categoryId, reqBody.Name, reqBody.Color, reqBody.Icon)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": category})
+}
+
+// DeleteCategory removes the category, its goals stay without category
+func (h *categoryHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	categoryId := r.PathValue("categoryId")
+
+	if err := h.service.DeleteCategory(context.Background(), actorFrom(r), categoryId); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"response": "Success Delete Category"})
+}
+
+func NewCategoryHandler(service service.CategoryService) CategoryHandler {
+	return &categoryHandler{
+		service: service,
+	}
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+type CheckInHandler interface {
+	CheckIn(w http.ResponseWriter, r *http.Request)
+	GetProgress(w http.ResponseWriter, r *http.Request)
+}
+
+type checkInHandler struct {
+	service service.CheckInService
+}
+
+// checkInBody is optional, an empty day is today in the user's timezone
+type checkInBody struct {
+	Day  string `json:"day"` // 2006-01-02
+	Note string `json:"note"`
+}
+
+func (h *checkInHandler) CheckIn(w http.ResponseWriter, r *http.Request) {
+	goalId := r.PathValue("goalId")
+
+	// body is optional here
+	var reqBody checkInBody
+	json.NewDecoder(r.Body).Decode(&reqBody)
+
+	progress, err := h.service.CheckIn(context.Background(), actorFrom(r), goalId, reqBody.Day, reqBody.Note)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": progress})
+}
+
+// GetProgress returns the goal with its check-ins and current / longest streak
+func (h *checkInHandler) GetProgress(w http.ResponseWriter, r *http.Request) {
+	goalId := r.PathValue("goalId")
+
+	progress, err := h.service.GetProgress(context.Background(), actorFrom(r), goalId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": progress})
+}
+
+func NewCheckInHandler(service service.CheckInService) CheckInHandler {
+	return &checkInHandler{
+		service: service,
+	}
+}
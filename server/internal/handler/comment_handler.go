@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+type CommentHandler interface {
+	GetTodoComments(w http.ResponseWriter, r *http.Request)
+	CreateComment(w http.ResponseWriter, r *http.Request)
+	UpdateComment(w http.ResponseWriter, r *http.Request)
+	DeleteComment(w http.ResponseWriter, r *http.Request)
+}
+
+type commentHandler struct {
+	service service.CommentService
+}
+
+func (h *commentHandler) GetTodoComments(w http.ResponseWriter, r *http.Request) {
+	todoId := r.PathValue("todoId")
+	if todoId == "" {
+		json.NewEncoder(w).Encode(map[string]string{"Error": "TodoId is empty in Handler"})
+		return
+	}
+
+	comments, err := h.service.GetTodoComments(context.Background(), actorFrom(r), todoId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": comments})
+}
+
+// commentBody is the markdown text of a comment
+type commentBody struct {
+	Body string `json:"body"`
+}
+
+func (h *commentHandler) CreateComment(w http.ResponseWriter, r *http.Request) {
+	var reqBody commentBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	userId := r.PathValue("userId")
+	todoId := r.PathValue("todoId")
+
+	comment, err := h.service.CreateComment(context.Background(), actorFrom(r), userId, todoId, reqBody.Body)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": comment})
+}
+
+func (h *commentHandler) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	var reqBody commentBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	userId := r.PathValue("userId")
+	commentId := r.PathValue("commentId")
+
+	comment, err := h.service.UpdateComment(context.Background(), actorFrom(r), userId, commentId, reqBody.Body)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": comment})
+}
+
+func (h *commentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+	commentId := r.PathValue("commentId")
+
+	if err := h.service.DeleteComment(context.Background(), actorFrom(r), userId, commentId); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"response": "Success Delete Comment"})
+}
+
+func NewCommentHandler(service service.CommentService) CommentHandler {
+	return &commentHandler{
+		service: service,
+	}
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+type FocusHandler interface {
+	StartSession(w http.ResponseWriter, r *http.Request)
+	PauseSession(w http.ResponseWriter, r *http.Request)
+	ResumeSession(w http.ResponseWriter, r *http.Request)
+	CompleteSession(w http.ResponseWriter, r *http.Request)
+	GetActiveSession(w http.ResponseWriter, r *http.Request)
+	Stats(w http.ResponseWriter, r *http.Request)
+}
+
+type focusHandler struct {
+	service service.FocusService
+}
+
+// startFocusBody is optional, missing lengths use FOCUS_WORK_MINUTES / FOCUS_BREAK_MINUTES.
+// breakMinutes 0 skips the break
+type startFocusBody struct {
+	WorkMinutes  int  `json:"workMinutes"`
+	BreakMinutes *int `json:"breakMinutes"`
+}
+
+func (h *focusHandler) StartSession(w http.ResponseWriter, r *http.Request) {
+	// body is optional here
+	var reqBody startFocusBody
+	json.NewDecoder(r.Body).Decode(&reqBody)
+
+	userId := r.PathValue("userId")
+	todoId := r.PathValue("todoId")
+
+	session, err := h.service.StartSession(context.Background(), actorFrom(r), userId, todoId, reqBody.WorkMinutes, reqBody.BreakMinutes)
+	writeFocusSession(w, session, err)
+}
+
+func (h *focusHandler) PauseSession(w http.ResponseWriter, r *http.Request) {
+	session, err := h.service.PauseSession(context.Background(), actorFrom(r), r.PathValue("userId"))
+	writeFocusSession(w, session, err)
+}
+
+func (h *focusHandler) ResumeSession(w http.ResponseWriter, r *http.Request) {
+	session, err := h.service.ResumeSession(context.Background(), actorFrom(r), r.PathValue("userId"))
+	writeFocusSession(w, session, err)
+}
+
+func (h *focusHandler) CompleteSession(w http.ResponseWriter, r *http.Request) {
+	session, err := h.service.CompleteSession(context.Background(), actorFrom(r), r.PathValue("userId"))
+	writeFocusSession(w, session, err)
+}
+
+func (h *focusHandler) GetActiveSession(w http.ResponseWriter, r *http.Request) {
+	session, err := h.service.GetActiveSession(context.Background(), actorFrom(r), r.PathValue("userId"))
+	writeFocusSession(w, session, err)
+}
+
+// Stats returns focus totals per {period} (day / week)
+// query: ?from=2025-01-01&to=2025-01-31&tz=Europe/Berlin&workspaceId= (to is inclusive, workspaceId optional,
+// with it the totals are of every member)
+func (h *focusHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+	period := r.PathValue("period")
+
+	values := r.URL.Query()
+	loc, err := loadLocation(values.Get("tz"))
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	from, to, err := parseDayRange(values.Get("from"), values.Get("to"), loc)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	rows, err := h.service.Stats(context.Background(), actorFrom(r), userId, values.Get("workspaceId"), period, from, to, loc.String())
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": rows})
+}
+
+func writeFocusSession(w http.ResponseWriter, session model.FocusSession, err error) {
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": session})
+}
+
+func NewFocusHandler(service service.FocusService) FocusHandler {
+	return &focusHandler{
+		service: service,
+	}
+}
@@ -1,114 +1,308 @@
-package handler
-
-import (
-	"context"
-	"encoding/json"
-	"github.com/ndk123-web/fast-todo/internal/service"
-	"net/http"
-)
-
-type GoalHandler interface {
-	GetUserGoals(w http.ResponseWriter, r *http.Request)
-	CreateUserGoal(w http.ResponseWriter, r *http.Request)
-	UpdateUserGoal(w http.ResponseWriter, r *http.Request)
-	DeleteUserGoal(w http.ResponseWriter, r *http.Request)
-}
-
-type goalHandler struct {
-	service service.GoalService
-}
-
-func (h *goalHandler) GetUserGoals(w http.ResponseWriter, r *http.Request) {
-	userId := r.PathValue("userId")
-	workspaceId := r.PathValue("workspaceId")
-
-	if userId == "" || workspaceId == "" {
-		json.NewEncoder(w).Encode(map[string]string{"Error": "UserId / Workspace ID is empty in Handler"})
-		return
-	}
-
-	goals, err := h.service.GetUserGoals(context.Background(), userId, workspaceId)
-	if err != nil {
-		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
-		return
-	}
-
-	json.NewEncoder(w).Encode(map[string]any{"response": goals})
-}
-
-type createGoalReqBody struct {
-	GoalName   string `json:"goalName"`
-	TargetDays int64  `json:"targetDays"`
-	Category   string `json:"category"`
-}
-
-func (h *goalHandler) CreateUserGoal(w http.ResponseWriter, r *http.Request) {
-	var reqBody createGoalReqBody
-	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
-		return
-	}
-
-	userId := r.PathValue("userId")
-	workspaceId := r.PathValue("workspaceId")
-
-	goal, err := h.service.CreateUserGoal(context.Background(), userId, workspaceId, reqBody.GoalName, reqBody.TargetDays, reqBody.Category)
-	if err != nil {
-		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
-		return
-	}
-
-	json.NewEncoder(w).Encode(map[string]any{"response": goal})
-}
-
-type updateGoalBody struct {
-	UpdatedGoalName   string `json:"updatedGoalName"`
-	UpdatedTargetDays int    `json:"updatedTargetDays"`
-	UpdatedCategory   string `json:"updatedCategory"`
-}
-
-func (h *goalHandler) UpdateUserGoal(w http.ResponseWriter, r *http.Request) {
-	var reqBody updateGoalBody
-	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		json.NewEncoder(w).Encode(map[string]any{"Error": err.Error()})
-		return
-	}
-
-	if reqBody.UpdatedCategory == "" || reqBody.UpdatedGoalName == "" || reqBody.UpdatedTargetDays == 0 {
-		json.NewEncoder(w).Encode(map[string]any{"Error": "Category/TargetDays/GoalName is Empty"})
-		return
-	}
-
-	goalId := r.PathValue("goalId")
-
-	_, err := h.service.UpdateUserGoal(context.Background(), goalId, reqBody.UpdatedGoalName, reqBody.UpdatedTargetDays, reqBody.UpdatedCategory)
-	if err != nil {
-		json.NewEncoder(w).Encode(map[string]any{"Error": err.Error()})
-		return
-	}
-
-	json.NewEncoder(w).Encode(map[string]string{"response": "Success Update Goal"})
-}
-
-func (h *goalHandler) DeleteUserGoal(w http.ResponseWriter, r *http.Request) {
-	goalIdTobeDelete := r.PathValue("goalId")
-
-	if goalIdTobeDelete == "" {
-		json.NewEncoder(w).Encode(map[string]string{"Error": "Goal Id is Empty In Handler"})
-		return
-	}
-
-	isDeleted, err := h.service.DeleteUserGoal(context.Background(), goalIdTobeDelete)
-	if err != nil || !isDeleted {
-		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
-		return
-	}
-
-	json.NewEncoder(w).Encode(map[string]string{"response": "Success Delete Goal"})
-}
-
-func NewGoalHandler(service service.GoalService) GoalHandler {
-	return &goalHandler{
-		service: service,
-	}
-}
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/service"
+	"net/http"
+)
+
+type GoalHandler interface {
+	GetUserGoals(w http.ResponseWriter, r *http.Request)
+	CreateUserGoal(w http.ResponseWriter, r *http.Request)
+	UpdateUserGoal(w http.ResponseWriter, r *http.Request)
+	DeleteUserGoal(w http.ResponseWriter, r *http.Request)
+	GetGoalDetail(w http.ResponseWriter, r *http.Request)
+	SetProgressMode(w http.ResponseWriter, r *http.Request)
+	RecordValue(w http.ResponseWriter, r *http.Request)
+	SetMilestone(w http.ResponseWriter, r *http.Request)
+	CategoryRollups(w http.ResponseWriter, r *http.Request)
+	CategoryRollup(w http.ResponseWriter, r *http.Request)
+	SetDeadline(w http.ResponseWriter, r *http.Request)
+	GoalsBehind(w http.ResponseWriter, r *http.Request)
+}
+
+type goalHandler struct {
+	service service.GoalService
+}
+
+func (h *goalHandler) GetUserGoals(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+	workspaceId := r.PathValue("workspaceId")
+
+	if userId == "" || workspaceId == "" {
+		json.NewEncoder(w).Encode(map[string]string{"Error": "UserId / Workspace ID is empty in Handler"})
+		return
+	}
+
+	goals, err := h.service.GetUserGoals(context.Background(), actorFrom(r), userId, workspaceId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"response": goals})
+}
+
+// createGoalReqBody, type is days (default) / habit / numeric / milestone
+// and only the fields of that type are used
+type createGoalReqBody struct {
+	GoalName   string `json:"goalName"`
+	TargetDays int64  `json:"targetDays"`
+	Category   string `json:"category"`
+
+	Type         string               `json:"type"`
+	Frequency    *model.GoalFrequency `json:"frequency"` // habit: {"times": 3, "period": "week"}
+	Unit         string               `json:"unit"`
+	TargetValue  float64              `json:"targetValue"`
+	CurrentValue float64              `json:"currentValue"`
+	Milestones   []string             `json:"milestones"` // titles in order
+	Deadline     string               `json:"deadline"`   // optional, 2006-01-02
+}
+
+func (h *goalHandler) CreateUserGoal(w http.ResponseWriter, r *http.Request) {
+	var reqBody createGoalReqBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	userId := r.PathValue("userId")
+	workspaceId := r.PathValue("workspaceId")
+
+	milestones := make([]model.Milestone, 0, len(reqBody.Milestones))
+	for _, title := range reqBody.Milestones {
+		milestones = append(milestones, model.Milestone{Title: title})
+	}
+
+	goal, err := h.service.CreateUserGoal(context.Background(), actorFrom(r), userId, workspaceId, model.Goals{
+		Title:        reqBody.GoalName,
+		TargetDays:   int(reqBody.TargetDays),
+		Category:     reqBody.Category,
+		Type:         reqBody.Type,
+		Frequency:    reqBody.Frequency,
+		Unit:         reqBody.Unit,
+		TargetValue:  reqBody.TargetValue,
+		CurrentValue: reqBody.CurrentValue,
+		Milestones:   milestones,
+		Deadline:     reqBody.Deadline,
+	})
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"response": goal})
+}
+
+// updateGoalBody, the target fields of the goal's type are required
+type updateGoalBody struct {
+	UpdatedGoalName   string `json:"updatedGoalName"`
+	UpdatedTargetDays int    `json:"updatedTargetDays"`
+	UpdatedCategory   string `json:"updatedCategory"`
+
+	UpdatedFrequency   *model.GoalFrequency `json:"updatedFrequency"`
+	UpdatedUnit        string               `json:"updatedUnit"`
+	UpdatedTargetValue float64              `json:"updatedTargetValue"`
+}
+
+func (h *goalHandler) UpdateUserGoal(w http.ResponseWriter, r *http.Request) {
+	var reqBody updateGoalBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]any{"Error": err.Error()})
+		return
+	}
+
+	if reqBody.UpdatedCategory == "" || reqBody.UpdatedGoalName == "" {
+		json.NewEncoder(w).Encode(map[string]any{"Error": "Category/GoalName is Empty"})
+		return
+	}
+
+	goalId := r.PathValue("goalId")
+
+	_, err := h.service.UpdateUserGoal(context.Background(), actorFrom(r), goalId, model.GoalUpdate{
+		Title:       reqBody.UpdatedGoalName,
+		Category:    reqBody.UpdatedCategory,
+		TargetDays:  reqBody.UpdatedTargetDays,
+		Frequency:   reqBody.UpdatedFrequency,
+		Unit:        reqBody.UpdatedUnit,
+		TargetValue: reqBody.UpdatedTargetValue,
+	})
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]any{"Error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"response": "Success Update Goal"})
+}
+
+func (h *goalHandler) DeleteUserGoal(w http.ResponseWriter, r *http.Request) {
+	goalIdTobeDelete := r.PathValue("goalId")
+
+	if goalIdTobeDelete == "" {
+		json.NewEncoder(w).Encode(map[string]string{"Error": "Goal Id is Empty In Handler"})
+		return
+	}
+
+	isDeleted, err := h.service.DeleteUserGoal(context.Background(), actorFrom(r), goalIdTobeDelete)
+	if err != nil || !isDeleted {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"response": "Success Delete Goal"})
+}
+
+// GetGoalDetail returns the goal with its open and closed linked todos
+func (h *goalHandler) GetGoalDetail(w http.ResponseWriter, r *http.Request) {
+	goalId := r.PathValue("goalId")
+
+	detail, err := h.service.GetGoalDetail(context.Background(), actorFrom(r), goalId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"response": detail})
+}
+
+type progressModeBody struct {
+	Mode string `json:"mode"` // "check-ins" / "todos"
+}
+
+func (h *goalHandler) SetProgressMode(w http.ResponseWriter, r *http.Request) {
+	goalId := r.PathValue("goalId")
+
+	var reqBody progressModeBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	goal, err := h.service.SetProgressMode(context.Background(), actorFrom(r), goalId, reqBody.Mode)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"response": goal})
+}
+
+// recordValueBody sets the value or adds to it, exactly one is given
+type recordValueBody struct {
+	Value *float64 `json:"value"`
+	Add   *float64 `json:"add"`
+}
+
+// RecordValue updates the current value of a numeric goal
+func (h *goalHandler) RecordValue(w http.ResponseWriter, r *http.Request) {
+	goalId := r.PathValue("goalId")
+
+	var reqBody recordValueBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	goal, err := h.service.RecordValue(context.Background(), actorFrom(r), goalId, reqBody.Value, reqBody.Add)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"response": goal})
+}
+
+type setMilestoneBody struct {
+	Done bool `json:"done"`
+}
+
+// SetMilestone checks or unchecks a milestone of a milestone goal
+func (h *goalHandler) SetMilestone(w http.ResponseWriter, r *http.Request) {
+	goalId := r.PathValue("goalId")
+	milestoneId := r.PathValue("milestoneId")
+
+	var reqBody setMilestoneBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	goal, err := h.service.SetMilestone(context.Background(), actorFrom(r), goalId, milestoneId, reqBody.Done)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"response": goal})
+}
+
+// CategoryRollups returns active goals, completion and streaks per category
+func (h *goalHandler) CategoryRollups(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	rollups, err := h.service.CategoryRollups(context.Background(), actorFrom(r), userId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": rollups})
+}
+
+func (h *goalHandler) CategoryRollup(w http.ResponseWriter, r *http.Request) {
+	categoryId := r.PathValue("categoryId")
+
+	rollup, err := h.service.CategoryRollup(context.Background(), actorFrom(r), categoryId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": rollup})
+}
+
+type setDeadlineBody struct {
+	Deadline string `json:"deadline"` // 2006-01-02, empty removes it
+}
+
+func (h *goalHandler) SetDeadline(w http.ResponseWriter, r *http.Request) {
+	goalId := r.PathValue("goalId")
+
+	var reqBody setDeadlineBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	goal, err := h.service.SetDeadline(context.Background(), actorFrom(r), goalId, reqBody.Deadline)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"response": goal})
+}
+
+// GoalsBehind lists the goals of every workspace that are at risk or off track
+func (h *goalHandler) GoalsBehind(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	goals, err := h.service.GoalsBehind(context.Background(), actorFrom(r), userId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": goals})
+}
+
+func NewGoalHandler(service service.GoalService) GoalHandler {
+	return &goalHandler{
+		service: service,
+	}
+}
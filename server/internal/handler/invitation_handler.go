@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+type InvitationHandler interface {
+	CreateInvitation(w http.ResponseWriter, r *http.Request)
+	GetPendingInvitations(w http.ResponseWriter, r *http.Request)
+	RevokeInvitation(w http.ResponseWriter, r *http.Request)
+	GetMyInvitations(w http.ResponseWriter, r *http.Request)
+	AcceptInvitation(w http.ResponseWriter, r *http.Request)
+	AcceptInvitationById(w http.ResponseWriter, r *http.Request)
+}
+
+type invitationHandler struct {
+	service service.InvitationService
+}
+
+// createInvitationBody invites the person with email, or makes a link when
+// email is empty
+type createInvitationBody struct {
+	Email          string `json:"email"`
+	Role           string `json:"role"`           // owner / editor / viewer
+	MaxUses        int    `json:"maxUses"`        // links only, 1 by default
+	ExpiresInHours int    `json:"expiresInHours"` // optional, 7 days by default
+}
+
+func (h *invitationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	workspaceId := r.PathValue("workspaceId")
+
+	var reqBody createInvitationBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	invitation := model.Invitation{Email: reqBody.Email, Role: reqBody.Role, MaxUses: reqBody.MaxUses}
+	expiresIn := time.Duration(reqBody.ExpiresInHours) * time.Hour
+
+	created, err := h.service.CreateInvitation(context.Background(), actorFrom(r), workspaceId, invitation, expiresIn)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": created})
+}
+
+func (h *invitationHandler) GetPendingInvitations(w http.ResponseWriter, r *http.Request) {
+	workspaceId := r.PathValue("workspaceId")
+
+	invitations, err := h.service.GetPendingInvitations(context.Background(), actorFrom(r), workspaceId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": invitations})
+}
+
+func (h *invitationHandler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	invitationId := r.PathValue("invitationId")
+
+	invitation, err := h.service.RevokeInvitation(context.Background(), actorFrom(r), invitationId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": invitation})
+}
+
+func (h *invitationHandler) GetMyInvitations(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	invitations, err := h.service.GetMyInvitations(context.Background(), actorFrom(r), userId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": invitations})
+}
+
+type acceptInvitationBody struct {
+	Token string `json:"token"`
+}
+
+func (h *invitationHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var reqBody acceptInvitationBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	workspace, err := h.service.AcceptInvitation(context.Background(), actorFrom(r), reqBody.Token)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": workspace})
+}
+
+func (h *invitationHandler) AcceptInvitationById(w http.ResponseWriter, r *http.Request) {
+	invitationId := r.PathValue("invitationId")
+
+	workspace, err := h.service.AcceptInvitationById(context.Background(), actorFrom(r), invitationId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": workspace})
+}
+
+func NewInvitationHandler(service service.InvitationService) InvitationHandler {
+	return &invitationHandler{
+		service: service,
+	}
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+type NotificationHandler interface {
+	GetNotifications(w http.ResponseWriter, r *http.Request)
+	MarkRead(w http.ResponseWriter, r *http.Request)
+}
+
+type notificationHandler struct {
+	service service.NotificationService
+}
+
+// GetNotifications returns the newest notifications, ?unread=true skips read ones
+func (h *notificationHandler) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	notifications, err := h.service.GetNotifications(context.Background(), actorFrom(r), userId, unreadOnly)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": notifications})
+}
+
+func (h *notificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	notificationId := r.PathValue("notificationId")
+
+	notification, err := h.service.MarkRead(context.Background(), actorFrom(r), notificationId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": notification})
+}
+
+func NewNotificationHandler(service service.NotificationService) NotificationHandler {
+	return &notificationHandler{
+		service: service,
+	}
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+type OKRHandler interface {
+	SetOKR(w http.ResponseWriter, r *http.Request)
+	GetOKRTree(w http.ResponseWriter, r *http.Request)
+	ScoreObjective(w http.ResponseWriter, r *http.Request)
+	GetScores(w http.ResponseWriter, r *http.Request)
+}
+
+type okrHandler struct {
+	service service.OKRService
+}
+
+// setOKRBody places a goal in the OKR tree, an empty parentId makes it a top
+// level goal
+type setOKRBody struct {
+	ParentId string  `json:"parentId"`
+	Weight   float64 `json:"weight"` // optional, 1 by default
+	Period   string  `json:"period"` // 2026, 2026-H1, 2026-Q4 or 2026-10, optional
+}
+
+func (h *okrHandler) SetOKR(w http.ResponseWriter, r *http.Request) {
+	goalId := r.PathValue("goalId")
+
+	var reqBody setOKRBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	goal, err := h.service.SetOKR(context.Background(), actorFrom(r), goalId, reqBody.ParentId, reqBody.Weight, reqBody.Period)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": goal})
+}
+
+// GetOKRTree returns the objectives of the workspace, ?period= filters them
+func (h *okrHandler) GetOKRTree(w http.ResponseWriter, r *http.Request) {
+	workspaceId := r.PathValue("workspaceId")
+	period := r.URL.Query().Get("period")
+
+	tree, err := h.service.GetOKRTree(context.Background(), actorFrom(r), workspaceId, period)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": tree})
+}
+
+func (h *okrHandler) ScoreObjective(w http.ResponseWriter, r *http.Request) {
+	goalId := r.PathValue("goalId")
+
+	score, err := h.service.ScoreObjective(context.Background(), actorFrom(r), goalId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": score})
+}
+
+func (h *okrHandler) GetScores(w http.ResponseWriter, r *http.Request) {
+	goalId := r.PathValue("goalId")
+
+	scores, err := h.service.GetScores(context.Background(), actorFrom(r), goalId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": scores})
+}
+
+func NewOKRHandler(service service.OKRService) OKRHandler {
+	return &okrHandler{
+		service: service,
+	}
+}
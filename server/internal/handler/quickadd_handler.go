@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+type QuickAddHandler interface {
+	QuickAdd(w http.ResponseWriter, r *http.Request)
+}
+
+type quickAddHandler struct {
+	service service.QuickAddService
+}
+
+type quickAddBody struct {
+	Text     string `json:"text"`     // "Pay rent tomorrow 9am !high #finance every month"
+	Timezone string `json:"timezone"` // optional, defaults to the user's timezone
+}
+
+// QuickAdd creates a todo from free text, ?preview=true only returns the parsed todo
+func (h *quickAddHandler) QuickAdd(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+	workspaceId := r.PathValue("workspaceId")
+	preview := r.URL.Query().Get("preview") == "true"
+
+	var reqBody quickAddBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
+		return
+	}
+
+	todo, err := h.service.QuickAdd(context.Background(), actorFrom(r), userId, workspaceId, reqBody.Text, reqBody.Timezone, preview)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": todo, "preview": preview, "success": "true"})
+}
+
+func NewQuickAddHandler(service service.QuickAddService) QuickAddHandler {
+	return &quickAddHandler{
+		service: service,
+	}
+}
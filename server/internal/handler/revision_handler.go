@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ndk123-web/fast-todo/internal/middleware"
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+type RevisionHandler interface {
+	GetHistory(w http.ResponseWriter, r *http.Request)
+	Revert(w http.ResponseWriter, r *http.Request)
+}
+
+type revisionHandler struct {
+	service service.RevisionService
+	goals   service.GoalService // goal reverts also update the progress
+}
+
+// GetHistory returns the revisions of a todo / goal / workspace, oldest first
+func (h *revisionHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	entityType := r.PathValue("entityType")
+	entityId := r.PathValue("entityId")
+
+	revisions, err := h.service.GetHistory(context.Background(), actorFrom(r), entityType, entityId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": revisions})
+}
+
+// Revert brings the entity back to the state of {version}, 0 is the state
+// before the first change
+func (h *revisionHandler) Revert(w http.ResponseWriter, r *http.Request) {
+	entityType := r.PathValue("entityType")
+	entityId := r.PathValue("entityId")
+
+	version, err := strconv.Atoi(r.PathValue("version"))
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": "version must be a number"})
+		return
+	}
+
+	var revision model.Revision
+	if entityType == model.EntityGoal {
+		revision, err = h.goals.RevertGoal(context.Background(), actorFrom(r), entityId, version)
+	} else {
+		revision, err = h.service.Revert(context.Background(), actorFrom(r), entityType, entityId, version)
+	}
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": revision})
+}
+
+// actorFrom reads the calling user that the auth middleware put in the context
+func actorFrom(r *http.Request) service.Actor {
+	return service.Actor{
+		UserId: middleware.CallerId(r.Context()),
+		Email:  middleware.CallerEmail(r.Context()),
+	}
+}
+
+func NewRevisionHandler(service service.RevisionService, goals service.GoalService) RevisionHandler {
+	return &revisionHandler{
+		service: service,
+		goals:   goals,
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+type SearchHandler interface {
+	Search(w http.ResponseWriter, r *http.Request)
+}
+
+type searchHandler struct {
+	service service.SearchService
+}
+
+// Search returns ranked results with highlights
+// query: ?q="release notes" priority:high is:open ws:Work&limit=20
+func (h *searchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	values := r.URL.Query()
+	limit, _ := strconv.Atoi(values.Get("limit")) // 0 means default
+
+	results, err := h.service.Search(context.Background(), actorFrom(r), userId, values.Get("q"), limit)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": results})
+}
+
+func NewSearchHandler(service service.SearchService) SearchHandler {
+	return &searchHandler{
+		service: service,
+	}
+}
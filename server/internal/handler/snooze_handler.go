@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+type SnoozeHandler interface {
+	SnoozeTodo(w http.ResponseWriter, r *http.Request)
+	UnsnoozeTodo(w http.ResponseWriter, r *http.Request)
+	GetSnoozedTodos(w http.ResponseWriter, r *http.Request)
+}
+
+type snoozeHandler struct {
+	service service.SnoozeService
+}
+
+// snoozeBody is the request payload for snoozing a todo
+type snoozeBody struct {
+	Until  string `json:"until"`  // RFC3339 or "monday" / "tomorrow 9am" / "2026-11-02"
+	Notify bool   `json:"notify"` // send a notification when the todo wakes
+}
+
+func (h *snoozeHandler) SnoozeTodo(w http.ResponseWriter, r *http.Request) {
+	todoId := r.PathValue("todoId")
+
+	var reqBody snoozeBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	todo, err := h.service.SnoozeTodo(context.Background(), actorFrom(r), todoId, reqBody.Until, reqBody.Notify)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": todo})
+}
+
+func (h *snoozeHandler) UnsnoozeTodo(w http.ResponseWriter, r *http.Request) {
+	todoId := r.PathValue("todoId")
+
+	todo, err := h.service.UnsnoozeTodo(context.Background(), actorFrom(r), todoId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": todo})
+}
+
+// GetSnoozedTodos returns the snoozed todos of the user, the first to wake first
+func (h *snoozeHandler) GetSnoozedTodos(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	todos, err := h.service.GetSnoozedTodos(context.Background(), actorFrom(r), userId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": todos})
+}
+
+func NewSnoozeHandler(service service.SnoozeService) SnoozeHandler {
+	return &snoozeHandler{
+		service: service,
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+type TemplateHandler interface {
+	GetTemplates(w http.ResponseWriter, r *http.Request)
+	CreateTemplate(w http.ResponseWriter, r *http.Request)
+	DeleteTemplate(w http.ResponseWriter, r *http.Request)
+	Instantiate(w http.ResponseWriter, r *http.Request)
+}
+
+type templateHandler struct {
+	service service.TemplateService
+}
+
+func (h *templateHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	templates, err := h.service.GetTemplates(context.Background(), actorFrom(r), userId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": templates})
+}
+
+// createTemplateBody is the request payload for saving a template, either
+// the items are sent or the ids of existing todos to save with their subtasks
+type createTemplateBody struct {
+	Name    string               `json:"name"`
+	Items   []model.TemplateItem `json:"items"`
+	TodoIds []string             `json:"todoIds"`
+}
+
+func (h *templateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	var reqBody createTemplateBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	var template model.Template
+	var err error
+	if len(reqBody.TodoIds) > 0 {
+		template, err = h.service.CreateTemplateFromTodos(context.Background(), actorFrom(r), userId, reqBody.Name, reqBody.TodoIds)
+	} else {
+		template, err = h.service.CreateTemplate(context.Background(), actorFrom(r), userId, model.Template{Name: reqBody.Name, Items: reqBody.Items})
+	}
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": template})
+}
+
+func (h *templateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	templateId := r.PathValue("templateId")
+
+	if err := h.service.DeleteTemplate(context.Background(), actorFrom(r), templateId); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": "template deleted"})
+}
+
+// instantiateBody is the request payload for creating todos from a template
+type instantiateBody struct {
+	Variables map[string]string `json:"variables"` // values for the {{variables}}
+	StartDate string            `json:"startDate"` // YYYY-MM-DD, due offsets count from it, default today
+}
+
+func (h *templateHandler) Instantiate(w http.ResponseWriter, r *http.Request) {
+	templateId := r.PathValue("templateId")
+	workspaceId := r.PathValue("workspaceId")
+
+	var reqBody instantiateBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	todos, err := h.service.Instantiate(context.Background(), actorFrom(r), templateId, workspaceId, reqBody.Variables, reqBody.StartDate)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": todos})
+}
+
+func NewTemplateHandler(service service.TemplateService) TemplateHandler {
+	return &templateHandler{
+		service: service,
+	}
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+type TimeHandler interface {
+	StartTimer(w http.ResponseWriter, r *http.Request)
+	StopTimer(w http.ResponseWriter, r *http.Request)
+	GetRunningTimer(w http.ResponseWriter, r *http.Request)
+	AddTimeEntry(w http.ResponseWriter, r *http.Request)
+	GetTodoTimeEntries(w http.ResponseWriter, r *http.Request)
+	DeleteTimeEntry(w http.ResponseWriter, r *http.Request)
+	TimeReport(w http.ResponseWriter, r *http.Request)
+	EstimateReport(w http.ResponseWriter, r *http.Request)
+}
+
+type timeHandler struct {
+	service service.TimeService
+}
+
+type startTimerBody struct {
+	Note string `json:"note"`
+}
+
+func (h *timeHandler) StartTimer(w http.ResponseWriter, r *http.Request) {
+	// body is optional here
+	var reqBody startTimerBody
+	json.NewDecoder(r.Body).Decode(&reqBody)
+
+	userId := r.PathValue("userId")
+	todoId := r.PathValue("todoId")
+
+	entry, err := h.service.StartTimer(context.Background(), actorFrom(r), userId, todoId, reqBody.Note)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": entry})
+}
+
+func (h *timeHandler) StopTimer(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	entry, err := h.service.StopTimer(context.Background(), actorFrom(r), userId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": entry})
+}
+
+func (h *timeHandler) GetRunningTimer(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	entry, err := h.service.GetRunningTimer(context.Background(), actorFrom(r), userId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": entry})
+}
+
+type addTimeEntryBody struct {
+	StartedAt time.Time `json:"startedAt"` // RFC 3339
+	Minutes   int       `json:"minutes"`
+	Note      string    `json:"note"`
+}
+
+func (h *timeHandler) AddTimeEntry(w http.ResponseWriter, r *http.Request) {
+	var reqBody addTimeEntryBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	userId := r.PathValue("userId")
+	todoId := r.PathValue("todoId")
+
+	entry, err := h.service.AddTimeEntry(context.Background(), actorFrom(r), userId, todoId, reqBody.StartedAt, reqBody.Minutes, reqBody.Note)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": entry})
+}
+
+func (h *timeHandler) GetTodoTimeEntries(w http.ResponseWriter, r *http.Request) {
+	todoId := r.PathValue("todoId")
+
+	entries, err := h.service.GetTodoTimeEntries(context.Background(), actorFrom(r), todoId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": entries})
+}
+
+func (h *timeHandler) DeleteTimeEntry(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+	entryId := r.PathValue("entryId")
+
+	if err := h.service.DeleteTimeEntry(context.Background(), actorFrom(r), userId, entryId); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"response": "Success Delete Time Entry"})
+}
+
+// TimeReport returns totals grouped by {groupBy} (workspace / priority / day)
+// query: ?from=2025-01-01&to=2025-01-31&tz=Europe/Berlin (to is inclusive)
+func (h *timeHandler) TimeReport(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+	groupBy := r.PathValue("groupBy")
+
+	values := r.URL.Query()
+	loc, err := loadLocation(values.Get("tz"))
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	from, to, err := parseDayRange(values.Get("from"), values.Get("to"), loc)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	rows, err := h.service.TimeReport(context.Background(), actorFrom(r), userId, groupBy, from, to, loc.String())
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": rows})
+}
+
+// EstimateReport compares estimates with tracked time, ?workspaceId= is optional
+func (h *timeHandler) EstimateReport(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+	workspaceId := r.URL.Query().Get("workspaceId")
+
+	rows, err := h.service.EstimateReport(context.Background(), actorFrom(r), userId, workspaceId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": rows})
+}
+
+// loadLocation loads an IANA timezone name, empty means UTC
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.New("invalid timezone " + name)
+	}
+	return loc, nil
+}
+
+// parseDayRange turns from / to days (2006-01-02) into [from, to+1day)
+// missing values default to the last 30 days
+func parseDayRange(fromValue string, toValue string, loc *time.Location) (time.Time, time.Time, error) {
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	to := today
+	if toValue != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", toValue, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("to must look like 2006-01-02")
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, -29)
+	if fromValue != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", fromValue, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("from must look like 2006-01-02")
+		}
+		from = parsed
+	}
+
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+func NewTimeHandler(service service.TimeService) TimeHandler {
+	return &timeHandler{
+		service: service,
+	}
+}
@@ -1,205 +1,388 @@
-// Package handler manages HTTP request handling for todo operations
-package handler
-
-import (
-	"context"
-	"encoding/json"
-	"fmt"
-	"net/http"
-
-	"github.com/ndk123-web/fast-todo/internal/middleware"
-	"github.com/ndk123-web/fast-todo/internal/model"
-	"github.com/ndk123-web/fast-todo/internal/service"
-)
-
-// TodoHandler defines the interface for HTTP request handlers for todo operations
-type TodoHandler interface {
-	GetTodos(w http.ResponseWriter, r *http.Request)
-	CreateTodo(w http.ResponseWriter, r *http.Request)
-	UpdateTodo(w http.ResponseWriter, r *http.Request)
-	DeleteTodo(w http.ResponseWriter, r *http.Request)
-	GetSpecificTodo(w http.ResponseWriter, r *http.Request)
-	ToogleTodo(w http.ResponseWriter, r *http.Request)
-}
-
-// todoHandler implements TodoHandler with a service layer dependency
-type todoHandler struct {
-	service service.TodoService // Service layer for business logic
-}
-
-// NewTodoHandler creates a new instance of TodoHandler with the provided service
-func NewTodoHandler(service service.TodoService) TodoHandler {
-	return &todoHandler{
-		service: service,
-	}
-}
-
-// GetTodos handles HTTP GET requests to retrieve all todo items
-// Returns a JSON array of todos or an error message
-func (h *todoHandler) GetTodos(w http.ResponseWriter, r *http.Request) {
-	todos, err := h.service.GetTodos(context.Background())
-	if err != nil {
-		http.Error(w, "Error fetching todos", http.StatusInternalServerError)
-		return
-	}
-
-	userEmail, ok := r.Context().Value(middleware.UserEmailKey).(string)
-	if !ok {
-		http.Error(w, "UserEmail Not Exists in Email", http.StatusExpectationFailed)
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{"response": todos, "userEmail": userEmail})
-}
-
-type toggleBody struct {
-	Toggle string `json:"toggle"`
-	ID     string `json:"id"`
-	UserId string `json:"userId"`
-}
-
-func (h *todoHandler) ToogleTodo(w http.ResponseWriter, r *http.Request) {
-	// get req body
-	// send to the service
-	var reqBody toggleBody
-	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-		json.NewEncoder(w).Encode(map[string]any{"success": "false", "Error": err.Error()})
-		return
-	}
-
-	ok, err := h.service.ToggleTodo(context.Background(), reqBody.ID, reqBody.Toggle, reqBody.UserId)
-	w.Header().Set("Content-Type", "application/json")
-	if err != nil || !ok {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]any{"response": "false", "error": func() string {
-			if err != nil {
-				return err.Error()
-			}
-			return "toggle failed"
-		}()})
-		return
-	}
-	json.NewEncoder(w).Encode(map[string]any{"response": "true"})
-}
-
-// CreateTodo handles HTTP POST requests to create a new todo item
-// Expects a JSON payload with todo details
-// Returns the created todo or an error message
-func (h *todoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
-
-	// Regex Based to get userId and workspaceID
-	// var todoPath = regexp.MustCompile(`^/api/v1/users/([0-9a-zA-Z\-]+)/create-todo/([0-9a-zA-Z\-]+)$`)
-	// matchers := todoPath.FindStringSubmatch(r.URL.Path)
-
-	// userId := matchers[1]
-	// workspaceId := matchers[2]
-
-	userId := r.PathValue("userId")
-	workspaceId := r.PathValue("workspaceId")
-
-	// debug
-	fmt.Println("User ID:", userId)
-	fmt.Println("Workspace ID:", workspaceId)
-
-	if userId == "" || workspaceId == "" {
-		http.Error(w, "UserId / WorkspaceID is empty", http.StatusUnauthorized)
-		return
-	}
-
-	var todo model.Todo
-	err := json.NewDecoder(r.Body).Decode(&todo)
-
-	if err != nil {
-		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
-		return
-	}
-
-	fmt.Println("Body: ", r.Body)
-
-	todores, todoerr := h.service.CreateTodo(context.Background(), todo, workspaceId, userId)
-
-	if todoerr != nil {
-		json.NewEncoder(w).Encode(map[string]string{"Error": todoerr.Error(), "success": "false"})
-	}
-
-	fmt.Println("Todo Create : ", todores)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{"response": todores, "success": "true"})
-}
-
-// updateTodo represents the request payload for updating a todo
-type updateTodo struct {
-	ID   string `json:"id"`   // ID of the todo to update
-	Task string `json:"task"` // New task text
-	// WorkspaceId string `json:"workspaceId"`  // No Need of workspaceID because ID is already unique
-	Priority string `json:"priority"`
-}
-
-// UpdateTodo handles HTTP PUT requests to update an existing todo
-// Expects a JSON payload with id and updated task
-// Returns the updated todo or an error message
-func (h *todoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
-	var tobeUpdate updateTodo
-	if err := json.NewDecoder(r.Body).Decode(&tobeUpdate); err != nil {
-		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
-		return
-	}
-
-	todo, err2 := h.service.UpdateTodo(context.Background(), tobeUpdate.ID, tobeUpdate.Task, tobeUpdate.Priority)
-	if err2 != nil {
-		json.NewEncoder(w).Encode(map[string]string{"Error": err2.Error(), "success": "false"})
-		return
-	}
-
-	json.NewEncoder(w).Encode(map[string]any{"response": todo, "success": "true"})
-}
-
-// DeleteTodo handles HTTP DELETE requests to remove a todo
-// Expects a JSON payload with the todo ID
-// Returns success status or an error message
-func (h *todoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
-
-	todoId := r.PathValue("todoId")
-	if todoId == "" {
-		json.NewEncoder(w).Encode(map[string]any{"success": "false", "Error": "todoId in params is empty"})
-		return
-	}
-
-	ok, err2 := h.service.DeleteTodo(context.Background(), todoId)
-	if err2 != nil {
-		json.NewEncoder(w).Encode(map[string]string{"error": err2.Error(), "success": "false"})
-	}
-
-	if !ok {
-		json.NewEncoder(w).Encode(map[string]string{"error": "Delete False", "success": "false"})
-	}
-
-	json.NewEncoder(w).Encode(map[string]string{"success": "true"})
-}
-
-func (h *todoHandler) GetSpecificTodo(w http.ResponseWriter, r *http.Request) {
-	// var todoPath = regexp.MustCompile(`^/api/v1/users/([0-9a-zA-Z\-]+)/get-specific-todo/([0-9a-zA-Z\-]+)$`)
-	// matchers := todoPath.FindStringSubmatch(r.URL.Path)
-
-	// if len(matchers) < 3 {
-	// 	http.Error(w, "Invalid URL", http.StatusBadRequest)
-	// 	return
-	// }
-
-	// userId := matchers[1]
-	// workspaceId := matchers[2]
-
-	userId := r.PathValue("userId")
-	workspaceId := r.PathValue("workspaceId")
-
-	fmt.Println("User ID:", userId)
-	fmt.Println("Workspace ID:", workspaceId)
-
-	todo, err := h.service.GetSpecificTodo(context.Background(), workspaceId, userId)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	json.NewEncoder(w).Encode(map[string]any{"response": todo})
-}
+// Package handler manages HTTP request handling for todo operations
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ndk123-web/fast-todo/internal/middleware"
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+// TodoHandler defines the interface for HTTP request handlers for todo operations
+type TodoHandler interface {
+	GetTodos(w http.ResponseWriter, r *http.Request)
+	CreateTodo(w http.ResponseWriter, r *http.Request)
+	UpdateTodo(w http.ResponseWriter, r *http.Request)
+	DeleteTodo(w http.ResponseWriter, r *http.Request)
+	GetSpecificTodo(w http.ResponseWriter, r *http.Request)
+	ToogleTodo(w http.ResponseWriter, r *http.Request)
+	MoveTodo(w http.ResponseWriter, r *http.Request)
+	SetEstimate(w http.ResponseWriter, r *http.Request)
+	BatchTodos(w http.ResponseWriter, r *http.Request)
+	GetMatrix(w http.ResponseWriter, r *http.Request)
+	LinkGoal(w http.ResponseWriter, r *http.Request)
+	AssignTodo(w http.ResponseWriter, r *http.Request)
+	GetAssignedTodos(w http.ResponseWriter, r *http.Request)
+}
+
+// todoHandler implements TodoHandler with a service layer dependency
+type todoHandler struct {
+	service service.TodoService // Service layer for business logic
+}
+
+// NewTodoHandler creates a new instance of TodoHandler with the provided service
+func NewTodoHandler(service service.TodoService) TodoHandler {
+	return &todoHandler{
+		service: service,
+	}
+}
+
+// GetTodos handles HTTP GET requests to retrieve the todos of the caller's workspaces
+// Returns a JSON array of todos or an error message
+func (h *todoHandler) GetTodos(w http.ResponseWriter, r *http.Request) {
+	todos, err := h.service.GetTodos(context.Background(), actorFrom(r))
+	if err != nil {
+		http.Error(w, "Error fetching todos", http.StatusInternalServerError)
+		return
+	}
+
+	userEmail, ok := r.Context().Value(middleware.UserEmailKey).(string)
+	if !ok {
+		http.Error(w, "UserEmail Not Exists in Email", http.StatusExpectationFailed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": todos, "userEmail": userEmail})
+}
+
+type toggleBody struct {
+	Toggle string `json:"toggle"`
+	ID     string `json:"id"`
+	UserId string `json:"userId"`
+}
+
+func (h *todoHandler) ToogleTodo(w http.ResponseWriter, r *http.Request) {
+	// get req body
+	// send to the service
+	var reqBody toggleBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]any{"success": "false", "Error": err.Error()})
+		return
+	}
+
+	ok, err := h.service.ToggleTodo(context.Background(), actorFrom(r), reqBody.ID, reqBody.Toggle)
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil || !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]any{"response": "false", "error": func() string {
+			if err != nil {
+				return err.Error()
+			}
+			return "toggle failed"
+		}()})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"response": "true"})
+}
+
+// CreateTodo handles HTTP POST requests to create a new todo item
+// Expects a JSON payload with todo details
+// Returns the created todo or an error message
+func (h *todoHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
+
+	// Regex Based to get userId and workspaceID
+	// var todoPath = regexp.MustCompile(`^/api/v1/users/([0-9a-zA-Z\-]+)/create-todo/([0-9a-zA-Z\-]+)$`)
+	// matchers := todoPath.FindStringSubmatch(r.URL.Path)
+
+	// userId := matchers[1]
+	// workspaceId := matchers[2]
+
+	userId := r.PathValue("userId")
+	workspaceId := r.PathValue("workspaceId")
+
+	// debug
+	fmt.Println("User ID:", userId)
+	fmt.Println("Workspace ID:", workspaceId)
+
+	if userId == "" || workspaceId == "" {
+		http.Error(w, "UserId / WorkspaceID is empty", http.StatusUnauthorized)
+		return
+	}
+
+	var todo model.Todo
+	err := json.NewDecoder(r.Body).Decode(&todo)
+
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
+		return
+	}
+
+	fmt.Println("Body: ", r.Body)
+
+	todores, todoerr := h.service.CreateTodo(context.Background(), actorFrom(r), todo, workspaceId, userId)
+
+	if todoerr != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": todoerr.Error(), "success": "false"})
+		return
+	}
+
+	fmt.Println("Todo Create : ", todores)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": todores, "success": "true"})
+}
+
+// updateTodo represents the request payload for updating a todo
+type updateTodo struct {
+	ID string `json:"id"` // ID of the todo to update
+	// WorkspaceId string `json:"workspaceId"`  // No Need of workspaceID because ID is already unique
+
+	// task / priority / urgent / important, missing fields stay as they are
+	model.TodoUpdate
+}
+
+// UpdateTodo handles HTTP PUT requests to update an existing todo
+// Expects a JSON payload with id and updated task
+// Returns the updated todo or an error message
+func (h *todoHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
+	var tobeUpdate updateTodo
+	if err := json.NewDecoder(r.Body).Decode(&tobeUpdate); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
+		return
+	}
+
+	todo, err2 := h.service.UpdateTodo(context.Background(), actorFrom(r), tobeUpdate.ID, tobeUpdate.TodoUpdate)
+	if err2 != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err2.Error(), "success": "false"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"response": todo, "success": "true"})
+}
+
+// DeleteTodo handles HTTP DELETE requests to remove a todo
+// Expects a JSON payload with the todo ID
+// Returns success status or an error message
+func (h *todoHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
+
+	todoId := r.PathValue("todoId")
+	if todoId == "" {
+		json.NewEncoder(w).Encode(map[string]any{"success": "false", "Error": "todoId in params is empty"})
+		return
+	}
+
+	ok, err2 := h.service.DeleteTodo(context.Background(), actorFrom(r), todoId)
+	if err2 != nil {
+		json.NewEncoder(w).Encode(map[string]string{"error": err2.Error(), "success": "false"})
+		return
+	}
+
+	if !ok {
+		json.NewEncoder(w).Encode(map[string]string{"error": "Delete False", "success": "false"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"success": "true"})
+}
+
+func (h *todoHandler) GetSpecificTodo(w http.ResponseWriter, r *http.Request) {
+	// var todoPath = regexp.MustCompile(`^/api/v1/users/([0-9a-zA-Z\-]+)/get-specific-todo/([0-9a-zA-Z\-]+)$`)
+	// matchers := todoPath.FindStringSubmatch(r.URL.Path)
+
+	// if len(matchers) < 3 {
+	// 	http.Error(w, "Invalid URL", http.StatusBadRequest)
+	// 	return
+	// }
+
+	// userId := matchers[1]
+	// workspaceId := matchers[2]
+
+	userId := r.PathValue("userId")
+	workspaceId := r.PathValue("workspaceId")
+
+	fmt.Println("User ID:", userId)
+	fmt.Println("Workspace ID:", workspaceId)
+
+	// ?sort=priority puts high priority first, default is the manual order
+	todo, err := h.service.GetSpecificTodo(context.Background(), actorFrom(r), workspaceId, userId, r.URL.Query().Get("sort"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"response": todo})
+}
+
+// moveTodoBody is the request payload for moving a todo
+type moveTodoBody struct {
+	AfterId  string `json:"afterId"`  // todo right above the new position
+	BeforeId string `json:"beforeId"` // todo right below the new position
+	Status   string `json:"status"`   // optional "completed" / "not-started" when moved to another column
+}
+
+// MoveTodo handles HTTP PUT requests to reorder a todo
+// Returns the moved todo with its new rank
+func (h *todoHandler) MoveTodo(w http.ResponseWriter, r *http.Request) {
+	todoId := r.PathValue("todoId")
+	if todoId == "" {
+		json.NewEncoder(w).Encode(map[string]any{"success": "false", "Error": "todoId in params is empty"})
+		return
+	}
+
+	var reqBody moveTodoBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
+		return
+	}
+
+	if reqBody.AfterId == "" && reqBody.BeforeId == "" {
+		json.NewEncoder(w).Encode(map[string]string{"Error": "afterId / beforeId both empty", "success": "false"})
+		return
+	}
+
+	todo, err := h.service.MoveTodo(context.Background(), actorFrom(r), todoId, reqBody.AfterId, reqBody.BeforeId, reqBody.Status)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": todo, "success": "true"})
+}
+
+// estimateBody is the request payload for setting an estimate
+type estimateBody struct {
+	EstimateMinutes int `json:"estimateMinutes"` // 0 removes the estimate
+}
+
+// SetEstimate handles HTTP PUT requests to set the estimate of a todo
+func (h *todoHandler) SetEstimate(w http.ResponseWriter, r *http.Request) {
+	todoId := r.PathValue("todoId")
+
+	var reqBody estimateBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
+		return
+	}
+
+	todo, err := h.service.SetEstimate(context.Background(), actorFrom(r), todoId, reqBody.EstimateMinutes)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": todo, "success": "true"})
+}
+
+// batchBody is the request payload for the batch endpoint
+type batchBody struct {
+	Mode string          `json:"mode"` // "atomic" / "best-effort"
+	Ops  []model.BatchOp `json:"ops"`
+}
+
+// BatchTodos handles HTTP POST requests with many todo operations at once
+// Returns a result for every item in the order they were sent
+func (h *todoHandler) BatchTodos(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	var reqBody batchBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
+		return
+	}
+
+	response, err := h.service.BatchTodos(context.Background(), actorFrom(r), userId, reqBody.Mode, reqBody.Ops)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": response, "success": "true"})
+}
+
+// GetMatrix handles HTTP GET requests for the Eisenhower matrix of a workspace
+// Returns the open todos grouped into doFirst / schedule / delegate / eliminate
+func (h *todoHandler) GetMatrix(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+	workspaceId := r.PathValue("workspaceId")
+
+	matrix, err := h.service.GetMatrix(context.Background(), actorFrom(r), workspaceId, userId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": matrix, "success": "true"})
+}
+
+// linkGoalBody is the request payload for linking a todo to a goal
+type linkGoalBody struct {
+	GoalId string `json:"goalId"` // empty removes the link
+}
+
+// LinkGoal handles HTTP PUT requests to make a todo count towards a goal
+func (h *todoHandler) LinkGoal(w http.ResponseWriter, r *http.Request) {
+	todoId := r.PathValue("todoId")
+
+	var reqBody linkGoalBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
+		return
+	}
+
+	todo, err := h.service.LinkGoal(context.Background(), actorFrom(r), todoId, reqBody.GoalId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": todo, "success": "true"})
+}
+
+// assignTodoBody is the request payload for assigning a todo
+type assignTodoBody struct {
+	AssigneeIds []string `json:"assigneeIds"` // members of the workspace, empty unassigns everyone
+}
+
+// AssignTodo handles HTTP PUT requests to replace the assignees of a todo
+func (h *todoHandler) AssignTodo(w http.ResponseWriter, r *http.Request) {
+	todoId := r.PathValue("todoId")
+
+	var reqBody assignTodoBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
+		return
+	}
+
+	todo, err := h.service.AssignTodo(context.Background(), actorFrom(r), todoId, reqBody.AssigneeIds)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": todo, "success": "true"})
+}
+
+// GetAssignedTodos handles HTTP GET requests for the open todos assigned to
+// the user in all of the user's workspaces
+func (h *todoHandler) GetAssignedTodos(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	todos, err := h.service.GetAssignedTodos(context.Background(), actorFrom(r), userId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error(), "success": "false"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": todos, "success": "true"})
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+type TransferHandler interface {
+	MoveTodos(w http.ResponseWriter, r *http.Request)
+	CopyTodos(w http.ResponseWriter, r *http.Request)
+	MoveGoals(w http.ResponseWriter, r *http.Request)
+	CopyGoals(w http.ResponseWriter, r *http.Request)
+}
+
+type transferHandler struct {
+	service service.TransferService
+}
+
+// transferBody is the request payload for moving / copying items
+type transferBody struct {
+	Ids         []string `json:"ids"`         // selected todos / goals, subtasks come along
+	WorkspaceId string   `json:"workspaceId"` // target workspace
+}
+
+func (h *transferHandler) MoveTodos(w http.ResponseWriter, r *http.Request) {
+	h.transfer(w, r, func(ctx context.Context, actor service.Actor, body transferBody) (any, error) {
+		return h.service.MoveTodos(ctx, actor, body.Ids, body.WorkspaceId)
+	})
+}
+
+func (h *transferHandler) CopyTodos(w http.ResponseWriter, r *http.Request) {
+	h.transfer(w, r, func(ctx context.Context, actor service.Actor, body transferBody) (any, error) {
+		return h.service.CopyTodos(ctx, actor, body.Ids, body.WorkspaceId)
+	})
+}
+
+func (h *transferHandler) MoveGoals(w http.ResponseWriter, r *http.Request) {
+	h.transfer(w, r, func(ctx context.Context, actor service.Actor, body transferBody) (any, error) {
+		return h.service.MoveGoals(ctx, actor, body.Ids, body.WorkspaceId)
+	})
+}
+
+func (h *transferHandler) CopyGoals(w http.ResponseWriter, r *http.Request) {
+	h.transfer(w, r, func(ctx context.Context, actor service.Actor, body transferBody) (any, error) {
+		return h.service.CopyGoals(ctx, actor, body.Ids, body.WorkspaceId)
+	})
+}
+
+// transfer decodes the body and writes the moved / copied items
+func (h *transferHandler) transfer(w http.ResponseWriter, r *http.Request, run func(ctx context.Context, actor service.Actor, body transferBody) (any, error)) {
+	var reqBody transferBody
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	items, err := run(context.Background(), actorFrom(r), reqBody)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": items})
+}
+
+func NewTransferHandler(service service.TransferService) TransferHandler {
+	return &transferHandler{
+		service: service,
+	}
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/ndk123-web/fast-todo/internal/service"
+)
+
+type TrashHandler interface {
+	GetTrash(w http.ResponseWriter, r *http.Request)
+	RestoreTodo(w http.ResponseWriter, r *http.Request)
+	RestoreGoal(w http.ResponseWriter, r *http.Request)
+	RestoreWorkspace(w http.ResponseWriter, r *http.Request)
+}
+
+type trashHandler struct {
+	service service.TrashService
+}
+
+// GetTrash returns the deleted todos, goals and workspaces the user can restore
+func (h *trashHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("userId")
+
+	trash, err := h.service.GetTrash(context.Background(), actorFrom(r), userId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": trash})
+}
+
+func (h *trashHandler) RestoreTodo(w http.ResponseWriter, r *http.Request) {
+	todoId := r.PathValue("todoId")
+
+	todo, err := h.service.RestoreTodo(context.Background(), actorFrom(r), todoId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": todo})
+}
+
+func (h *trashHandler) RestoreGoal(w http.ResponseWriter, r *http.Request) {
+	goalId := r.PathValue("goalId")
+
+	goal, err := h.service.RestoreGoal(context.Background(), actorFrom(r), goalId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": goal})
+}
+
+func (h *trashHandler) RestoreWorkspace(w http.ResponseWriter, r *http.Request) {
+	workspaceId := r.PathValue("workspaceId")
+
+	workspace, err := h.service.RestoreWorkspace(context.Background(), actorFrom(r), workspaceId)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]string{"Error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"response": workspace})
+}
+
+func NewTrashHandler(service service.TrashService) TrashHandler {
+	return &trashHandler{
+		service: service,
+	}
+}
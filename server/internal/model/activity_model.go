@@ -0,0 +1,104 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// activity types of the workspace feed
+const (
+	ActivityTodoCreated   = "todo-created"
+	ActivityTodoUpdated   = "todo-updated"
+	ActivityTodoCompleted = "todo-completed"
+	ActivityTodoReopened  = "todo-reopened"
+	ActivityTodoDeleted   = "todo-deleted"
+	ActivityTodoAssigned  = "todo-assigned"
+	ActivityTodoMoved     = "todo-moved"
+	ActivityTodoCopied    = "todo-copied"
+	ActivityTodoRestored  = "todo-restored"
+	ActivityTodoReverted  = "todo-reverted"
+
+	ActivityGoalCreated   = "goal-created"
+	ActivityGoalUpdated   = "goal-updated"
+	ActivityGoalDeleted   = "goal-deleted"
+	ActivityGoalProgress  = "goal-progress" // a value or milestone was recorded
+	ActivityGoalCheckedIn = "goal-checked-in"
+	ActivityGoalMoved     = "goal-moved"
+	ActivityGoalCopied    = "goal-copied"
+	ActivityGoalRestored  = "goal-restored"
+	ActivityGoalReverted  = "goal-reverted"
+
+	ActivityWorkspaceCreated  = "workspace-created"
+	ActivityWorkspaceRenamed  = "workspace-renamed"
+	ActivityWorkspaceDeleted  = "workspace-deleted"
+	ActivityWorkspaceRestored = "workspace-restored"
+	ActivityWorkspaceReverted = "workspace-reverted"
+
+	ActivityMemberAdded       = "member-added"
+	ActivityMemberJoined      = "member-joined" // through an invitation
+	ActivityMemberRoleChanged = "member-role-changed"
+	ActivityMemberRemoved     = "member-removed"
+	ActivityMemberLeft        = "member-left"
+)
+
+// EntityMember is the entity of member activities, EntityId is the member
+const EntityMember = "member"
+
+// ActivityTypes lists every type, used to validate feed filters
+var ActivityTypes = []string{
+	ActivityTodoCreated, ActivityTodoUpdated, ActivityTodoCompleted, ActivityTodoReopened, ActivityTodoDeleted, ActivityTodoAssigned,
+	ActivityTodoMoved, ActivityTodoCopied, ActivityTodoRestored, ActivityTodoReverted,
+	ActivityGoalCreated, ActivityGoalUpdated, ActivityGoalDeleted, ActivityGoalProgress, ActivityGoalCheckedIn,
+	ActivityGoalMoved, ActivityGoalCopied, ActivityGoalRestored, ActivityGoalReverted,
+	ActivityWorkspaceCreated, ActivityWorkspaceRenamed, ActivityWorkspaceDeleted, ActivityWorkspaceRestored, ActivityWorkspaceReverted,
+	ActivityMemberAdded, ActivityMemberJoined, ActivityMemberRoleChanged, ActivityMemberRemoved, ActivityMemberLeft,
+}
+
+// Activity is one entry of the feed of a workspace, like
+// "Alice completed 'Deploy'". Names are kept as they were at the time
+type Activity struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	WorkspaceId primitive.ObjectID `bson:"workspaceId" json:"workspaceId"`
+	Type        string             `bson:"type" json:"type"`
+
+	// position in the feed of the workspace, 1, 2, 3.. in commit order
+	Seq int64 `bson:"seq" json:"seq"`
+
+	ActorId   primitive.ObjectID `bson:"actorId,omitempty" json:"actorId,omitempty"`
+	ActorName string             `bson:"actorName,omitempty" json:"actorName,omitempty"`
+
+	// the todo / goal / workspace / member the activity is about
+	EntityType string             `bson:"entityType" json:"entityType"`
+	EntityId   primitive.ObjectID `bson:"entityId,omitempty" json:"entityId,omitempty"`
+	Title      string             `bson:"title,omitempty" json:"title,omitempty"`
+
+	// extra facts of the type, like the old name of a rename, the new role or
+	// the other workspace of a move
+	Details map[string]any `bson:"details,omitempty" json:"details,omitempty"`
+
+	Message   string    `bson:"message" json:"message"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// ActivityQuery filters and pages the feed. Before pages back from a Seq,
+// Since polls for activities after a Seq and SinceTime for the ones created
+// from a time on, 0 / zero is not set
+type ActivityQuery struct {
+	ActorId   primitive.ObjectID
+	Types     []string
+	Before    int64
+	Since     int64
+	SinceTime time.Time
+	Limit     int
+}
+
+// ActivityPage is one page of the feed. Without Since the activities are
+// newest first and Cursor is the Before of the next page, with Since they are
+// oldest first and Cursor is the Since of the next poll. Cursor is the Seq of
+// an activity, a poll by time that found nothing has none
+type ActivityPage struct {
+	Activities []Activity `json:"activities"`
+	Cursor     string     `json:"cursor,omitempty"`
+	HasMore    bool       `json:"hasMore"`
+}
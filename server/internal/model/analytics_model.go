@@ -0,0 +1,42 @@
+package model
+
+// DayCount is one group of a per day count, Day is 2006-01-02
+type DayCount struct {
+	Day   string `bson:"_id" json:"day"`
+	Count int    `bson:"count" json:"count"`
+}
+
+// HeatmapDay is one cell of the contribution heatmap, days without
+// activity are included with zero counts
+type HeatmapDay struct {
+	Day      string `json:"day"`
+	Todos    int    `json:"todos"`    // todos completed that day
+	CheckIns int    `json:"checkIns"` // goal check-ins of that day
+	Total    int    `json:"total"`
+}
+
+// CategoryStats is the completion rate of the live goals of one category
+type CategoryStats struct {
+	Category string  `bson:"_id" json:"category"`
+	Goals    int     `bson:"goals" json:"goals"`
+	Done     int     `bson:"done" json:"done"`
+	Rate     float64 `bson:"rate" json:"rate"` // Done / Goals
+}
+
+// WeekdayStats is the activity of one weekday inside a range, Average is
+// Total divided by how often the weekday occurs in the range
+type WeekdayStats struct {
+	Weekday string  `json:"weekday"`
+	Days    int     `json:"days"`
+	Total   int     `json:"total"`
+	Average float64 `json:"average"`
+}
+
+// GoalStats are the completion statistics of a user, best and worst weekday
+// compare the averages
+type GoalStats struct {
+	Categories   []CategoryStats `json:"categories"`
+	Weekdays     []WeekdayStats  `json:"weekdays"`
+	BestWeekday  string          `json:"bestWeekday"`
+	WorstWeekday string          `json:"worstWeekday"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Attachment struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	TodoId primitive.ObjectID `bson:"todoId" json:"todoId"`
+	UserId primitive.ObjectID `bson:"userId" json:"userId"`
+
+	FileName string `bson:"fileName" json:"fileName"`
+
+	// ContentType is sniffed from the file content, not taken from the client
+	ContentType string `bson:"contentType" json:"contentType"`
+	Size        int64  `bson:"size" json:"size"`
+
+	// key of the content inside the BlobStore, never sent to the client
+	StorageKey string `bson:"storageKey" json:"-"`
+
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
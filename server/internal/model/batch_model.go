@@ -0,0 +1,38 @@
+package model
+
+// batch modes
+const (
+	BatchAtomic     = "atomic"      // all items are written or none
+	BatchBestEffort = "best-effort" // failing items do not stop the others
+)
+
+// BatchOp is one item of a batch request, Op is create / update / toggle / delete
+type BatchOp struct {
+	Op          string `json:"op"`
+	TodoId      string `json:"todoId,omitempty"`      // update / toggle / delete
+	WorkspaceId string `json:"workspaceId,omitempty"` // create
+	Task        string `json:"task,omitempty"`        // create / update
+	Priority    string `json:"priority,omitempty"`    // create / update
+	Toggle      string `json:"toggle,omitempty"`      // toggle: "completed" / "not-started"
+
+	// set by the service for creates
+	Rank string `json:"-"`
+}
+
+// BatchResult is the outcome of the item at Index, Status is
+// "ok", "failed" or "skipped" (not written because the atomic batch failed)
+type BatchResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	TodoId string `json:"todoId,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchResponse counts applied and failed items, skipped ones are in neither
+type BatchResponse struct {
+	Mode    string        `json:"mode"`
+	Applied int           `json:"applied"`
+	Failed  int           `json:"failed"`
+	Results []BatchResult `json:"results"`
+}
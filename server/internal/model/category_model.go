@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GoalCategory groups the goals of a user. Key is the lower cased name, so
+// "Fitness" and "fitness" are the same category
+type GoalCategory struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	UserId primitive.ObjectID `bson:"userId" json:"userId"`
+	Name   string             `bson:"name" json:"name"`
+	Key    string             `bson:"key" json:"-"`
+	Color  string             `bson:"color,omitempty" json:"color,omitempty"` // #RRGGBB
+	Icon   string             `bson:"icon,omitempty" json:"icon,omitempty"`   // icon name or emoji
+
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// CategoryRollup sums up the live goals of one category, goals without a
+// category are rolled up under a category with a zero id.
+// Streaks only count while they are still running
+type CategoryRollup struct {
+	Category        GoalCategory `json:"category"`
+	TotalGoals      int          `json:"totalGoals"`
+	ActiveGoals     int          `json:"activeGoals"`
+	DoneGoals       int          `json:"doneGoals"`
+	CompletionRate  float64      `json:"completionRate"`  // DoneGoals / TotalGoals
+	AverageProgress float64      `json:"averageProgress"` // of all goals, 0 to 1
+	RunningStreaks  int          `json:"runningStreaks"`  // goals with a running streak
+	BestStreak      int          `json:"bestStreak"`      // longest running streak
+	LongestStreak   int          `json:"longestStreak"`   // longest streak ever
+}
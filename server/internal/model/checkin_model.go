@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GoalCheckIn logs that the goal was worked on that day, one per goal and day
+type GoalCheckIn struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	GoalId primitive.ObjectID `bson:"goalId" json:"goalId"`
+	UserId primitive.ObjectID `bson:"userId" json:"userId"`
+
+	// 2006-01-02 in the user's timezone
+	Day  string `bson:"day" json:"day"`
+	Note string `bson:"note,omitempty" json:"note,omitempty"`
+
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// GoalProgress is a goal with its check-ins, CurrentStreak is 0 once a day
+// was missed
+type GoalProgress struct {
+	Goal          Goals         `json:"goal"`
+	CheckIns      []GoalCheckIn `json:"checkIns"`
+	CurrentStreak int           `json:"currentStreak"`
+	LongestStreak int           `json:"longestStreak"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Comment struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	TodoId primitive.ObjectID `bson:"todoId" json:"todoId"`
+	UserId primitive.ObjectID `bson:"userId" json:"userId"`
+
+	// Body is the markdown written by the user
+	// BodyHTML is the sanitized html rendered on the server
+	Body     string `bson:"body" json:"body"`
+	BodyHTML string `bson:"bodyHtml" json:"bodyHtml"`
+
+	// users resolved from @mentions in the body
+	Mentions []primitive.ObjectID `bson:"mentions,omitempty" json:"mentions,omitempty"`
+
+	Edited    bool      `bson:"edited" json:"edited"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
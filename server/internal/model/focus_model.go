@@ -0,0 +1,58 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// focus session states
+const (
+	FocusRunning   = "running"
+	FocusPaused    = "paused"
+	FocusCompleted = "completed"
+)
+
+// focus session phases, derived from the focused time
+const (
+	FocusPhaseWork  = "work"
+	FocusPhaseBreak = "break"
+	FocusPhaseOver  = "over" // work and break are both used up
+)
+
+// FocusSession is one pomodoro on a todo: WorkMinutes of focus followed by
+// a BreakMinutes break. Paused time does not count as focus
+type FocusSession struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	UserId      primitive.ObjectID `bson:"userId" json:"userId"`
+	TodoId      primitive.ObjectID `bson:"todoId" json:"todoId"`
+	WorkspaceId primitive.ObjectID `bson:"workspaceId" json:"workspaceId"`
+
+	WorkMinutes  int `bson:"workMinutes" json:"workMinutes"`
+	BreakMinutes int `bson:"breakMinutes" json:"breakMinutes"`
+
+	State     string     `bson:"state" json:"state"`
+	StartedAt time.Time  `bson:"startedAt" json:"startedAt"`
+	PausedAt  *time.Time `bson:"pausedAt,omitempty" json:"pausedAt,omitempty"`
+
+	// total time spent paused, without the current pause
+	PausedSeconds int64 `bson:"pausedSeconds" json:"pausedSeconds"`
+
+	// only one running / paused session per user (unique partial index)
+	Active bool `bson:"active" json:"active"`
+
+	// filled on complete, capped at WorkMinutes
+	CompletedAt  *time.Time `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+	FocusSeconds int64      `bson:"focusSeconds" json:"focusSeconds"`
+
+	// not stored, computed when the session is returned
+	Phase            string `bson:"-" json:"phase,omitempty"`
+	RemainingSeconds int64  `bson:"-" json:"remainingSeconds"`
+}
+
+// FocusStatsRow is the focus of one day / week
+type FocusStatsRow struct {
+	Key          string `bson:"_id" json:"key"` // 2006-01-02 or 2006-W01
+	Sessions     int    `bson:"sessions" json:"sessions"`
+	FocusSeconds int64  `bson:"focusSeconds" json:"focusSeconds"`
+}
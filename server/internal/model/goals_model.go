@@ -1,14 +1,143 @@
-package model
-
-import "go.mongodb.org/mongo-driver/bson/primitive"
-
-type Goals struct {
-	ID            primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
-	Title         string             `bson:"title" json:"title"`
-	UserId        primitive.ObjectID `bson:"userId" json:"userId"`
-	TargetDays    int                `bson:"targetDays" json:"targetDays"`
-	Category      string             `bson:"category" json:"category"`
-	Done          bool               `bson:"done" json:"done"`
-	CurrentTarget int                `bson:"currentTarget" json:"currentTarget"`
-	WorkspaceId   primitive.ObjectID `bson:"workspaceId" json:"workspaceId"`
-}
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Goals struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"_id"`
+	Title         string             `bson:"title" json:"title"`
+	UserId        primitive.ObjectID `bson:"userId" json:"userId"`
+	TargetDays    int                `bson:"targetDays" json:"targetDays"`
+	Category      string             `bson:"category" json:"category"`
+	Done          bool               `bson:"done" json:"done"`
+	CurrentTarget int                `bson:"currentTarget" json:"currentTarget"`
+	WorkspaceId   primitive.ObjectID `bson:"workspaceId" json:"workspaceId"`
+
+	// the managed category, Category holds its name
+	CategoryId primitive.ObjectID `bson:"categoryId,omitempty" json:"categoryId,omitempty"`
+
+	// one of the goal types, empty is GoalTypeDays (TargetDays check-ins)
+	Type string `bson:"type,omitempty" json:"type,omitempty"`
+
+	// habit goals are checked in Frequency.Times per period and never end,
+	// PeriodCheckIns counts the check-ins of the period from PeriodStart
+	Frequency      *GoalFrequency `bson:"frequency,omitempty" json:"frequency,omitempty"`
+	PeriodStart    string         `bson:"periodStart,omitempty" json:"periodStart,omitempty"`
+	PeriodCheckIns int            `bson:"periodCheckIns,omitempty" json:"periodCheckIns,omitempty"`
+
+	// numeric goals are done once CurrentValue reaches TargetValue
+	Unit         string  `bson:"unit,omitempty" json:"unit,omitempty"`
+	TargetValue  float64 `bson:"targetValue,omitempty" json:"targetValue,omitempty"`
+	CurrentValue float64 `bson:"currentValue,omitempty" json:"currentValue,omitempty"`
+
+	// milestone goals are done once every milestone is, kept in order
+	Milestones []Milestone `bson:"milestones,omitempty" json:"milestones,omitempty"`
+
+	// OKRs: a key result points to its objective (same workspace), Weight is
+	// its share in the objective's progress (0 counts as 1). Period scopes the
+	// goal to 2026 / 2026-H1 / 2026-Q4 / 2026-10, Score is the final score of
+	// an objective once its period is over
+	ParentId primitive.ObjectID `bson:"parentId,omitempty" json:"parentId,omitempty"`
+	Weight   float64            `bson:"weight,omitempty" json:"weight,omitempty"`
+	Period   string             `bson:"period,omitempty" json:"period,omitempty"`
+	Score    *float64           `bson:"score,omitempty" json:"score,omitempty"`
+
+	// optional last day (2006-01-02 in the user's timezone) to reach the
+	// target, habit goals have none
+	Deadline string `bson:"deadline,omitempty" json:"deadline,omitempty"`
+
+	// 0 to 1, not stored, computed per type when the goal is returned
+	Progress float64 `bson:"-" json:"progress"`
+
+	// not stored, set for goals with a deadline
+	Forecast *GoalForecast `bson:"-" json:"forecast,omitempty"`
+
+	// where CurrentTarget comes from, GoalProgressCheckIns (default) or
+	// GoalProgressTodos (completed linked todos)
+	ProgressMode string `bson:"progressMode,omitempty" json:"progressMode,omitempty"`
+
+	// kept up to date by the daily check-ins, days are 2006-01-02 in the
+	// user's timezone. CurrentStreak is as of LastCheckIn
+	CurrentStreak int        `bson:"currentStreak" json:"currentStreak"`
+	LongestStreak int        `bson:"longestStreak" json:"longestStreak"`
+	LastCheckIn   string     `bson:"lastCheckIn,omitempty" json:"lastCheckIn,omitempty"`
+	CompletedAt   *time.Time `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+
+	// set when the goal is in the trash
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+}
+
+// goal types
+const (
+	GoalTypeDays      = "days"
+	GoalTypeHabit     = "habit"
+	GoalTypeNumeric   = "numeric"
+	GoalTypeMilestone = "milestone"
+	GoalTypeObjective = "objective" // progress rolls up from its key results
+)
+
+// forecast statuses of a goal with a deadline
+const (
+	GoalOnTrack  = "on-track"
+	GoalAtRisk   = "at-risk"
+	GoalOffTrack = "off-track"
+)
+
+// GoalForecast projects when a goal is reached at its pace so far. Velocity
+// and RequiredVelocity are in the goal's own units (check-ins, the numeric
+// unit, milestones) per day
+type GoalForecast struct {
+	Status           string  `json:"status"`
+	ProjectedDate    string  `json:"projectedDate,omitempty"` // empty while there is no progress
+	DaysLeft         int     `json:"daysLeft"`                // until the deadline, negative once it passed
+	Velocity         float64 `json:"velocity"`
+	RequiredVelocity float64 `json:"requiredVelocity"` // to make the deadline from today on
+}
+
+// GoalFrequency is how often a habit is done, Period is day / week / month
+type GoalFrequency struct {
+	Times  int    `bson:"times" json:"times"`
+	Period string `bson:"period" json:"period"`
+}
+
+// Milestone is one step of a milestone goal
+type Milestone struct {
+	ID     primitive.ObjectID `bson:"_id" json:"_id"`
+	Title  string             `bson:"title" json:"title"`
+	Done   bool               `bson:"done" json:"done"`
+	DoneAt *time.Time         `bson:"doneAt,omitempty" json:"doneAt,omitempty"`
+}
+
+// GoalUpdate is the editable part of a goal, fields that do not belong to
+// the goal's type are ignored
+type GoalUpdate struct {
+	Title       string
+	Category    string
+	TargetDays  int
+	Frequency   *GoalFrequency
+	Unit        string
+	TargetValue float64
+}
+
+// goal progress modes
+const (
+	GoalProgressCheckIns = "check-ins"
+	GoalProgressTodos    = "todos"
+)
+
+// GoalTodoCount counts the live todos linked to a goal
+type GoalTodoCount struct {
+	GoalId primitive.ObjectID `bson:"_id"`
+	Done   int                `bson:"done"`
+	Total  int                `bson:"total"`
+}
+
+// GoalDetail is a goal with the todos that contribute to it
+type GoalDetail struct {
+	Goal        Goals  `json:"goal"`
+	OpenTodos   []Todo `json:"openTodos"`
+	ClosedTodos []Todo `json:"closedTodos"`
+}
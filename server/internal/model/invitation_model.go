@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Invitation lets people join a workspace with a preset role. An invitation
+// with an email is for that person only, one without is a shareable link
+// that can be used MaxUses times
+type Invitation struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	WorkspaceId primitive.ObjectID `bson:"workspaceId" json:"workspaceId"`
+	InvitedBy   primitive.ObjectID `bson:"invitedBy" json:"invitedBy"`
+	Email       string             `bson:"email,omitempty" json:"email,omitempty"` // lower cased
+	Role        string             `bson:"role" json:"role"`
+
+	// only a hash of the token is stored, the token itself is returned once
+	// when the invitation is created
+	TokenHash string `bson:"tokenHash" json:"-"`
+	Token     string `bson:"-" json:"token,omitempty"`
+
+	MaxUses    int                  `bson:"maxUses" json:"maxUses"`
+	Uses       int                  `bson:"uses" json:"uses"`
+	AcceptedBy []primitive.ObjectID `bson:"acceptedBy,omitempty" json:"acceptedBy,omitempty"`
+
+	ExpiresAt time.Time  `bson:"expiresAt" json:"expiresAt"`
+	RevokedAt *time.Time `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	CreatedAt time.Time  `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,9 @@
+package model
+
+// Matrix groups open todos into the four Eisenhower quadrants
+type Matrix struct {
+	DoFirst   []Todo `json:"doFirst"`   // urgent and important
+	Schedule  []Todo `json:"schedule"`  // important, not urgent
+	Delegate  []Todo `json:"delegate"`  // urgent, not important
+	Eliminate []Todo `json:"eliminate"` // neither
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// notification types
+const (
+	NotificationSnoozeWoke      = "snooze-woke"      // a snoozed todo is back in its list
+	NotificationWorkspaceInvite = "workspace-invite" // the user is invited to a workspace
+)
+
+// Notification is a message for a user, shown until it is read
+type Notification struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	UserId  primitive.ObjectID `bson:"userId" json:"userId"`
+	Type    string             `bson:"type" json:"type"`
+	Message string             `bson:"message" json:"message"`
+
+	// the todo the notification is about, if any
+	TodoId primitive.ObjectID `bson:"todoId,omitempty" json:"todoId,omitempty"`
+
+	// the invitation the notification is about, if any
+	InvitationId primitive.ObjectID `bson:"invitationId,omitempty" json:"invitationId,omitempty"`
+
+	Read      bool      `bson:"read" json:"read"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
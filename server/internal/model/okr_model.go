@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OKRNode is a goal with its key results, nested objectives have their own
+type OKRNode struct {
+	Goal     Goals     `json:"goal"`
+	Children []OKRNode `json:"children"`
+}
+
+// OKRScore is a scoring of an objective (0 to 1). Scores can be taken any
+// time, the Final one is taken once the period is over
+type OKRScore struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	GoalId      primitive.ObjectID `bson:"goalId" json:"goalId"`
+	UserId      primitive.ObjectID `bson:"userId" json:"userId"`
+	WorkspaceId primitive.ObjectID `bson:"workspaceId" json:"workspaceId"`
+	Period      string             `bson:"period,omitempty" json:"period,omitempty"`
+
+	Score      float64          `bson:"score" json:"score"`
+	KeyResults []KeyResultScore `bson:"keyResults" json:"keyResults"`
+	Final      bool             `bson:"final" json:"final"`
+	ScoredAt   time.Time        `bson:"scoredAt" json:"scoredAt"`
+}
+
+// KeyResultScore is the progress of one key result when it was scored
+type KeyResultScore struct {
+	GoalId primitive.ObjectID `bson:"goalId" json:"goalId"`
+	Title  string             `bson:"title" json:"title"`
+	Weight float64            `bson:"weight" json:"weight"`
+	Score  float64            `bson:"score" json:"score"`
+}
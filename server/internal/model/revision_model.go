@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// entity types that keep a revision log
+const (
+	EntityTodo      = "todo"
+	EntityGoal      = "goal"
+	EntityWorkspace = "workspace"
+)
+
+// Revision is one append-only entry of the change history of an entity
+type Revision struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	EntityType string             `bson:"entityType" json:"entityType"`
+	EntityId   primitive.ObjectID `bson:"entityId" json:"entityId"`
+
+	// Version counts from 1 for every entity
+	Version int `bson:"version" json:"version"`
+
+	// "update" or "revert", RevertedTo is the version a revert went back to,
+	// 0 is the state before the first revision
+	Action     string `bson:"action" json:"action"`
+	RevertedTo *int   `bson:"revertedTo,omitempty" json:"revertedTo,omitempty"`
+
+	ActorId    primitive.ObjectID `bson:"actorId,omitempty" json:"actorId,omitempty"`
+	ActorEmail string             `bson:"actorEmail,omitempty" json:"actorEmail,omitempty"`
+
+	Changes   []FieldChange `bson:"changes" json:"changes"`
+	CreatedAt time.Time     `bson:"createdAt" json:"createdAt"`
+}
+
+// FieldChange is the old and new value of one field
+type FieldChange struct {
+	Field string `bson:"field" json:"field"`
+	Old   any    `bson:"old" json:"old"`
+	New   any    `bson:"new" json:"new"`
+}
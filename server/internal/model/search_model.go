@@ -0,0 +1,16 @@
+package model
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// SearchResult is a todo, goal or workspace matching a search query
+type SearchResult struct {
+	Type          string             `json:"type"` // EntityTodo / EntityGoal / EntityWorkspace
+	ID            primitive.ObjectID `json:"_id"`
+	Title         string             `json:"title"`
+	Highlight     string             `json:"highlight"` // escaped title with <mark> around matches
+	Score         float64            `json:"score"`
+	WorkspaceId   primitive.ObjectID `json:"workspaceId,omitempty"`
+	WorkspaceName string             `json:"workspaceName,omitempty"`
+	Priority      string             `json:"priority,omitempty"`
+	Done          bool               `json:"done"`
+}
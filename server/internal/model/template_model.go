@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Template is a named set of todos that can be created in a workspace at once
+type Template struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	UserId primitive.ObjectID `bson:"userId" json:"userId"`
+	Name   string             `bson:"name" json:"name"`
+	Items  []TemplateItem     `bson:"items" json:"items"`
+
+	// {{variables}} used by the items, filled when the template is saved
+	Variables []string `bson:"variables" json:"variables"`
+
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// TemplateItem is one todo of a template, subtasks are nested in it.
+// Task and labels can contain {{variables}}
+type TemplateItem struct {
+	Task            string   `bson:"task" json:"task"`
+	Priority        string   `bson:"priority" json:"priority"`
+	Labels          []string `bson:"labels,omitempty" json:"labels,omitempty"`
+	EstimateMinutes int      `bson:"estimateMinutes,omitempty" json:"estimateMinutes,omitempty"`
+
+	// due date in days after the start date of the instance, nil means no due
+	// date. DueTime ("17:00") makes it due at that time instead of all day
+	DueOffsetDays *int   `bson:"dueOffsetDays,omitempty" json:"dueOffsetDays,omitempty"`
+	DueTime       string `bson:"dueTime,omitempty" json:"dueTime,omitempty"`
+
+	Subtasks []TemplateItem `bson:"subtasks,omitempty" json:"subtasks,omitempty"`
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TimeEntry is time spent on a todo, either from a timer or added by hand
+type TimeEntry struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	UserId      primitive.ObjectID `bson:"userId" json:"userId"`
+	TodoId      primitive.ObjectID `bson:"todoId" json:"todoId"`
+	WorkspaceId primitive.ObjectID `bson:"workspaceId" json:"workspaceId"`
+
+	StartedAt time.Time  `bson:"startedAt" json:"startedAt"`
+	EndedAt   *time.Time `bson:"endedAt,omitempty" json:"endedAt,omitempty"`
+
+	// only one running entry per user (unique partial index)
+	Running bool `bson:"running" json:"running"`
+
+	// filled when the timer stops or for manual entries
+	DurationSeconds int64 `bson:"durationSeconds" json:"durationSeconds"`
+
+	Manual    bool      `bson:"manual" json:"manual"`
+	Note      string    `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// TimeReportRow is one group of a tracked time report
+type TimeReportRow struct {
+	Key          string `bson:"_id" json:"key"`
+	Label        string `bson:"label" json:"label"`
+	TotalSeconds int64  `bson:"totalSeconds" json:"totalSeconds"`
+	Entries      int    `bson:"entries" json:"entries"`
+}
+
+// EstimateReportRow compares the estimate of a todo with the tracked time
+type EstimateReportRow struct {
+	TodoId          primitive.ObjectID `bson:"_id" json:"todoId"`
+	Task            string             `bson:"task" json:"task"`
+	Done            bool               `bson:"done" json:"done"`
+	EstimateMinutes int                `bson:"estimateMinutes" json:"estimateMinutes"`
+	TrackedMinutes  int                `bson:"trackedMinutes" json:"trackedMinutes"`
+	// tracked - estimate, positive means over the estimate
+	DifferenceMinutes int `bson:"differenceMinutes" json:"differenceMinutes"`
+}
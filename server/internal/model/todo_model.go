@@ -1,19 +1,113 @@
-package model
-
-import "go.mongodb.org/mongo-driver/bson/primitive"
-
-// ID has type of primitive.ObjectID
-type Todo struct {
-	ID   primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
-	Task string             `bson:"task,omitempty" json:"task,omitempty"`
-
-	// This is Foreign Key / ref to User
-	UserId      primitive.ObjectID `bson:"userId,omitempty" json:"userId,omitempty"`
-	WorkspaceId primitive.ObjectID `bson:"workspaceId,omitempty" json:"workspaceId,omitempty"`
-
-	Priority string `bson:"priority" json:"priority"`
-
-	// why not omitempty
-	// because if false then it wont show in json / bson response
-	Done bool `bson:"done" json:"done"`
-}
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ID has type of primitive.ObjectID
+type Todo struct {
+	ID   primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	Task string             `bson:"task,omitempty" json:"task,omitempty"`
+
+	// This is Foreign Key / ref to User
+	UserId      primitive.ObjectID `bson:"userId,omitempty" json:"userId,omitempty"`
+	WorkspaceId primitive.ObjectID `bson:"workspaceId,omitempty" json:"workspaceId,omitempty"`
+
+	// set on subtasks, the parent lives in the same workspace
+	ParentId primitive.ObjectID `bson:"parentId,omitempty" json:"parentId,omitempty"`
+
+	// optional goal the todo contributes to, in the same workspace
+	GoalId primitive.ObjectID `bson:"goalId,omitempty" json:"goalId,omitempty"`
+
+	// members of the workspace the todo is assigned to, a member that
+	// leaves the workspace is unassigned
+	AssigneeIds []primitive.ObjectID `bson:"assigneeIds,omitempty" json:"assigneeIds,omitempty"`
+
+	// low / medium / high, the weight is stored too so lists can sort by it
+	Priority       string `bson:"priority" json:"priority"`
+	PriorityWeight int    `bson:"priorityWeight,omitempty" json:"priorityWeight,omitempty"`
+
+	// optional Eisenhower classification, nil means derived from the due
+	// date (urgent) and the priority (important)
+	Urgent    *bool `bson:"urgent,omitempty" json:"urgent,omitempty"`
+	Important *bool `bson:"important,omitempty" json:"important,omitempty"`
+
+	// why not omitempty
+	// because if false then it wont show in json / bson response
+	Done bool `bson:"done" json:"done"`
+
+	// when the todo was completed, cleared when it is reopened
+	CompletedAt *time.Time `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+
+	// fractional rank key (pkg/nrank), todos are listed in ascending rank
+	Rank string `bson:"rank,omitempty" json:"rank,omitempty"`
+
+	// planned effort, compared with tracked time in the estimate report
+	EstimateMinutes int `bson:"estimateMinutes,omitempty" json:"estimateMinutes,omitempty"`
+
+	// due date, AllDay means only the day counts (DueAt is its midnight in the user's timezone)
+	DueAt  *time.Time `bson:"dueAt,omitempty" json:"dueAt,omitempty"`
+	AllDay bool       `bson:"allDay,omitempty" json:"allDay,omitempty"`
+
+	Labels     []string    `bson:"labels,omitempty" json:"labels,omitempty"`
+	Recurrence *Recurrence `bson:"recurrence,omitempty" json:"recurrence,omitempty"`
+
+	// hidden from the default listings until SnoozedUntil, the snooze
+	// scheduler clears it at wake-up and notifies the user when SnoozeNotify is set
+	SnoozedUntil *time.Time `bson:"snoozedUntil,omitempty" json:"snoozedUntil,omitempty"`
+	SnoozeNotify bool       `bson:"snoozeNotify,omitempty" json:"snoozeNotify,omitempty"`
+
+	// set when the todo is in the trash, purged after the retention period
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+
+	// not stored, filled from the comments collection when listing
+	CommentCount int `bson:"-" json:"commentCount"`
+}
+
+// Recurrence repeats a todo every Interval units of Frequency
+type Recurrence struct {
+	Frequency string `bson:"frequency" json:"frequency"` // daily / weekly / monthly / yearly
+	Interval  int    `bson:"interval" json:"interval"`
+	Weekday   string `bson:"weekday,omitempty" json:"weekday,omitempty"` // "monday" for every monday
+}
+
+// TodoUpdate is a partial update, nil fields are left as they are
+type TodoUpdate struct {
+	Task      *string `json:"task"`
+	Priority  *string `json:"priority"`
+	Urgent    *bool   `json:"urgent"`
+	Important *bool   `json:"important"`
+}
+
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+)
+
+var priorityWeights = map[string]int{
+	PriorityLow:    1,
+	PriorityMedium: 2,
+	PriorityHigh:   3,
+}
+
+// NormalizePriority validates a priority (case insensitive), empty means medium
+func NormalizePriority(priority string) (string, error) {
+	priority = strings.ToLower(strings.TrimSpace(priority))
+	if priority == "" {
+		return PriorityMedium, nil
+	}
+	if _, ok := priorityWeights[priority]; !ok {
+		return "", errors.New("priority must be low, medium or high")
+	}
+	return priority, nil
+}
+
+// PriorityWeight is higher for more important priorities, 0 when unknown
+func PriorityWeight(priority string) int {
+	return priorityWeights[priority]
+}
@@ -0,0 +1,8 @@
+package model
+
+// Trash is everything a user deleted that is not purged yet
+type Trash struct {
+	Todos      []Todo      `json:"todos"`
+	Goals      []Goals     `json:"goals"`
+	Workspaces []Workspace `json:"workspaces"`
+}
@@ -1,15 +1,17 @@
-package model
-
-import (
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"time"
-)
-
-type User struct {
-	ID        primitive.ObjectID `json:"_id,omitempty" bson:"id,omitempty"`
-	Name      string             `json:"name" bson:"name"`
-	Email     string             `json:"email" bson:"email"`
-	CreatedAt time.Time          `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
-	UpdatedAt time.Time          `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
-	ImageLink string             `json:"imageLink,omitempty" bson:"imageLink,omitempty"`
-}
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type User struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Name      string             `json:"name" bson:"name"`
+	FullName  string             `json:"fullName,omitempty" bson:"fullName,omitempty"`
+	Email     string             `json:"email" bson:"email"`
+	CreatedAt time.Time          `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	UpdatedAt time.Time          `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+	ImageLink string             `json:"imageLink,omitempty" bson:"imageLink,omitempty"`
+	Timezone  string             `json:"timezone,omitempty" bson:"timezone,omitempty"` // IANA name, empty means UTC
+}
@@ -1,15 +1,65 @@
-package model
-
-import (
-	"time"
-
-	"go.mongodb.org/mongo-driver/bson/primitive"
-)
-
-type Workspace struct {
-	ID            primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
-	UserId        primitive.ObjectID `bson:"userId,omitEmpty" json:"userId,omitempty"`
-	WorkspaceName string             `bson:"workspaceName" json:"worskpaceName"`
-	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
-	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`
-}
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Workspace struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	UserId        primitive.ObjectID `bson:"userId,omitEmpty" json:"userId,omitempty"`
+	WorkspaceName string             `bson:"workspaceName" json:"worskpaceName"`
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`
+
+	// set when the workspace is in the trash
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+
+	// people the workspace is shared with, the creator (UserId) is always an
+	// owner and is not listed here
+	Members []WorkspaceMember `bson:"members,omitempty" json:"members,omitempty"`
+
+	// role of the caller, filled on read
+	Role string `bson:"-" json:"role,omitempty"`
+}
+
+// roles in a workspace, owners manage members and delete the workspace,
+// editors change todos and goals, viewers only read
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+type WorkspaceMember struct {
+	UserId   primitive.ObjectID `bson:"userId" json:"userId"`
+	Email    string             `bson:"-" json:"email,omitempty"` // filled when members are listed
+	Role     string             `bson:"role" json:"role"`
+	JoinedAt time.Time          `bson:"joinedAt" json:"joinedAt"`
+}
+
+// RoleOf returns the role of the user in the workspace, "" when the user is
+// not a member
+func (w Workspace) RoleOf(userId string) string {
+	if w.UserId.Hex() == userId {
+		return RoleOwner
+	}
+	for _, member := range w.Members {
+		if member.UserId.Hex() == userId {
+			return member.Role
+		}
+	}
+	return ""
+}
+
+// RoleAllows tells if role may do what needs at least the role need
+func RoleAllows(role string, need string) bool {
+	rank := map[string]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+	return rank[role] > 0 && rank[role] >= rank[need]
+}
+
+// ValidRole tells if role can be given to a member
+func ValidRole(role string) bool {
+	return role == RoleOwner || role == RoleEditor || role == RoleViewer
+}
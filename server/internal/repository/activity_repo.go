@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ActivityRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	CreateActivity(ctx context.Context, activity model.Activity) (model.Activity, error)
+	GetActivities(ctx context.Context, workspaceId primitive.ObjectID, query model.ActivityQuery) ([]model.Activity, error)
+	PurgeWorkspaceActivities(ctx context.Context, workspaceId string) error
+}
+
+type activityRepository struct {
+	activityCollection *mongo.Collection
+	counterCollection  *mongo.Collection // last Seq of every workspace, _id is the workspaceId
+}
+
+// EnsureIndexes creates the index the feed pages and polls on
+func (r *activityRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.activityCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "workspaceId", Value: 1}, {Key: "seq", Value: -1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// CreateActivity stores the activity with the next Seq of its workspace. Run
+// it in a transaction: the counter stays locked until the activity is
+// committed, so a poll never sees a Seq before a smaller one is visible
+func (r *activityRepository) CreateActivity(ctx context.Context, activity model.Activity) (model.Activity, error) {
+	if activity.WorkspaceId.IsZero() || activity.Type == "" {
+		return model.Activity{}, errors.New("WorkspaceId / Type is Empty in Repo")
+	}
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	update := bson.M{"$inc": bson.M{"seq": 1}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	if err := r.counterCollection.FindOneAndUpdate(ctx, bson.M{"_id": activity.WorkspaceId}, update, opts).Decode(&counter); err != nil {
+		return model.Activity{}, err
+	}
+
+	activity.ID = primitive.NewObjectID()
+	activity.Seq = counter.Seq
+	activity.CreatedAt = time.Now()
+
+	if _, err := r.activityCollection.InsertOne(ctx, activity); err != nil {
+		return model.Activity{}, err
+	}
+
+	return activity, nil
+}
+
+// GetActivities returns up to query.Limit activities of the workspace,
+// newest first or, with query.Since / SinceTime, oldest first after them
+func (r *activityRepository) GetActivities(ctx context.Context, workspaceId primitive.ObjectID, query model.ActivityQuery) ([]model.Activity, error) {
+	filter := bson.M{"workspaceId": workspaceId}
+	if !query.ActorId.IsZero() {
+		filter["actorId"] = query.ActorId
+	}
+	if len(query.Types) > 0 {
+		filter["type"] = bson.M{"$in": query.Types}
+	}
+
+	order := -1
+	switch {
+	case query.Since > 0:
+		filter["seq"] = bson.M{"$gt": query.Since}
+		order = 1
+	case !query.SinceTime.IsZero():
+		filter["createdAt"] = bson.M{"$gte": query.SinceTime}
+		order = 1
+	case query.Before > 0:
+		filter["seq"] = bson.M{"$lt": query.Before}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: order}}).SetLimit(int64(query.Limit))
+
+	cursor, err := r.activityCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	activities := []model.Activity{}
+	if err := cursor.All(ctx, &activities); err != nil {
+		return nil, err
+	}
+
+	return activities, nil
+}
+
+// PurgeWorkspaceActivities removes the feed of a purged workspace
+func (r *activityRepository) PurgeWorkspaceActivities(ctx context.Context, workspaceId string) error {
+	oid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.activityCollection.DeleteMany(ctx, bson.M{"workspaceId": oid}); err != nil {
+		return err
+	}
+	_, err = r.counterCollection.DeleteOne(ctx, bson.M{"_id": oid})
+	return err
+}
+
+func NewActivityRepository(activityCollection *mongo.Collection, counterCollection *mongo.Collection) ActivityRepository {
+	return &activityRepository{
+		activityCollection: activityCollection,
+		counterCollection:  counterCollection,
+	}
+}
@@ -0,0 +1,252 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AnalyticsRepository counts the activity of a user for the heatmap and the
+// goal statistics. Days are 2006-01-02 in the given timezone, fromDay / toDay
+// are inclusive and from / to is [from, to)
+type AnalyticsRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	CompletedTodosByDay(ctx context.Context, userId string, from time.Time, to time.Time, timezone string) ([]model.DayCount, error)
+	CheckInsByDay(ctx context.Context, userId string, fromDay string, toDay string) ([]model.DayCount, error)
+	GoalCategories(ctx context.Context, userId string) ([]model.CategoryStats, error)
+}
+
+// analyticsCollections is shared by both analytics backends
+type analyticsCollections struct {
+	todoCollection    *mongo.Collection
+	goalCollection    *mongo.Collection
+	checkInCollection *mongo.Collection
+}
+
+func (c *analyticsCollections) completedTodosFilter(userId string, from time.Time, to time.Time) (bson.M, error) {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{
+		"userId":      userOid,
+		"done":        true,
+		"deletedAt":   nil,
+		"completedAt": bson.M{"$gte": from, "$lt": to},
+	}, nil
+}
+
+func (c *analyticsCollections) checkInsFilter(userId string, fromDay string, toDay string) (bson.M, error) {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"userId": userOid, "day": bson.M{"$gte": fromDay, "$lte": toDay}}, nil
+}
+
+func (c *analyticsCollections) goalsFilter(userId string) (bson.M, error) {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"userId": userOid, "deletedAt": nil}, nil
+}
+
+// aggregationAnalyticsRepository computes everything with aggregation pipelines
+type aggregationAnalyticsRepository struct {
+	analyticsCollections
+}
+
+// EnsureIndexes creates the index the per day completion count runs on
+func (r *aggregationAnalyticsRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.todoCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "completedAt", Value: 1}},
+	})
+	return err
+}
+
+func (r *aggregationAnalyticsRepository) CompletedTodosByDay(ctx context.Context, userId string, from time.Time, to time.Time, timezone string) ([]model.DayCount, error) {
+	match, err := r.completedTodosFilter(userId, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$completedAt", "timezone": timezone}},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+	return aggregateAll[model.DayCount](ctx, r.todoCollection, pipeline)
+}
+
+func (r *aggregationAnalyticsRepository) CheckInsByDay(ctx context.Context, userId string, fromDay string, toDay string) ([]model.DayCount, error) {
+	match, err := r.checkInsFilter(userId, fromDay, toDay)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{"_id": "$day", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+	return aggregateAll[model.DayCount](ctx, r.checkInCollection, pipeline)
+}
+
+func (r *aggregationAnalyticsRepository) GoalCategories(ctx context.Context, userId string) ([]model.CategoryStats, error) {
+	match, err := r.goalsFilter(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"$ifNull": bson.A{"$category", ""}},
+			"goals": bson.M{"$sum": 1},
+			"done":  bson.M{"$sum": bson.M{"$cond": bson.A{"$done", 1, 0}}},
+		}}},
+		{{Key: "$set", Value: bson.M{"rate": bson.M{"$divide": bson.A{"$done", "$goals"}}}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+	return aggregateAll[model.CategoryStats](ctx, r.goalCollection, pipeline)
+}
+
+// memoryAnalyticsRepository loads the matching documents and counts them in
+// process, for Mongo compatible stores without aggregation support
+type memoryAnalyticsRepository struct {
+	analyticsCollections
+}
+
+// EnsureIndexes has nothing to do, the documents are found by userId
+func (r *memoryAnalyticsRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+func (r *memoryAnalyticsRepository) CompletedTodosByDay(ctx context.Context, userId string, from time.Time, to time.Time, timezone string) ([]model.DayCount, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := r.completedTodosFilter(userId, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	todos, err := findAll[model.Todo](ctx, r.todoCollection, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, todo := range todos {
+		counts[todo.CompletedAt.In(loc).Format("2006-01-02")]++
+	}
+	return sortedDayCounts(counts), nil
+}
+
+func (r *memoryAnalyticsRepository) CheckInsByDay(ctx context.Context, userId string, fromDay string, toDay string) ([]model.DayCount, error) {
+	filter, err := r.checkInsFilter(userId, fromDay, toDay)
+	if err != nil {
+		return nil, err
+	}
+
+	checkIns, err := findAll[model.GoalCheckIn](ctx, r.checkInCollection, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, checkIn := range checkIns {
+		counts[checkIn.Day]++
+	}
+	return sortedDayCounts(counts), nil
+}
+
+func (r *memoryAnalyticsRepository) GoalCategories(ctx context.Context, userId string) ([]model.CategoryStats, error) {
+	filter, err := r.goalsFilter(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	goals, err := findAll[model.Goals](ctx, r.goalCollection, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	byCategory := make(map[string]*model.CategoryStats)
+	for _, goal := range goals {
+		stats, ok := byCategory[goal.Category]
+		if !ok {
+			stats = &model.CategoryStats{Category: goal.Category}
+			byCategory[goal.Category] = stats
+		}
+		stats.Goals++
+		if goal.Done {
+			stats.Done++
+		}
+	}
+
+	categories := make([]model.CategoryStats, 0, len(byCategory))
+	for _, stats := range byCategory {
+		stats.Rate = float64(stats.Done) / float64(stats.Goals)
+		categories = append(categories, *stats)
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].Category < categories[j].Category })
+
+	return categories, nil
+}
+
+func sortedDayCounts(counts map[string]int) []model.DayCount {
+	days := make([]model.DayCount, 0, len(counts))
+	for day, count := range counts {
+		days = append(days, model.DayCount{Day: day, Count: count})
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Day < days[j].Day })
+	return days
+}
+
+func aggregateAll[T any](ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline) ([]T, error) {
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := []T{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// NewAggregationAnalyticsRepository computes the analytics with aggregation pipelines
+func NewAggregationAnalyticsRepository(todoCollection *mongo.Collection, goalCollection *mongo.Collection, checkInCollection *mongo.Collection) AnalyticsRepository {
+	return &aggregationAnalyticsRepository{
+		analyticsCollections: analyticsCollections{
+			todoCollection:    todoCollection,
+			goalCollection:    goalCollection,
+			checkInCollection: checkInCollection,
+		},
+	}
+}
+
+// NewMemoryAnalyticsRepository computes the analytics in process
+func NewMemoryAnalyticsRepository(todoCollection *mongo.Collection, goalCollection *mongo.Collection, checkInCollection *mongo.Collection) AnalyticsRepository {
+	return &memoryAnalyticsRepository{
+		analyticsCollections: analyticsCollections{
+			todoCollection:    todoCollection,
+			goalCollection:    goalCollection,
+			checkInCollection: checkInCollection,
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AttachmentRepository interface {
+	CreateAttachment(ctx context.Context, attachment model.Attachment) (model.Attachment, error)
+	GetAttachmentById(ctx context.Context, attachmentId string) (model.Attachment, error)
+	GetTodoAttachments(ctx context.Context, todoId string) ([]model.Attachment, error)
+	DeleteAttachment(ctx context.Context, attachmentId string) error
+}
+
+type attachmentRepository struct {
+	attachmentCollection *mongo.Collection
+}
+
+func (r *attachmentRepository) CreateAttachment(ctx context.Context, attachment model.Attachment) (model.Attachment, error) {
+	if attachment.TodoId.IsZero() || attachment.StorageKey == "" {
+		return model.Attachment{}, errors.New("TodoId / StorageKey is Empty in Repo")
+	}
+
+	if attachment.ID.IsZero() {
+		attachment.ID = primitive.NewObjectID()
+	}
+
+	if _, err := r.attachmentCollection.InsertOne(ctx, attachment); err != nil {
+		return model.Attachment{}, err
+	}
+
+	return attachment, nil
+}
+
+func (r *attachmentRepository) GetAttachmentById(ctx context.Context, attachmentId string) (model.Attachment, error) {
+	oid, err := primitive.ObjectIDFromHex(attachmentId)
+	if err != nil {
+		return model.Attachment{}, err
+	}
+
+	var attachment model.Attachment
+	if err := r.attachmentCollection.FindOne(ctx, bson.M{"_id": oid}).Decode(&attachment); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Attachment{}, errors.New("attachment not found")
+		}
+		return model.Attachment{}, err
+	}
+
+	return attachment, nil
+}
+
+func (r *attachmentRepository) GetTodoAttachments(ctx context.Context, todoId string) ([]model.Attachment, error) {
+	todoOid, err := primitive.ObjectIDFromHex(todoId)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"createdAt": 1})
+	cursor, err := r.attachmentCollection.Find(ctx, bson.M{"todoId": todoOid}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	attachments := []model.Attachment{}
+	if err := cursor.All(ctx, &attachments); err != nil {
+		return nil, err
+	}
+
+	return attachments, nil
+}
+
+func (r *attachmentRepository) DeleteAttachment(ctx context.Context, attachmentId string) error {
+	oid, err := primitive.ObjectIDFromHex(attachmentId)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.attachmentCollection.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return errors.New("attachment not found")
+	}
+
+	return nil
+}
+
+func NewAttachmentRepository(attachmentCollection *mongo.Collection) AttachmentRepository {
+	return &attachmentRepository{
+		attachmentCollection: attachmentCollection,
+	}
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrCategoryExists = errors.New("a category with this name already exists")
+
+type CategoryRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	CreateCategory(ctx context.Context, category model.GoalCategory) (model.GoalCategory, error)
+	FindOrCreateCategory(ctx context.Context, category model.GoalCategory) (model.GoalCategory, error)
+	GetCategoryById(ctx context.Context, categoryId string) (model.GoalCategory, error)
+	GetUserCategories(ctx context.Context, userId string) ([]model.GoalCategory, error)
+	SetFields(ctx context.Context, categoryId string, fields map[string]any) (model.GoalCategory, error)
+	DeleteCategory(ctx context.Context, categoryId string) error
+}
+
+type categoryRepository struct {
+	categoryCollection *mongo.Collection
+}
+
+// EnsureIndexes makes the lower cased name unique per user
+func (r *categoryRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.categoryCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "key", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (r *categoryRepository) CreateCategory(ctx context.Context, category model.GoalCategory) (model.GoalCategory, error) {
+	if category.UserId.IsZero() || category.Key == "" {
+		return model.GoalCategory{}, errors.New("UserId / Name is Empty in Repo")
+	}
+
+	category.ID = primitive.NewObjectID()
+	category.CreatedAt = time.Now()
+
+	if _, err := r.categoryCollection.InsertOne(ctx, category); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return model.GoalCategory{}, ErrCategoryExists
+		}
+		return model.GoalCategory{}, err
+	}
+
+	return category, nil
+}
+
+// FindOrCreateCategory returns the user's category with the same key, it is
+// created from category when there is none yet
+func (r *categoryRepository) FindOrCreateCategory(ctx context.Context, category model.GoalCategory) (model.GoalCategory, error) {
+	if category.UserId.IsZero() || category.Key == "" {
+		return model.GoalCategory{}, errors.New("UserId / Name is Empty in Repo")
+	}
+
+	filter := bson.M{"userId": category.UserId, "key": category.Key}
+	update := bson.M{"$setOnInsert": bson.M{
+		"name":      category.Name,
+		"color":     category.Color,
+		"icon":      category.Icon,
+		"createdAt": time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var found model.GoalCategory
+	if err := r.categoryCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&found); err != nil {
+		return model.GoalCategory{}, err
+	}
+
+	return found, nil
+}
+
+func (r *categoryRepository) GetCategoryById(ctx context.Context, categoryId string) (model.GoalCategory, error) {
+	oid, err := primitive.ObjectIDFromHex(categoryId)
+	if err != nil {
+		return model.GoalCategory{}, err
+	}
+
+	var category model.GoalCategory
+	if err := r.categoryCollection.FindOne(ctx, bson.M{"_id": oid}).Decode(&category); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.GoalCategory{}, errors.New("category not found")
+		}
+		return model.GoalCategory{}, err
+	}
+
+	return category, nil
+}
+
+// GetUserCategories returns the categories of the user sorted by name
+func (r *categoryRepository) GetUserCategories(ctx context.Context, userId string) ([]model.GoalCategory, error) {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.categoryCollection.Find(ctx, bson.M{"userId": userOid}, options.Find().SetSort(bson.M{"key": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	categories := []model.GoalCategory{}
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+func (r *categoryRepository) SetFields(ctx context.Context, categoryId string, fields map[string]any) (model.GoalCategory, error) {
+	oid, err := primitive.ObjectIDFromHex(categoryId)
+	if err != nil {
+		return model.GoalCategory{}, err
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.GoalCategory
+	if err := r.categoryCollection.FindOneAndUpdate(ctx, bson.M{"_id": oid}, bson.M{"$set": fields}, opts).Decode(&updated); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.GoalCategory{}, errors.New("category not found")
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return model.GoalCategory{}, ErrCategoryExists
+		}
+		return model.GoalCategory{}, err
+	}
+
+	return updated, nil
+}
+
+func (r *categoryRepository) DeleteCategory(ctx context.Context, categoryId string) error {
+	oid, err := primitive.ObjectIDFromHex(categoryId)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.categoryCollection.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return errors.New("category not found")
+	}
+
+	return nil
+}
+
+func NewCategoryRepository(categoryCollection *mongo.Collection) CategoryRepository {
+	return &categoryRepository{
+		categoryCollection: categoryCollection,
+	}
+}
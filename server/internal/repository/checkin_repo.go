@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrAlreadyCheckedIn = errors.New("goal is already checked in for this day")
+
+type CheckInRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	CreateCheckIn(ctx context.Context, checkIn model.GoalCheckIn) (model.GoalCheckIn, error)
+	GetGoalCheckIns(ctx context.Context, goalId primitive.ObjectID) ([]model.GoalCheckIn, error)
+	DeleteGoalCheckIns(ctx context.Context, goalIds []primitive.ObjectID) error
+}
+
+type checkInRepository struct {
+	checkInCollection *mongo.Collection
+}
+
+// EnsureIndexes creates the index that allows one check-in per goal and day
+func (r *checkInRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.checkInCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "goalId", Value: 1}, {Key: "day", Value: 1}},
+		Options: options.Index().SetName("one_checkin_per_goal_day").SetUnique(true),
+	})
+	return err
+}
+
+func (r *checkInRepository) CreateCheckIn(ctx context.Context, checkIn model.GoalCheckIn) (model.GoalCheckIn, error) {
+	checkIn.ID = primitive.NewObjectID()
+	checkIn.CreatedAt = time.Now()
+
+	if _, err := r.checkInCollection.InsertOne(ctx, checkIn); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return model.GoalCheckIn{}, ErrAlreadyCheckedIn
+		}
+		return model.GoalCheckIn{}, err
+	}
+
+	return checkIn, nil
+}
+
+// GetGoalCheckIns returns the check-ins of a goal, oldest day first
+func (r *checkInRepository) GetGoalCheckIns(ctx context.Context, goalId primitive.ObjectID) ([]model.GoalCheckIn, error) {
+	cursor, err := r.checkInCollection.Find(ctx, bson.M{"goalId": goalId}, options.Find().SetSort(bson.M{"day": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	checkIns := []model.GoalCheckIn{}
+	if err := cursor.All(ctx, &checkIns); err != nil {
+		return nil, err
+	}
+
+	return checkIns, nil
+}
+
+// DeleteGoalCheckIns removes the check-ins of purged goals
+func (r *checkInRepository) DeleteGoalCheckIns(ctx context.Context, goalIds []primitive.ObjectID) error {
+	_, err := r.checkInCollection.DeleteMany(ctx, bson.M{"goalId": bson.M{"$in": goalIds}})
+	return err
+}
+
+func NewCheckInRepository(checkInCollection *mongo.Collection) CheckInRepository {
+	return &checkInRepository{
+		checkInCollection: checkInCollection,
+	}
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CommentRepository interface {
+	CreateComment(ctx context.Context, comment model.Comment) (model.Comment, error)
+	GetCommentById(ctx context.Context, commentId string) (model.Comment, error)
+	UpdateComment(ctx context.Context, commentId string, body string, bodyHTML string, mentions []primitive.ObjectID) (model.Comment, error)
+	DeleteComment(ctx context.Context, commentId string) error
+	GetTodoComments(ctx context.Context, todoId string) ([]model.Comment, error)
+	CountByTodoIds(ctx context.Context, todoIds []primitive.ObjectID) (map[primitive.ObjectID]int, error)
+	DeleteTodoComments(ctx context.Context, todoId string) error
+}
+
+type commentRepository struct {
+	commentCollection *mongo.Collection
+}
+
+func (r *commentRepository) CreateComment(ctx context.Context, comment model.Comment) (model.Comment, error) {
+	if comment.TodoId.IsZero() || comment.UserId.IsZero() {
+		return model.Comment{}, errors.New("TodoId / UserId is Empty in Repo")
+	}
+
+	comment.ID = primitive.NewObjectID()
+	comment.CreatedAt = time.Now()
+	comment.UpdatedAt = comment.CreatedAt
+
+	if _, err := r.commentCollection.InsertOne(ctx, comment); err != nil {
+		return model.Comment{}, err
+	}
+
+	return comment, nil
+}
+
+func (r *commentRepository) GetCommentById(ctx context.Context, commentId string) (model.Comment, error) {
+	oid, err := primitive.ObjectIDFromHex(commentId)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	var comment model.Comment
+	if err := r.commentCollection.FindOne(ctx, bson.M{"_id": oid}).Decode(&comment); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Comment{}, errors.New("comment not found")
+		}
+		return model.Comment{}, err
+	}
+
+	return comment, nil
+}
+
+func (r *commentRepository) UpdateComment(ctx context.Context, commentId string, body string, bodyHTML string, mentions []primitive.ObjectID) (model.Comment, error) {
+	oid, err := primitive.ObjectIDFromHex(commentId)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"body":      body,
+		"bodyHtml":  bodyHTML,
+		"mentions":  mentions,
+		"edited":    true,
+		"updatedAt": time.Now(),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Comment
+	if err := r.commentCollection.FindOneAndUpdate(ctx, bson.M{"_id": oid}, update, opts).Decode(&updated); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Comment{}, errors.New("comment not found")
+		}
+		return model.Comment{}, err
+	}
+
+	return updated, nil
+}
+
+func (r *commentRepository) DeleteComment(ctx context.Context, commentId string) error {
+	oid, err := primitive.ObjectIDFromHex(commentId)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.commentCollection.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return errors.New("comment not found")
+	}
+
+	return nil
+}
+
+// GetTodoComments returns the thread of a todo, oldest comment first
+func (r *commentRepository) GetTodoComments(ctx context.Context, todoId string) ([]model.Comment, error) {
+	todoOid, err := primitive.ObjectIDFromHex(todoId)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.commentCollection.Find(ctx, bson.M{"todoId": todoOid}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	comments := []model.Comment{}
+	if err := cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// CountByTodoIds counts comments of many todos with one aggregation
+func (r *commentRepository) CountByTodoIds(ctx context.Context, todoIds []primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+	counts := make(map[primitive.ObjectID]int)
+	if len(todoIds) == 0 {
+		return counts, nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"todoId": bson.M{"$in": todoIds}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$todoId", "count": bson.M{"$sum": 1}}}},
+	}
+
+	cursor, err := r.commentCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var row struct {
+			TodoId primitive.ObjectID `bson:"_id"`
+			Count  int                `bson:"count"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, err
+		}
+		counts[row.TodoId] = row.Count
+	}
+
+	return counts, nil
+}
+
+// DeleteTodoComments removes the whole thread when its todo is removed
+func (r *commentRepository) DeleteTodoComments(ctx context.Context, todoId string) error {
+	todoOid, err := primitive.ObjectIDFromHex(todoId)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.commentCollection.DeleteMany(ctx, bson.M{"todoId": todoOid})
+	return err
+}
+
+func NewCommentRepository(commentCollection *mongo.Collection) CommentRepository {
+	return &commentRepository{
+		commentCollection: commentCollection,
+	}
+}
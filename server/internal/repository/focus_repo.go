@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrFocusActive = errors.New("a focus session is already active for this user")
+
+type FocusRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	StartSession(ctx context.Context, session model.FocusSession) (model.FocusSession, error)
+	GetActiveSession(ctx context.Context, userId string) (model.FocusSession, error)
+	Transition(ctx context.Context, sessionId primitive.ObjectID, fromState string, fields map[string]any) (model.FocusSession, error)
+	Stats(ctx context.Context, userId string, workspaceId string, from time.Time, to time.Time, format string, timezone string) ([]model.FocusStatsRow, error)
+	MoveTodoSessions(ctx context.Context, todoIds []primitive.ObjectID, workspaceId primitive.ObjectID) error
+	DeleteTodoSessions(ctx context.Context, todoIds []primitive.ObjectID) error
+}
+
+type focusRepository struct {
+	focusCollection *mongo.Collection
+}
+
+// EnsureIndexes creates the index that allows only one active session per user
+func (r *focusRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.focusCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}},
+			Options: options.Index().
+				SetName("one_active_focus_per_user").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"active": true}),
+		},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "completedAt", Value: 1}}},
+	})
+	return err
+}
+
+func (r *focusRepository) StartSession(ctx context.Context, session model.FocusSession) (model.FocusSession, error) {
+	session.ID = primitive.NewObjectID()
+	session.State = model.FocusRunning
+	session.Active = true
+
+	if _, err := r.focusCollection.InsertOne(ctx, session); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return model.FocusSession{}, ErrFocusActive
+		}
+		return model.FocusSession{}, err
+	}
+
+	return session, nil
+}
+
+func (r *focusRepository) GetActiveSession(ctx context.Context, userId string) (model.FocusSession, error) {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.FocusSession{}, err
+	}
+
+	var session model.FocusSession
+	if err := r.focusCollection.FindOne(ctx, bson.M{"userId": userOid, "active": true}).Decode(&session); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.FocusSession{}, errors.New("no active focus session")
+		}
+		return model.FocusSession{}, err
+	}
+
+	return session, nil
+}
+
+// Transition sets fields only while the session is still in fromState, so a
+// double click can not pause or complete a session twice
+func (r *focusRepository) Transition(ctx context.Context, sessionId primitive.ObjectID, fromState string, fields map[string]any) (model.FocusSession, error) {
+	update := bson.M{"$set": fields}
+	if _, ok := fields["pausedAt"]; !ok {
+		update["$unset"] = bson.M{"pausedAt": ""}
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.FocusSession
+	if err := r.focusCollection.FindOneAndUpdate(ctx, bson.M{"_id": sessionId, "state": fromState}, update, opts).Decode(&updated); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.FocusSession{}, errors.New("focus session is not " + fromState)
+		}
+		return model.FocusSession{}, err
+	}
+
+	return updated, nil
+}
+
+// Stats totals completed sessions inside [from, to) per day ("%Y-%m-%d") or
+// ISO week ("%G-W%V") in timezone. With a workspaceId it counts the sessions
+// of every member in that workspace, else the sessions of the user
+func (r *focusRepository) Stats(ctx context.Context, userId string, workspaceId string, from time.Time, to time.Time, format string, timezone string) ([]model.FocusStatsRow, error) {
+	match := bson.M{
+		"state":       model.FocusCompleted,
+		"completedAt": bson.M{"$gte": from, "$lt": to},
+	}
+	if workspaceId != "" {
+		workspaceOid, err := primitive.ObjectIDFromHex(workspaceId)
+		if err != nil {
+			return nil, err
+		}
+		match["workspaceId"] = workspaceOid
+	} else {
+		userOid, err := primitive.ObjectIDFromHex(userId)
+		if err != nil {
+			return nil, err
+		}
+		match["userId"] = userOid
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$dateToString": bson.M{
+				"format":   format,
+				"date":     "$completedAt",
+				"timezone": timezone,
+			}},
+			"sessions":     bson.M{"$sum": 1},
+			"focusSeconds": bson.M{"$sum": "$focusSeconds"},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	cursor, err := r.focusCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rows := []model.FocusStatsRow{}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// MoveTodoSessions keeps the workspace of sessions in line with their moved todos
+func (r *focusRepository) MoveTodoSessions(ctx context.Context, todoIds []primitive.ObjectID, workspaceId primitive.ObjectID) error {
+	filter := bson.M{"todoId": bson.M{"$in": todoIds}}
+	_, err := r.focusCollection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"workspaceId": workspaceId}})
+	return err
+}
+
+// DeleteTodoSessions removes the focus sessions of purged todos
+func (r *focusRepository) DeleteTodoSessions(ctx context.Context, todoIds []primitive.ObjectID) error {
+	_, err := r.focusCollection.DeleteMany(ctx, bson.M{"todoId": bson.M{"$in": todoIds}})
+	return err
+}
+
+func NewFocusRepository(focusCollection *mongo.Collection) FocusRepository {
+	return &focusRepository{
+		focusCollection: focusCollection,
+	}
+}
@@ -1,152 +1,424 @@
-package repository
-
-import (
-	"context"
-	"errors"
-	"github.com/ndk123-web/fast-todo/internal/model"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-)
-
-type GoalRepository interface {
-	GetUserGoals(ctx context.Context, userId string, workspaceId string) ([]model.Goals, error)
-	CreateUserGoal(ctx context.Context, userId string, workspaceId string, goalName string, targetDays int64, category string) (model.Goals, error)
-	UpdateUserGoal(ctx context.Context, goalId string, updatedGoalName string, updatedTargetDays int, updatedCategory string) (bool, error)
-	DeleteUserGoal(ctx context.Context, goalId string) (bool, error)
-}
-
-type goalRepository struct {
-	goalCollection *mongo.Collection
-}
-
-func (r *goalRepository) GetUserGoals(ctx context.Context, userId string, workspaceId string) ([]model.Goals, error) {
-	var err error
-
-	if userId == "" {
-		return nil, errors.New("UserId is Empty")
-	}
-
-	// convert userId and WorkspaceId from string -> ObjectId
-	userOid, err := primitive.ObjectIDFromHex(userId)
-	if err != nil {
-		return nil, err
-	}
-	workspaceOid, err := primitive.ObjectIDFromHex(workspaceId)
-	if err != nil {
-		return nil, err
-	}
-
-	// filter
-	filter := bson.M{"userId": userOid, "workspaceId": workspaceOid}
-
-	cursor, err := r.goalCollection.Find(ctx, filter)
-	if err != nil {
-		return nil, err
-	}
-
-	var goalsDocs []model.Goals
-	for cursor.Next(ctx) {
-		var goal model.Goals
-		if err := cursor.Decode(&goal); err != nil {
-			return nil, err
-		}
-		goalsDocs = append(goalsDocs, goal)
-	}
-
-	return goalsDocs, nil
-}
-
-func (r *goalRepository) CreateUserGoal(ctx context.Context, userId string, workspaceId string, goalName string, targetDays int64, category string) (model.Goals, error) {
-	if userId == "" || workspaceId == "" {
-		return model.Goals{}, errors.New("UserId / WorkspaceId is Empty in Repo")
-	}
-
-	// convert string -> ObjectId
-	userOid, err := primitive.ObjectIDFromHex(userId)
-	if err != nil {
-		return model.Goals{}, err
-	}
-	workspaceOid, err := primitive.ObjectIDFromHex(workspaceId)
-	if err != nil {
-		return model.Goals{}, err
-	}
-
-	insert := model.Goals{
-		// create the new ObjectId
-		ID:          primitive.NewObjectID(),
-		UserId:      userOid,
-		WorkspaceId: workspaceOid,
-		TargetDays:  int(targetDays),
-		Title:       goalName,
-		Category:    category,
-	}
-
-	insertedRes, err := r.goalCollection.InsertOne(ctx, insert)
-	if err != nil {
-		return model.Goals{}, err
-	}
-
-	// inject the objectId with response
-	if oid, ok := insertedRes.InsertedID.(primitive.ObjectID); ok {
-		insert.ID = oid
-	}
-
-	return insert, nil
-}
-
-func (r *goalRepository) UpdateUserGoal(ctx context.Context, goalId string, updatedGoalName string, updatedTargetDays int, updatedCategory string) (bool, error) {
-	if goalId == "" {
-		return false, errors.New("Goal ID is Empty in Repo")
-	}
-
-	// convert goalId string -> objectId
-	oid, err := primitive.ObjectIDFromHex(goalId)
-	if err != nil {
-		return false, err
-	}
-
-	filter := bson.M{"_id": oid}
-	update := bson.M{"$set": bson.M{"title": updatedGoalName, "targetDays": updatedTargetDays, "category": updatedCategory}}
-
-	updated, err := r.goalCollection.UpdateOne(ctx, filter, update)
-	if err != nil {
-		return false, err
-	}
-
-	if updated.MatchedCount == 0 {
-		return false, errors.New("GoalId Document Not Found")
-	}
-
-	return true, nil
-}
-
-func (r *goalRepository) DeleteUserGoal(ctx context.Context, goalId string) (bool, error) {
-	if goalId == "" {
-		return false, errors.New("Goal Id is Empty in Repository")
-	}
-
-	// convert string -> ObjectId
-	oid, err := primitive.ObjectIDFromHex(goalId)
-	if err != nil {
-		return false, err
-	}
-
-	filter := bson.M{"_id": oid}
-	deletedRes, err := r.goalCollection.DeleteOne(ctx, filter)
-	if err != nil {
-		return false, err
-	}
-
-	if deletedRes.DeletedCount == 0 {
-		return false, errors.New("Documents Not Found")
-	}
-
-	return true, nil
-}
-
-func NewGoalRepository(goalCollection *mongo.Collection) GoalRepository {
-	return &goalRepository{
-		goalCollection: goalCollection,
-	}
-}
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type GoalRepository interface {
+	GetWorkspaceGoals(ctx context.Context, workspaceId string) ([]model.Goals, error)
+	CreateUserGoal(ctx context.Context, userId string, workspaceId string, goal model.Goals) (model.Goals, error)
+	UpdateUserGoal(ctx context.Context, goalId string, updatedGoalName string, updatedTargetDays int, updatedCategory string) (bool, error)
+	DeleteUserGoal(ctx context.Context, goalId string) (bool, error)
+	GetGoalById(ctx context.Context, goalId string) (model.Goals, error)
+	SetFields(ctx context.Context, goalId string, fields map[string]any) (model.Goals, error)
+	RestoreGoal(ctx context.Context, goalId string) (model.Goals, error)
+	GetDeletedGoals(ctx context.Context, workspaceIds []primitive.ObjectID) ([]model.Goals, error)
+	PurgeDeletedGoals(ctx context.Context, deletedBefore time.Time) ([]string, error)
+	PurgeWorkspaceGoals(ctx context.Context, workspaceId string) ([]string, error)
+	GetGoalsByIds(ctx context.Context, goalIds []primitive.ObjectID) ([]model.Goals, error)
+	MoveGoals(ctx context.Context, goalIds []primitive.ObjectID, workspaceId primitive.ObjectID) error
+	InsertGoals(ctx context.Context, goals []model.Goals) error
+	GetAllUserGoals(ctx context.Context, userId string) ([]model.Goals, error)
+	GetGoalsWithoutCategoryId(ctx context.Context) ([]model.Goals, error)
+	LinkCategory(ctx context.Context, goalIds []primitive.ObjectID, category model.GoalCategory) error
+	RenameCategory(ctx context.Context, categoryId primitive.ObjectID, name string) error
+	UnlinkCategory(ctx context.Context, categoryId primitive.ObjectID) error
+	GetGoalTree(ctx context.Context, goalIds []primitive.ObjectID) ([]model.Goals, error)
+	GetUnscoredObjectives(ctx context.Context) ([]model.Goals, error)
+	DetachGoals(ctx context.Context, goalIds []primitive.ObjectID) error
+	DetachChildren(ctx context.Context, parentIds []primitive.ObjectID, keepIds []primitive.ObjectID) error
+	SetPeriod(ctx context.Context, goalIds []primitive.ObjectID, period string) error
+}
+
+type goalRepository struct {
+	goalCollection *mongo.Collection
+}
+
+// GetWorkspaceGoals returns the live goals of a workspace, of every member
+func (r *goalRepository) GetWorkspaceGoals(ctx context.Context, workspaceId string) ([]model.Goals, error) {
+	workspaceOid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	// filter
+	filter := bson.M{"workspaceId": workspaceOid, "deletedAt": nil}
+
+	cursor, err := r.goalCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var goalsDocs []model.Goals
+	for cursor.Next(ctx) {
+		var goal model.Goals
+		if err := cursor.Decode(&goal); err != nil {
+			return nil, err
+		}
+		goalsDocs = append(goalsDocs, goal)
+	}
+
+	return goalsDocs, nil
+}
+
+func (r *goalRepository) CreateUserGoal(ctx context.Context, userId string, workspaceId string, goal model.Goals) (model.Goals, error) {
+	if userId == "" || workspaceId == "" {
+		return model.Goals{}, errors.New("UserId / WorkspaceId is Empty in Repo")
+	}
+
+	// convert string -> ObjectId
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.Goals{}, err
+	}
+	workspaceOid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return model.Goals{}, err
+	}
+
+	insert := goal
+	// create the new ObjectId
+	insert.ID = primitive.NewObjectID()
+	insert.UserId = userOid
+	insert.WorkspaceId = workspaceOid
+
+	insertedRes, err := r.goalCollection.InsertOne(ctx, insert)
+	if err != nil {
+		return model.Goals{}, err
+	}
+
+	// inject the objectId with response
+	if oid, ok := insertedRes.InsertedID.(primitive.ObjectID); ok {
+		insert.ID = oid
+	}
+
+	return insert, nil
+}
+
+func (r *goalRepository) UpdateUserGoal(ctx context.Context, goalId string, updatedGoalName string, updatedTargetDays int, updatedCategory string) (bool, error) {
+	if goalId == "" {
+		return false, errors.New("Goal ID is Empty in Repo")
+	}
+
+	// convert goalId string -> objectId
+	oid, err := primitive.ObjectIDFromHex(goalId)
+	if err != nil {
+		return false, err
+	}
+
+	filter := bson.M{"_id": oid}
+	update := bson.M{"$set": bson.M{"title": updatedGoalName, "targetDays": updatedTargetDays, "category": updatedCategory}}
+
+	updated, err := r.goalCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	if updated.MatchedCount == 0 {
+		return false, errors.New("GoalId Document Not Found")
+	}
+
+	return true, nil
+}
+
+func (r *goalRepository) DeleteUserGoal(ctx context.Context, goalId string) (bool, error) {
+	if goalId == "" {
+		return false, errors.New("Goal Id is Empty in Repository")
+	}
+
+	// convert string -> ObjectId
+	oid, err := primitive.ObjectIDFromHex(goalId)
+	if err != nil {
+		return false, err
+	}
+
+	// soft delete, the purge job removes it after the retention period
+	filter := bson.M{"_id": oid, "deletedAt": nil}
+	update := bson.M{"$set": bson.M{"deletedAt": time.Now()}}
+	deletedRes, err := r.goalCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	if deletedRes.MatchedCount == 0 {
+		return false, errors.New("Documents Not Found")
+	}
+
+	return true, nil
+}
+
+func (r *goalRepository) GetGoalById(ctx context.Context, goalId string) (model.Goals, error) {
+	oid, err := primitive.ObjectIDFromHex(goalId)
+	if err != nil {
+		return model.Goals{}, err
+	}
+
+	var goal model.Goals
+	if err := r.goalCollection.FindOne(ctx, bson.M{"_id": oid}).Decode(&goal); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Goals{}, errors.New("GoalId Document Not Found")
+		}
+		return model.Goals{}, err
+	}
+
+	return goal, nil
+}
+
+// SetFields writes the given bson fields as they are, used to revert a goal
+func (r *goalRepository) SetFields(ctx context.Context, goalId string, fields map[string]any) (model.Goals, error) {
+	oid, err := primitive.ObjectIDFromHex(goalId)
+	if err != nil {
+		return model.Goals{}, err
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Goals
+	if err := r.goalCollection.FindOneAndUpdate(ctx, bson.M{"_id": oid}, bson.M{"$set": fields}, opts).Decode(&updated); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Goals{}, errors.New("GoalId Document Not Found")
+		}
+		return model.Goals{}, err
+	}
+
+	return updated, nil
+}
+
+// RestoreGoal takes a goal out of the trash
+func (r *goalRepository) RestoreGoal(ctx context.Context, goalId string) (model.Goals, error) {
+	oid, err := primitive.ObjectIDFromHex(goalId)
+	if err != nil {
+		return model.Goals{}, err
+	}
+
+	filter := bson.M{"_id": oid, "deletedAt": bson.M{"$ne": nil}}
+	update := bson.M{"$unset": bson.M{"deletedAt": ""}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var restored model.Goals
+	if err := r.goalCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&restored); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Goals{}, errors.New("goal not found in trash")
+		}
+		return model.Goals{}, err
+	}
+
+	return restored, nil
+}
+
+// GetDeletedGoals lists the goals of the workspaces that are in the trash,
+// newest first
+func (r *goalRepository) GetDeletedGoals(ctx context.Context, workspaceIds []primitive.ObjectID) ([]model.Goals, error) {
+	filter := bson.M{"workspaceId": bson.M{"$in": workspaceIds}, "deletedAt": bson.M{"$ne": nil}}
+	opts := options.Find().SetSort(bson.M{"deletedAt": -1})
+
+	cursor, err := r.goalCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	goals := []model.Goals{}
+	if err := cursor.All(ctx, &goals); err != nil {
+		return nil, err
+	}
+
+	return goals, nil
+}
+
+// PurgeDeletedGoals hard deletes goals in the trash since before deletedBefore
+// and returns their ids (for check-in / history cleanup)
+func (r *goalRepository) PurgeDeletedGoals(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	return r.purge(ctx, bson.M{"deletedAt": bson.M{"$lt": deletedBefore}})
+}
+
+// PurgeWorkspaceGoals hard deletes every goal of a purged workspace
+func (r *goalRepository) PurgeWorkspaceGoals(ctx context.Context, workspaceId string) ([]string, error) {
+	workspaceOid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.purge(ctx, bson.M{"workspaceId": workspaceOid})
+}
+
+func (r *goalRepository) purge(ctx context.Context, filter bson.M) ([]string, error) {
+	values, err := r.goalCollection.Distinct(ctx, "_id", filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	// filter again, a goal restored in the meantime must survive
+	if _, err := r.goalCollection.DeleteMany(ctx, bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$in": values}}}}); err != nil {
+		return nil, err
+	}
+
+	var goalIds []string
+	for _, value := range values {
+		if oid, ok := value.(primitive.ObjectID); ok {
+			goalIds = append(goalIds, oid.Hex())
+		}
+	}
+
+	return goalIds, nil
+}
+
+// GetGoalsByIds returns the live goals with the given ids
+func (r *goalRepository) GetGoalsByIds(ctx context.Context, goalIds []primitive.ObjectID) ([]model.Goals, error) {
+	cursor, err := r.goalCollection.Find(ctx, bson.M{"_id": bson.M{"$in": goalIds}, "deletedAt": nil})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	goals := []model.Goals{}
+	if err := cursor.All(ctx, &goals); err != nil {
+		return nil, err
+	}
+
+	return goals, nil
+}
+
+// MoveGoals puts the goals into another workspace
+func (r *goalRepository) MoveGoals(ctx context.Context, goalIds []primitive.ObjectID, workspaceId primitive.ObjectID) error {
+	filter := bson.M{"_id": bson.M{"$in": goalIds}, "deletedAt": nil}
+	_, err := r.goalCollection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"workspaceId": workspaceId}})
+	return err
+}
+
+// InsertGoals inserts ready made goals (ids set by the caller)
+func (r *goalRepository) InsertGoals(ctx context.Context, goals []model.Goals) error {
+	if len(goals) == 0 {
+		return nil
+	}
+
+	docs := make([]any, 0, len(goals))
+	for _, goal := range goals {
+		docs = append(docs, goal)
+	}
+
+	_, err := r.goalCollection.InsertMany(ctx, docs)
+	return err
+}
+
+// GetAllUserGoals returns the live goals of the user in every workspace
+func (r *goalRepository) GetAllUserGoals(ctx context.Context, userId string) ([]model.Goals, error) {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return findAll[model.Goals](ctx, r.goalCollection, bson.M{"userId": userOid, "deletedAt": nil})
+}
+
+// GetGoalsWithoutCategoryId returns goals (trash included) that have a free
+// text category but no managed one, oldest first
+func (r *goalRepository) GetGoalsWithoutCategoryId(ctx context.Context) ([]model.Goals, error) {
+	filter := bson.M{"category": bson.M{"$nin": bson.A{"", nil}}, "categoryId": nil}
+	return findAll[model.Goals](ctx, r.goalCollection, filter, options.Find().SetSort(bson.M{"_id": 1}))
+}
+
+// LinkCategory puts the goals into the category and copies its name
+func (r *goalRepository) LinkCategory(ctx context.Context, goalIds []primitive.ObjectID, category model.GoalCategory) error {
+	update := bson.M{"$set": bson.M{"categoryId": category.ID, "category": category.Name}}
+	_, err := r.goalCollection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": goalIds}}, update)
+	return err
+}
+
+// RenameCategory copies the new name of a category to its goals
+func (r *goalRepository) RenameCategory(ctx context.Context, categoryId primitive.ObjectID, name string) error {
+	_, err := r.goalCollection.UpdateMany(ctx, bson.M{"categoryId": categoryId}, bson.M{"$set": bson.M{"category": name}})
+	return err
+}
+
+// UnlinkCategory leaves the goals of a deleted category without category
+func (r *goalRepository) UnlinkCategory(ctx context.Context, categoryId primitive.ObjectID) error {
+	update := bson.M{"$set": bson.M{"category": ""}, "$unset": bson.M{"categoryId": ""}}
+	_, err := r.goalCollection.UpdateMany(ctx, bson.M{"categoryId": categoryId}, update)
+	return err
+}
+
+// GetGoalTree returns the live goals with the given ids and all their key
+// results (any depth), objectives before their key results
+func (r *goalRepository) GetGoalTree(ctx context.Context, goalIds []primitive.ObjectID) ([]model.Goals, error) {
+	var tree []model.Goals
+	seen := make(map[primitive.ObjectID]bool)
+
+	filter := bson.M{"_id": bson.M{"$in": goalIds}, "deletedAt": nil}
+	for {
+		level, err := findAll[model.Goals](ctx, r.goalCollection, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		var parentIds []primitive.ObjectID
+		for _, goal := range level {
+			if seen[goal.ID] {
+				continue
+			}
+			seen[goal.ID] = true
+			tree = append(tree, goal)
+			parentIds = append(parentIds, goal.ID)
+		}
+		if len(parentIds) == 0 {
+			return tree, nil
+		}
+
+		// next level down
+		filter = bson.M{"parentId": bson.M{"$in": parentIds}, "deletedAt": nil}
+	}
+}
+
+// GetUnscoredObjectives returns the live objectives with a period and no
+// final score yet
+func (r *goalRepository) GetUnscoredObjectives(ctx context.Context) ([]model.Goals, error) {
+	filter := bson.M{
+		"type":      model.GoalTypeObjective,
+		"period":    bson.M{"$nin": bson.A{"", nil}},
+		"score":     nil,
+		"deletedAt": nil,
+	}
+	return findAll[model.Goals](ctx, r.goalCollection, filter)
+}
+
+// DetachGoals makes the goals top level goals
+func (r *goalRepository) DetachGoals(ctx context.Context, goalIds []primitive.ObjectID) error {
+	if len(goalIds) == 0 {
+		return nil
+	}
+	_, err := r.goalCollection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": goalIds}}, bson.M{"$unset": bson.M{"parentId": ""}})
+	return err
+}
+
+// DetachChildren makes the key results of the parents top level goals,
+// except the ones in keepIds
+func (r *goalRepository) DetachChildren(ctx context.Context, parentIds []primitive.ObjectID, keepIds []primitive.ObjectID) error {
+	filter := bson.M{"parentId": bson.M{"$in": parentIds}, "_id": bson.M{"$nin": keepIds}}
+	_, err := r.goalCollection.UpdateMany(ctx, filter, bson.M{"$unset": bson.M{"parentId": ""}})
+	return err
+}
+
+// SetPeriod moves the goals to the OKR period
+func (r *goalRepository) SetPeriod(ctx context.Context, goalIds []primitive.ObjectID, period string) error {
+	if len(goalIds) == 0 {
+		return nil
+	}
+	_, err := r.goalCollection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": goalIds}}, bson.M{"$set": bson.M{"period": period}})
+	return err
+}
+
+func NewGoalRepository(goalCollection *mongo.Collection) GoalRepository {
+	return &goalRepository{
+		goalCollection: goalCollection,
+	}
+}
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvitationUnavailable is returned for invitations that are revoked,
+// expired, used up or already used by the user
+var ErrInvitationUnavailable = errors.New("invitation is no longer valid")
+
+type InvitationRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	CreateInvitation(ctx context.Context, invitation model.Invitation) (model.Invitation, error)
+	GetInvitationById(ctx context.Context, invitationId string) (model.Invitation, error)
+	GetInvitationByTokenHash(ctx context.Context, tokenHash string) (model.Invitation, error)
+	GetPendingInvitations(ctx context.Context, workspaceId primitive.ObjectID, now time.Time) ([]model.Invitation, error)
+	GetPendingInvitationsForEmail(ctx context.Context, email string, now time.Time) ([]model.Invitation, error)
+	UseInvitation(ctx context.Context, invitationId primitive.ObjectID, userId primitive.ObjectID, now time.Time) (model.Invitation, error)
+	RevokeInvitation(ctx context.Context, invitationId primitive.ObjectID) (model.Invitation, error)
+}
+
+type invitationRepository struct {
+	invitationCollection *mongo.Collection
+}
+
+// EnsureIndexes makes tokens unique and keeps the pending listings fast
+func (r *invitationRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.invitationCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "tokenHash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{Keys: bson.D{{Key: "workspaceId", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "email", Value: 1}}},
+	})
+	return err
+}
+
+func (r *invitationRepository) CreateInvitation(ctx context.Context, invitation model.Invitation) (model.Invitation, error) {
+	if invitation.WorkspaceId.IsZero() || invitation.TokenHash == "" {
+		return model.Invitation{}, errors.New("WorkspaceId / Token is Empty in Repo")
+	}
+
+	invitation.ID = primitive.NewObjectID()
+	invitation.CreatedAt = time.Now()
+
+	if _, err := r.invitationCollection.InsertOne(ctx, invitation); err != nil {
+		return model.Invitation{}, err
+	}
+
+	return invitation, nil
+}
+
+func (r *invitationRepository) GetInvitationById(ctx context.Context, invitationId string) (model.Invitation, error) {
+	oid, err := primitive.ObjectIDFromHex(invitationId)
+	if err != nil {
+		return model.Invitation{}, err
+	}
+
+	return r.findOne(ctx, bson.M{"_id": oid})
+}
+
+func (r *invitationRepository) GetInvitationByTokenHash(ctx context.Context, tokenHash string) (model.Invitation, error) {
+	return r.findOne(ctx, bson.M{"tokenHash": tokenHash})
+}
+
+// GetPendingInvitations lists the invitations of a workspace that can still
+// be accepted, newest first
+func (r *invitationRepository) GetPendingInvitations(ctx context.Context, workspaceId primitive.ObjectID, now time.Time) ([]model.Invitation, error) {
+	filter := pendingInvitations(now)
+	filter["workspaceId"] = workspaceId
+	return r.find(ctx, filter)
+}
+
+// GetPendingInvitationsForEmail lists the invitations addressed to email that
+// can still be accepted, newest first
+func (r *invitationRepository) GetPendingInvitationsForEmail(ctx context.Context, email string, now time.Time) ([]model.Invitation, error) {
+	filter := pendingInvitations(now)
+	filter["email"] = email
+	return r.find(ctx, filter)
+}
+
+// UseInvitation counts one use by the user, only while the invitation is
+// pending and the user has not used it yet
+func (r *invitationRepository) UseInvitation(ctx context.Context, invitationId primitive.ObjectID, userId primitive.ObjectID, now time.Time) (model.Invitation, error) {
+	filter := pendingInvitations(now)
+	filter["_id"] = invitationId
+	filter["acceptedBy"] = bson.M{"$ne": userId}
+
+	update := bson.M{"$inc": bson.M{"uses": 1}, "$push": bson.M{"acceptedBy": userId}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var used model.Invitation
+	if err := r.invitationCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&used); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Invitation{}, ErrInvitationUnavailable
+		}
+		return model.Invitation{}, err
+	}
+
+	return used, nil
+}
+
+func (r *invitationRepository) RevokeInvitation(ctx context.Context, invitationId primitive.ObjectID) (model.Invitation, error) {
+	filter := bson.M{"_id": invitationId, "revokedAt": nil}
+	update := bson.M{"$set": bson.M{"revokedAt": time.Now()}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var revoked model.Invitation
+	if err := r.invitationCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&revoked); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Invitation{}, errors.New("invitation is revoked already")
+		}
+		return model.Invitation{}, err
+	}
+
+	return revoked, nil
+}
+
+// pendingInvitations matches invitations that are not revoked, expired or
+// used up
+func pendingInvitations(now time.Time) bson.M {
+	return bson.M{
+		"revokedAt": nil,
+		"expiresAt": bson.M{"$gt": now},
+		"$expr":     bson.M{"$lt": bson.A{"$uses", "$maxUses"}},
+	}
+}
+
+func (r *invitationRepository) findOne(ctx context.Context, filter bson.M) (model.Invitation, error) {
+	var invitation model.Invitation
+	if err := r.invitationCollection.FindOne(ctx, filter).Decode(&invitation); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Invitation{}, errors.New("invitation not found")
+		}
+		return model.Invitation{}, err
+	}
+	return invitation, nil
+}
+
+func (r *invitationRepository) find(ctx context.Context, filter bson.M) ([]model.Invitation, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	cursor, err := r.invitationCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	invitations := []model.Invitation{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+func NewInvitationRepository(invitationCollection *mongo.Collection) InvitationRepository {
+	return &invitationRepository{
+		invitationCollection: invitationCollection,
+	}
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type NotificationRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	CreateNotification(ctx context.Context, notification model.Notification) (model.Notification, error)
+	GetNotificationById(ctx context.Context, notificationId string) (model.Notification, error)
+	GetUserNotifications(ctx context.Context, userId string, unreadOnly bool, limit int) ([]model.Notification, error)
+	MarkRead(ctx context.Context, notificationId string) (model.Notification, error)
+}
+
+type notificationRepository struct {
+	notificationCollection *mongo.Collection
+}
+
+// EnsureIndexes creates the index used to list the notifications of a user
+func (r *notificationRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.notificationCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}},
+	})
+	return err
+}
+
+func (r *notificationRepository) CreateNotification(ctx context.Context, notification model.Notification) (model.Notification, error) {
+	if notification.UserId.IsZero() {
+		return model.Notification{}, errors.New("UserId is Empty in Repo")
+	}
+
+	notification.ID = primitive.NewObjectID()
+	notification.Read = false
+	notification.CreatedAt = time.Now()
+
+	if _, err := r.notificationCollection.InsertOne(ctx, notification); err != nil {
+		return model.Notification{}, err
+	}
+
+	return notification, nil
+}
+
+func (r *notificationRepository) GetNotificationById(ctx context.Context, notificationId string) (model.Notification, error) {
+	oid, err := primitive.ObjectIDFromHex(notificationId)
+	if err != nil {
+		return model.Notification{}, err
+	}
+
+	var notification model.Notification
+	if err := r.notificationCollection.FindOne(ctx, bson.M{"_id": oid}).Decode(&notification); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Notification{}, errors.New("notification not found")
+		}
+		return model.Notification{}, err
+	}
+
+	return notification, nil
+}
+
+// GetUserNotifications returns the newest notifications of the user first
+func (r *notificationRepository) GetUserNotifications(ctx context.Context, userId string, unreadOnly bool, limit int) ([]model.Notification, error) {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"userId": userOid}
+	if unreadOnly {
+		filter["read"] = false
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(int64(limit))
+
+	cursor, err := r.notificationCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	notifications := []model.Notification{}
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (r *notificationRepository) MarkRead(ctx context.Context, notificationId string) (model.Notification, error) {
+	oid, err := primitive.ObjectIDFromHex(notificationId)
+	if err != nil {
+		return model.Notification{}, err
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Notification
+	if err := r.notificationCollection.FindOneAndUpdate(ctx, bson.M{"_id": oid}, bson.M{"$set": bson.M{"read": true}}, opts).Decode(&updated); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Notification{}, errors.New("notification not found")
+		}
+		return model.Notification{}, err
+	}
+
+	return updated, nil
+}
+
+func NewNotificationRepository(notificationCollection *mongo.Collection) NotificationRepository {
+	return &notificationRepository{
+		notificationCollection: notificationCollection,
+	}
+}
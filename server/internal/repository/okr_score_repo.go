@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrAlreadyScored = errors.New("objective already has a final score")
+
+type OKRScoreRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	CreateScore(ctx context.Context, score model.OKRScore) (model.OKRScore, error)
+	GetGoalScores(ctx context.Context, goalId primitive.ObjectID) ([]model.OKRScore, error)
+}
+
+type okrScoreRepository struct {
+	scoreCollection *mongo.Collection
+}
+
+// EnsureIndexes allows one final score per objective
+func (r *okrScoreRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.scoreCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "goalId", Value: 1}},
+			Options: options.Index().
+				SetName("one_final_score_per_goal").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"final": true}),
+		},
+		{Keys: bson.D{{Key: "goalId", Value: 1}, {Key: "scoredAt", Value: -1}}},
+	})
+	return err
+}
+
+func (r *okrScoreRepository) CreateScore(ctx context.Context, score model.OKRScore) (model.OKRScore, error) {
+	score.ID = primitive.NewObjectID()
+	score.ScoredAt = time.Now()
+
+	if _, err := r.scoreCollection.InsertOne(ctx, score); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return model.OKRScore{}, ErrAlreadyScored
+		}
+		return model.OKRScore{}, err
+	}
+
+	return score, nil
+}
+
+// GetGoalScores returns the score history of an objective, newest first
+func (r *okrScoreRepository) GetGoalScores(ctx context.Context, goalId primitive.ObjectID) ([]model.OKRScore, error) {
+	opts := options.Find().SetSort(bson.M{"scoredAt": -1})
+	return findAll[model.OKRScore](ctx, r.scoreCollection, bson.M{"goalId": goalId}, opts)
+}
+
+func NewOKRScoreRepository(scoreCollection *mongo.Collection) OKRScoreRepository {
+	return &okrScoreRepository{
+		scoreCollection: scoreCollection,
+	}
+}
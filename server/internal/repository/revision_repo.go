@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RevisionRepository is append only, revisions are never updated and only
+// deleted together with their purged entity
+type RevisionRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	CreateRevision(ctx context.Context, revision model.Revision) (model.Revision, error)
+	GetHistory(ctx context.Context, entityType string, entityId string) ([]model.Revision, error)
+	DeleteEntityRevisions(ctx context.Context, entityType string, entityIds []primitive.ObjectID) error
+}
+
+type revisionRepository struct {
+	revisionCollection *mongo.Collection
+}
+
+// EnsureIndexes keeps versions unique per entity
+func (r *revisionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.revisionCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "entityType", Value: 1},
+			{Key: "entityId", Value: 1},
+			{Key: "version", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// CreateRevision appends the revision with the next version of the entity,
+// two writers racing for the same version retry with the next one
+func (r *revisionRepository) CreateRevision(ctx context.Context, revision model.Revision) (model.Revision, error) {
+	if revision.EntityType == "" || revision.EntityId.IsZero() {
+		return model.Revision{}, errors.New("EntityType / EntityId is Empty in Repo")
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		filter := bson.M{"entityType": revision.EntityType, "entityId": revision.EntityId}
+		opts := options.FindOne().SetSort(bson.M{"version": -1}).SetProjection(bson.M{"version": 1})
+
+		var last model.Revision
+		err := r.revisionCollection.FindOne(ctx, filter, opts).Decode(&last)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Revision{}, err
+		}
+
+		revision.ID = primitive.NewObjectID()
+		revision.Version = last.Version + 1
+		revision.CreatedAt = time.Now()
+
+		_, err = r.revisionCollection.InsertOne(ctx, revision)
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		if err != nil {
+			return model.Revision{}, err
+		}
+
+		return revision, nil
+	}
+
+	return model.Revision{}, errors.New("could not write revision, try again")
+}
+
+// GetHistory returns every revision of the entity, oldest first
+func (r *revisionRepository) GetHistory(ctx context.Context, entityType string, entityId string) ([]model.Revision, error) {
+	entityOid, err := primitive.ObjectIDFromHex(entityId)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"entityType": entityType, "entityId": entityOid}
+	opts := options.Find().SetSort(bson.M{"version": 1})
+
+	cursor, err := r.revisionCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	revisions := []model.Revision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// DeleteEntityRevisions removes the history of purged entities
+func (r *revisionRepository) DeleteEntityRevisions(ctx context.Context, entityType string, entityIds []primitive.ObjectID) error {
+	_, err := r.revisionCollection.DeleteMany(ctx, bson.M{"entityType": entityType, "entityId": bson.M{"$in": entityIds}})
+	return err
+}
+
+func NewRevisionRepository(revisionCollection *mongo.Collection) RevisionRepository {
+	return &revisionRepository{
+		revisionCollection: revisionCollection,
+	}
+}
@@ -0,0 +1,400 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/pkg/nsearch"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SearchRepository searches the todos, goals and workspaces a user is a
+// member of, owned or shared.
+// Filters of the query must be validated by the caller
+type SearchRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Search(ctx context.Context, userId string, query nsearch.Query, limit int) ([]model.SearchResult, error)
+}
+
+// searchCollections is shared by both search backends
+type searchCollections struct {
+	todoCollection      *mongo.Collection
+	goalCollection      *mongo.Collection
+	workspaceCollection *mongo.Collection
+}
+
+// searchScope holds one mongo filter per entity type, nil when the query
+// filters exclude that type
+type searchScope struct {
+	todo       bson.M
+	goal       bson.M
+	workspace  bson.M
+	workspaces map[primitive.ObjectID]string // live workspaces of the user, owned or shared, id -> name
+}
+
+// scope turns the filters of the query into mongo filters:
+// type:todo|goal|workspace, priority:<p> (todos only), is:open|done (todos
+// and goals), ws:<name> (by workspace name, case insensitive)
+func (c *searchCollections) scope(ctx context.Context, userId string, query nsearch.Query) (searchScope, error) {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return searchScope{}, err
+	}
+
+	member := bson.A{bson.M{"userId": userOid}, bson.M{"members.userId": userOid}}
+	workspaces, err := findAll[model.Workspace](ctx, c.workspaceCollection, bson.M{"$or": member, "deletedAt": nil})
+	if err != nil {
+		return searchScope{}, err
+	}
+
+	// items are found through the workspaces, whoever created them
+	memberOf := []primitive.ObjectID{}
+	for _, workspace := range workspaces {
+		memberOf = append(memberOf, workspace.ID)
+	}
+
+	scope := searchScope{
+		todo:       bson.M{"workspaceId": bson.M{"$in": memberOf}, "deletedAt": nil},
+		goal:       bson.M{"workspaceId": bson.M{"$in": memberOf}, "deletedAt": nil},
+		workspace:  bson.M{"_id": bson.M{"$in": memberOf}, "deletedAt": nil},
+		workspaces: make(map[primitive.ObjectID]string),
+	}
+	for _, workspace := range workspaces {
+		scope.workspaces[workspace.ID] = workspace.WorkspaceName
+	}
+
+	if types := query.Filters["type"]; len(types) > 0 {
+		if !contains(types, model.EntityTodo) {
+			scope.todo = nil
+		}
+		if !contains(types, model.EntityGoal) {
+			scope.goal = nil
+		}
+		if !contains(types, model.EntityWorkspace) {
+			scope.workspace = nil
+		}
+	}
+
+	if priorities := query.Filters["priority"]; len(priorities) > 0 {
+		scope.goal, scope.workspace = nil, nil
+		if scope.todo != nil {
+			scope.todo["priority"] = bson.M{"$in": priorities}
+		}
+	}
+
+	// is:open and is:done together means either
+	if states := query.Filters["is"]; len(states) > 0 {
+		scope.workspace = nil
+		if open, done := contains(states, "open"), contains(states, "done"); open != done {
+			if scope.todo != nil {
+				scope.todo["done"] = done
+			}
+			if scope.goal != nil {
+				scope.goal["done"] = done
+			}
+		}
+	}
+
+	if names := query.Filters["ws"]; len(names) > 0 {
+		workspaceIds := []primitive.ObjectID{}
+		for _, workspace := range workspaces {
+			if containsFold(names, workspace.WorkspaceName) {
+				workspaceIds = append(workspaceIds, workspace.ID)
+			}
+		}
+		if scope.todo != nil {
+			scope.todo["workspaceId"] = bson.M{"$in": workspaceIds}
+		}
+		if scope.goal != nil {
+			scope.goal["workspaceId"] = bson.M{"$in": workspaceIds}
+		}
+		if scope.workspace != nil {
+			scope.workspace["_id"] = bson.M{"$in": workspaceIds}
+		}
+	}
+
+	return scope, nil
+}
+
+// finish drops items of workspaces in the trash, sorts best first, cuts to
+// limit and adds workspace names and highlights
+func (s searchScope) finish(results []model.SearchResult, query nsearch.Query, limit int) []model.SearchResult {
+	live := results[:0]
+	for _, result := range results {
+		if result.Type != model.EntityWorkspace {
+			name, ok := s.workspaces[result.WorkspaceId]
+			if !ok {
+				continue
+			}
+			result.WorkspaceName = name
+		}
+		live = append(live, result)
+	}
+
+	sort.SliceStable(live, func(i, j int) bool { return live[i].Score > live[j].Score })
+	if len(live) > limit {
+		live = live[:limit]
+	}
+
+	for i := range live {
+		live[i].Highlight = nsearch.Highlight(live[i].Title, query)
+	}
+	return live
+}
+
+// textSearchRepository uses Mongo text indexes, scores are Mongo's textScore
+type textSearchRepository struct {
+	searchCollections
+}
+
+// EnsureIndexes creates one text index per collection
+func (r *textSearchRepository) EnsureIndexes(ctx context.Context) error {
+	indexes := []struct {
+		collection *mongo.Collection
+		field      string
+	}{
+		{r.todoCollection, "task"},
+		{r.goalCollection, "title"},
+		{r.workspaceCollection, "workspaceName"},
+	}
+
+	for _, index := range indexes {
+		_, err := index.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys: bson.D{{Key: index.field, Value: "text"}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *textSearchRepository) Search(ctx context.Context, userId string, query nsearch.Query, limit int) ([]model.SearchResult, error) {
+	scope, err := r.scope(ctx, userId, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []model.SearchResult
+
+	if scope.todo != nil {
+		todos, err := findScored[model.Todo](ctx, r.todoCollection, scope.todo, query, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, todo := range todos {
+			results = append(results, todoResult(todo.Doc, todo.Score))
+		}
+	}
+
+	if scope.goal != nil {
+		goals, err := findScored[model.Goals](ctx, r.goalCollection, scope.goal, query, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, goal := range goals {
+			results = append(results, goalResult(goal.Doc, goal.Score))
+		}
+	}
+
+	if scope.workspace != nil {
+		workspaces, err := findScored[model.Workspace](ctx, r.workspaceCollection, scope.workspace, query, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, workspace := range workspaces {
+			results = append(results, workspaceResult(workspace.Doc, workspace.Score))
+		}
+	}
+
+	return scope.finish(results, query, limit), nil
+}
+
+// memorySearchRepository loads the user's documents and ranks them with an
+// in-process index, for Mongo compatible stores without $text support
+type memorySearchRepository struct {
+	searchCollections
+}
+
+// EnsureIndexes has nothing to do, the index is built per search
+func (r *memorySearchRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+func (r *memorySearchRepository) Search(ctx context.Context, userId string, query nsearch.Query, limit int) ([]model.SearchResult, error) {
+	scope, err := r.scope(ctx, userId, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []model.SearchResult
+
+	if scope.todo != nil {
+		todos, err := findAll[model.Todo](ctx, r.todoCollection, scope.todo)
+		if err != nil {
+			return nil, err
+		}
+		for _, todo := range todos {
+			candidates = append(candidates, todoResult(todo, 0))
+		}
+	}
+
+	if scope.goal != nil {
+		goals, err := findAll[model.Goals](ctx, r.goalCollection, scope.goal)
+		if err != nil {
+			return nil, err
+		}
+		for _, goal := range goals {
+			candidates = append(candidates, goalResult(goal, 0))
+		}
+	}
+
+	if scope.workspace != nil {
+		workspaces, err := findAll[model.Workspace](ctx, r.workspaceCollection, scope.workspace)
+		if err != nil {
+			return nil, err
+		}
+		for _, workspace := range workspaces {
+			candidates = append(candidates, workspaceResult(workspace, 0))
+		}
+	}
+
+	// only filters, nothing to rank
+	if !query.HasText() {
+		return scope.finish(candidates, query, limit), nil
+	}
+
+	index := nsearch.NewIndex()
+	for i, candidate := range candidates {
+		index.Add(strconv.Itoa(i), candidate.Title)
+	}
+
+	var results []model.SearchResult
+	for _, hit := range index.Search(query) {
+		i, _ := strconv.Atoi(hit.ID)
+		result := candidates[i]
+		result.Score = hit.Score
+		results = append(results, result)
+	}
+
+	return scope.finish(results, query, limit), nil
+}
+
+// scored is a document with its text score
+type scored[T any] struct {
+	Doc   T       `bson:",inline"`
+	Score float64 `bson:"score"`
+}
+
+// findScored runs a $text search when the query has text, otherwise it only
+// applies the filter and returns the newest documents
+func findScored[T any](ctx context.Context, collection *mongo.Collection, filter bson.M, query nsearch.Query, limit int) ([]scored[T], error) {
+	opts := options.Find().SetLimit(int64(limit)).SetSort(bson.M{"_id": -1})
+
+	if query.HasText() {
+		textFilter := bson.M{"$text": bson.M{"$search": query.Text()}}
+		for key, value := range filter {
+			textFilter[key] = value
+		}
+		filter = textFilter
+
+		score := bson.M{"$meta": "textScore"}
+		opts = options.Find().SetLimit(int64(limit)).SetProjection(bson.M{"score": score}).SetSort(bson.M{"score": score})
+	}
+
+	return findAll[scored[T]](ctx, collection, filter, opts)
+}
+
+func findAll[T any](ctx context.Context, collection *mongo.Collection, filter bson.M, opts ...*options.FindOptions) ([]T, error) {
+	cursor, err := collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	docs := []T{}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func todoResult(todo model.Todo, score float64) model.SearchResult {
+	return model.SearchResult{
+		Type:        model.EntityTodo,
+		ID:          todo.ID,
+		Title:       todo.Task,
+		Score:       score,
+		WorkspaceId: todo.WorkspaceId,
+		Priority:    todo.Priority,
+		Done:        todo.Done,
+	}
+}
+
+func goalResult(goal model.Goals, score float64) model.SearchResult {
+	return model.SearchResult{
+		Type:        model.EntityGoal,
+		ID:          goal.ID,
+		Title:       goal.Title,
+		Score:       score,
+		WorkspaceId: goal.WorkspaceId,
+		Done:        goal.Done,
+	}
+}
+
+func workspaceResult(workspace model.Workspace, score float64) model.SearchResult {
+	return model.SearchResult{
+		Type:          model.EntityWorkspace,
+		ID:            workspace.ID,
+		Title:         workspace.WorkspaceName,
+		Score:         score,
+		WorkspaceId:   workspace.ID,
+		WorkspaceName: workspace.WorkspaceName,
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewTextSearchRepository searches with Mongo text indexes
+func NewTextSearchRepository(todoCollection *mongo.Collection, goalCollection *mongo.Collection, workspaceCollection *mongo.Collection) SearchRepository {
+	return &textSearchRepository{
+		searchCollections: searchCollections{
+			todoCollection:      todoCollection,
+			goalCollection:      goalCollection,
+			workspaceCollection: workspaceCollection,
+		},
+	}
+}
+
+// NewMemorySearchRepository searches with an in-process index
+func NewMemorySearchRepository(todoCollection *mongo.Collection, goalCollection *mongo.Collection, workspaceCollection *mongo.Collection) SearchRepository {
+	return &memorySearchRepository{
+		searchCollections: searchCollections{
+			todoCollection:      todoCollection,
+			goalCollection:      goalCollection,
+			workspaceCollection: workspaceCollection,
+		},
+	}
+}
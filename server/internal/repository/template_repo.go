@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TemplateRepository interface {
+	CreateTemplate(ctx context.Context, template model.Template) (model.Template, error)
+	GetTemplateById(ctx context.Context, templateId string) (model.Template, error)
+	GetUserTemplates(ctx context.Context, userId string) ([]model.Template, error)
+	DeleteTemplate(ctx context.Context, templateId string) error
+}
+
+type templateRepository struct {
+	templateCollection *mongo.Collection
+}
+
+func (r *templateRepository) CreateTemplate(ctx context.Context, template model.Template) (model.Template, error) {
+	if template.UserId.IsZero() {
+		return model.Template{}, errors.New("UserId is Empty in Repo")
+	}
+
+	template.ID = primitive.NewObjectID()
+	template.CreatedAt = time.Now()
+
+	if _, err := r.templateCollection.InsertOne(ctx, template); err != nil {
+		return model.Template{}, err
+	}
+
+	return template, nil
+}
+
+func (r *templateRepository) GetTemplateById(ctx context.Context, templateId string) (model.Template, error) {
+	oid, err := primitive.ObjectIDFromHex(templateId)
+	if err != nil {
+		return model.Template{}, err
+	}
+
+	var template model.Template
+	if err := r.templateCollection.FindOne(ctx, bson.M{"_id": oid}).Decode(&template); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Template{}, errors.New("template not found")
+		}
+		return model.Template{}, err
+	}
+
+	return template, nil
+}
+
+// GetUserTemplates returns the templates of the user sorted by name
+func (r *templateRepository) GetUserTemplates(ctx context.Context, userId string) ([]model.Template, error) {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.templateCollection.Find(ctx, bson.M{"userId": userOid}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	templates := []model.Template{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+func (r *templateRepository) DeleteTemplate(ctx context.Context, templateId string) error {
+	oid, err := primitive.ObjectIDFromHex(templateId)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.templateCollection.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return errors.New("template not found")
+	}
+
+	return nil
+}
+
+func NewTemplateRepository(templateCollection *mongo.Collection) TemplateRepository {
+	return &templateRepository{
+		templateCollection: templateCollection,
+	}
+}
@@ -0,0 +1,350 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrTimerRunning = errors.New("a timer is already running for this user")
+
+type TimeEntryRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	StartTimer(ctx context.Context, entry model.TimeEntry) (model.TimeEntry, error)
+	GetRunningTimer(ctx context.Context, userId string) (model.TimeEntry, error)
+	StopTimer(ctx context.Context, entryId primitive.ObjectID, endedAt time.Time, durationSeconds int64) (model.TimeEntry, error)
+	CreateEntry(ctx context.Context, entry model.TimeEntry) (model.TimeEntry, error)
+	GetTodoEntries(ctx context.Context, todoId string) ([]model.TimeEntry, error)
+	DeleteEntry(ctx context.Context, userId string, entryId string) error
+	ReportByWorkspace(ctx context.Context, userId string, from time.Time, to time.Time) ([]model.TimeReportRow, error)
+	ReportByPriority(ctx context.Context, userId string, from time.Time, to time.Time) ([]model.TimeReportRow, error)
+	ReportByDay(ctx context.Context, userId string, from time.Time, to time.Time, timezone string) ([]model.TimeReportRow, error)
+	EstimateReport(ctx context.Context, userId string, workspaceId string) ([]model.EstimateReportRow, error)
+	MoveTodoEntries(ctx context.Context, todoIds []primitive.ObjectID, workspaceId primitive.ObjectID) error
+	DeleteTodoEntries(ctx context.Context, todoIds []primitive.ObjectID) error
+}
+
+type timeEntryRepository struct {
+	timeCollection *mongo.Collection
+}
+
+// EnsureIndexes creates the index that allows only one running timer per user
+func (r *timeEntryRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.timeCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "userId", Value: 1}},
+			Options: options.Index().
+				SetName("one_running_timer_per_user").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"running": true}),
+		},
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "startedAt", Value: 1}}},
+		{Keys: bson.D{{Key: "todoId", Value: 1}}},
+	})
+	return err
+}
+
+func (r *timeEntryRepository) StartTimer(ctx context.Context, entry model.TimeEntry) (model.TimeEntry, error) {
+	entry.ID = primitive.NewObjectID()
+	entry.Running = true
+	entry.CreatedAt = time.Now()
+
+	if _, err := r.timeCollection.InsertOne(ctx, entry); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return model.TimeEntry{}, ErrTimerRunning
+		}
+		return model.TimeEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (r *timeEntryRepository) GetRunningTimer(ctx context.Context, userId string) (model.TimeEntry, error) {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+
+	var entry model.TimeEntry
+	if err := r.timeCollection.FindOne(ctx, bson.M{"userId": userOid, "running": true}).Decode(&entry); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.TimeEntry{}, errors.New("no running timer")
+		}
+		return model.TimeEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (r *timeEntryRepository) StopTimer(ctx context.Context, entryId primitive.ObjectID, endedAt time.Time, durationSeconds int64) (model.TimeEntry, error) {
+	// running: true in the filter so a timer is never stopped twice
+	filter := bson.M{"_id": entryId, "running": true}
+	update := bson.M{"$set": bson.M{
+		"running":         false,
+		"endedAt":         endedAt,
+		"durationSeconds": durationSeconds,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var stopped model.TimeEntry
+	if err := r.timeCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&stopped); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.TimeEntry{}, errors.New("no running timer")
+		}
+		return model.TimeEntry{}, err
+	}
+
+	return stopped, nil
+}
+
+func (r *timeEntryRepository) CreateEntry(ctx context.Context, entry model.TimeEntry) (model.TimeEntry, error) {
+	entry.ID = primitive.NewObjectID()
+	entry.Running = false
+	entry.CreatedAt = time.Now()
+
+	if _, err := r.timeCollection.InsertOne(ctx, entry); err != nil {
+		return model.TimeEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (r *timeEntryRepository) GetTodoEntries(ctx context.Context, todoId string) ([]model.TimeEntry, error) {
+	todoOid, err := primitive.ObjectIDFromHex(todoId)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.M{"startedAt": -1})
+	cursor, err := r.timeCollection.Find(ctx, bson.M{"todoId": todoOid}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []model.TimeEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r *timeEntryRepository) DeleteEntry(ctx context.Context, userId string, entryId string) error {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return err
+	}
+	entryOid, err := primitive.ObjectIDFromHex(entryId)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.timeCollection.DeleteOne(ctx, bson.M{"_id": entryOid, "userId": userOid})
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return errors.New("time entry not found")
+	}
+
+	return nil
+}
+
+// finishedEntries matches the stopped entries of a user inside [from, to)
+func finishedEntries(userOid primitive.ObjectID, from time.Time, to time.Time) bson.D {
+	return bson.D{{Key: "$match", Value: bson.M{
+		"userId":    userOid,
+		"running":   false,
+		"startedAt": bson.M{"$gte": from, "$lt": to},
+	}}}
+}
+
+func (r *timeEntryRepository) ReportByWorkspace(ctx context.Context, userId string, from time.Time, to time.Time) ([]model.TimeReportRow, error) {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{
+		finishedEntries(userOid, from, to),
+		{{Key: "$group", Value: bson.M{
+			"_id":          "$workspaceId",
+			"totalSeconds": bson.M{"$sum": "$durationSeconds"},
+			"entries":      bson.M{"$sum": 1},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "workspaces",
+			"localField":   "_id",
+			"foreignField": "_id",
+			"as":           "workspace",
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":          bson.M{"$toString": "$_id"},
+			"label":        bson.M{"$ifNull": bson.A{bson.M{"$first": "$workspace.workspaceName"}, ""}},
+			"totalSeconds": 1,
+			"entries":      1,
+		}}},
+		{{Key: "$sort", Value: bson.M{"totalSeconds": -1}}},
+	}
+
+	return r.aggregateReport(ctx, pipeline)
+}
+
+func (r *timeEntryRepository) ReportByPriority(ctx context.Context, userId string, from time.Time, to time.Time) ([]model.TimeReportRow, error) {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := mongo.Pipeline{
+		finishedEntries(userOid, from, to),
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "todos",
+			"localField":   "todoId",
+			"foreignField": "_id",
+			"as":           "todo",
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":          bson.M{"$ifNull": bson.A{bson.M{"$first": "$todo.priority"}, ""}},
+			"totalSeconds": bson.M{"$sum": "$durationSeconds"},
+			"entries":      bson.M{"$sum": 1},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":          1,
+			"label":        "$_id",
+			"totalSeconds": 1,
+			"entries":      1,
+		}}},
+		{{Key: "$sort", Value: bson.M{"totalSeconds": -1}}},
+	}
+
+	return r.aggregateReport(ctx, pipeline)
+}
+
+func (r *timeEntryRepository) ReportByDay(ctx context.Context, userId string, from time.Time, to time.Time, timezone string) ([]model.TimeReportRow, error) {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	// days are cut in the timezone of the user, not in UTC
+	pipeline := mongo.Pipeline{
+		finishedEntries(userOid, from, to),
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$dateToString": bson.M{
+				"format":   "%Y-%m-%d",
+				"date":     "$startedAt",
+				"timezone": timezone,
+			}},
+			"totalSeconds": bson.M{"$sum": "$durationSeconds"},
+			"entries":      bson.M{"$sum": 1},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":          1,
+			"label":        "$_id",
+			"totalSeconds": 1,
+			"entries":      1,
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+
+	return r.aggregateReport(ctx, pipeline)
+}
+
+func (r *timeEntryRepository) aggregateReport(ctx context.Context, pipeline mongo.Pipeline) ([]model.TimeReportRow, error) {
+	cursor, err := r.timeCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rows := []model.TimeReportRow{}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// EstimateReport lists tracked vs estimated minutes for every todo the user
+// tracked time on, workspaceId is optional
+func (r *timeEntryRepository) EstimateReport(ctx context.Context, userId string, workspaceId string) ([]model.EstimateReportRow, error) {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	match := bson.M{"userId": userOid, "running": false}
+	if workspaceId != "" {
+		workspaceOid, err := primitive.ObjectIDFromHex(workspaceId)
+		if err != nil {
+			return nil, err
+		}
+		match["workspaceId"] = workspaceOid
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":          "$todoId",
+			"totalSeconds": bson.M{"$sum": "$durationSeconds"},
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "todos",
+			"localField":   "_id",
+			"foreignField": "_id",
+			"as":           "todo",
+		}}},
+		{{Key: "$unwind", Value: "$todo"}},
+		{{Key: "$project", Value: bson.M{
+			"task":            "$todo.task",
+			"done":            "$todo.done",
+			"estimateMinutes": bson.M{"$ifNull": bson.A{"$todo.estimateMinutes", 0}},
+			"trackedMinutes":  bson.M{"$toInt": bson.M{"$round": bson.A{bson.M{"$divide": bson.A{"$totalSeconds", 60}}, 0}}},
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"differenceMinutes": bson.M{"$subtract": bson.A{"$trackedMinutes", "$estimateMinutes"}},
+		}}},
+		{{Key: "$sort", Value: bson.M{"differenceMinutes": -1}}},
+	}
+
+	cursor, err := r.timeCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	rows := []model.EstimateReportRow{}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// MoveTodoEntries keeps the workspace of entries in line with their moved todos
+func (r *timeEntryRepository) MoveTodoEntries(ctx context.Context, todoIds []primitive.ObjectID, workspaceId primitive.ObjectID) error {
+	filter := bson.M{"todoId": bson.M{"$in": todoIds}}
+	_, err := r.timeCollection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"workspaceId": workspaceId}})
+	return err
+}
+
+// DeleteTodoEntries removes the tracked time of purged todos
+func (r *timeEntryRepository) DeleteTodoEntries(ctx context.Context, todoIds []primitive.ObjectID) error {
+	_, err := r.timeCollection.DeleteMany(ctx, bson.M{"todoId": bson.M{"$in": todoIds}})
+	return err
+}
+
+func NewTimeEntryRepository(timeCollection *mongo.Collection) TimeEntryRepository {
+	return &timeEntryRepository{
+		timeCollection: timeCollection,
+	}
+}
@@ -1,225 +1,1310 @@
-// Package repository handles data access layer operations for todos
-package repository
-
-import (
-	"context"
-	"errors"
-	"fmt"
-	"github.com/ndk123-web/fast-todo/internal/model"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-)
-
-type TodoRepository interface {
-	GetAll(ctx context.Context) ([]model.Todo, error)
-	CreateTodo(ctx context.Context, todo model.Todo, workspaceId string, userId string) (model.Todo, error)
-	UpdateTodo(ctx context.Context, todoId string, updatedTask string, priority string) (model.Todo, error)
-	DeleteTodo(ctx context.Context, todoId string) (bool, error)
-	GetSpecificTodo(ctx context.Context, workspaceId string, userId string) ([]model.Todo, error)
-	ToggleTodo(ctx context.Context, todoId string, toggle string, userId string) (bool, error)
-}
-
-// todoRepo implements TodoRepository with MongoDB as the data store
-type todoRepo struct {
-	collection *mongo.Collection // MongoDB collection for todos
-}
-
-// GetAll retrieves all todo items from the database
-func (r *todoRepo) GetAll(ctx context.Context) ([]model.Todo, error) {
-	cursor, err := r.collection.Find(ctx, bson.D{})
-
-	if err != nil {
-		return nil, err
-	}
-
-	// in the end close the curson
-	defer cursor.Close(ctx)
-
-	// result will be here
-	var todos []model.Todo
-
-	// loop over the response
-	for cursor.Next(ctx) {
-		var todo model.Todo
-		if err := cursor.Decode(&todo); err != nil {
-			return nil, err
-		}
-
-		// append todos
-		todos = append(todos, todo)
-	}
-
-	return todos, nil
-}
-
-func (r *todoRepo) ToggleTodo(ctx context.Context, todoId, toggle, userId string) (bool, error) {
-	if todoId == "" || toggle == "" || userId == "" {
-		return false, errors.New("missing required fields: todoId/toggle/userId")
-	}
-
-	fmt.Println("TodoId: ", todoId)
-	fmt.Println("Toggle: ", toggle)
-	fmt.Println("UserId: ", userId)
-
-	todoOid, err := primitive.ObjectIDFromHex(todoId)
-	if err != nil {
-		return false, err
-	}
-	userOid, err := primitive.ObjectIDFromHex(userId)
-	if err != nil {
-		return false, err
-	}
-
-	// determine bool value from toggle
-	var doneValue bool
-	switch toggle {
-	case "completed":
-		doneValue = true
-	case "not-started":
-		doneValue = false
-	default:
-		return false, errors.New("invalid toggle value")
-	}
-
-	filter := bson.M{"_id": todoOid, "userId": userOid}
-	update := bson.M{"$set": bson.M{"done": doneValue}}
-
-	updated, err := r.collection.UpdateOne(ctx, filter, update)
-	if err != nil {
-		return false, err
-	}
-
-	if updated.ModifiedCount == 0 {
-		return false, errors.New("no todo found for update")
-	}
-
-	return true, nil
-}
-
-// CreateTodo adds a new todo item to the database
-func (r *todoRepo) CreateTodo(ctx context.Context, todo model.Todo, workspaceId string, userId string) (model.Todo, error) {
-	if todo.Task == "" {
-		return model.Todo{}, errors.New("Task is Invalid / Empty")
-	}
-
-	// string -> object Id
-	workspaceOid, err := primitive.ObjectIDFromHex(workspaceId)
-	if err != nil {
-		return model.Todo{}, err
-	}
-	userOid, err := primitive.ObjectIDFromHex(userId)
-	if err != nil {
-		return model.Todo{}, err
-	}
-
-	todo.WorkspaceId = workspaceOid
-	todo.UserId = userOid
-
-	insertedId, err := r.collection.InsertOne(ctx, todo)
-	if err != nil {
-		return model.Todo{}, err
-	}
-
-	todo.ID = insertedId.InsertedID.(primitive.ObjectID)
-	return todo, nil
-}
-
-// UpdateTodo modifies an existing todo's task text
-func (r *todoRepo) UpdateTodo(ctx context.Context, todoId string, updatedTask string, priority string) (model.Todo, error) {
-	if todoId == "" {
-		return model.Todo{}, errors.New("Todo Id is Empty")
-	}
-
-	// convert the string id to object Id
-	oid, err2 := primitive.ObjectIDFromHex(todoId)
-
-	if err2 != nil {
-		return model.Todo{}, err2
-	}
-
-	// always convert string -> object id
-	filter := bson.M{"_id": oid}
-	update := bson.M{"$set": bson.M{"task": updatedTask, "priority": priority}}
-
-	_, err := r.collection.UpdateOne(ctx, filter, update)
-
-	if err != nil {
-		return model.Todo{}, err
-	}
-
-	// find the todo
-	var updatedTodo model.Todo
-	err3 := r.collection.FindOne(ctx, filter).Decode(&updatedTodo)
-
-	if err3 != nil {
-		return model.Todo{}, err
-	}
-
-	return updatedTodo, nil
-}
-
-// DeleteTodo removes a todo item by its ID
-func (r *todoRepo) DeleteTodo(ctx context.Context, todoId string) (bool, error) {
-
-	// string -> ObjectId
-	oid, err := primitive.ObjectIDFromHex(todoId)
-	if err != nil {
-		return false, err
-	}
-
-	// filter with Object ID
-	filter := bson.M{"_id": oid}
-
-	// filter and delete Document
-	_, err2 := r.collection.DeleteOne(ctx, filter)
-	if err2 != nil {
-		return false, err
-	}
-
-	return true, nil
-}
-
-func (r *todoRepo) GetSpecificTodo(ctx context.Context, workspaceId string, userId string) ([]model.Todo, error) {
-	// convert workspaceId and UserId into object
-	workspaceOid, err := primitive.ObjectIDFromHex(workspaceId)
-	if err != nil {
-		return nil, err
-	}
-	userOid, err := primitive.ObjectIDFromHex(userId)
-	if err != nil {
-		return nil, err
-	}
-
-	// filter the documents
-	filter := bson.M{"workspaceId": workspaceOid, "userId": userOid}
-	cursor, err := r.collection.Find(ctx, filter)
-
-	// otherwise cursor remains open and can cause memory leaks
-	defer cursor.Close(ctx)
-
-	if err != nil {
-		return nil, err
-	}
-
-	// get into the todos
-	var todos []model.Todo
-	for cursor.Next(ctx) {
-		var todo model.Todo
-		if err := cursor.Decode(&todo); err != nil {
-			return nil, err
-		}
-		todos = append(todos, todo)
-	}
-
-	// return todos and nil as no error
-	return todos, nil
-}
-
-// NewTodoRepository creates and returns a new instance of TodoRepository
-// It initializes the MongoDB collection for todo operations
-func NewTodoRepository(col *mongo.Collection) TodoRepository {
-	return &todoRepo{
-		collection: col,
-	}
-}
+// Package repository handles data access layer operations for todos
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/pkg/nrank"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sort"
+	"time"
+)
+
+type TodoRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	GetAll(ctx context.Context, workspaceIds []primitive.ObjectID) ([]model.Todo, error)
+	CreateTodo(ctx context.Context, todo model.Todo, workspaceId string, userId string) (model.Todo, error)
+	DeleteTodo(ctx context.Context, todoId string) (bool, error)
+	GetSpecificTodo(ctx context.Context, workspaceId string, sortBy string) ([]model.Todo, error)
+	ToggleTodo(ctx context.Context, todoId string, toggle string) (bool, error)
+	GetTodoById(ctx context.Context, todoId string) (model.Todo, error)
+	GetDeletedTodoById(ctx context.Context, todoId string) (model.Todo, error)
+	GetLastRank(ctx context.Context, workspaceId string) (string, error)
+	GetNeighbourRank(ctx context.Context, workspaceId string, rank string, next bool) (string, error)
+	MoveTodo(ctx context.Context, todoId string, rank string, done *bool) (model.Todo, error)
+	GetWorkspacesToRebalance(ctx context.Context, maxRankLength int) ([]string, error)
+	GetWorkspaceTodosByRank(ctx context.Context, workspaceId string) ([]model.Todo, error)
+	UpdateRanks(ctx context.Context, ranks map[primitive.ObjectID]string) error
+	SetEstimate(ctx context.Context, todoId string, estimateMinutes int) (model.Todo, error)
+	SetFields(ctx context.Context, todoId string, fields map[string]any) (model.Todo, error)
+	RestoreTodo(ctx context.Context, todoId string) (model.Todo, error)
+	GetDeletedTodos(ctx context.Context, workspaceIds []primitive.ObjectID) ([]model.Todo, error)
+	PurgeDeletedTodos(ctx context.Context, deletedBefore time.Time) ([]string, error)
+	PurgeSubtasks(ctx context.Context, parentIds []string) ([]string, error)
+	TrashSubtasks(ctx context.Context, parentIds []primitive.ObjectID) ([]model.Todo, error)
+	RestoreSubtasks(ctx context.Context, parentId primitive.ObjectID, deletedSince time.Time) ([]model.Todo, error)
+	PurgeWorkspaceTodos(ctx context.Context, workspaceId string) ([]string, error)
+	GetTodosByIds(ctx context.Context, todoIds []primitive.ObjectID) ([]model.Todo, error)
+	BulkWrite(ctx context.Context, userId string, ops []model.BatchOp, atomic bool) (map[int]error, error)
+	GetOpenTodos(ctx context.Context, workspaceId string) ([]model.Todo, error)
+	MigratePriorities(ctx context.Context) error
+	GetTodoTree(ctx context.Context, todoIds []primitive.ObjectID) ([]model.Todo, error)
+	RelocateTodos(ctx context.Context, todos []model.Todo) error
+	InsertTodos(ctx context.Context, todos []model.Todo) error
+	SetSnooze(ctx context.Context, todoId string, until *time.Time, notify bool) (model.Todo, error)
+	GetSnoozedTodos(ctx context.Context, userId string) ([]model.Todo, error)
+	WakeSnoozedTodos(ctx context.Context, now time.Time) ([]model.Todo, error)
+	SetGoal(ctx context.Context, todoId string, goalId primitive.ObjectID) (model.Todo, error)
+	GetGoalTodos(ctx context.Context, goalId primitive.ObjectID) ([]model.Todo, error)
+	CountGoalTodos(ctx context.Context, goalIds []primitive.ObjectID) (map[primitive.ObjectID]model.GoalTodoCount, error)
+	UnlinkGoals(ctx context.Context, goalIds []primitive.ObjectID) error
+	SetAssignees(ctx context.Context, todoId string, assigneeIds []primitive.ObjectID) (model.Todo, error)
+	GetAssignedTodos(ctx context.Context, userId primitive.ObjectID, workspaceIds []primitive.ObjectID) ([]model.Todo, error)
+	UnassignUser(ctx context.Context, workspaceId primitive.ObjectID, userId primitive.ObjectID) error
+}
+
+// ErrBatchAborted is returned when an atomic batch was rolled back
+var ErrBatchAborted = errors.New("batch aborted, nothing was written")
+
+// ErrRankTaken is returned when another todo of the workspace got the same
+// rank first, the unique rank index rejects the second write
+var ErrRankTaken = errors.New("rank is already taken in this workspace, try again")
+
+// todoRepo implements TodoRepository with MongoDB as the data store
+type todoRepo struct {
+	collection *mongo.Collection // MongoDB collection for todos
+}
+
+// GetAll retrieves the todos of the workspaces that are not in the trash
+func (r *todoRepo) GetAll(ctx context.Context, workspaceIds []primitive.ObjectID) ([]model.Todo, error) {
+	if len(workspaceIds) == 0 {
+		return []model.Todo{}, nil
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"workspaceId": bson.M{"$in": workspaceIds}, "deletedAt": nil})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// in the end close the curson
+	defer cursor.Close(ctx)
+
+	// result will be here
+	todos := []model.Todo{}
+
+	// loop over the response
+	for cursor.Next(ctx) {
+		var todo model.Todo
+		if err := cursor.Decode(&todo); err != nil {
+			return nil, err
+		}
+
+		// append todos
+		todos = append(todos, todo)
+	}
+
+	return todos, nil
+}
+
+// ToggleTodo sets the status of a todo, the caller's access to its workspace
+// is checked by the service
+func (r *todoRepo) ToggleTodo(ctx context.Context, todoId, toggle string) (bool, error) {
+	if todoId == "" || toggle == "" {
+		return false, errors.New("missing required fields: todoId/toggle")
+	}
+
+	fmt.Println("TodoId: ", todoId)
+	fmt.Println("Toggle: ", toggle)
+
+	todoOid, err := primitive.ObjectIDFromHex(todoId)
+	if err != nil {
+		return false, err
+	}
+
+	// determine bool value from toggle
+	var doneValue bool
+	switch toggle {
+	case "completed":
+		doneValue = true
+	case "not-started":
+		doneValue = false
+	default:
+		return false, errors.New("invalid toggle value")
+	}
+
+	filter := bson.M{"_id": todoOid, "deletedAt": nil}
+	update := bson.A{bson.M{"$set": completionFields(doneValue)}}
+
+	updated, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	if updated.ModifiedCount == 0 {
+		return false, errors.New("no todo found for update")
+	}
+
+	return true, nil
+}
+
+// CreateTodo adds a new todo item to the database
+func (r *todoRepo) CreateTodo(ctx context.Context, todo model.Todo, workspaceId string, userId string) (model.Todo, error) {
+	if todo.Task == "" {
+		return model.Todo{}, errors.New("Task is Invalid / Empty")
+	}
+
+	// string -> object Id
+	workspaceOid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	todo.WorkspaceId = workspaceOid
+	todo.UserId = userOid
+
+	insertedId, err := r.collection.InsertOne(ctx, todo)
+	if mongo.IsDuplicateKeyError(err) {
+		return model.Todo{}, ErrRankTaken
+	}
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	todo.ID = insertedId.InsertedID.(primitive.ObjectID)
+	return todo, nil
+}
+
+// DeleteTodo moves a todo item to the trash by its ID
+// the document is removed for real by the purge job
+func (r *todoRepo) DeleteTodo(ctx context.Context, todoId string) (bool, error) {
+
+	// string -> ObjectId
+	oid, err := primitive.ObjectIDFromHex(todoId)
+	if err != nil {
+		return false, err
+	}
+
+	// filter with Object ID (only todos that are not in the trash yet)
+	filter := bson.M{"_id": oid, "deletedAt": nil}
+	update := bson.M{"$set": bson.M{"deletedAt": time.Now()}}
+
+	// filter and soft delete Document
+	res, err2 := r.collection.UpdateOne(ctx, filter, update)
+	if err2 != nil {
+		return false, err2
+	}
+
+	if res.MatchedCount == 0 {
+		return false, errors.New("todo not found")
+	}
+
+	return true, nil
+}
+
+// GetSpecificTodo lists the todos of a workspace in manual order, sortBy
+// "priority" puts the highest priority first (manual order inside a priority).
+// Todos of every member are listed
+func (r *todoRepo) GetSpecificTodo(ctx context.Context, workspaceId string, sortBy string) ([]model.Todo, error) {
+	// convert workspaceId into object
+	workspaceOid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	// filter the documents
+	// snoozed todos come back when the snooze scheduler wakes them
+	filter := bson.M{"workspaceId": workspaceOid, "deletedAt": nil, "snoozedUntil": nil}
+
+	// manual order first, _id keeps todos without a rank stable
+	sort := bson.D{{Key: "rank", Value: 1}, {Key: "_id", Value: 1}}
+	if sortBy == "priority" {
+		sort = append(bson.D{{Key: "priorityWeight", Value: -1}}, sort...)
+	}
+	opts := options.Find().SetSort(sort)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+
+	// otherwise cursor remains open and can cause memory leaks
+	defer cursor.Close(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// get into the todos
+	var todos []model.Todo
+	for cursor.Next(ctx) {
+		var todo model.Todo
+		if err := cursor.Decode(&todo); err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+
+	// return todos and nil as no error
+	return todos, nil
+}
+
+// GetTodoById finds a single live todo by its ID, todos in the trash are
+// not found
+func (r *todoRepo) GetTodoById(ctx context.Context, todoId string) (model.Todo, error) {
+	oid, err := primitive.ObjectIDFromHex(todoId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	var todo model.Todo
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "deletedAt": nil}).Decode(&todo); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Todo{}, errors.New("todo not found")
+		}
+		return model.Todo{}, err
+	}
+
+	return todo, nil
+}
+
+// GetDeletedTodoById finds a single todo in the trash by its ID
+func (r *todoRepo) GetDeletedTodoById(ctx context.Context, todoId string) (model.Todo, error) {
+	oid, err := primitive.ObjectIDFromHex(todoId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	var todo model.Todo
+	if err := r.collection.FindOne(ctx, bson.M{"_id": oid, "deletedAt": bson.M{"$ne": nil}}).Decode(&todo); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Todo{}, errors.New("todo not found in trash")
+		}
+		return model.Todo{}, err
+	}
+
+	return todo, nil
+}
+
+// GetLastRank returns the highest rank in the workspace ("" if there is none)
+func (r *todoRepo) GetLastRank(ctx context.Context, workspaceId string) (string, error) {
+	workspaceOid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return "", err
+	}
+
+	filter := bson.M{"workspaceId": workspaceOid, "rank": bson.M{"$gt": ""}}
+	opts := options.FindOne().SetSort(bson.M{"rank": -1}).SetProjection(bson.M{"rank": 1})
+
+	var last model.Todo
+	err = r.collection.FindOne(ctx, filter, opts).Decode(&last)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return last.Rank, nil
+}
+
+// GetNeighbourRank returns the rank right after (next=true) or right before the
+// given rank inside the workspace, "" when the rank is at the edge of the list
+func (r *todoRepo) GetNeighbourRank(ctx context.Context, workspaceId string, rank string, next bool) (string, error) {
+	workspaceOid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return "", err
+	}
+
+	// previous neighbour: biggest rank below, next neighbour: smallest rank above
+	rankFilter, sort := bson.M{"$lt": rank, "$gt": ""}, -1
+	if next {
+		rankFilter, sort = bson.M{"$gt": rank}, 1
+	}
+
+	filter := bson.M{"workspaceId": workspaceOid, "rank": rankFilter}
+	opts := options.FindOne().SetSort(bson.M{"rank": sort}).SetProjection(bson.M{"rank": 1})
+
+	var neighbour model.Todo
+	err = r.collection.FindOne(ctx, filter, opts).Decode(&neighbour)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return neighbour.Rank, nil
+}
+
+// MoveTodo writes the new rank (and status when moved to another column)
+// it only touches the moved document
+func (r *todoRepo) MoveTodo(ctx context.Context, todoId string, rank string, done *bool) (model.Todo, error) {
+	if todoId == "" || rank == "" {
+		return model.Todo{}, errors.New("todoId / rank is empty in repo")
+	}
+
+	oid, err := primitive.ObjectIDFromHex(todoId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	set := bson.M{"rank": rank}
+	if done != nil {
+		set = completionFields(*done)
+		set["rank"] = rank
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var moved model.Todo
+	err = r.collection.FindOneAndUpdate(ctx, bson.M{"_id": oid}, bson.A{bson.M{"$set": set}}, opts).Decode(&moved)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Todo{}, errors.New("todo not found")
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return model.Todo{}, ErrRankTaken
+		}
+		return model.Todo{}, err
+	}
+
+	return moved, nil
+}
+
+// GetWorkspacesToRebalance returns workspaces that have a rank longer than
+// maxRankLength or todos without any rank
+func (r *todoRepo) GetWorkspacesToRebalance(ctx context.Context, maxRankLength int) ([]string, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"rank": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{"$expr": bson.M{"$gt": bson.A{
+			bson.M{"$strLenBytes": bson.M{"$ifNull": bson.A{"$rank", ""}}},
+			maxRankLength,
+		}}},
+	}}
+
+	values, err := r.collection.Distinct(ctx, "workspaceId", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var workspaceIds []string
+	for _, value := range values {
+		if oid, ok := value.(primitive.ObjectID); ok {
+			workspaceIds = append(workspaceIds, oid.Hex())
+		}
+	}
+
+	return workspaceIds, nil
+}
+
+// GetWorkspaceTodosByRank returns every todo of the workspace in display order,
+// old todos without a rank come after the ranked ones in creation order
+func (r *todoRepo) GetWorkspaceTodosByRank(ctx context.Context, workspaceId string) ([]model.Todo, error) {
+	workspaceOid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "rank", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"workspaceId": workspaceOid}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var todos []model.Todo
+	if err := cursor.All(ctx, &todos); err != nil {
+		return nil, err
+	}
+
+	// "" sorts first in mongo, the stable sort keeps the _id order of the rest
+	sort.SliceStable(todos, func(i, j int) bool {
+		return todos[i].Rank != "" && todos[j].Rank == ""
+	})
+
+	return todos, nil
+}
+
+// UpdateRanks writes many ranks at once with a single BulkWrite. The old
+// ranks are emptied first, so a new rank never runs into the old rank of
+// another todo in the unique index
+func (r *todoRepo) UpdateRanks(ctx context.Context, ranks map[primitive.ObjectID]string) error {
+	if len(ranks) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(ranks))
+	for oid := range ranks {
+		ids = append(ids, oid)
+	}
+
+	models := make([]mongo.WriteModel, 0, len(ranks)+1)
+	models = append(models, mongo.NewUpdateManyModel().
+		SetFilter(bson.M{"_id": bson.M{"$in": ids}}).
+		SetUpdate(bson.M{"$set": bson.M{"rank": ""}}))
+	for oid, rank := range ranks {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": oid}).
+			SetUpdate(bson.M{"$set": bson.M{"rank": rank}}))
+	}
+
+	_, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(true))
+	return err
+}
+
+// SetEstimate stores the planned minutes of a todo, 0 removes the estimate
+func (r *todoRepo) SetEstimate(ctx context.Context, todoId string, estimateMinutes int) (model.Todo, error) {
+	oid, err := primitive.ObjectIDFromHex(todoId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	update := bson.M{"$set": bson.M{"estimateMinutes": estimateMinutes}}
+	if estimateMinutes == 0 {
+		update = bson.M{"$unset": bson.M{"estimateMinutes": ""}}
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Todo
+	if err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": oid}, update, opts).Decode(&updated); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Todo{}, errors.New("todo not found")
+		}
+		return model.Todo{}, err
+	}
+
+	return updated, nil
+}
+
+// SetFields writes the given bson fields as they are, used to revert a todo
+func (r *todoRepo) SetFields(ctx context.Context, todoId string, fields map[string]any) (model.Todo, error) {
+	oid, err := primitive.ObjectIDFromHex(todoId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Todo
+	if err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": oid}, bson.M{"$set": fields}, opts).Decode(&updated); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Todo{}, errors.New("todo not found")
+		}
+		return model.Todo{}, err
+	}
+
+	return updated, nil
+}
+
+// RestoreTodo takes a todo out of the trash
+func (r *todoRepo) RestoreTodo(ctx context.Context, todoId string) (model.Todo, error) {
+	oid, err := primitive.ObjectIDFromHex(todoId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	filter := bson.M{"_id": oid, "deletedAt": bson.M{"$ne": nil}}
+	update := bson.M{"$unset": bson.M{"deletedAt": ""}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var restored model.Todo
+	if err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&restored); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Todo{}, errors.New("todo not found in trash")
+		}
+		return model.Todo{}, err
+	}
+
+	return restored, nil
+}
+
+// TrashSubtasks moves the live subtasks (any depth) of deleted todos to the
+// trash and returns them
+func (r *todoRepo) TrashSubtasks(ctx context.Context, parentIds []primitive.ObjectID) ([]model.Todo, error) {
+	return r.cascade(ctx, parentIds, bson.M{"deletedAt": nil}, bson.M{"$set": bson.M{"deletedAt": time.Now()}})
+}
+
+// RestoreSubtasks takes the subtasks (any depth) of a restored todo out of the
+// trash that were deleted with it or after it, subtasks deleted on their own
+// before stay in the trash
+func (r *todoRepo) RestoreSubtasks(ctx context.Context, parentId primitive.ObjectID, deletedSince time.Time) ([]model.Todo, error) {
+	filter := bson.M{"deletedAt": bson.M{"$gte": deletedSince}}
+	return r.cascade(ctx, []primitive.ObjectID{parentId}, filter, bson.M{"$unset": bson.M{"deletedAt": ""}})
+}
+
+// cascade applies update to the subtasks of parentIds that match filter, level
+// by level, and returns them as they were before the update
+func (r *todoRepo) cascade(ctx context.Context, parentIds []primitive.ObjectID, filter bson.M, update bson.M) ([]model.Todo, error) {
+	subtasks := []model.Todo{}
+	for len(parentIds) > 0 {
+		levelFilter := bson.M{"parentId": bson.M{"$in": parentIds}}
+		for key, value := range filter {
+			levelFilter[key] = value
+		}
+
+		cursor, err := r.collection.Find(ctx, levelFilter)
+		if err != nil {
+			return nil, err
+		}
+		var level []model.Todo
+		if err := cursor.All(ctx, &level); err != nil {
+			return nil, err
+		}
+
+		// next level down
+		parentIds = make([]primitive.ObjectID, 0, len(level))
+		for _, todo := range level {
+			parentIds = append(parentIds, todo.ID)
+		}
+		if len(parentIds) == 0 {
+			break
+		}
+
+		if _, err := r.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": parentIds}}, update); err != nil {
+			return nil, err
+		}
+		subtasks = append(subtasks, level...)
+	}
+
+	return subtasks, nil
+}
+
+// GetDeletedTodos lists the todos of the workspaces that are in the trash,
+// newest first
+func (r *todoRepo) GetDeletedTodos(ctx context.Context, workspaceIds []primitive.ObjectID) ([]model.Todo, error) {
+	filter := bson.M{"workspaceId": bson.M{"$in": workspaceIds}, "deletedAt": bson.M{"$ne": nil}}
+	opts := options.Find().SetSort(bson.M{"deletedAt": -1})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	todos := []model.Todo{}
+	if err := cursor.All(ctx, &todos); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// PurgeDeletedTodos hard deletes todos that are in the trash since before
+// deletedBefore and returns their ids (for comment / file cleanup)
+func (r *todoRepo) PurgeDeletedTodos(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	return r.purge(ctx, bson.M{"deletedAt": bson.M{"$lt": deletedBefore}})
+}
+
+// PurgeWorkspaceTodos hard deletes every todo of a purged workspace
+func (r *todoRepo) PurgeWorkspaceTodos(ctx context.Context, workspaceId string) ([]string, error) {
+	workspaceOid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.purge(ctx, bson.M{"workspaceId": workspaceOid})
+}
+
+// PurgeSubtasks hard deletes the subtasks (any depth) of purged todos, in
+// the trash or not, and returns their ids
+func (r *todoRepo) PurgeSubtasks(ctx context.Context, parentIds []string) ([]string, error) {
+	var purged []string
+	for len(parentIds) > 0 {
+		oids := make([]primitive.ObjectID, 0, len(parentIds))
+		for _, parentId := range parentIds {
+			if oid, err := primitive.ObjectIDFromHex(parentId); err == nil {
+				oids = append(oids, oid)
+			}
+		}
+
+		// next level down
+		todoIds, err := r.purge(ctx, bson.M{"parentId": bson.M{"$in": oids}})
+		if err != nil {
+			return nil, err
+		}
+		purged = append(purged, todoIds...)
+		parentIds = todoIds
+	}
+
+	return purged, nil
+}
+
+func (r *todoRepo) purge(ctx context.Context, filter bson.M) ([]string, error) {
+	values, err := r.collection.Distinct(ctx, "_id", filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	// filter again, a todo restored in the meantime must survive
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$in": values}}}}); err != nil {
+		return nil, err
+	}
+
+	var todoIds []string
+	for _, value := range values {
+		if oid, ok := value.(primitive.ObjectID); ok {
+			todoIds = append(todoIds, oid.Hex())
+		}
+	}
+
+	return todoIds, nil
+}
+
+// GetTodosByIds returns the todos among todoIds that are not in the trash,
+// the caller checks the workspaces they belong to
+func (r *todoRepo) GetTodosByIds(ctx context.Context, todoIds []primitive.ObjectID) ([]model.Todo, error) {
+	filter := bson.M{"_id": bson.M{"$in": todoIds}, "deletedAt": nil}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	todos := []model.Todo{}
+	if err := cursor.All(ctx, &todos); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// BulkWrite runs checked batch items in one round trip and returns the write
+// errors by item index, ErrRankTaken for a create whose rank was taken in the
+// meantime and "todo not found" for an update / toggle / delete that matched
+// no live todo. creates carry their new TodoId and Rank and belong to userId.
+// atomic runs them ordered inside a transaction (needs a replica set), any
+// failure rolls back everything and returns ErrBatchAborted
+func (r *todoRepo) BulkWrite(ctx context.Context, userId string, ops []model.BatchOp, atomic bool) (map[int]error, error) {
+	if len(ops) == 0 {
+		return nil, nil
+	}
+
+	// mongo keeps milliseconds, the trimmed time is what deletes read back
+	deletedAt := time.Now().Truncate(time.Millisecond)
+	models, err := batchWriteModels(userId, ops, deletedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if !atomic {
+		// unordered, a failing item does not stop the ones after it
+		res, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		failed, err := bulkWriteErrors(err)
+		if err != nil {
+			return nil, err
+		}
+		return r.unmatchedItems(ctx, ops, res, failed, deletedAt)
+	}
+
+	session, err := r.collection.Database().Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	var notFound map[int]error
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		res, err := r.collection.BulkWrite(sc, models, options.BulkWrite().SetOrdered(true))
+		if err != nil {
+			return nil, err
+		}
+		// an item that matched nothing rolls the batch back like a failed one
+		if notFound, err = r.unmatchedItems(sc, ops, res, nil, deletedAt); err != nil {
+			return nil, err
+		}
+		if len(notFound) > 0 {
+			return nil, ErrBatchAborted
+		}
+		return nil, nil
+	})
+	if err == nil {
+		return nil, nil
+	}
+	if errors.Is(err, ErrBatchAborted) {
+		return notFound, ErrBatchAborted
+	}
+
+	failed, err := bulkWriteErrors(err)
+	if err != nil {
+		return nil, err
+	}
+	return failed, ErrBatchAborted
+}
+
+// unmatchedItems adds "todo not found" to failed for every update / toggle /
+// delete that matched no todo, for example because it was deleted since the
+// service loaded it. The bulk result only counts the matches, so the todos are
+// read back when the count is short: a live todo or one this batch deleted
+// was matched, any other one was not
+func (r *todoRepo) unmatchedItems(ctx context.Context, ops []model.BatchOp, res *mongo.BulkWriteResult, failed map[int]error, deletedAt time.Time) (map[int]error, error) {
+	var todoIds []primitive.ObjectID
+	batchDeletes := make(map[primitive.ObjectID]bool)
+	for i, op := range ops {
+		if op.Op == "create" || failed[i] != nil {
+			continue
+		}
+		oid, _ := primitive.ObjectIDFromHex(op.TodoId)
+		todoIds = append(todoIds, oid)
+		if op.Op == "delete" {
+			batchDeletes[oid] = true
+		}
+	}
+	if res == nil || res.MatchedCount >= int64(len(todoIds)) {
+		return failed, nil
+	}
+
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": todoIds}}, options.Find().SetProjection(bson.M{"deletedAt": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var todos []model.Todo
+	if err := cursor.All(ctx, &todos); err != nil {
+		return nil, err
+	}
+
+	matched := make(map[primitive.ObjectID]bool, len(todos))
+	for _, todo := range todos {
+		matched[todo.ID] = todo.DeletedAt == nil || (batchDeletes[todo.ID] && todo.DeletedAt.Equal(deletedAt))
+	}
+
+	if failed == nil {
+		failed = make(map[int]error)
+	}
+	for i, op := range ops {
+		if op.Op == "create" || failed[i] != nil {
+			continue
+		}
+		if oid, _ := primitive.ObjectIDFromHex(op.TodoId); !matched[oid] {
+			failed[i] = errors.New("todo not found")
+		}
+	}
+	return failed, nil
+}
+
+// batchWriteModels turns batch items into write models, deletes go to the
+// trash at deletedAt
+func batchWriteModels(userId string, ops []model.BatchOp, deletedAt time.Time) ([]mongo.WriteModel, error) {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	models := make([]mongo.WriteModel, 0, len(ops))
+	for _, op := range ops {
+		todoOid, err := primitive.ObjectIDFromHex(op.TodoId)
+		if err != nil {
+			return nil, err
+		}
+
+		// only live todos can be changed, the service checked their workspaces
+		filter := bson.M{"_id": todoOid, "deletedAt": nil}
+
+		switch op.Op {
+		case "create":
+			workspaceOid, err := primitive.ObjectIDFromHex(op.WorkspaceId)
+			if err != nil {
+				return nil, err
+			}
+			models = append(models, mongo.NewInsertOneModel().SetDocument(model.Todo{
+				ID:             todoOid,
+				Task:           op.Task,
+				UserId:         userOid,
+				WorkspaceId:    workspaceOid,
+				Priority:       op.Priority,
+				PriorityWeight: model.PriorityWeight(op.Priority),
+				Rank:           op.Rank,
+			}))
+		case "update":
+			set := bson.M{}
+			if op.Task != "" {
+				set["task"] = op.Task
+			}
+			if op.Priority != "" {
+				set["priority"] = op.Priority
+				set["priorityWeight"] = model.PriorityWeight(op.Priority)
+			}
+			models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.M{"$set": set}))
+		case "toggle":
+			update := bson.A{bson.M{"$set": completionFields(op.Toggle == "completed")}}
+			models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update))
+		case "delete":
+			update := bson.M{"$set": bson.M{"deletedAt": deletedAt}}
+			models = append(models, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update))
+		default:
+			return nil, fmt.Errorf("unknown batch op %q", op.Op)
+		}
+	}
+
+	return models, nil
+}
+
+// completionFields is the $set stage of an update pipeline that changes the
+// status, completedAt keeps the first completion until the todo is reopened
+func completionFields(done bool) bson.M {
+	if !done {
+		return bson.M{"done": false, "completedAt": nil}
+	}
+	return bson.M{"done": true, "completedAt": bson.M{"$ifNull": bson.A{"$completedAt", "$$NOW"}}}
+}
+
+// bulkWriteErrors splits per item write errors from errors of the whole call,
+// the only unique key an item can run into is the rank
+func bulkWriteErrors(err error) (map[int]error, error) {
+	if err == nil {
+		return nil, nil
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || len(bulkErr.WriteErrors) == 0 {
+		return nil, err
+	}
+
+	failed := make(map[int]error)
+	for _, writeErr := range bulkErr.WriteErrors {
+		if mongo.IsDuplicateKeyError(writeErr) {
+			failed[writeErr.Index] = ErrRankTaken
+			continue
+		}
+		failed[writeErr.Index] = errors.New(writeErr.Message)
+	}
+	return failed, nil
+}
+
+// GetOpenTodos returns the todos of a workspace that are not done, highest
+// priority first
+func (r *todoRepo) GetOpenTodos(ctx context.Context, workspaceId string) ([]model.Todo, error) {
+	workspaceOid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"workspaceId": workspaceOid, "done": false, "deletedAt": nil, "snoozedUntil": nil}
+	opts := options.Find().SetSort(bson.D{{Key: "priorityWeight", Value: -1}, {Key: "rank", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	todos := []model.Todo{}
+	if err := cursor.All(ctx, &todos); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// MigratePriorities brings old free-form priorities to the enum and stores
+// their weight, "High" becomes "high" and unknown values become medium.
+// it only touches documents that are not migrated yet
+func (r *todoRepo) MigratePriorities(ctx context.Context) error {
+	known := bson.A{}
+	for _, priority := range []string{model.PriorityLow, model.PriorityMedium, model.PriorityHigh} {
+		known = append(known, priority)
+
+		filter := bson.M{
+			"priority":       primitive.Regex{Pattern: "^\\s*" + priority + "\\s*$", Options: "i"},
+			"priorityWeight": bson.M{"$ne": model.PriorityWeight(priority)},
+		}
+		update := bson.M{"$set": bson.M{"priority": priority, "priorityWeight": model.PriorityWeight(priority)}}
+		if _, err := r.collection.UpdateMany(ctx, filter, update); err != nil {
+			return err
+		}
+	}
+
+	filter := bson.M{"priority": bson.M{"$nin": known}}
+	update := bson.M{"$set": bson.M{"priority": model.PriorityMedium, "priorityWeight": model.PriorityWeight(model.PriorityMedium)}}
+	_, err := r.collection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+// GetTodoTree returns the live todos with the given ids and all their
+// subtasks (any depth), parents before their subtasks
+func (r *todoRepo) GetTodoTree(ctx context.Context, todoIds []primitive.ObjectID) ([]model.Todo, error) {
+	var tree []model.Todo
+	seen := make(map[primitive.ObjectID]bool)
+
+	filter := bson.M{"_id": bson.M{"$in": todoIds}, "deletedAt": nil}
+	for {
+		opts := options.Find().SetSort(bson.D{{Key: "rank", Value: 1}, {Key: "_id", Value: 1}})
+		cursor, err := r.collection.Find(ctx, filter, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		var level []model.Todo
+		if err := cursor.All(ctx, &level); err != nil {
+			return nil, err
+		}
+
+		var parentIds []primitive.ObjectID
+		for _, todo := range level {
+			if seen[todo.ID] {
+				continue
+			}
+			seen[todo.ID] = true
+			tree = append(tree, todo)
+			parentIds = append(parentIds, todo.ID)
+		}
+		if len(parentIds) == 0 {
+			return tree, nil
+		}
+
+		// next level down
+		filter = bson.M{"parentId": bson.M{"$in": parentIds}, "deletedAt": nil}
+	}
+}
+
+// RelocateTodos writes workspaceId, rank, parentId and assigneeIds of every
+// todo, an empty parentId removes the link. ErrRankTaken when one of the ranks
+// was taken in the meantime
+func (r *todoRepo) RelocateTodos(ctx context.Context, todos []model.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(todos))
+	for _, todo := range todos {
+		set := bson.M{"workspaceId": todo.WorkspaceId, "rank": todo.Rank}
+		unset := bson.M{}
+		if todo.ParentId.IsZero() {
+			unset["parentId"] = ""
+		} else {
+			set["parentId"] = todo.ParentId
+		}
+		if todo.GoalId.IsZero() {
+			unset["goalId"] = ""
+		}
+		if len(todo.AssigneeIds) == 0 {
+			unset["assigneeIds"] = ""
+		} else {
+			set["assigneeIds"] = todo.AssigneeIds
+		}
+
+		update := bson.M{"$set": set}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": todo.ID}).SetUpdate(update))
+	}
+
+	_, err := r.collection.BulkWrite(ctx, models)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrRankTaken
+	}
+	return err
+}
+
+// InsertTodos inserts ready made todos (ids set by the caller), ErrRankTaken
+// when one of the ranks was taken in the meantime
+func (r *todoRepo) InsertTodos(ctx context.Context, todos []model.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	docs := make([]any, 0, len(todos))
+	for _, todo := range todos {
+		docs = append(docs, todo)
+	}
+
+	_, err := r.collection.InsertMany(ctx, docs)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrRankTaken
+	}
+	return err
+}
+
+// EnsureIndexes creates the index the snooze scheduler polls and the one of
+// the assigned to me list, sparse because only some todos have the fields.
+// Ranks are unique per workspace, todos without a rank are left out
+func (r *todoRepo) EnsureIndexes(ctx context.Context) error {
+	if err := r.spreadDuplicateRanks(ctx); err != nil {
+		return err
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "snoozedUntil", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "assigneeIds", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "workspaceId", Value: 1}, {Key: "rank", Value: 1}},
+			Options: options.Index().
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"rank": bson.M{"$gt": ""}}),
+		},
+	})
+	return err
+}
+
+// spreadDuplicateRanks ranks every workspace again that has a rank twice,
+// left over from before the unique index. The order stays, todos with the
+// same rank keep their creation order
+func (r *todoRepo) spreadDuplicateRanks(ctx context.Context) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"rank": bson.M{"$gt": ""}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"workspaceId": "$workspaceId", "rank": "$rank"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$_id.workspaceId"}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		WorkspaceId primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		todos, err := r.GetWorkspaceTodosByRank(ctx, row.WorkspaceId.Hex())
+		if err != nil {
+			return err
+		}
+
+		keys := nrank.Spread(len(todos))
+		ranks := make(map[primitive.ObjectID]string, len(todos))
+		for i, todo := range todos {
+			ranks[todo.ID] = keys[i]
+		}
+		if err := r.UpdateRanks(ctx, ranks); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetSnooze hides a live todo until the given time, nil wakes it right away
+func (r *todoRepo) SetSnooze(ctx context.Context, todoId string, until *time.Time, notify bool) (model.Todo, error) {
+	oid, err := primitive.ObjectIDFromHex(todoId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	update := bson.M{"$unset": bson.M{"snoozedUntil": "", "snoozeNotify": ""}}
+	if until != nil {
+		update = bson.M{"$set": bson.M{"snoozedUntil": until, "snoozeNotify": notify}}
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Todo
+	if err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": oid, "deletedAt": nil}, update, opts).Decode(&updated); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Todo{}, errors.New("todo not found")
+		}
+		return model.Todo{}, err
+	}
+
+	return updated, nil
+}
+
+// GetSnoozedTodos returns the snoozed todos of the user, the first to wake first
+func (r *todoRepo) GetSnoozedTodos(ctx context.Context, userId string) ([]model.Todo, error) {
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"userId": userOid, "deletedAt": nil, "snoozedUntil": bson.M{"$ne": nil}}
+	opts := options.Find().SetSort(bson.D{{Key: "snoozedUntil", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	todos := []model.Todo{}
+	if err := cursor.All(ctx, &todos); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// WakeSnoozedTodos clears the snooze of every todo whose time has come and
+// returns them as they were before. Each todo is woken by one atomic update,
+// so two servers running the scheduler never wake the same todo twice
+func (r *todoRepo) WakeSnoozedTodos(ctx context.Context, now time.Time) ([]model.Todo, error) {
+	filter := bson.M{"snoozedUntil": bson.M{"$lte": now}}
+	update := bson.M{"$unset": bson.M{"snoozedUntil": "", "snoozeNotify": ""}}
+
+	woken := []model.Todo{}
+	for {
+		var todo model.Todo
+		err := r.collection.FindOneAndUpdate(ctx, filter, update).Decode(&todo)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return woken, nil
+		}
+		if err != nil {
+			return woken, err
+		}
+		woken = append(woken, todo)
+	}
+}
+
+// SetGoal links a live todo to a goal, a zero goalId removes the link
+func (r *todoRepo) SetGoal(ctx context.Context, todoId string, goalId primitive.ObjectID) (model.Todo, error) {
+	oid, err := primitive.ObjectIDFromHex(todoId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	update := bson.M{"$unset": bson.M{"goalId": ""}}
+	if !goalId.IsZero() {
+		update = bson.M{"$set": bson.M{"goalId": goalId}}
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Todo
+	if err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": oid, "deletedAt": nil}, update, opts).Decode(&updated); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Todo{}, errors.New("todo not found")
+		}
+		return model.Todo{}, err
+	}
+
+	return updated, nil
+}
+
+// GetGoalTodos returns the live todos linked to the goal in list order
+func (r *todoRepo) GetGoalTodos(ctx context.Context, goalId primitive.ObjectID) ([]model.Todo, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "rank", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, bson.M{"goalId": goalId, "deletedAt": nil}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	todos := []model.Todo{}
+	if err := cursor.All(ctx, &todos); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// CountGoalTodos counts the done and all live todos of every goal, goals
+// without todos are missing from the map
+func (r *todoRepo) CountGoalTodos(ctx context.Context, goalIds []primitive.ObjectID) (map[primitive.ObjectID]model.GoalTodoCount, error) {
+	counts := make(map[primitive.ObjectID]model.GoalTodoCount)
+	if len(goalIds) == 0 {
+		return counts, nil
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"goalId": bson.M{"$in": goalIds}, "deletedAt": nil}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$goalId",
+			"done":  bson.M{"$sum": bson.M{"$cond": bson.A{"$done", 1, 0}}},
+			"total": bson.M{"$sum": 1},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []model.GoalTodoCount
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.GoalId] = row
+	}
+
+	return counts, nil
+}
+
+// UnlinkGoals removes the goal link from every todo of the goals
+func (r *todoRepo) UnlinkGoals(ctx context.Context, goalIds []primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{"goalId": bson.M{"$in": goalIds}}, bson.M{"$unset": bson.M{"goalId": ""}})
+	return err
+}
+
+// NewTodoRepository creates and returns a new instance of TodoRepository
+// It initializes the MongoDB collection for todo operations
+func NewTodoRepository(col *mongo.Collection) TodoRepository {
+	return &todoRepo{
+		collection: col,
+	}
+}
+
+// SetAssignees replaces the assignees of a live todo, an empty list
+// unassigns everyone
+func (r *todoRepo) SetAssignees(ctx context.Context, todoId string, assigneeIds []primitive.ObjectID) (model.Todo, error) {
+	oid, err := primitive.ObjectIDFromHex(todoId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	update := bson.M{"$unset": bson.M{"assigneeIds": ""}}
+	if len(assigneeIds) > 0 {
+		update = bson.M{"$set": bson.M{"assigneeIds": assigneeIds}}
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Todo
+	if err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": oid, "deletedAt": nil}, update, opts).Decode(&updated); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Todo{}, errors.New("todo not found")
+		}
+		return model.Todo{}, err
+	}
+
+	return updated, nil
+}
+
+// GetAssignedTodos returns the open todos assigned to the user in the given
+// workspaces, most important first and then by due date
+func (r *todoRepo) GetAssignedTodos(ctx context.Context, userId primitive.ObjectID, workspaceIds []primitive.ObjectID) ([]model.Todo, error) {
+	todos := []model.Todo{}
+	if len(workspaceIds) == 0 {
+		return todos, nil
+	}
+
+	filter := bson.M{
+		"assigneeIds":  userId,
+		"workspaceId":  bson.M{"$in": workspaceIds},
+		"done":         false,
+		"deletedAt":    nil,
+		"snoozedUntil": nil,
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "priorityWeight", Value: -1}, {Key: "dueAt", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &todos); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// UnassignUser takes the user off every todo of the workspace, trashed ones
+// included so a restore does not bring the assignment back
+func (r *todoRepo) UnassignUser(ctx context.Context, workspaceId primitive.ObjectID, userId primitive.ObjectID) error {
+	filter := bson.M{"workspaceId": workspaceId, "assigneeIds": userId}
+	_, err := r.collection.UpdateMany(ctx, filter, bson.M{"$pull": bson.M{"assigneeIds": userId}})
+	return err
+}
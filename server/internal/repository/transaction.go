@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Transactor runs work across repositories in one multi-document transaction.
+// Repository calls made with the ctx handed to fn take part in it, Mongo needs
+// to run as a replica set for this
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type mongoTransactor struct {
+	client *mongo.Client
+}
+
+// WithTransaction commits when fn returns nil and aborts otherwise, the
+// driver retries fn on transient transaction errors
+func (t *mongoTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+func NewTransactor(client *mongo.Client) Transactor {
+	return &mongoTransactor{
+		client: client,
+	}
+}
@@ -1,186 +1,413 @@
-package repository
-
-import (
-	"context"
-	"errors"
-
-	"fmt"
-	"github.com/ndk123-web/fast-todo/internal/model"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"time"
-)
-
-// WorkSpaceRepository interface
-type WorkSpaceRepository interface {
-	GetAllUserWorkspace(ctx context.Context, userId string) ([]model.Workspace, error)
-	CreateWorkspace(ctx context.Context, userId string, workspaceName string) (string, error)
-	UpdatedWorkspace(ctx context.Context, userId string, workspaceName string, updatedWorkspace string) error
-	DeleteWorkspace(ctx context.Context, userId string, workspaceName string) error
-}
-
-// workspaceRepository struct
-type workspaceRepository struct {
-	workspaceCollection *mongo.Collection
-}
-
-// GetAllUserWorkspace gets all workspaces for a user
-func (r *workspaceRepository) GetAllUserWorkspace(ctx context.Context, userId string) ([]model.Workspace, error) {
-
-	// validate userId
-	if userId == "" {
-		return nil, errors.New("UserId in Repo is Empty")
-	}
-
-	// convert userId -> oid
-	oid, err := primitive.ObjectIDFromHex(userId)
-	if err != nil {
-		return nil, err
-	}
-
-	// filter for finding only workspaces of user
-	filter := bson.M{"userId": oid}
-
-	// get the workspaces here
-	cursor, err := r.workspaceCollection.Find(ctx, filter)
-	if err != nil {
-		return nil, err
-	}
-
-	// fetch workspaces
-	var workspaces []model.Workspace
-	for cursor.Next(ctx) {
-		var workspace model.Workspace
-		cursor.Decode(&workspace)
-		workspaces = append(workspaces, workspace)
-	}
-
-	// finally return the workspaces
-	return workspaces, nil
-}
-
-type createWorkspaceInDb struct {
-	UserId        primitive.ObjectID `json:"userId" bson:"userId"`
-	WorkspaceName string             `json:"workspaceName" bson:"workspaceName"`
-	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
-	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`
-}
-
-func (r *workspaceRepository) CreateWorkspace(ctx context.Context, userId string, workspaceName string) (string, error) {
-	if userId == "" || workspaceName == "" {
-		return "",errors.New("User Email / workspaceName is Empty in Repo")
-	}
-
-	// convert first string to objectId
-	oid, err := primitive.ObjectIDFromHex(userId)
-	if err != nil {
-		return "",err
-	}
-
-	// check if workspace already exists for user
-	filter := bson.M{"userId": oid, "workspaceName": workspaceName}
-	res := r.workspaceCollection.FindOne(ctx, filter)
-
-	// check for error
-	err = res.Err()
-
-	// if no error, workspace exists
-	if err == nil {
-		//  Document found → duplicate
-		return "",errors.New("workspace already exists for this user")
-	} else if !errors.Is(err, mongo.ErrNoDocuments) {
-		// Some DB issue
-		return "",err
-	}
-
-	// structure for stroring inside db
-	insertDoc := createWorkspaceInDb{
-		UserId:        oid,
-		WorkspaceName: workspaceName,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
-	}
-
-	// insert inside db
-	insertResult, err := r.workspaceCollection.InsertOne(ctx, insertDoc)
-	if err != nil {
-		return "",err
-	}
-
-	// InsertId is interface {} and .(primitive.ObjectId) it means inside that there is value in form of ObjectId and using .Hex() we conver Object id into Readable Hex String
-	fmt.Println("Insert Result: ", insertResult.InsertedID.(primitive.ObjectID).Hex())
-	stringInsertedId := insertResult.InsertedID.(primitive.ObjectID).Hex()
-	return stringInsertedId, nil
-}
-
-func (r *workspaceRepository) UpdatedWorkspace(ctx context.Context, userId string, workspaceName string, updatedWorkspace string) error {
-	if userId == "" || workspaceName == "" {
-		return errors.New("UserId / Workspace name Empty")
-	}
-
-	// convert userId to oid
-	oid, err := primitive.ObjectIDFromHex(userId)
-	if err != nil {
-		return err
-	}
-
-	// update the workspace name
-	filter := bson.M{"userId": oid, "workspaceName": workspaceName}
-	update := bson.M{"$set": bson.M{
-		"workspaceName": updatedWorkspace,
-		"updatedAt":     time.Now(),
-	}}
-
-	// perform the update
-	res, err := r.workspaceCollection.UpdateOne(ctx, filter, update)
-	if err != nil {
-		return err
-	}
-
-	// check if any document was modified or not
-	// if no document matched the filter, it means workspace doesn't exist
-	if res.MatchedCount == 0 {
-		return errors.New("No workspace found for given userId and workspaceName")
-	}
-
-	// debug
-	fmt.Println("Updated Workspace Count:", res.ModifiedCount)
-
-	// success
-	return nil
-}
-
-func (r *workspaceRepository) DeleteWorkspace(ctx context.Context, userId string, workspaceName string) error {
-	if userId == "" || workspaceName == "" {
-		return errors.New("UserId / Workspace name Empty")
-	}
-
-	// convert userId to oid
-	oid, err := primitive.ObjectIDFromHex(userId)
-	if err != nil {
-		return err
-	}
-
-	// filter for deletion
-	filter := bson.M{"userId": oid, "workspaceName": workspaceName}
-
-	// perform deletion
-	res, err := r.workspaceCollection.DeleteOne(ctx, filter)
-	if err != nil {
-		return err
-	}
-
-	// check if any document was deleted
-	if res.DeletedCount == 0 {
-		return errors.New("No workspace found to delete for given userId and workspaceName")
-	}
-
-	return nil
-}
-
-func NewWorkspaceRepository(workspaceCollection *mongo.Collection) WorkSpaceRepository {
-	return &workspaceRepository{
-		workspaceCollection: workspaceCollection,
-	}
-}
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"fmt"
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// WorkSpaceRepository interface
+type WorkSpaceRepository interface {
+	GetAllUserWorkspace(ctx context.Context, userId string) ([]model.Workspace, error)
+	CreateWorkspace(ctx context.Context, userId string, workspaceName string) (string, error)
+	UpdatedWorkspace(ctx context.Context, userId string, workspaceName string, updatedWorkspace string) error
+	DeleteWorkspace(ctx context.Context, userId string, workspaceName string) error
+	GetWorkspaceById(ctx context.Context, workspaceId string) (model.Workspace, error)
+	GetWorkspaceByName(ctx context.Context, userId string, workspaceName string) (model.Workspace, error)
+	SetFields(ctx context.Context, workspaceId string, fields map[string]any) (model.Workspace, error)
+	RestoreWorkspace(ctx context.Context, workspaceId string) (model.Workspace, error)
+	GetDeletedWorkspaces(ctx context.Context, userId string) ([]model.Workspace, error)
+	PurgeDeletedWorkspaces(ctx context.Context, deletedBefore time.Time) ([]string, error)
+	AddMember(ctx context.Context, workspaceId string, member model.WorkspaceMember) (model.Workspace, error)
+	SetMemberRole(ctx context.Context, workspaceId string, userId primitive.ObjectID, role string) (model.Workspace, error)
+	RemoveMember(ctx context.Context, workspaceId string, userId primitive.ObjectID) (model.Workspace, error)
+}
+
+// workspaceRepository struct
+type workspaceRepository struct {
+	workspaceCollection *mongo.Collection
+}
+
+// GetAllUserWorkspace gets all workspaces for a user, owned and shared ones
+func (r *workspaceRepository) GetAllUserWorkspace(ctx context.Context, userId string) ([]model.Workspace, error) {
+
+	// validate userId
+	if userId == "" {
+		return nil, errors.New("UserId in Repo is Empty")
+	}
+
+	// convert userId -> oid
+	oid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	// filter for finding only workspaces of user or shared with the user
+	filter := bson.M{"$or": bson.A{bson.M{"userId": oid}, bson.M{"members.userId": oid}}, "deletedAt": nil}
+
+	// get the workspaces here
+	cursor, err := r.workspaceCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// fetch workspaces
+	var workspaces []model.Workspace
+	for cursor.Next(ctx) {
+		var workspace model.Workspace
+		cursor.Decode(&workspace)
+		workspaces = append(workspaces, workspace)
+	}
+
+	// finally return the workspaces
+	return workspaces, nil
+}
+
+type createWorkspaceInDb struct {
+	UserId        primitive.ObjectID `json:"userId" bson:"userId"`
+	WorkspaceName string             `json:"workspaceName" bson:"workspaceName"`
+	CreatedAt     time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+func (r *workspaceRepository) CreateWorkspace(ctx context.Context, userId string, workspaceName string) (string, error) {
+	if userId == "" || workspaceName == "" {
+		return "",errors.New("User Email / workspaceName is Empty in Repo")
+	}
+
+	// convert first string to objectId
+	oid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return "",err
+	}
+
+	// check if workspace already exists for user
+	filter := bson.M{"userId": oid, "workspaceName": workspaceName, "deletedAt": nil}
+	res := r.workspaceCollection.FindOne(ctx, filter)
+
+	// check for error
+	err = res.Err()
+
+	// if no error, workspace exists
+	if err == nil {
+		//  Document found → duplicate
+		return "",errors.New("workspace already exists for this user")
+	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+		// Some DB issue
+		return "",err
+	}
+
+	// structure for stroring inside db
+	insertDoc := createWorkspaceInDb{
+		UserId:        oid,
+		WorkspaceName: workspaceName,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	// insert inside db
+	insertResult, err := r.workspaceCollection.InsertOne(ctx, insertDoc)
+	if err != nil {
+		return "",err
+	}
+
+	// InsertId is interface {} and .(primitive.ObjectId) it means inside that there is value in form of ObjectId and using .Hex() we conver Object id into Readable Hex String
+	fmt.Println("Insert Result: ", insertResult.InsertedID.(primitive.ObjectID).Hex())
+	stringInsertedId := insertResult.InsertedID.(primitive.ObjectID).Hex()
+	return stringInsertedId, nil
+}
+
+func (r *workspaceRepository) UpdatedWorkspace(ctx context.Context, userId string, workspaceName string, updatedWorkspace string) error {
+	if userId == "" || workspaceName == "" {
+		return errors.New("UserId / Workspace name Empty")
+	}
+
+	// convert userId to oid
+	oid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return err
+	}
+
+	// update the workspace name
+	filter := bson.M{"userId": oid, "workspaceName": workspaceName, "deletedAt": nil}
+	update := bson.M{"$set": bson.M{
+		"workspaceName": updatedWorkspace,
+		"updatedAt":     time.Now(),
+	}}
+
+	// perform the update
+	res, err := r.workspaceCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	// check if any document was modified or not
+	// if no document matched the filter, it means workspace doesn't exist
+	if res.MatchedCount == 0 {
+		return errors.New("No workspace found for given userId and workspaceName")
+	}
+
+	// debug
+	fmt.Println("Updated Workspace Count:", res.ModifiedCount)
+
+	// success
+	return nil
+}
+
+func (r *workspaceRepository) DeleteWorkspace(ctx context.Context, userId string, workspaceName string) error {
+	if userId == "" || workspaceName == "" {
+		return errors.New("UserId / Workspace name Empty")
+	}
+
+	// convert userId to oid
+	oid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return err
+	}
+
+	// filter for deletion
+	filter := bson.M{"userId": oid, "workspaceName": workspaceName, "deletedAt": nil}
+
+	// perform soft deletion, the purge job removes it (and its todos / goals) later
+	update := bson.M{"$set": bson.M{"deletedAt": time.Now()}}
+	res, err := r.workspaceCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	// check if any document was deleted
+	if res.MatchedCount == 0 {
+		return errors.New("No workspace found to delete for given userId and workspaceName")
+	}
+
+	return nil
+}
+
+func (r *workspaceRepository) GetWorkspaceById(ctx context.Context, workspaceId string) (model.Workspace, error) {
+	oid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+
+	var workspace model.Workspace
+	if err := r.workspaceCollection.FindOne(ctx, bson.M{"_id": oid}).Decode(&workspace); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Workspace{}, errors.New("workspace not found")
+		}
+		return model.Workspace{}, err
+	}
+
+	return workspace, nil
+}
+
+func (r *workspaceRepository) GetWorkspaceByName(ctx context.Context, userId string, workspaceName string) (model.Workspace, error) {
+	oid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+
+	var workspace model.Workspace
+	filter := bson.M{"userId": oid, "workspaceName": workspaceName, "deletedAt": nil}
+	if err := r.workspaceCollection.FindOne(ctx, filter).Decode(&workspace); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Workspace{}, errors.New("No workspace found for given userId and workspaceName")
+		}
+		return model.Workspace{}, err
+	}
+
+	return workspace, nil
+}
+
+// SetFields writes the given bson fields as they are, used to revert a workspace
+func (r *workspaceRepository) SetFields(ctx context.Context, workspaceId string, fields map[string]any) (model.Workspace, error) {
+	oid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+
+	set := bson.M{"updatedAt": time.Now()}
+	for field, value := range fields {
+		set[field] = value
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Workspace
+	if err := r.workspaceCollection.FindOneAndUpdate(ctx, bson.M{"_id": oid}, bson.M{"$set": set}, opts).Decode(&updated); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Workspace{}, errors.New("workspace not found")
+		}
+		return model.Workspace{}, err
+	}
+
+	return updated, nil
+}
+
+// RestoreWorkspace takes a workspace out of the trash, unless another
+// workspace with the same name was created in the meantime
+func (r *workspaceRepository) RestoreWorkspace(ctx context.Context, workspaceId string) (model.Workspace, error) {
+	oid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+
+	var deleted model.Workspace
+	err = r.workspaceCollection.FindOne(ctx, bson.M{"_id": oid, "deletedAt": bson.M{"$ne": nil}}).Decode(&deleted)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return model.Workspace{}, errors.New("workspace not found in trash")
+	}
+	if err != nil {
+		return model.Workspace{}, err
+	}
+
+	if _, err := r.GetWorkspaceByName(ctx, deleted.UserId.Hex(), deleted.WorkspaceName); err == nil {
+		return model.Workspace{}, errors.New("workspace already exists for this user")
+	}
+
+	filter := bson.M{"_id": oid, "deletedAt": bson.M{"$ne": nil}}
+	update := bson.M{"$unset": bson.M{"deletedAt": ""}, "$set": bson.M{"updatedAt": time.Now()}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var restored model.Workspace
+	if err := r.workspaceCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&restored); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Workspace{}, errors.New("workspace not found in trash")
+		}
+		return model.Workspace{}, err
+	}
+
+	return restored, nil
+}
+
+// GetDeletedWorkspaces lists the workspaces in the trash the user owns, as
+// creator or owner member, newest first
+func (r *workspaceRepository) GetDeletedWorkspaces(ctx context.Context, userId string) ([]model.Workspace, error) {
+	oid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := bson.A{bson.M{"userId": oid}, bson.M{"members": bson.M{"$elemMatch": bson.M{"userId": oid, "role": model.RoleOwner}}}}
+	filter := bson.M{"$or": owners, "deletedAt": bson.M{"$ne": nil}}
+	opts := options.Find().SetSort(bson.M{"deletedAt": -1})
+
+	cursor, err := r.workspaceCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	workspaces := []model.Workspace{}
+	if err := cursor.All(ctx, &workspaces); err != nil {
+		return nil, err
+	}
+
+	return workspaces, nil
+}
+
+// PurgeDeletedWorkspaces hard deletes workspaces in the trash since before
+// deletedBefore and returns their ids, so their todos / goals can follow
+func (r *workspaceRepository) PurgeDeletedWorkspaces(ctx context.Context, deletedBefore time.Time) ([]string, error) {
+	filter := bson.M{"deletedAt": bson.M{"$lt": deletedBefore}}
+
+	values, err := r.workspaceCollection.Distinct(ctx, "_id", filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	// filter again, a workspace restored in the meantime must survive
+	if _, err := r.workspaceCollection.DeleteMany(ctx, bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$in": values}}}}); err != nil {
+		return nil, err
+	}
+
+	var workspaceIds []string
+	for _, value := range values {
+		if oid, ok := value.(primitive.ObjectID); ok {
+			workspaceIds = append(workspaceIds, oid.Hex())
+		}
+	}
+
+	return workspaceIds, nil
+}
+
+// AddMember shares the workspace with a user, unless the user is a member
+// already
+func (r *workspaceRepository) AddMember(ctx context.Context, workspaceId string, member model.WorkspaceMember) (model.Workspace, error) {
+	oid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+
+	filter := bson.M{"_id": oid, "deletedAt": nil, "userId": bson.M{"$ne": member.UserId}, "members.userId": bson.M{"$ne": member.UserId}}
+	update := bson.M{"$push": bson.M{"members": member}, "$set": bson.M{"updatedAt": time.Now()}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Workspace
+	if err := r.workspaceCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Workspace{}, errors.New("user is already a member of this workspace")
+		}
+		return model.Workspace{}, err
+	}
+
+	return updated, nil
+}
+
+// SetMemberRole changes the role of a member
+func (r *workspaceRepository) SetMemberRole(ctx context.Context, workspaceId string, userId primitive.ObjectID, role string) (model.Workspace, error) {
+	oid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+
+	filter := bson.M{"_id": oid, "deletedAt": nil, "members.userId": userId}
+	update := bson.M{"$set": bson.M{"members.$.role": role, "updatedAt": time.Now()}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Workspace
+	if err := r.workspaceCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Workspace{}, errors.New("member not found")
+		}
+		return model.Workspace{}, err
+	}
+
+	return updated, nil
+}
+
+// RemoveMember takes the workspace away from a member
+func (r *workspaceRepository) RemoveMember(ctx context.Context, workspaceId string, userId primitive.ObjectID) (model.Workspace, error) {
+	oid, err := primitive.ObjectIDFromHex(workspaceId)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+
+	filter := bson.M{"_id": oid, "deletedAt": nil, "members.userId": userId}
+	update := bson.M{"$pull": bson.M{"members": bson.M{"userId": userId}}, "$set": bson.M{"updatedAt": time.Now()}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated model.Workspace
+	if err := r.workspaceCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return model.Workspace{}, errors.New("member not found")
+		}
+		return model.Workspace{}, err
+	}
+
+	return updated, nil
+}
+
+func NewWorkspaceRepository(workspaceCollection *mongo.Collection) WorkSpaceRepository {
+	return &workspaceRepository{
+		workspaceCollection: workspaceCollection,
+	}
+}
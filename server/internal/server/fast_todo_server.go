@@ -1,83 +1,271 @@
-package server
-
-import (
-	"net/http"
-
-	"github.com/ndk123-web/fast-todo/internal/handler"
-	"github.com/ndk123-web/fast-todo/internal/middleware"
-)
-
-type Server struct {
-	todoHandler      handler.TodoHandler
-	userHandler      handler.UserHandler
-	goalHandler      handler.GoalHandler
-	workspaceHandler handler.WorkspaceHandler
-}
-
-func NewServer(todoHandler handler.TodoHandler, userHandler handler.UserHandler, goalHandler handler.GoalHandler, workspaceHandler handler.WorkspaceHandler) *Server {
-	return &Server{
-		todoHandler:      todoHandler,
-		userHandler:      userHandler,
-		goalHandler:      goalHandler,
-		workspaceHandler: workspaceHandler,
-	}
-}
-
-func (s *Server) Start(port string) error {
-
-	// custom mux (not default mux)
-	// in short its custom router
-	mux := http.NewServeMux()
-
-	// For Admin Purpose
-	mux.Handle("GET /api/v1/todos/all-user-todos", middleware.AuthMiddleware(http.HandlerFunc((s.todoHandler.GetTodos))))
-
-	// we need to add here JWT Middleware
-	mux.Handle("POST /api/v1/users/{userId}/create-todo/{workspaceId}", middleware.AuthMiddleware(http.HandlerFunc(s.todoHandler.CreateTodo))) // using workspaceId and UserId can add the todo
-	mux.Handle("PUT /api/v1/todos/update-todo", middleware.AuthMiddleware((http.HandlerFunc(s.todoHandler.UpdateTodo))))                       // using ID of todo we can directly can update the todo
-	mux.Handle("DELETE /api/v1/todos/delete-todo/{todoId}", middleware.AuthMiddleware(http.HandlerFunc(s.todoHandler.DeleteTodo)))             // using ID of todo we can directly can delte the todo
-	mux.Handle("GET /api/v1/users/{userId}/get-ws-todo/{workspaceID}", middleware.AuthMiddleware(http.HandlerFunc(s.todoHandler.GetSpecificTodo)))
-	mux.Handle("POST /api/v1/users/toggle-todo", middleware.AuthMiddleware(http.HandlerFunc(s.todoHandler.ToogleTodo)))
-
-	// No Need Of Middleware (Signin and Signup)
-	mux.HandleFunc("POST /api/v1/users/signup", s.userHandler.SignUpUser)
-	mux.HandleFunc("POST /api/v1/users/signin", s.userHandler.SignInUser)
-
-	// for the refresh token routes (Currently No Need)
-	mux.HandleFunc("POST /api/v1/user/refresh-token", s.userHandler.RefreshToken)
-
-	// Goals Routes (Need Auth Middleware)
-	mux.Handle("GET /api/v1/goals/u/{userId}/get-gw/{workspaceId}", middleware.AuthMiddleware(http.HandlerFunc(s.goalHandler.GetUserGoals)))
-	mux.Handle("POST /api/v1/goals/u/{userId}/create-gw/{workspaceId}", middleware.AuthMiddleware(http.HandlerFunc(s.goalHandler.CreateUserGoal)))
-	mux.Handle("PUT /api/v1/goals/update-goal/{goalId}", middleware.AuthMiddleware(http.HandlerFunc(s.goalHandler.UpdateUserGoal)))
-	mux.Handle("DELETE /api/v1/goals/delete-goal/{goalId}", middleware.AuthMiddleware(http.HandlerFunc(s.goalHandler.DeleteUserGoal)))
-
-	// workspace Routes (Need Auth Middleware)
-	mux.Handle("GET /api/v1/workspaces/get-user-workspaces", middleware.AuthMiddleware(http.HandlerFunc(s.workspaceHandler.GetAllUserWorkspace)))
-	mux.Handle("POST /api/v1/workspaces/create-workspace", middleware.AuthMiddleware(http.HandlerFunc(s.workspaceHandler.CreateWorkspace)))
-	mux.Handle("PUT /api/v1/workspaces/update-workspace", middleware.AuthMiddleware(http.HandlerFunc(s.workspaceHandler.UpdateWorkspace)))
-	mux.Handle("DELETE /api/v1/workspaces/delete-workspace", middleware.AuthMiddleware(http.HandlerFunc(s.workspaceHandler.DeleteWorkspace)))
-
-	// it means cors -> log -> actual handler(mux)
-	// global logging and cors middleware
-	wrappedMux := middleware.LoggingMiddleware(middleware.CorsMiddleware(mux))
-	return http.ListenAndServe(port, wrappedMux)
-}
-
-/*
-mux.m = {
-   "/todos": HandlerFunc(getTodos),
-   "/users": HandlerFunc(getUsers),
-}
-
-Request: /todos
-↓
-mux.ServeHTTP()
-↓
-mux.Handler() → returns HandlerFunc(getTodos)
-↓
-HandlerFunc.ServeHTTP()
-↓
-calls getTodos(w, r)
-
-*/
+package server
+
+import (
+	"net/http"
+
+	"github.com/ndk123-web/fast-todo/internal/handler"
+	"github.com/ndk123-web/fast-todo/internal/middleware"
+)
+
+type Server struct {
+	todoHandler         handler.TodoHandler
+	userHandler         handler.UserHandler
+	goalHandler         handler.GoalHandler
+	workspaceHandler    handler.WorkspaceHandler
+	commentHandler      handler.CommentHandler
+	attachmentHandler   handler.AttachmentHandler
+	timeHandler         handler.TimeHandler
+	revisionHandler     handler.RevisionHandler
+	trashHandler        handler.TrashHandler
+	searchHandler       handler.SearchHandler
+	quickAddHandler     handler.QuickAddHandler
+	transferHandler     handler.TransferHandler
+	templateHandler     handler.TemplateHandler
+	snoozeHandler       handler.SnoozeHandler
+	notificationHandler handler.NotificationHandler
+	focusHandler        handler.FocusHandler
+	checkInHandler      handler.CheckInHandler
+	analyticsHandler    handler.AnalyticsHandler
+	categoryHandler     handler.CategoryHandler
+	okrHandler          handler.OKRHandler
+	invitationHandler   handler.InvitationHandler
+	activityHandler     handler.ActivityHandler
+}
+
+func NewServer(
+	todoHandler handler.TodoHandler,
+	userHandler handler.UserHandler,
+	goalHandler handler.GoalHandler,
+	workspaceHandler handler.WorkspaceHandler,
+	commentHandler handler.CommentHandler,
+	attachmentHandler handler.AttachmentHandler,
+	timeHandler handler.TimeHandler,
+	revisionHandler handler.RevisionHandler,
+	trashHandler handler.TrashHandler,
+	searchHandler handler.SearchHandler,
+	quickAddHandler handler.QuickAddHandler,
+	transferHandler handler.TransferHandler,
+	templateHandler handler.TemplateHandler,
+	snoozeHandler handler.SnoozeHandler,
+	notificationHandler handler.NotificationHandler,
+	focusHandler handler.FocusHandler,
+	checkInHandler handler.CheckInHandler,
+	analyticsHandler handler.AnalyticsHandler,
+	categoryHandler handler.CategoryHandler,
+	okrHandler handler.OKRHandler,
+	invitationHandler handler.InvitationHandler,
+	activityHandler handler.ActivityHandler,
+) *Server {
+	return &Server{
+		todoHandler:         todoHandler,
+		userHandler:         userHandler,
+		goalHandler:         goalHandler,
+		workspaceHandler:    workspaceHandler,
+		commentHandler:      commentHandler,
+		attachmentHandler:   attachmentHandler,
+		timeHandler:         timeHandler,
+		revisionHandler:     revisionHandler,
+		trashHandler:        trashHandler,
+		searchHandler:       searchHandler,
+		quickAddHandler:     quickAddHandler,
+		transferHandler:     transferHandler,
+		templateHandler:     templateHandler,
+		snoozeHandler:       snoozeHandler,
+		notificationHandler: notificationHandler,
+		focusHandler:        focusHandler,
+		checkInHandler:      checkInHandler,
+		analyticsHandler:    analyticsHandler,
+		categoryHandler:     categoryHandler,
+		okrHandler:          okrHandler,
+		invitationHandler:   invitationHandler,
+		activityHandler:     activityHandler,
+	}
+}
+
+func (s *Server) Start(port string) error {
+
+	// custom mux (not default mux)
+	// in short its custom router
+	mux := http.NewServeMux()
+
+	// For Admin Purpose
+	mux.Handle("GET /api/v1/todos/all-user-todos", middleware.AuthMiddleware(http.HandlerFunc((s.todoHandler.GetTodos))))
+
+	// we need to add here JWT Middleware
+	mux.Handle("POST /api/v1/users/{userId}/create-todo/{workspaceId}", middleware.AuthMiddleware(http.HandlerFunc(s.todoHandler.CreateTodo))) // using workspaceId and UserId can add the todo
+	mux.Handle("PUT /api/v1/todos/update-todo", middleware.AuthMiddleware((http.HandlerFunc(s.todoHandler.UpdateTodo))))                       // using ID of todo we can directly can update the todo
+	mux.Handle("DELETE /api/v1/todos/delete-todo/{todoId}", middleware.AuthMiddleware(http.HandlerFunc(s.todoHandler.DeleteTodo)))             // using ID of todo we can directly can delte the todo
+	mux.Handle("GET /api/v1/users/{userId}/get-ws-todo/{workspaceId}", middleware.AuthMiddleware(http.HandlerFunc(s.todoHandler.GetSpecificTodo)))
+	mux.Handle("POST /api/v1/users/toggle-todo", middleware.AuthMiddleware(http.HandlerFunc(s.todoHandler.ToogleTodo)))
+	mux.Handle("GET /api/v1/users/{userId}/matrix/{workspaceId}", middleware.AuthMiddleware(http.HandlerFunc(s.todoHandler.GetMatrix)))
+	mux.Handle("PUT /api/v1/todos/set-estimate/{todoId}", middleware.AuthMiddleware(http.HandlerFunc(s.todoHandler.SetEstimate)))
+	mux.Handle("PUT /api/v1/todos/move-todo/{todoId}", middleware.AuthMiddleware(http.HandlerFunc(s.todoHandler.MoveTodo)))      // reorder using afterId / beforeId neighbours
+	mux.Handle("POST /api/v1/users/{userId}/batch-todos", middleware.AuthMiddleware(http.HandlerFunc(s.todoHandler.BatchTodos))) // mode: atomic / best-effort
+
+	// quick-add from free text, ?preview=true parses without saving
+	mux.Handle("POST /api/v1/users/{userId}/quick-add/{workspaceId}", middleware.AuthMiddleware(http.HandlerFunc(s.quickAddHandler.QuickAdd)))
+
+	// Comment Routes (Need Auth Middleware)
+	mux.Handle("GET /api/v1/todos/{todoId}/get-comments", middleware.AuthMiddleware(http.HandlerFunc(s.commentHandler.GetTodoComments)))
+	mux.Handle("POST /api/v1/users/{userId}/create-comment/{todoId}", middleware.AuthMiddleware(http.HandlerFunc(s.commentHandler.CreateComment)))
+	mux.Handle("PUT /api/v1/users/{userId}/update-comment/{commentId}", middleware.AuthMiddleware(http.HandlerFunc(s.commentHandler.UpdateComment)))
+	mux.Handle("DELETE /api/v1/users/{userId}/delete-comment/{commentId}", middleware.AuthMiddleware(http.HandlerFunc(s.commentHandler.DeleteComment)))
+
+	// Attachment Routes (Need Auth Middleware)
+	mux.Handle("GET /api/v1/todos/{todoId}/get-attachments", middleware.AuthMiddleware(http.HandlerFunc(s.attachmentHandler.GetTodoAttachments)))
+	mux.Handle("GET /api/v1/attachments/download/{attachmentId}", middleware.AuthMiddleware(http.HandlerFunc(s.attachmentHandler.DownloadAttachment)))
+	mux.Handle("POST /api/v1/users/{userId}/upload-attachment/{todoId}", middleware.AuthMiddleware(http.HandlerFunc(s.attachmentHandler.UploadAttachment))) // multipart form, field "file"
+	mux.Handle("DELETE /api/v1/users/{userId}/delete-attachment/{attachmentId}", middleware.AuthMiddleware(http.HandlerFunc(s.attachmentHandler.DeleteAttachment)))
+
+	// Time Tracking Routes (Need Auth Middleware)
+	mux.Handle("POST /api/v1/users/{userId}/start-timer/{todoId}", middleware.AuthMiddleware(http.HandlerFunc(s.timeHandler.StartTimer)))
+	mux.Handle("POST /api/v1/users/{userId}/stop-timer", middleware.AuthMiddleware(http.HandlerFunc(s.timeHandler.StopTimer)))
+	mux.Handle("GET /api/v1/users/{userId}/running-timer", middleware.AuthMiddleware(http.HandlerFunc(s.timeHandler.GetRunningTimer)))
+	mux.Handle("POST /api/v1/users/{userId}/add-time-entry/{todoId}", middleware.AuthMiddleware(http.HandlerFunc(s.timeHandler.AddTimeEntry)))
+	mux.Handle("GET /api/v1/todos/{todoId}/get-time-entries", middleware.AuthMiddleware(http.HandlerFunc(s.timeHandler.GetTodoTimeEntries)))
+	mux.Handle("DELETE /api/v1/users/{userId}/delete-time-entry/{entryId}", middleware.AuthMiddleware(http.HandlerFunc(s.timeHandler.DeleteTimeEntry)))
+	mux.Handle("GET /api/v1/users/{userId}/time-report/{groupBy}", middleware.AuthMiddleware(http.HandlerFunc(s.timeHandler.TimeReport))) // groupBy: workspace / priority / day
+	mux.Handle("GET /api/v1/users/{userId}/estimate-report", middleware.AuthMiddleware(http.HandlerFunc(s.timeHandler.EstimateReport)))
+
+	// History Routes (Need Auth Middleware), entityType: todo / goal / workspace
+	mux.Handle("GET /api/v1/history/{entityType}/{entityId}", middleware.AuthMiddleware(http.HandlerFunc(s.revisionHandler.GetHistory)))
+	mux.Handle("POST /api/v1/history/{entityType}/{entityId}/revert/{version}", middleware.AuthMiddleware(http.HandlerFunc(s.revisionHandler.Revert)))
+
+	// Trash Routes (Need Auth Middleware), deleted items are purged after TRASH_RETENTION
+	mux.Handle("GET /api/v1/users/{userId}/trash", middleware.AuthMiddleware(http.HandlerFunc(s.trashHandler.GetTrash)))
+	mux.Handle("POST /api/v1/todos/restore-todo/{todoId}", middleware.AuthMiddleware(http.HandlerFunc(s.trashHandler.RestoreTodo)))
+	mux.Handle("POST /api/v1/goals/restore-goal/{goalId}", middleware.AuthMiddleware(http.HandlerFunc(s.trashHandler.RestoreGoal)))
+	mux.Handle("POST /api/v1/workspaces/restore-workspace/{workspaceId}", middleware.AuthMiddleware(http.HandlerFunc(s.trashHandler.RestoreWorkspace)))
+
+	// Move / Copy Routes (Need Auth Middleware), body {ids, workspaceId}, subtasks come along
+	mux.Handle("POST /api/v1/todos/move-todos", middleware.AuthMiddleware(http.HandlerFunc(s.transferHandler.MoveTodos)))
+	mux.Handle("POST /api/v1/todos/copy-todos", middleware.AuthMiddleware(http.HandlerFunc(s.transferHandler.CopyTodos)))
+	mux.Handle("POST /api/v1/goals/move-goals", middleware.AuthMiddleware(http.HandlerFunc(s.transferHandler.MoveGoals)))
+	mux.Handle("POST /api/v1/goals/copy-goals", middleware.AuthMiddleware(http.HandlerFunc(s.transferHandler.CopyGoals)))
+
+	// Template Routes (Need Auth Middleware), instantiate fills {{variables}} from the body
+	mux.Handle("GET /api/v1/users/{userId}/templates", middleware.AuthMiddleware(http.HandlerFunc(s.templateHandler.GetTemplates)))
+	mux.Handle("POST /api/v1/users/{userId}/create-template", middleware.AuthMiddleware(http.HandlerFunc(s.templateHandler.CreateTemplate)))
+	mux.Handle("DELETE /api/v1/templates/delete-template/{templateId}", middleware.AuthMiddleware(http.HandlerFunc(s.templateHandler.DeleteTemplate)))
+	mux.Handle("POST /api/v1/templates/{templateId}/instantiate/{workspaceId}", middleware.AuthMiddleware(http.HandlerFunc(s.templateHandler.Instantiate)))
+
+	// Focus Session Routes (Need Auth Middleware), one active session per user like the timer
+	mux.Handle("POST /api/v1/users/{userId}/start-focus/{todoId}", middleware.AuthMiddleware(http.HandlerFunc(s.focusHandler.StartSession)))
+	mux.Handle("POST /api/v1/users/{userId}/pause-focus", middleware.AuthMiddleware(http.HandlerFunc(s.focusHandler.PauseSession)))
+	mux.Handle("POST /api/v1/users/{userId}/resume-focus", middleware.AuthMiddleware(http.HandlerFunc(s.focusHandler.ResumeSession)))
+	mux.Handle("POST /api/v1/users/{userId}/complete-focus", middleware.AuthMiddleware(http.HandlerFunc(s.focusHandler.CompleteSession)))
+	mux.Handle("GET /api/v1/users/{userId}/active-focus", middleware.AuthMiddleware(http.HandlerFunc(s.focusHandler.GetActiveSession)))
+	mux.Handle("GET /api/v1/users/{userId}/focus-stats/{period}", middleware.AuthMiddleware(http.HandlerFunc(s.focusHandler.Stats))) // period: day / week
+
+	// Analytics Routes (Need Auth Middleware), ?from=&to=&tz= like the time report
+	mux.Handle("GET /api/v1/users/{userId}/heatmap", middleware.AuthMiddleware(http.HandlerFunc(s.analyticsHandler.Heatmap)))
+	mux.Handle("GET /api/v1/users/{userId}/goal-stats", middleware.AuthMiddleware(http.HandlerFunc(s.analyticsHandler.GoalStats)))
+
+	// Snooze Routes (Need Auth Middleware), snoozed todos are hidden from the lists until they wake
+	mux.Handle("PUT /api/v1/todos/snooze-todo/{todoId}", middleware.AuthMiddleware(http.HandlerFunc(s.snoozeHandler.SnoozeTodo)))
+	mux.Handle("PUT /api/v1/todos/unsnooze-todo/{todoId}", middleware.AuthMiddleware(http.HandlerFunc(s.snoozeHandler.UnsnoozeTodo)))
+	mux.Handle("GET /api/v1/users/{userId}/snoozed", middleware.AuthMiddleware(http.HandlerFunc(s.snoozeHandler.GetSnoozedTodos)))
+
+	// Notification Routes (Need Auth Middleware)
+	mux.Handle("GET /api/v1/users/{userId}/notifications", middleware.AuthMiddleware(http.HandlerFunc(s.notificationHandler.GetNotifications)))
+	mux.Handle("PUT /api/v1/notifications/read-notification/{notificationId}", middleware.AuthMiddleware(http.HandlerFunc(s.notificationHandler.MarkRead)))
+
+	// Search Route (Need Auth Middleware), ?q= supports "phrases", priority:, is:, ws: and type:
+	mux.Handle("GET /api/v1/users/{userId}/search", middleware.AuthMiddleware(http.HandlerFunc(s.searchHandler.Search)))
+
+	// No Need Of Middleware (Signin and Signup)
+	mux.HandleFunc("POST /api/v1/users/signup", s.userHandler.SignUpUser)
+	mux.HandleFunc("POST /api/v1/users/signin", s.userHandler.SignInUser)
+
+	mux.Handle("PUT /api/v1/users/{userId}/set-timezone", middleware.AuthMiddleware(http.HandlerFunc(s.userHandler.SetTimezone)))
+
+	// for the refresh token routes (Currently No Need)
+	mux.HandleFunc("POST /api/v1/user/refresh-token", s.userHandler.RefreshToken)
+
+	// Goals Routes (Need Auth Middleware)
+	mux.Handle("GET /api/v1/goals/u/{userId}/get-gw/{workspaceId}", middleware.AuthMiddleware(http.HandlerFunc(s.goalHandler.GetUserGoals)))
+	mux.Handle("POST /api/v1/goals/u/{userId}/create-gw/{workspaceId}", middleware.AuthMiddleware(http.HandlerFunc(s.goalHandler.CreateUserGoal)))
+	mux.Handle("PUT /api/v1/goals/update-goal/{goalId}", middleware.AuthMiddleware(http.HandlerFunc(s.goalHandler.UpdateUserGoal)))
+	mux.Handle("DELETE /api/v1/goals/delete-goal/{goalId}", middleware.AuthMiddleware(http.HandlerFunc(s.goalHandler.DeleteUserGoal)))
+
+	// goals can take their progress from linked todos instead of check-ins
+	mux.Handle("GET /api/v1/goals/{goalId}/detail", middleware.AuthMiddleware(http.HandlerFunc(s.goalHandler.GetGoalDetail)))
+	mux.Handle("PUT /api/v1/goals/set-progress-mode/{goalId}", middleware.AuthMiddleware(http.HandlerFunc(s.goalHandler.SetProgressMode))) // mode: check-ins / todos
+	mux.Handle("PUT /api/v1/todos/link-goal/{todoId}", middleware.AuthMiddleware(http.HandlerFunc(s.todoHandler.LinkGoal)))
+
+	// typed goals, numeric goals record a value and milestone goals check off milestones
+	mux.Handle("PUT /api/v1/goals/record-value/{goalId}", middleware.AuthMiddleware(http.HandlerFunc(s.goalHandler.RecordValue)))
+	mux.Handle("PUT /api/v1/goals/{goalId}/milestones/{milestoneId}", middleware.AuthMiddleware(http.HandlerFunc(s.goalHandler.SetMilestone)))
+
+	// Goal Deadline Routes (Need Auth Middleware), goals with a deadline carry a forecast (on-track / at-risk / off-track)
+	mux.Handle("PUT /api/v1/goals/set-deadline/{goalId}", middleware.AuthMiddleware(http.HandlerFunc(s.goalHandler.SetDeadline)))
+	mux.Handle("GET /api/v1/users/{userId}/goals-behind", middleware.AuthMiddleware(http.HandlerFunc(s.goalHandler.GoalsBehind)))
+
+	// Goal Category Routes (Need Auth Middleware), goals pick a category by name (case insensitive)
+	mux.Handle("GET /api/v1/users/{userId}/categories", middleware.AuthMiddleware(http.HandlerFunc(s.categoryHandler.GetCategories)))
+	mux.Handle("POST /api/v1/users/{userId}/create-category", middleware.AuthMiddleware(http.HandlerFunc(s.categoryHandler.CreateCategory)))
+	mux.Handle("PUT /api/v1/categories/update-category/{categoryId}", middleware.AuthMiddleware(http.HandlerFunc(s.categoryHandler.UpdateCategory)))
+	mux.Handle("DELETE /api/v1/categories/delete-category/{categoryId}", middleware.AuthMiddleware(http.HandlerFunc(s.categoryHandler.DeleteCategory)))
+	mux.Handle("GET /api/v1/users/{userId}/category-rollups", middleware.AuthMiddleware(http.HandlerFunc(s.goalHandler.CategoryRollups)))
+	mux.Handle("GET /api/v1/categories/{categoryId}/rollup", middleware.AuthMiddleware(http.HandlerFunc(s.goalHandler.CategoryRollup)))
+
+	// OKR Routes (Need Auth Middleware), objectives roll up the weighted progress of their key results
+	mux.Handle("PUT /api/v1/goals/set-okr/{goalId}", middleware.AuthMiddleware(http.HandlerFunc(s.okrHandler.SetOKR)))
+	mux.Handle("GET /api/v1/workspaces/{workspaceId}/okrs", middleware.AuthMiddleware(http.HandlerFunc(s.okrHandler.GetOKRTree)))
+	mux.Handle("POST /api/v1/goals/score-objective/{goalId}", middleware.AuthMiddleware(http.HandlerFunc(s.okrHandler.ScoreObjective)))
+	mux.Handle("GET /api/v1/goals/{goalId}/scores", middleware.AuthMiddleware(http.HandlerFunc(s.okrHandler.GetScores)))
+
+	// Invitation Routes (Need Auth Middleware), owners invite by email or link, signup takes an inviteToken too
+	mux.Handle("POST /api/v1/workspaces/create-invitation/{workspaceId}", middleware.AuthMiddleware(http.HandlerFunc(s.invitationHandler.CreateInvitation)))
+	mux.Handle("GET /api/v1/workspaces/{workspaceId}/invitations", middleware.AuthMiddleware(http.HandlerFunc(s.invitationHandler.GetPendingInvitations)))
+	mux.Handle("DELETE /api/v1/invitations/revoke-invitation/{invitationId}", middleware.AuthMiddleware(http.HandlerFunc(s.invitationHandler.RevokeInvitation)))
+	mux.Handle("GET /api/v1/users/{userId}/invitations", middleware.AuthMiddleware(http.HandlerFunc(s.invitationHandler.GetMyInvitations)))
+	mux.Handle("POST /api/v1/invitations/accept-invitation", middleware.AuthMiddleware(http.HandlerFunc(s.invitationHandler.AcceptInvitation)))
+	mux.Handle("POST /api/v1/invitations/accept-invitation/{invitationId}", middleware.AuthMiddleware(http.HandlerFunc(s.invitationHandler.AcceptInvitationById)))
+
+	// Goal Check-in Routes (Need Auth Middleware), days goals are done once currentTarget reaches targetDays
+	mux.Handle("POST /api/v1/goals/check-in/{goalId}", middleware.AuthMiddleware(http.HandlerFunc(s.checkInHandler.CheckIn)))
+	mux.Handle("GET /api/v1/goals/{goalId}/progress", middleware.AuthMiddleware(http.HandlerFunc(s.checkInHandler.GetProgress)))
+
+	// workspace Routes (Need Auth Middleware)
+	mux.Handle("GET /api/v1/workspaces/get-user-workspaces", middleware.AuthMiddleware(http.HandlerFunc(s.workspaceHandler.GetAllUserWorkspace)))
+	mux.Handle("POST /api/v1/workspaces/create-workspace", middleware.AuthMiddleware(http.HandlerFunc(s.workspaceHandler.CreateWorkspace)))
+	mux.Handle("PUT /api/v1/workspaces/update-workspace", middleware.AuthMiddleware(http.HandlerFunc(s.workspaceHandler.UpdateWorkspace)))
+	mux.Handle("DELETE /api/v1/workspaces/delete-workspace", middleware.AuthMiddleware(http.HandlerFunc(s.workspaceHandler.DeleteWorkspace)))
+
+	// Workspace Member Routes (Need Auth Middleware), role: owner / editor / viewer
+	mux.Handle("GET /api/v1/workspaces/{workspaceId}/members", middleware.AuthMiddleware(http.HandlerFunc(s.workspaceHandler.GetMembers)))
+	mux.Handle("POST /api/v1/workspaces/add-member/{workspaceId}", middleware.AuthMiddleware(http.HandlerFunc(s.workspaceHandler.AddMember)))
+	mux.Handle("PUT /api/v1/workspaces/{workspaceId}/members/{memberId}", middleware.AuthMiddleware(http.HandlerFunc(s.workspaceHandler.SetMemberRole)))
+	mux.Handle("DELETE /api/v1/workspaces/{workspaceId}/members/{memberId}", middleware.AuthMiddleware(http.HandlerFunc(s.workspaceHandler.RemoveMember)))
+
+	// todo assignees are members of the todo's workspace, assigned-todos covers every workspace of the user
+	mux.Handle("PUT /api/v1/todos/assign-todo/{todoId}", middleware.AuthMiddleware(http.HandlerFunc(s.todoHandler.AssignTodo)))
+	mux.Handle("GET /api/v1/users/{userId}/assigned-todos", middleware.AuthMiddleware(http.HandlerFunc(s.todoHandler.GetAssignedTodos)))
+
+	// Activity Feed Routes (Need Auth Middleware), ?actorId=&types=&limit=&before=&since=
+	mux.Handle("GET /api/v1/workspaces/{workspaceId}/activity", middleware.AuthMiddleware(http.HandlerFunc(s.activityHandler.GetFeed)))
+
+	// it means cors -> log -> actual handler(mux)
+	// global logging and cors middleware
+	wrappedMux := middleware.LoggingMiddleware(middleware.CorsMiddleware(mux))
+	return http.ListenAndServe(port, wrappedMux)
+}
+
+/*
+mux.m = {
+   "/todos": HandlerFunc(getTodos),
+   "/users": HandlerFunc(getUsers),
+}
+
+Request: /todos
+↓
+mux.ServeHTTP()
+↓
+mux.Handler() → returns HandlerFunc(getTodos)
+↓
+HandlerFunc.ServeHTTP()
+↓
+calls getTodos(w, r)
+
+*/
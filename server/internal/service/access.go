@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+)
+
+// workspaceAccess returns the workspace when the caller may add to it or take
+// items out of it (owner or editor), workspaces in the trash count as not found
+func workspaceAccess(ctx context.Context, workspaceRepo repository.WorkSpaceRepository, actor Actor, workspaceId string) (model.Workspace, error) {
+	return workspaceRoleAccess(ctx, workspaceRepo, actor, workspaceId, model.RoleEditor)
+}
+
+// workspaceView returns the workspace when the caller may see its items, any
+// member including viewers
+func workspaceView(ctx context.Context, workspaceRepo repository.WorkSpaceRepository, actor Actor, workspaceId string) (model.Workspace, error) {
+	return workspaceRoleAccess(ctx, workspaceRepo, actor, workspaceId, model.RoleViewer)
+}
+
+// workspaceRoleAccess returns the workspace with the caller's role filled in
+// when that role is at least need
+func workspaceRoleAccess(ctx context.Context, workspaceRepo repository.WorkSpaceRepository, actor Actor, workspaceId string, need string) (model.Workspace, error) {
+	if actor.UserId == "" {
+		return model.Workspace{}, errors.New("caller is unknown")
+	}
+	if workspaceId == "" {
+		return model.Workspace{}, errors.New("WorkspaceId is Empty in Service")
+	}
+
+	workspace, err := workspaceRepo.GetWorkspaceById(ctx, workspaceId)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	if workspace.DeletedAt != nil {
+		return model.Workspace{}, errors.New("workspace not found")
+	}
+
+	workspace.Role = workspace.RoleOf(actor.UserId)
+	if workspace.Role == "" {
+		return model.Workspace{}, errors.New("no access to workspace")
+	}
+	if !model.RoleAllows(workspace.Role, need) {
+		return model.Workspace{}, errors.New("your role in this workspace does not allow this")
+	}
+
+	return workspace, nil
+}
+
+// todoAccess returns the live todo when the caller has at least the role need
+// in its workspace
+func todoAccess(ctx context.Context, todoRepo repository.TodoRepository, workspaceRepo repository.WorkSpaceRepository, actor Actor, todoId string, need string) (model.Todo, error) {
+	if todoId == "" {
+		return model.Todo{}, errors.New("TodoId is Empty in Service")
+	}
+
+	todo, err := todoRepo.GetTodoById(ctx, todoId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	if _, err := workspaceRoleAccess(ctx, workspaceRepo, actor, todo.WorkspaceId.Hex(), need); err != nil {
+		return model.Todo{}, err
+	}
+
+	return todo, nil
+}
+
+// goalAccess returns the live goal when the caller has at least the role need
+// in its workspace
+func goalAccess(ctx context.Context, goalRepo repository.GoalRepository, workspaceRepo repository.WorkSpaceRepository, actor Actor, goalId string, need string) (model.Goals, error) {
+	if goalId == "" {
+		return model.Goals{}, errors.New("GoalId is Empty in Service")
+	}
+
+	goal, err := goalRepo.GetGoalById(ctx, goalId)
+	if err != nil {
+		return model.Goals{}, err
+	}
+	if goal.DeletedAt != nil {
+		return model.Goals{}, errors.New("GoalId Document Not Found")
+	}
+	if _, err := workspaceRoleAccess(ctx, workspaceRepo, actor, goal.WorkspaceId.Hex(), need); err != nil {
+		return model.Goals{}, err
+	}
+
+	return goal, nil
+}
+
+// checkCaller rejects requests for the data of another user, userId is the
+// one sent in the path / body
+func checkCaller(actor Actor, userId string) error {
+	if actor.UserId == "" {
+		return errors.New("caller is unknown")
+	}
+	if userId != "" && userId != actor.UserId {
+		return errors.New("userId does not match the caller")
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// feed page sizes
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 100
+)
+
+// ActivityService keeps the feed of every workspace, the services that change
+// todos, goals and workspaces record their changes in it
+type ActivityService interface {
+	Record(ctx context.Context, actor Actor, activity model.Activity)
+	GetFeed(ctx context.Context, actor Actor, workspaceId string, query model.ActivityQuery) (model.ActivityPage, error)
+}
+
+type activityService struct {
+	repo          repository.ActivityRepository
+	workspaceRepo repository.WorkSpaceRepository
+	userRepo      repository.UserRepository
+	transactor    repository.Transactor
+}
+
+// Record adds the activity to the feed of its workspace with the name of the
+// actor and a message. The change is already written when this runs, so a
+// failure is only logged
+func (s *activityService) Record(ctx context.Context, actor Actor, activity model.Activity) {
+	activity.ActorName = actor.Email
+	if actorOid, err := primitive.ObjectIDFromHex(actor.UserId); err == nil {
+		activity.ActorId = actorOid
+		if user, err := s.userRepo.GetUserById(ctx, actor.UserId); err == nil {
+			activity.ActorName = displayName(user)
+		}
+	}
+	activity.Message = activityMessage(activity)
+
+	// the Seq of the activity is handed out in the same transaction
+	err := s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := s.repo.CreateActivity(ctx, activity)
+		return err
+	})
+	if err != nil {
+		log.Println("Failed to record activity:", err)
+	}
+}
+
+// GetFeed returns one page of the feed to any member of the workspace
+func (s *activityService) GetFeed(ctx context.Context, actor Actor, workspaceId string, query model.ActivityQuery) (model.ActivityPage, error) {
+	workspace, err := workspaceView(ctx, s.workspaceRepo, actor, workspaceId)
+	if err != nil {
+		return model.ActivityPage{}, err
+	}
+
+	for _, activityType := range query.Types {
+		if !slices.Contains(model.ActivityTypes, activityType) {
+			return model.ActivityPage{}, fmt.Errorf("unknown activity type %q", activityType)
+		}
+	}
+	if query.Before > 0 && (query.Since > 0 || !query.SinceTime.IsZero()) {
+		return model.ActivityPage{}, errors.New("before and since can not be used together")
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultActivityLimit
+	}
+	if query.Limit > maxActivityLimit {
+		query.Limit = maxActivityLimit
+	}
+
+	// one more than asked tells if there is a next page
+	limit := query.Limit
+	query.Limit++
+	activities, err := s.repo.GetActivities(ctx, workspace.ID, query)
+	if err != nil {
+		return model.ActivityPage{}, err
+	}
+
+	page := model.ActivityPage{Activities: activities, HasMore: len(activities) > limit}
+	if page.HasMore {
+		page.Activities = activities[:limit]
+	}
+
+	// a poll that found nothing keeps the cursor it was given
+	switch {
+	case len(page.Activities) > 0:
+		page.Cursor = strconv.FormatInt(page.Activities[len(page.Activities)-1].Seq, 10)
+	case query.Since > 0:
+		page.Cursor = strconv.FormatInt(query.Since, 10)
+	}
+
+	return page, nil
+}
+
+// activityMessage is the sentence shown in the feed
+func activityMessage(activity model.Activity) string {
+	actor := activity.ActorName
+	if actor == "" {
+		actor = "Someone"
+	}
+	role, _ := activity.Details["role"].(string)
+
+	switch activity.Type {
+	case model.ActivityTodoCreated, model.ActivityGoalCreated:
+		if template, ok := activity.Details["template"].(string); ok {
+			return fmt.Sprintf("%s created '%s' from the template '%s'", actor, activity.Title, template)
+		}
+		return fmt.Sprintf("%s created '%s'", actor, activity.Title)
+	case model.ActivityTodoUpdated, model.ActivityGoalUpdated:
+		if from, ok := activity.Details["from"].(string); ok && from != activity.Title {
+			return fmt.Sprintf("%s renamed '%s' to '%s'", actor, from, activity.Title)
+		}
+		return fmt.Sprintf("%s updated '%s'", actor, activity.Title)
+	case model.ActivityTodoCompleted:
+		return fmt.Sprintf("%s completed '%s'", actor, activity.Title)
+	case model.ActivityTodoReopened:
+		return fmt.Sprintf("%s reopened '%s'", actor, activity.Title)
+	case model.ActivityTodoDeleted, model.ActivityGoalDeleted:
+		return fmt.Sprintf("%s deleted '%s'", actor, activity.Title)
+	case model.ActivityTodoAssigned:
+		return fmt.Sprintf("%s changed the assignees of '%s'", actor, activity.Title)
+	case model.ActivityTodoMoved, model.ActivityGoalMoved:
+		if to, ok := activity.Details["to"].(string); ok {
+			return fmt.Sprintf("%s moved '%s' to '%s'", actor, activity.Title, to)
+		}
+		from, _ := activity.Details["from"].(string)
+		return fmt.Sprintf("%s moved '%s' here from '%s'", actor, activity.Title, from)
+	case model.ActivityTodoCopied, model.ActivityGoalCopied:
+		from, _ := activity.Details["from"].(string)
+		return fmt.Sprintf("%s copied '%s' from '%s'", actor, activity.Title, from)
+	case model.ActivityTodoRestored, model.ActivityGoalRestored:
+		return fmt.Sprintf("%s restored '%s' from the trash", actor, activity.Title)
+	case model.ActivityTodoReverted, model.ActivityGoalReverted:
+		return fmt.Sprintf("%s reverted '%s' to version %v", actor, activity.Title, activity.Details["version"])
+	case model.ActivityGoalProgress:
+		if milestone, ok := activity.Details["milestone"].(string); ok {
+			if done, _ := activity.Details["done"].(bool); !done {
+				return fmt.Sprintf("%s unchecked the milestone '%s' of '%s'", actor, milestone, activity.Title)
+			}
+			return fmt.Sprintf("%s checked the milestone '%s' of '%s'", actor, milestone, activity.Title)
+		}
+		if value, ok := activity.Details["value"]; ok {
+			return fmt.Sprintf("%s set the progress of '%s' to %v", actor, activity.Title, value)
+		}
+		return fmt.Sprintf("%s updated the progress of '%s'", actor, activity.Title)
+	case model.ActivityGoalCheckedIn:
+		return fmt.Sprintf("%s checked in on '%s' for %v", actor, activity.Title, activity.Details["day"])
+	case model.ActivityWorkspaceCreated:
+		return fmt.Sprintf("%s created the workspace", actor)
+	case model.ActivityWorkspaceRenamed:
+		return fmt.Sprintf("%s renamed the workspace to '%s'", actor, activity.Title)
+	case model.ActivityWorkspaceDeleted:
+		return fmt.Sprintf("%s moved the workspace to the trash", actor)
+	case model.ActivityWorkspaceRestored:
+		return fmt.Sprintf("%s restored the workspace from the trash", actor)
+	case model.ActivityWorkspaceReverted:
+		return fmt.Sprintf("%s reverted the workspace to version %v", actor, activity.Details["version"])
+	case model.ActivityMemberAdded:
+		return fmt.Sprintf("%s added %s as %s", actor, activity.Title, role)
+	case model.ActivityMemberJoined:
+		return fmt.Sprintf("%s joined as %s", actor, role)
+	case model.ActivityMemberRoleChanged:
+		return fmt.Sprintf("%s changed the role of %s to %s", actor, activity.Title, role)
+	case model.ActivityMemberRemoved:
+		return fmt.Sprintf("%s removed %s", actor, activity.Title)
+	case model.ActivityMemberLeft:
+		return fmt.Sprintf("%s left the workspace", actor)
+	}
+	return fmt.Sprintf("%s changed '%s'", actor, activity.Title)
+}
+
+// displayName is the full name of the user, the email when it is not set
+func displayName(user model.User) string {
+	if user.FullName != "" {
+		return user.FullName
+	}
+	return user.Email
+}
+
+func NewActivityService(repo repository.ActivityRepository, workspaceRepo repository.WorkSpaceRepository, userRepo repository.UserRepository, transactor repository.Transactor) ActivityService {
+	return &activityService{
+		repo:          repo,
+		workspaceRepo: workspaceRepo,
+		userRepo:      userRepo,
+		transactor:    transactor,
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+)
+
+// a heatmap covers at most a year (plus a leap day)
+const maxAnalyticsDays = 366
+
+type AnalyticsService interface {
+	Heatmap(ctx context.Context, actor Actor, userId string, from time.Time, to time.Time, timezone string) ([]model.HeatmapDay, error)
+	GoalStats(ctx context.Context, actor Actor, userId string, from time.Time, to time.Time, timezone string) (model.GoalStats, error)
+}
+
+type analyticsService struct {
+	repo repository.AnalyticsRepository
+}
+
+// Heatmap counts completed todos and goal check-ins per day of [from, to),
+// from and to are midnights in timezone
+func (s *analyticsService) Heatmap(ctx context.Context, actor Actor, userId string, from time.Time, to time.Time, timezone string) ([]model.HeatmapDay, error) {
+	if userId == "" {
+		return nil, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return nil, err
+	}
+
+	days := daysBetween(from, to)
+	if days < 1 || days > maxAnalyticsDays {
+		return nil, errors.New("the range must cover 1 to 366 days")
+	}
+
+	todoCounts, err := s.repo.CompletedTodosByDay(ctx, userId, from, to, timezone)
+	if err != nil {
+		return nil, err
+	}
+	checkInCounts, err := s.repo.CheckInsByDay(ctx, userId, from.Format("2006-01-02"), to.AddDate(0, 0, -1).Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+
+	todos := dayCountMap(todoCounts)
+	checkIns := dayCountMap(checkInCounts)
+
+	heatmap := make([]model.HeatmapDay, 0, days)
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		heatmap = append(heatmap, model.HeatmapDay{
+			Day:      key,
+			Todos:    todos[key],
+			CheckIns: checkIns[key],
+			Total:    todos[key] + checkIns[key],
+		})
+	}
+
+	return heatmap, nil
+}
+
+// GoalStats returns the completion rate per goal category and the activity
+// per weekday of [from, to), weekdays are taken from the heatmap days
+func (s *analyticsService) GoalStats(ctx context.Context, actor Actor, userId string, from time.Time, to time.Time, timezone string) (model.GoalStats, error) {
+	heatmap, err := s.Heatmap(ctx, actor, userId, from, to, timezone)
+	if err != nil {
+		return model.GoalStats{}, err
+	}
+
+	categories, err := s.repo.GoalCategories(ctx, userId)
+	if err != nil {
+		return model.GoalStats{}, err
+	}
+
+	weekdays := make([]model.WeekdayStats, 7)
+	for weekday := range weekdays {
+		weekdays[weekday].Weekday = strings.ToLower(time.Weekday(weekday).String())
+	}
+	for _, day := range heatmap {
+		date, err := time.Parse("2006-01-02", day.Day)
+		if err != nil {
+			return model.GoalStats{}, err
+		}
+		stats := &weekdays[date.Weekday()]
+		stats.Days++
+		stats.Total += day.Total
+	}
+
+	stats := model.GoalStats{Categories: categories, Weekdays: weekdays}
+
+	// ties go to the earlier weekday, a range without activity has neither
+	var best, worst *model.WeekdayStats
+	for i := range weekdays {
+		weekday := &weekdays[i]
+		if weekday.Days == 0 {
+			continue
+		}
+		weekday.Average = float64(weekday.Total) / float64(weekday.Days)
+		if best == nil || weekday.Average > best.Average {
+			best = weekday
+		}
+		if worst == nil || weekday.Average < worst.Average {
+			worst = weekday
+		}
+	}
+	if best != nil && best.Total > 0 {
+		stats.BestWeekday = best.Weekday
+		stats.WorstWeekday = worst.Weekday
+	}
+
+	return stats, nil
+}
+
+func dayCountMap(counts []model.DayCount) map[string]int {
+	byDay := make(map[string]int, len(counts))
+	for _, count := range counts {
+		byDay[count.Day] = count.Count
+	}
+	return byDay
+}
+
+func NewAnalyticsService(repo repository.AnalyticsRepository) AnalyticsService {
+	return &analyticsService{
+		repo: repo,
+	}
+}
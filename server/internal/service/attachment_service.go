@@ -0,0 +1,217 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"github.com/ndk123-web/fast-todo/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// content types we accept, checked against the sniffed type
+var allowedAttachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+type AttachmentService interface {
+	UploadAttachment(ctx context.Context, actor Actor, userId string, todoId string, fileName string, file io.Reader, size int64) (model.Attachment, error)
+	GetAttachment(ctx context.Context, actor Actor, attachmentId string) (model.Attachment, io.ReadCloser, error)
+	GetTodoAttachments(ctx context.Context, actor Actor, todoId string) ([]model.Attachment, error)
+	DeleteAttachment(ctx context.Context, actor Actor, userId string, attachmentId string) error
+	DeleteTodoAttachments(ctx context.Context, todoId string) error
+}
+
+type attachmentService struct {
+	repo          repository.AttachmentRepository
+	todoRepo      repository.TodoRepository
+	workspaceRepo repository.WorkSpaceRepository
+	store         storage.BlobStore
+	maxBytes      int64
+}
+
+// UploadAttachment stores a file on a todo of a workspace the caller edits
+func (s *attachmentService) UploadAttachment(ctx context.Context, actor Actor, userId string, todoId string, fileName string, file io.Reader, size int64) (model.Attachment, error) {
+	if userId == "" || todoId == "" {
+		return model.Attachment{}, errors.New("UserId / TodoId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return model.Attachment{}, err
+	}
+	if size <= 0 {
+		return model.Attachment{}, errors.New("file is empty")
+	}
+	if size > s.maxBytes {
+		return model.Attachment{}, fmt.Errorf("file is larger than %d bytes", s.maxBytes)
+	}
+
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.Attachment{}, err
+	}
+
+	todo, err := todoAccess(ctx, s.todoRepo, s.workspaceRepo, actor, todoId, model.RoleEditor)
+	if err != nil {
+		return model.Attachment{}, err
+	}
+
+	// sniff the real type from the first 512 bytes, the client header can lie
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return model.Attachment{}, err
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	if !allowedAttachmentTypes[contentType] {
+		return model.Attachment{}, fmt.Errorf("file type %s is not allowed", contentType)
+	}
+
+	attachment := model.Attachment{
+		ID:          primitive.NewObjectID(),
+		TodoId:      todo.ID,
+		UserId:      userOid,
+		FileName:    cleanFileName(fileName),
+		ContentType: contentType,
+		Size:        size,
+		CreatedAt:   time.Now(),
+	}
+	attachment.StorageKey = "attachments/" + todo.ID.Hex() + "/" + attachment.ID.Hex()
+
+	// never store more than the size we checked
+	body := io.LimitReader(io.MultiReader(bytes.NewReader(head), file), size)
+	if err := s.store.Put(ctx, attachment.StorageKey, body, size, contentType); err != nil {
+		return model.Attachment{}, err
+	}
+
+	created, err := s.repo.CreateAttachment(ctx, attachment)
+	if err != nil {
+		// do not leave an orphan blob behind
+		s.store.Delete(ctx, attachment.StorageKey)
+		return model.Attachment{}, err
+	}
+
+	return created, nil
+}
+
+// GetAttachment returns the metadata and the content to members of the todo's
+// workspace, caller closes the content
+func (s *attachmentService) GetAttachment(ctx context.Context, actor Actor, attachmentId string) (model.Attachment, io.ReadCloser, error) {
+	if attachmentId == "" {
+		return model.Attachment{}, nil, errors.New("AttachmentId is Empty in Service")
+	}
+
+	attachment, err := s.repo.GetAttachmentById(ctx, attachmentId)
+	if err != nil {
+		return model.Attachment{}, nil, err
+	}
+	if _, err := todoAccess(ctx, s.todoRepo, s.workspaceRepo, actor, attachment.TodoId.Hex(), model.RoleViewer); err != nil {
+		return model.Attachment{}, nil, err
+	}
+
+	content, err := s.store.Get(ctx, attachment.StorageKey)
+	if err != nil {
+		return model.Attachment{}, nil, err
+	}
+
+	return attachment, content, nil
+}
+
+func (s *attachmentService) GetTodoAttachments(ctx context.Context, actor Actor, todoId string) ([]model.Attachment, error) {
+	if todoId == "" {
+		return nil, errors.New("TodoId is Empty in Service")
+	}
+	if _, err := todoAccess(ctx, s.todoRepo, s.workspaceRepo, actor, todoId, model.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetTodoAttachments(ctx, todoId)
+}
+
+func (s *attachmentService) DeleteAttachment(ctx context.Context, actor Actor, userId string, attachmentId string) error {
+	if userId == "" || attachmentId == "" {
+		return errors.New("UserId / AttachmentId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return err
+	}
+
+	attachment, err := s.repo.GetAttachmentById(ctx, attachmentId)
+	if err != nil {
+		return err
+	}
+
+	if attachment.UserId.Hex() != userId {
+		return errors.New("only the uploader can delete this attachment")
+	}
+	if _, err := todoAccess(ctx, s.todoRepo, s.workspaceRepo, actor, attachment.TodoId.Hex(), model.RoleEditor); err != nil {
+		return err
+	}
+
+	if err := s.store.Delete(ctx, attachment.StorageKey); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteAttachment(ctx, attachmentId)
+}
+
+// DeleteTodoAttachments removes every file of a todo, used when the todo is deleted
+func (s *attachmentService) DeleteTodoAttachments(ctx context.Context, todoId string) error {
+	attachments, err := s.repo.GetTodoAttachments(ctx, todoId)
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range attachments {
+		if err := s.store.Delete(ctx, attachment.StorageKey); err != nil {
+			// keep the document so the cleanup can be retried later
+			log.Println("Failed to delete attachment blob:", attachment.StorageKey, err)
+			continue
+		}
+		if err := s.repo.DeleteAttachment(ctx, attachment.ID.Hex()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cleanFileName keeps only the base name without control characters and quotes
+func cleanFileName(fileName string) string {
+	fileName = filepath.Base(strings.ReplaceAll(fileName, "\\", "/"))
+	fileName = strings.Map(func(r rune) rune {
+		if r < 32 || r == 127 || r == '"' {
+			return -1
+		}
+		return r
+	}, fileName)
+
+	if fileName == "" || fileName == "." || fileName == "/" {
+		return "file"
+	}
+	return fileName
+}
+
+func NewAttachmentService(repo repository.AttachmentRepository, todoRepo repository.TodoRepository, workspaceRepo repository.WorkSpaceRepository, store storage.BlobStore, maxBytes int64) AttachmentService {
+	return &attachmentService{
+		repo:          repo,
+		todoRepo:      todoRepo,
+		workspaceRepo: workspaceRepo,
+		store:         store,
+		maxBytes:      maxBytes,
+	}
+}
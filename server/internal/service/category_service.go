@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// limits of a goal category
+const (
+	maxCategoryNameLength = 50
+	maxCategoryIconLength = 32
+)
+
+var categoryColor = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// CategoryService manages the goal categories of a user, names are unique
+// per user ignoring case and repeated spaces
+type CategoryService interface {
+	CreateCategory(ctx context.Context, actor Actor, userId string, category model.GoalCategory) (model.GoalCategory, error)
+	GetCategories(ctx context.Context, actor Actor, userId string) ([]model.GoalCategory, error)
+	UpdateCategory(ctx context.Context, actor Actor, categoryId string, name string, color string, icon string) (model.GoalCategory, error)
+	DeleteCategory(ctx context.Context, actor Actor, categoryId string) error
+	MigrateGoalCategories(ctx context.Context) error
+}
+
+type categoryService struct {
+	repo     repository.CategoryRepository
+	goalRepo repository.GoalRepository
+}
+
+func (s *categoryService) CreateCategory(ctx context.Context, actor Actor, userId string, category model.GoalCategory) (model.GoalCategory, error) {
+	if err := checkCaller(actor, userId); err != nil {
+		return model.GoalCategory{}, err
+	}
+
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.GoalCategory{}, err
+	}
+
+	category.UserId = userOid
+	if err := validateCategory(&category); err != nil {
+		return model.GoalCategory{}, err
+	}
+
+	return s.repo.CreateCategory(ctx, category)
+}
+
+func (s *categoryService) GetCategories(ctx context.Context, actor Actor, userId string) ([]model.GoalCategory, error) {
+	if userId == "" {
+		return nil, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return nil, err
+	}
+	return s.repo.GetUserCategories(ctx, userId)
+}
+
+// UpdateCategory renames / recolors the category, a new name is copied to
+// its goals
+func (s *categoryService) UpdateCategory(ctx context.Context, actor Actor, categoryId string, name string, color string, icon string) (model.GoalCategory, error) {
+	category, err := s.ownCategory(ctx, actor, categoryId)
+	if err != nil {
+		return model.GoalCategory{}, err
+	}
+
+	previousName := category.Name
+	category.Name, category.Color, category.Icon = name, color, icon
+	if err := validateCategory(&category); err != nil {
+		return model.GoalCategory{}, err
+	}
+
+	// the unique index rejects a name another category already has
+	updated, err := s.repo.SetFields(ctx, categoryId, map[string]any{
+		"name":  category.Name,
+		"key":   category.Key,
+		"color": category.Color,
+		"icon":  category.Icon,
+	})
+	if err != nil {
+		return model.GoalCategory{}, err
+	}
+
+	if updated.Name != previousName {
+		if err := s.goalRepo.RenameCategory(ctx, updated.ID, updated.Name); err != nil {
+			return model.GoalCategory{}, err
+		}
+	}
+
+	return updated, nil
+}
+
+// DeleteCategory removes the category, its goals are left without category
+func (s *categoryService) DeleteCategory(ctx context.Context, actor Actor, categoryId string) error {
+	category, err := s.ownCategory(ctx, actor, categoryId)
+	if err != nil {
+		return err
+	}
+
+	if err := s.goalRepo.UnlinkCategory(ctx, category.ID); err != nil {
+		return err
+	}
+	return s.repo.DeleteCategory(ctx, categoryId)
+}
+
+// MigrateGoalCategories links goals that only have a free text category to
+// a managed category of their user. Spellings that only differ in case and
+// spaces end up in one category, named like on the oldest goal. Goals that
+// are linked already are not touched, so it is safe to run on every start
+func (s *categoryService) MigrateGoalCategories(ctx context.Context) error {
+	goals, err := s.goalRepo.GetGoalsWithoutCategoryId(ctx)
+	if err != nil {
+		return err
+	}
+
+	type group struct {
+		category model.GoalCategory
+		goalIds  []primitive.ObjectID
+	}
+	var groups []*group
+	byKey := make(map[string]*group)
+
+	for _, goal := range goals {
+		category := model.GoalCategory{UserId: goal.UserId, Name: goal.Category}
+		normalizeCategoryName(&category)
+		if runes := []rune(category.Name); len(runes) > maxCategoryNameLength {
+			category.Name = string(runes[:maxCategoryNameLength])
+			normalizeCategoryName(&category)
+		}
+		if category.Key == "" {
+			continue
+		}
+
+		key := goal.UserId.Hex() + "/" + category.Key
+		if byKey[key] == nil {
+			byKey[key] = &group{category: category}
+			groups = append(groups, byKey[key])
+		}
+		byKey[key].goalIds = append(byKey[key].goalIds, goal.ID)
+	}
+
+	for _, group := range groups {
+		category, err := s.repo.FindOrCreateCategory(ctx, group.category)
+		if err != nil {
+			return err
+		}
+		if err := s.goalRepo.LinkCategory(ctx, group.goalIds, category); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *categoryService) ownCategory(ctx context.Context, actor Actor, categoryId string) (model.GoalCategory, error) {
+	if categoryId == "" {
+		return model.GoalCategory{}, errors.New("CategoryId is Empty in Service")
+	}
+
+	category, err := s.repo.GetCategoryById(ctx, categoryId)
+	if err != nil {
+		return model.GoalCategory{}, err
+	}
+	if category.UserId.Hex() != actor.UserId {
+		return model.GoalCategory{}, errors.New("category not found")
+	}
+
+	return category, nil
+}
+
+// normalizeCategoryName trims the name, collapses its spaces and sets the
+// key it is compared by
+func normalizeCategoryName(category *model.GoalCategory) {
+	category.Name = strings.Join(strings.Fields(category.Name), " ")
+	category.Key = strings.ToLower(category.Name)
+}
+
+func validateCategory(category *model.GoalCategory) error {
+	normalizeCategoryName(category)
+	if category.Key == "" {
+		return errors.New("category name is empty")
+	}
+	if len([]rune(category.Name)) > maxCategoryNameLength {
+		return errors.New("category name can have at most 50 characters")
+	}
+
+	category.Color = strings.TrimSpace(category.Color)
+	if category.Color != "" && !categoryColor.MatchString(category.Color) {
+		return errors.New("color must look like #RRGGBB")
+	}
+
+	category.Icon = strings.TrimSpace(category.Icon)
+	if len([]rune(category.Icon)) > maxCategoryIconLength {
+		return errors.New("icon can have at most 32 characters")
+	}
+
+	return nil
+}
+
+func NewCategoryService(repo repository.CategoryRepository, goalRepo repository.GoalRepository) CategoryService {
+	return &categoryService{
+		repo:     repo,
+		goalRepo: goalRepo,
+	}
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// longest note a check-in can carry
+const maxCheckInNoteLength = 1000
+
+// CheckInService logs daily progress on days and habit goals. For days goals
+// every check-in counts one day towards TargetDays, the goal is done once
+// CurrentTarget reaches it. Habit goals never end, their streaks count the
+// periods that reached the frequency
+type CheckInService interface {
+	CheckIn(ctx context.Context, actor Actor, goalId string, day string, note string) (model.GoalProgress, error)
+	GetProgress(ctx context.Context, actor Actor, goalId string) (model.GoalProgress, error)
+}
+
+type checkInService struct {
+	repo          repository.CheckInRepository
+	goalRepo      repository.GoalRepository
+	workspaceRepo repository.WorkSpaceRepository
+	userRepo      repository.UserRepository
+	transactor    repository.Transactor
+	activities    ActivityService
+	goals         GoalService
+}
+
+// CheckIn logs day (2006-01-02, default today in the user's timezone).
+// Past days can be filled in later, future days can not
+func (s *checkInService) CheckIn(ctx context.Context, actor Actor, goalId string, day string, note string) (model.GoalProgress, error) {
+	goal, err := s.ownGoal(ctx, actor, goalId)
+	if err != nil {
+		return model.GoalProgress{}, err
+	}
+	if goal.Done {
+		return model.GoalProgress{}, errors.New("goal is already done")
+	}
+	if goal.ProgressMode == model.GoalProgressTodos {
+		return model.GoalProgress{}, errors.New("progress of this goal comes from its todos")
+	}
+	if kind := goalType(goal); kind != model.GoalTypeDays && kind != model.GoalTypeHabit {
+		return model.GoalProgress{}, errors.New("only days and habit goals take check-ins")
+	}
+
+	note = strings.TrimSpace(note)
+	if len(note) > maxCheckInNoteLength {
+		return model.GoalProgress{}, errors.New("note can have at most 1000 characters")
+	}
+
+	userOid, err := primitive.ObjectIDFromHex(actor.UserId)
+	if err != nil {
+		return model.GoalProgress{}, err
+	}
+
+	today, err := s.today(ctx, actor)
+	if err != nil {
+		return model.GoalProgress{}, err
+	}
+	if day == "" {
+		day = today
+	}
+	if _, err := time.Parse("2006-01-02", day); err != nil {
+		return model.GoalProgress{}, errors.New("day must look like 2006-01-02")
+	}
+	if day > today {
+		return model.GoalProgress{}, errors.New("can not check in for a future day")
+	}
+
+	var progress model.GoalProgress
+	err = s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		// the unique index rejects a second check-in for the same day
+		_, err := s.repo.CreateCheckIn(ctx, model.GoalCheckIn{
+			GoalId: goal.ID,
+			UserId: userOid,
+			Day:    day,
+			Note:   note,
+		})
+		if err != nil {
+			return err
+		}
+
+		checkIns, err := s.repo.GetGoalCheckIns(ctx, goal.ID)
+		if err != nil {
+			return err
+		}
+
+		days := checkInDays(checkIns)
+		frequency := goalFrequency(goal)
+		current, longest := streaks(days, frequency, days[len(days)-1])
+
+		fields := map[string]any{
+			"currentTarget": len(days),
+			"currentStreak": current,
+			"longestStreak": longest,
+			"lastCheckIn":   days[len(days)-1],
+		}
+		if goalType(goal) == model.GoalTypeHabit {
+			start, count := periodCheckIns(days, frequency, today)
+			fields["periodStart"], fields["periodCheckIns"] = start, count
+		} else if goal.TargetDays > 0 && len(days) >= goal.TargetDays {
+			fields["done"] = true
+			fields["completedAt"] = time.Now()
+		}
+
+		updated, err := s.goalRepo.SetFields(ctx, goal.ID.Hex(), fields)
+		if err != nil {
+			return err
+		}
+
+		progress = goalProgress(updated, checkIns, today)
+		return nil
+	})
+	if err != nil {
+		return model.GoalProgress{}, err
+	}
+	s.goals.SyncProgress(ctx, []primitive.ObjectID{goal.ParentId})
+
+	activity := goalActivity(goal, model.ActivityGoalCheckedIn)
+	activity.Details = map[string]any{"day": day}
+	s.activities.Record(ctx, actor, activity)
+
+	return progress, nil
+}
+
+// GetProgress returns the goal with its check-ins and streaks as of today
+func (s *checkInService) GetProgress(ctx context.Context, actor Actor, goalId string) (model.GoalProgress, error) {
+	goal, err := goalAccess(ctx, s.goalRepo, s.workspaceRepo, actor, goalId, model.RoleViewer)
+	if err != nil {
+		return model.GoalProgress{}, err
+	}
+
+	checkIns, err := s.repo.GetGoalCheckIns(ctx, goal.ID)
+	if err != nil {
+		return model.GoalProgress{}, err
+	}
+
+	today, err := s.today(ctx, actor)
+	if err != nil {
+		return model.GoalProgress{}, err
+	}
+
+	return goalProgress(goal, checkIns, today), nil
+}
+
+func (s *checkInService) ownGoal(ctx context.Context, actor Actor, goalId string) (model.Goals, error) {
+	return goalAccess(ctx, s.goalRepo, s.workspaceRepo, actor, goalId, model.RoleEditor)
+}
+
+// today is the current day in the caller's timezone
+func (s *checkInService) today(ctx context.Context, actor Actor) (string, error) {
+	loc, err := userLocation(ctx, s.userRepo, actor.UserId, "")
+	if err != nil {
+		return "", err
+	}
+	return time.Now().In(loc).Format("2006-01-02"), nil
+}
+
+func goalProgress(goal model.Goals, checkIns []model.GoalCheckIn, today string) model.GoalProgress {
+	current, longest := streaks(checkInDays(checkIns), goalFrequency(goal), today)
+	goal.Progress = goalProgressValue(goal, today)
+	return model.GoalProgress{
+		Goal:          goal,
+		CheckIns:      checkIns,
+		CurrentStreak: current,
+		LongestStreak: longest,
+	}
+}
+
+func checkInDays(checkIns []model.GoalCheckIn) []string {
+	days := make([]string, 0, len(checkIns))
+	for _, checkIn := range checkIns {
+		days = append(days, checkIn.Day)
+	}
+	return days
+}
+
+// goalFrequency is the habit frequency, days goals are checked in once a day
+func goalFrequency(goal model.Goals) model.GoalFrequency {
+	if goal.Frequency == nil {
+		return model.GoalFrequency{Times: 1, Period: "day"}
+	}
+	return *goal.Frequency
+}
+
+// streaks returns the run of consecutive periods with at least
+// frequency.Times check-ins that ends with the period of today or the one
+// before (today's period may still be reached), and the longest run. days
+// must be sorted and distinct
+func streaks(days []string, frequency model.GoalFrequency, today string) (int, int) {
+	todayDay, err := time.Parse("2006-01-02", today)
+	if err != nil {
+		return 0, 0
+	}
+	current := periodStart(todayDay, frequency.Period)
+
+	counts := make(map[time.Time]int)
+	var starts []time.Time
+	for _, value := range days {
+		day, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			continue
+		}
+		start := periodStart(day, frequency.Period)
+		if counts[start] == 0 {
+			starts = append(starts, start)
+		}
+		counts[start]++
+	}
+
+	longest, run := 0, 0
+	var previous time.Time
+	for _, start := range starts {
+		if counts[start] < frequency.Times {
+			// the running period does not break the streak yet
+			if !start.Equal(current) {
+				run = 0
+			}
+			continue
+		}
+		if run > 0 && start.Equal(nextPeriod(previous, frequency.Period)) {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+		previous = start
+	}
+
+	if run == 0 || (!previous.Equal(current) && !nextPeriod(previous, frequency.Period).Equal(current)) {
+		return 0, longest
+	}
+	return run, longest
+}
+
+// periodCheckIns returns the start of today's period and its check-ins
+func periodCheckIns(days []string, frequency model.GoalFrequency, today string) (string, int) {
+	todayDay, err := time.Parse("2006-01-02", today)
+	if err != nil {
+		return "", 0
+	}
+	start := periodStart(todayDay, frequency.Period).Format("2006-01-02")
+
+	count := 0
+	for _, day := range days {
+		if day >= start && day <= today {
+			count++
+		}
+	}
+	return start, count
+}
+
+// periodStart is the first day of the period (day / week from monday / month)
+func periodStart(day time.Time, period string) time.Time {
+	switch period {
+	case "week":
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "month":
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	default:
+		return day
+	}
+}
+
+func nextPeriod(start time.Time, period string) time.Time {
+	switch period {
+	case "week":
+		return start.AddDate(0, 0, 7)
+	case "month":
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+func NewCheckInService(repo repository.CheckInRepository, goalRepo repository.GoalRepository, workspaceRepo repository.WorkSpaceRepository, userRepo repository.UserRepository, transactor repository.Transactor, activities ActivityService, goals GoalService) CheckInService {
+	return &checkInService{
+		repo:          repo,
+		goalRepo:      goalRepo,
+		workspaceRepo: workspaceRepo,
+		userRepo:      userRepo,
+		transactor:    transactor,
+		activities:    activities,
+		goals:         goals,
+	}
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"github.com/ndk123-web/fast-todo/pkg/nmarkdown"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// max length of a comment body in bytes
+const maxCommentLength = 10000
+
+// @alice or @alice@example.com, not part of an email address in the text
+var mentionRe = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9._%+-]+(?:@[A-Za-z0-9.-]+\.[A-Za-z]{2,})?)`)
+
+type CommentService interface {
+	CreateComment(ctx context.Context, actor Actor, userId string, todoId string, body string) (model.Comment, error)
+	UpdateComment(ctx context.Context, actor Actor, userId string, commentId string, body string) (model.Comment, error)
+	DeleteComment(ctx context.Context, actor Actor, userId string, commentId string) error
+	GetTodoComments(ctx context.Context, actor Actor, todoId string) ([]model.Comment, error)
+}
+
+type commentService struct {
+	repo          repository.CommentRepository
+	todoRepo      repository.TodoRepository
+	userRepo      repository.UserRepository
+	workspaceRepo repository.WorkSpaceRepository
+}
+
+// CreateComment adds a comment to a todo of a workspace the caller edits
+func (s *commentService) CreateComment(ctx context.Context, actor Actor, userId string, todoId string, body string) (model.Comment, error) {
+	if userId == "" || todoId == "" {
+		return model.Comment{}, errors.New("UserId / TodoId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return model.Comment{}, err
+	}
+
+	body, err := validCommentBody(body)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	// comment only on live todos the caller may change
+	todo, err := todoAccess(ctx, s.todoRepo, s.workspaceRepo, actor, todoId, model.RoleEditor)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	mentions, err := s.resolveMentions(ctx, body)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	return s.repo.CreateComment(ctx, model.Comment{
+		TodoId:   todo.ID,
+		UserId:   userOid,
+		Body:     body,
+		BodyHTML: nmarkdown.Render(body),
+		Mentions: mentions,
+	})
+}
+
+func (s *commentService) UpdateComment(ctx context.Context, actor Actor, userId string, commentId string, body string) (model.Comment, error) {
+	if userId == "" || commentId == "" {
+		return model.Comment{}, errors.New("UserId / CommentId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return model.Comment{}, err
+	}
+
+	body, err := validCommentBody(body)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	if err := s.checkAuthor(ctx, actor, commentId); err != nil {
+		return model.Comment{}, err
+	}
+
+	mentions, err := s.resolveMentions(ctx, body)
+	if err != nil {
+		return model.Comment{}, err
+	}
+
+	return s.repo.UpdateComment(ctx, commentId, body, nmarkdown.Render(body), mentions)
+}
+
+func (s *commentService) DeleteComment(ctx context.Context, actor Actor, userId string, commentId string) error {
+	if userId == "" || commentId == "" {
+		return errors.New("UserId / CommentId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return err
+	}
+
+	if err := s.checkAuthor(ctx, actor, commentId); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteComment(ctx, commentId)
+}
+
+// GetTodoComments lists the thread of a todo to any member of its workspace
+func (s *commentService) GetTodoComments(ctx context.Context, actor Actor, todoId string) ([]model.Comment, error) {
+	if todoId == "" {
+		return nil, errors.New("TodoId is Empty in Service")
+	}
+	if _, err := todoAccess(ctx, s.todoRepo, s.workspaceRepo, actor, todoId, model.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetTodoComments(ctx, todoId)
+}
+
+// checkAuthor makes sure only the author edits / deletes a comment, and only
+// while the author may still change the todo
+func (s *commentService) checkAuthor(ctx context.Context, actor Actor, commentId string) error {
+	comment, err := s.repo.GetCommentById(ctx, commentId)
+	if err != nil {
+		return err
+	}
+
+	if comment.UserId.Hex() != actor.UserId {
+		return errors.New("only the author can change this comment")
+	}
+
+	_, err = todoAccess(ctx, s.todoRepo, s.workspaceRepo, actor, comment.TodoId.Hex(), model.RoleEditor)
+	return err
+}
+
+// resolveMentions maps @handles in the body to user ids, handles that match
+// no user or more than one user are left as plain text
+func (s *commentService) resolveMentions(ctx context.Context, body string) ([]primitive.ObjectID, error) {
+	var handles []string
+	seen := make(map[string]bool)
+	for _, match := range mentionRe.FindAllStringSubmatch(body, -1) {
+		// "@alice." at the end of a sentence
+		handle := strings.ToLower(strings.TrimRight(match[1], "."))
+		if handle != "" && !seen[handle] {
+			seen[handle] = true
+			handles = append(handles, handle)
+		}
+	}
+
+	if len(handles) == 0 {
+		return nil, nil
+	}
+
+	users, err := s.userRepo.FindUsersByHandles(ctx, handles)
+	if err != nil {
+		return nil, err
+	}
+
+	var mentions []primitive.ObjectID
+	added := make(map[primitive.ObjectID]bool)
+	for _, handle := range handles {
+		var matched []primitive.ObjectID
+		for _, user := range users {
+			email := strings.ToLower(user.Email)
+			local, _, _ := strings.Cut(email, "@")
+			if email == handle || local == handle {
+				matched = append(matched, user.ID)
+			}
+		}
+
+		if len(matched) == 1 && !added[matched[0]] {
+			added[matched[0]] = true
+			mentions = append(mentions, matched[0])
+		}
+	}
+
+	return mentions, nil
+}
+
+func validCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", errors.New("comment body is empty")
+	}
+	if len(body) > maxCommentLength {
+		return "", errors.New("comment body is too long")
+	}
+	return body, nil
+}
+
+func NewCommentService(repo repository.CommentRepository, todoRepo repository.TodoRepository, userRepo repository.UserRepository, workspaceRepo repository.WorkSpaceRepository) CommentService {
+	return &commentService{
+		repo:          repo,
+		todoRepo:      todoRepo,
+		userRepo:      userRepo,
+		workspaceRepo: workspaceRepo,
+	}
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// limits for the lengths a session can be started with
+const (
+	maxFocusWorkMinutes  = 180
+	maxFocusBreakMinutes = 60
+)
+
+type FocusService interface {
+	StartSession(ctx context.Context, actor Actor, userId string, todoId string, workMinutes int, breakMinutes *int) (model.FocusSession, error)
+	PauseSession(ctx context.Context, actor Actor, userId string) (model.FocusSession, error)
+	ResumeSession(ctx context.Context, actor Actor, userId string) (model.FocusSession, error)
+	CompleteSession(ctx context.Context, actor Actor, userId string) (model.FocusSession, error)
+	GetActiveSession(ctx context.Context, actor Actor, userId string) (model.FocusSession, error)
+	Stats(ctx context.Context, actor Actor, userId string, workspaceId string, period string, from time.Time, to time.Time, timezone string) ([]model.FocusStatsRow, error)
+}
+
+type focusService struct {
+	repo                repository.FocusRepository
+	todoRepo            repository.TodoRepository
+	workspaceRepo       repository.WorkSpaceRepository
+	defaultWorkMinutes  int
+	defaultBreakMinutes int
+}
+
+// StartSession starts a session on the todo, 0 work minutes and a nil break
+// use the configured default lengths. A break of 0 is a session without one
+func (s *focusService) StartSession(ctx context.Context, actor Actor, userId string, todoId string, workMinutes int, breakMinutes *int) (model.FocusSession, error) {
+	if userId == "" || todoId == "" {
+		return model.FocusSession{}, errors.New("UserId / TodoId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return model.FocusSession{}, err
+	}
+
+	if workMinutes == 0 {
+		workMinutes = s.defaultWorkMinutes
+	}
+	rest := s.defaultBreakMinutes
+	if breakMinutes != nil {
+		rest = *breakMinutes
+	}
+	if workMinutes < 1 || workMinutes > maxFocusWorkMinutes {
+		return model.FocusSession{}, errors.New("workMinutes must be between 1 and 180")
+	}
+	if rest < 0 || rest > maxFocusBreakMinutes {
+		return model.FocusSession{}, errors.New("breakMinutes must be between 0 and 60")
+	}
+
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.FocusSession{}, err
+	}
+
+	todo, err := todoAccess(ctx, s.todoRepo, s.workspaceRepo, actor, todoId, model.RoleEditor)
+	if err != nil {
+		return model.FocusSession{}, err
+	}
+
+	// the unique index rejects a second active session
+	session, err := s.repo.StartSession(ctx, model.FocusSession{
+		UserId:       userOid,
+		TodoId:       todo.ID,
+		WorkspaceId:  todo.WorkspaceId,
+		WorkMinutes:  workMinutes,
+		BreakMinutes: rest,
+		StartedAt:    time.Now(),
+	})
+	if err != nil {
+		return model.FocusSession{}, err
+	}
+
+	return withPhase(session, time.Now()), nil
+}
+
+func (s *focusService) PauseSession(ctx context.Context, actor Actor, userId string) (model.FocusSession, error) {
+	session, err := s.active(ctx, actor, userId)
+	if err != nil {
+		return model.FocusSession{}, err
+	}
+
+	now := time.Now()
+	session, err = s.repo.Transition(ctx, session.ID, model.FocusRunning, map[string]any{
+		"state":    model.FocusPaused,
+		"pausedAt": now,
+	})
+	if err != nil {
+		return model.FocusSession{}, err
+	}
+
+	return withPhase(session, now), nil
+}
+
+func (s *focusService) ResumeSession(ctx context.Context, actor Actor, userId string) (model.FocusSession, error) {
+	session, err := s.active(ctx, actor, userId)
+	if err != nil {
+		return model.FocusSession{}, err
+	}
+	if session.State != model.FocusPaused || session.PausedAt == nil {
+		return model.FocusSession{}, errors.New("focus session is not paused")
+	}
+
+	now := time.Now()
+	session, err = s.repo.Transition(ctx, session.ID, model.FocusPaused, map[string]any{
+		"state":         model.FocusRunning,
+		"pausedSeconds": session.PausedSeconds + int64(now.Sub(*session.PausedAt).Seconds()),
+	})
+	if err != nil {
+		return model.FocusSession{}, err
+	}
+
+	return withPhase(session, now), nil
+}
+
+// CompleteSession ends the session, running or paused. Focus time is the time
+// it was not paused, at most the work length since the rest was break
+func (s *focusService) CompleteSession(ctx context.Context, actor Actor, userId string) (model.FocusSession, error) {
+	session, err := s.active(ctx, actor, userId)
+	if err != nil {
+		return model.FocusSession{}, err
+	}
+
+	now := time.Now()
+	session, err = s.repo.Transition(ctx, session.ID, session.State, map[string]any{
+		"state":         model.FocusCompleted,
+		"active":        false,
+		"completedAt":   now,
+		"pausedSeconds": pausedSeconds(session, now),
+		"focusSeconds":  min(focusedSeconds(session, now), int64(session.WorkMinutes)*60),
+	})
+	if err != nil {
+		return model.FocusSession{}, err
+	}
+
+	return withPhase(session, now), nil
+}
+
+func (s *focusService) GetActiveSession(ctx context.Context, actor Actor, userId string) (model.FocusSession, error) {
+	session, err := s.active(ctx, actor, userId)
+	if err != nil {
+		return model.FocusSession{}, err
+	}
+
+	return withPhase(session, time.Now()), nil
+}
+
+// Stats totals completed sessions per "day" or "week" (ISO weeks), of the
+// user in all workspaces or of every member in workspaceId
+func (s *focusService) Stats(ctx context.Context, actor Actor, userId string, workspaceId string, period string, from time.Time, to time.Time, timezone string) ([]model.FocusStatsRow, error) {
+	if userId == "" {
+		return nil, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return nil, err
+	}
+	if workspaceId != "" {
+		if _, err := workspaceView(ctx, s.workspaceRepo, actor, workspaceId); err != nil {
+			return nil, err
+		}
+	}
+	if !from.Before(to) {
+		return nil, errors.New("from must be before to")
+	}
+
+	switch period {
+	case "day":
+		return s.repo.Stats(ctx, userId, workspaceId, from, to, "%Y-%m-%d", timezone)
+	case "week":
+		return s.repo.Stats(ctx, userId, workspaceId, from, to, "%G-W%V", timezone)
+	default:
+		return nil, errors.New("period must be day or week")
+	}
+}
+
+// active is the running or paused session of the caller
+func (s *focusService) active(ctx context.Context, actor Actor, userId string) (model.FocusSession, error) {
+	if userId == "" {
+		return model.FocusSession{}, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return model.FocusSession{}, err
+	}
+	return s.repo.GetActiveSession(ctx, userId)
+}
+
+// pausedSeconds is the paused time up to now, the current pause included
+func pausedSeconds(session model.FocusSession, now time.Time) int64 {
+	paused := session.PausedSeconds
+	if session.State == model.FocusPaused && session.PausedAt != nil {
+		paused += int64(now.Sub(*session.PausedAt).Seconds())
+	}
+	return paused
+}
+
+func focusedSeconds(session model.FocusSession, now time.Time) int64 {
+	end := now
+	if session.CompletedAt != nil {
+		end = *session.CompletedAt
+	}
+	return max(int64(end.Sub(session.StartedAt).Seconds())-pausedSeconds(session, end), 0)
+}
+
+// withPhase fills the phase and the seconds left in it
+func withPhase(session model.FocusSession, now time.Time) model.FocusSession {
+	if session.State == model.FocusCompleted {
+		return session
+	}
+
+	work := int64(session.WorkMinutes) * 60
+	rest := int64(session.BreakMinutes) * 60
+	focused := focusedSeconds(session, now)
+
+	switch {
+	case focused < work:
+		session.Phase, session.RemainingSeconds = model.FocusPhaseWork, work-focused
+	case focused < work+rest:
+		session.Phase, session.RemainingSeconds = model.FocusPhaseBreak, work+rest-focused
+	default:
+		session.Phase, session.RemainingSeconds = model.FocusPhaseOver, 0
+	}
+	return session
+}
+
+func NewFocusService(repo repository.FocusRepository, todoRepo repository.TodoRepository, workspaceRepo repository.WorkSpaceRepository, defaultWorkMinutes int, defaultBreakMinutes int) FocusService {
+	return &focusService{
+		repo:                repo,
+		todoRepo:            todoRepo,
+		workspaceRepo:       workspaceRepo,
+		defaultWorkMinutes:  defaultWorkMinutes,
+		defaultBreakMinutes: defaultBreakMinutes,
+	}
+}
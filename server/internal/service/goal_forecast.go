@@ -0,0 +1,88 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+)
+
+// a goal that needs at most this many times its pace so far to make the
+// deadline is at risk, one that needs more is off track
+const atRiskPaceFactor = 1.5
+
+// goalForecast projects the goal at its pace since the day it was created,
+// today is midnight in the user's timezone. nil for goals without deadline.
+// A goal without any progress yet is at risk on the day it was created and
+// off track after that
+func goalForecast(goal model.Goals, today time.Time) *model.GoalForecast {
+	if goal.Deadline == "" || goalType(goal) == model.GoalTypeHabit {
+		return nil
+	}
+	deadline, err := time.ParseInLocation("2006-01-02", goal.Deadline, today.Location())
+	if err != nil {
+		return nil
+	}
+
+	current, target := goalAmounts(goal)
+
+	// the day the goal was created counts as a day worked on it
+	elapsed := max(daysBetween(startOfDay(goal.ID.Timestamp().In(today.Location())), today)+1, 1)
+
+	forecast := &model.GoalForecast{
+		DaysLeft: daysBetween(today, deadline),
+		Velocity: current / float64(elapsed),
+	}
+
+	if goal.Done {
+		forecast.Status = model.GoalOnTrack
+		if goal.CompletedAt != nil {
+			forecast.ProjectedDate = goal.CompletedAt.In(today.Location()).Format("2006-01-02")
+		}
+		return forecast
+	}
+
+	// today still counts towards the deadline
+	remaining := max(target-current, 0)
+	if forecast.DaysLeft >= 0 {
+		forecast.RequiredVelocity = remaining / float64(forecast.DaysLeft+1)
+	}
+	if forecast.Velocity > 0 {
+		days := max(int(math.Ceil(remaining/forecast.Velocity))-1, 0)
+		forecast.ProjectedDate = today.AddDate(0, 0, days).Format("2006-01-02")
+	}
+
+	switch {
+	case forecast.DaysLeft < 0:
+		forecast.Status = model.GoalOffTrack
+	case forecast.Velocity >= forecast.RequiredVelocity:
+		forecast.Status = model.GoalOnTrack
+	case goalType(goal) == model.GoalTypeDays && goal.ProgressMode != model.GoalProgressTodos && forecast.RequiredVelocity > 1:
+		// there is at most one check-in per day
+		forecast.Status = model.GoalOffTrack
+	case forecast.Velocity == 0 && elapsed == 1:
+		forecast.Status = model.GoalAtRisk
+	case forecast.Velocity > 0 && forecast.RequiredVelocity <= forecast.Velocity*atRiskPaceFactor:
+		forecast.Status = model.GoalAtRisk
+	default:
+		forecast.Status = model.GoalOffTrack
+	}
+
+	return forecast
+}
+
+// goalAmounts returns the progress and the target in the goal's own units
+func goalAmounts(goal model.Goals) (float64, float64) {
+	switch goalType(goal) {
+	case model.GoalTypeNumeric:
+		return goal.CurrentValue, goal.TargetValue
+	case model.GoalTypeMilestone:
+		return float64(doneMilestones(goal)), float64(len(goal.Milestones))
+	default:
+		if goal.TargetDays > 0 {
+			return float64(goal.CurrentTarget), float64(goal.TargetDays)
+		}
+		// todos mode without a target, the share of done todos
+		return goal.Progress, 1
+	}
+}
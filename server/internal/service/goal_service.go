@@ -1,58 +1,1085 @@
-package service
-
-import (
-	"context"
-	"errors"
-
-	"github.com/ndk123-web/fast-todo/internal/model"
-	"github.com/ndk123-web/fast-todo/internal/repository"
-)
-
-type GoalService interface {
-	GetUserGoals(ctx context.Context, userId string, workspaceId string) ([]model.Goals, error)
-	CreateUserGoal(ctx context.Context, userId string, workspaceId string, goalName string, targetDays int64, category string) (model.Goals, error)
-	UpdateUserGoal(ctx context.Context, goalId string, updatedGoalName string, updatedTargetDays int, updatedCategory string) (bool, error)
-	DeleteUserGoal(ctx context.Context, goalId string) (bool, error)
-}
-
-type goalService struct {
-	repo repository.GoalRepository
-}
-
-func (s *goalService) GetUserGoals(ctx context.Context, userId string, workspaceId string) ([]model.Goals, error) {
-	if userId == "" || workspaceId == "" {
-		return nil, errors.New("UserId / WorkspaceID is Empty in Service")
-	}
-
-	return s.repo.GetUserGoals(ctx, userId, workspaceId)
-}
-
-func (s *goalService) CreateUserGoal(ctx context.Context, userId string, workspaceId string, goalName string, targetDays int64, category string) (model.Goals, error) {
-	if userId == "" || workspaceId == "" {
-		return model.Goals{}, errors.New("UserId / WorkspaceId in Empty in Service")
-	}
-
-	return s.repo.CreateUserGoal(ctx, userId, workspaceId, goalName, targetDays, category)
-}
-
-func (s *goalService) UpdateUserGoal(ctx context.Context, goalId string, updatedGoalName string, updatedTargetDays int, updatedCategory string) (bool, error) {
-	if goalId == "" {
-		return false, errors.New("Goal Id Empty")
-	}
-
-	return s.repo.UpdateUserGoal(ctx, goalId, updatedGoalName, updatedTargetDays, updatedCategory)
-}
-
-func (s *goalService) DeleteUserGoal(ctx context.Context, goalId string) (bool, error) {
-	if goalId == "" {
-		return false, errors.New("Goal Id is Empty in Service")
-	}
-
-	return s.repo.DeleteUserGoal(ctx, goalId)
-}
-
-func NewGoalService(repo repository.GoalRepository) GoalService {
-	return &goalService{
-		repo: repo,
-	}
-}
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// limits of the typed goals
+const (
+	maxMilestones        = 50
+	maxGoalUnitLength    = 30
+	maxMilestoneTitleLen = 200
+)
+
+type GoalService interface {
+	GetUserGoals(ctx context.Context, actor Actor, userId string, workspaceId string) ([]model.Goals, error)
+	CreateUserGoal(ctx context.Context, actor Actor, userId string, workspaceId string, goal model.Goals) (model.Goals, error)
+	UpdateUserGoal(ctx context.Context, actor Actor, goalId string, update model.GoalUpdate) (bool, error)
+	DeleteUserGoal(ctx context.Context, actor Actor, goalId string) (bool, error)
+	GetGoalDetail(ctx context.Context, actor Actor, goalId string) (model.GoalDetail, error)
+	SetProgressMode(ctx context.Context, actor Actor, goalId string, mode string) (model.Goals, error)
+	RecordValue(ctx context.Context, actor Actor, goalId string, value *float64, add *float64) (model.Goals, error)
+	SetMilestone(ctx context.Context, actor Actor, goalId string, milestoneId string, done bool) (model.Goals, error)
+	CategoryRollups(ctx context.Context, actor Actor, userId string) ([]model.CategoryRollup, error)
+	CategoryRollup(ctx context.Context, actor Actor, categoryId string) (model.CategoryRollup, error)
+	SetDeadline(ctx context.Context, actor Actor, goalId string, deadline string) (model.Goals, error)
+	GoalsBehind(ctx context.Context, actor Actor, userId string) ([]model.Goals, error)
+	FillProgress(ctx context.Context, userId string, goals []model.Goals) ([]model.Goals, error)
+	SyncProgress(ctx context.Context, goalIds []primitive.ObjectID)
+	RevertGoal(ctx context.Context, actor Actor, goalId string, version int) (model.Revision, error)
+}
+
+type goalService struct {
+	repo          repository.GoalRepository
+	todoRepo      repository.TodoRepository
+	checkInRepo   repository.CheckInRepository
+	categoryRepo  repository.CategoryRepository
+	workspaceRepo repository.WorkSpaceRepository
+	userRepo      repository.UserRepository
+	revisions     RevisionService
+	activities    ActivityService
+}
+
+// GetUserGoals returns the goals of every member of the workspace
+func (s *goalService) GetUserGoals(ctx context.Context, actor Actor, userId string, workspaceId string) ([]model.Goals, error) {
+	if userId == "" || workspaceId == "" {
+		return nil, errors.New("UserId / WorkspaceID is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return nil, err
+	}
+	if _, err := workspaceView(ctx, s.workspaceRepo, actor, workspaceId); err != nil {
+		return nil, err
+	}
+
+	goals, err := s.repo.GetWorkspaceGoals(ctx, workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	goals, err = s.withTodoProgress(ctx, goals)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withProgress(ctx, userId, goals)
+}
+
+func (s *goalService) CreateUserGoal(ctx context.Context, actor Actor, userId string, workspaceId string, goal model.Goals) (model.Goals, error) {
+	if userId == "" || workspaceId == "" {
+		return model.Goals{}, errors.New("UserId / WorkspaceId in Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return model.Goals{}, err
+	}
+	if _, err := workspaceAccess(ctx, s.workspaceRepo, actor, workspaceId); err != nil {
+		return model.Goals{}, err
+	}
+
+	if err := validateGoal(&goal); err != nil {
+		return model.Goals{}, err
+	}
+
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.Goals{}, err
+	}
+	category, err := s.resolveCategory(ctx, userOid, goal.Category)
+	if err != nil {
+		return model.Goals{}, err
+	}
+	goal.CategoryId, goal.Category = category.ID, category.Name
+
+	if goal.Deadline != "" {
+		if err := s.checkDeadline(ctx, userId, goal.Deadline); err != nil {
+			return model.Goals{}, err
+		}
+	}
+
+	goal.Done = goal.Type == model.GoalTypeNumeric && goal.CurrentValue >= goal.TargetValue
+	if goal.Done {
+		now := time.Now()
+		goal.CompletedAt = &now
+	}
+
+	created, err := s.repo.CreateUserGoal(ctx, userId, workspaceId, goal)
+	if err != nil {
+		return model.Goals{}, err
+	}
+	s.activities.Record(ctx, actor, goalActivity(created, model.ActivityGoalCreated))
+
+	return s.oneWithProgress(ctx, created)
+}
+
+// UpdateUserGoal changes the title, the category and the target of the
+// goal's type, done follows the new target
+func (s *goalService) UpdateUserGoal(ctx context.Context, actor Actor, goalId string, update model.GoalUpdate) (bool, error) {
+	if goalId == "" {
+		return false, errors.New("Goal Id Empty")
+	}
+
+	before, err := s.ownGoal(ctx, actor, goalId)
+	if err != nil {
+		return false, err
+	}
+
+	after := before
+	after.Title, after.Category = update.Title, update.Category
+	switch goalType(before) {
+	case model.GoalTypeDays:
+		after.TargetDays = update.TargetDays
+	case model.GoalTypeHabit:
+		after.Frequency = update.Frequency
+	case model.GoalTypeNumeric:
+		after.Unit, after.TargetValue = update.Unit, update.TargetValue
+	}
+	if err := validateGoal(&after); err != nil {
+		return false, err
+	}
+
+	category, err := s.resolveCategory(ctx, before.UserId, after.Category)
+	if err != nil {
+		return false, err
+	}
+	after.CategoryId, after.Category = category.ID, category.Name
+
+	ok, err := s.repo.UpdateUserGoal(ctx, goalId, after.Title, after.TargetDays, after.Category)
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	fields := map[string]any{"categoryId": after.CategoryId}
+	if after.CategoryId.IsZero() {
+		fields["categoryId"] = nil
+	}
+	switch after.Type {
+	case model.GoalTypeDays:
+		// a lower target can be reached by the check-ins already done
+		if !before.Done && before.CurrentTarget >= after.TargetDays {
+			fields["done"], fields["completedAt"] = true, time.Now()
+		}
+	case model.GoalTypeHabit:
+		fields["frequency"] = after.Frequency
+		if before.Frequency == nil || after.Frequency.Period != before.Frequency.Period {
+			// the current period changed its length, count it again
+			fields["periodStart"], fields["periodCheckIns"] = "", 0
+		}
+	case model.GoalTypeNumeric:
+		fields["unit"], fields["targetValue"] = after.Unit, after.TargetValue
+		for field, value := range doneFields(before, after.CurrentValue >= after.TargetValue) {
+			fields[field] = value
+		}
+	}
+	if _, err := s.repo.SetFields(ctx, goalId, fields); err != nil {
+		return false, err
+	}
+	s.SyncProgress(ctx, []primitive.ObjectID{before.ID})
+
+	if err := s.revisions.Record(ctx, actor, model.EntityGoal, before.ID, goalSnapshot(before), goalSnapshot(after)); err != nil {
+		log.Println("Failed to record goal revision:", err)
+	}
+	s.activities.Record(ctx, actor, goalUpdateActivity(before, after.Title))
+
+	return true, nil
+}
+
+func (s *goalService) DeleteUserGoal(ctx context.Context, actor Actor, goalId string) (bool, error) {
+	if goalId == "" {
+		return false, errors.New("Goal Id is Empty in Service")
+	}
+	goal, err := s.ownGoal(ctx, actor, goalId)
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := s.repo.DeleteUserGoal(ctx, goalId)
+	if err != nil || !ok {
+		return ok, err
+	}
+	// the objective no longer counts this key result
+	s.SyncProgress(ctx, []primitive.ObjectID{goal.ParentId})
+
+	s.activities.Record(ctx, actor, goalActivity(goal, model.ActivityGoalDeleted))
+	return true, nil
+}
+
+// GetGoalDetail returns the goal with its open and done linked todos
+func (s *goalService) GetGoalDetail(ctx context.Context, actor Actor, goalId string) (model.GoalDetail, error) {
+	goal, err := goalAccess(ctx, s.repo, s.workspaceRepo, actor, goalId, model.RoleViewer)
+	if err != nil {
+		return model.GoalDetail{}, err
+	}
+
+	goals, err := s.withTodoProgress(ctx, []model.Goals{goal})
+	if err != nil {
+		return model.GoalDetail{}, err
+	}
+
+	todos, err := s.todoRepo.GetGoalTodos(ctx, goal.ID)
+	if err != nil {
+		return model.GoalDetail{}, err
+	}
+
+	goals, err = s.withProgress(ctx, actor.UserId, goals)
+	if err != nil {
+		return model.GoalDetail{}, err
+	}
+
+	detail := model.GoalDetail{Goal: goals[0], OpenTodos: []model.Todo{}, ClosedTodos: []model.Todo{}}
+	for _, todo := range todos {
+		if todo.Done {
+			detail.ClosedTodos = append(detail.ClosedTodos, todo)
+		} else {
+			detail.OpenTodos = append(detail.OpenTodos, todo)
+		}
+	}
+
+	return detail, nil
+}
+
+// SetProgressMode switches between progress from check-ins and from linked
+// todos, the progress is recounted from the new source right away
+func (s *goalService) SetProgressMode(ctx context.Context, actor Actor, goalId string, mode string) (model.Goals, error) {
+	if mode != model.GoalProgressCheckIns && mode != model.GoalProgressTodos {
+		return model.Goals{}, errors.New("mode must be check-ins or todos")
+	}
+
+	goal, err := s.ownGoal(ctx, actor, goalId)
+	if err != nil {
+		return model.Goals{}, err
+	}
+	if goalType(goal) != model.GoalTypeDays {
+		return model.Goals{}, errors.New("only days goals can take their progress from todos")
+	}
+
+	current, target := 0, goal.TargetDays
+	if mode == model.GoalProgressTodos {
+		counts, err := s.todoRepo.CountGoalTodos(ctx, []primitive.ObjectID{goal.ID})
+		if err != nil {
+			return model.Goals{}, err
+		}
+		current, target = todoGoalProgress(goal, counts[goal.ID])
+	} else {
+		checkIns, err := s.checkInRepo.GetGoalCheckIns(ctx, goal.ID)
+		if err != nil {
+			return model.Goals{}, err
+		}
+		current = len(checkIns)
+	}
+
+	updated, err := s.repo.SetFields(ctx, goalId, progressFields(goal, mode, current, target))
+	if err != nil {
+		return model.Goals{}, err
+	}
+	s.SyncProgress(ctx, []primitive.ObjectID{goal.ParentId})
+
+	return s.oneWithProgress(ctx, updated)
+}
+
+// RecordValue sets (value) or increases (add) the current value of a numeric
+// goal, exactly one of them must be given. Done follows the value both ways
+func (s *goalService) RecordValue(ctx context.Context, actor Actor, goalId string, value *float64, add *float64) (model.Goals, error) {
+	if (value == nil) == (add == nil) {
+		return model.Goals{}, errors.New("give either value or add")
+	}
+
+	goal, err := s.ownGoal(ctx, actor, goalId)
+	if err != nil {
+		return model.Goals{}, err
+	}
+	if goalType(goal) != model.GoalTypeNumeric {
+		return model.Goals{}, errors.New("only numeric goals have a value")
+	}
+
+	current := goal.CurrentValue
+	if value != nil {
+		current = *value
+	} else {
+		current += *add
+	}
+	if current < 0 {
+		return model.Goals{}, errors.New("value can not be negative")
+	}
+
+	fields := doneFields(goal, current >= goal.TargetValue)
+	fields["currentValue"] = current
+
+	updated, err := s.repo.SetFields(ctx, goalId, fields)
+	if err != nil {
+		return model.Goals{}, err
+	}
+	s.SyncProgress(ctx, []primitive.ObjectID{goal.ParentId})
+
+	activity := goalActivity(updated, model.ActivityGoalProgress)
+	activity.Details = map[string]any{"value": current}
+	s.activities.Record(ctx, actor, activity)
+
+	return s.oneWithProgress(ctx, updated)
+}
+
+// SetMilestone checks or unchecks one milestone, the goal is done while every
+// milestone is
+func (s *goalService) SetMilestone(ctx context.Context, actor Actor, goalId string, milestoneId string, done bool) (model.Goals, error) {
+	goal, err := s.ownGoal(ctx, actor, goalId)
+	if err != nil {
+		return model.Goals{}, err
+	}
+	if goalType(goal) != model.GoalTypeMilestone {
+		return model.Goals{}, errors.New("only milestone goals have milestones")
+	}
+
+	milestoneOid, err := primitive.ObjectIDFromHex(milestoneId)
+	if err != nil {
+		return model.Goals{}, err
+	}
+
+	found := false
+	allDone := true
+	var title string
+	for i := range goal.Milestones {
+		milestone := &goal.Milestones[i]
+		if milestone.ID == milestoneOid {
+			found = true
+			title = milestone.Title
+			if milestone.Done != done {
+				milestone.Done = done
+				milestone.DoneAt = nil
+				if done {
+					now := time.Now()
+					milestone.DoneAt = &now
+				}
+			}
+		}
+		allDone = allDone && milestone.Done
+	}
+	if !found {
+		return model.Goals{}, errors.New("milestone not found")
+	}
+
+	fields := doneFields(goal, allDone)
+	fields["milestones"] = goal.Milestones
+
+	updated, err := s.repo.SetFields(ctx, goalId, fields)
+	if err != nil {
+		return model.Goals{}, err
+	}
+	s.SyncProgress(ctx, []primitive.ObjectID{goal.ParentId})
+
+	activity := goalActivity(updated, model.ActivityGoalProgress)
+	activity.Details = map[string]any{"milestone": title, "done": done}
+	s.activities.Record(ctx, actor, activity)
+
+	return s.oneWithProgress(ctx, updated)
+}
+
+// withTodoProgress fills CurrentTarget / Done of goals in todos mode from the
+// done linked todos, nothing is stored
+func (s *goalService) withTodoProgress(ctx context.Context, goals []model.Goals) ([]model.Goals, error) {
+	var goalIds []primitive.ObjectID
+	for _, goal := range goals {
+		if goal.ProgressMode == model.GoalProgressTodos {
+			goalIds = append(goalIds, goal.ID)
+		}
+	}
+	if len(goalIds) == 0 {
+		return goals, nil
+	}
+
+	counts, err := s.todoRepo.CountGoalTodos(ctx, goalIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range goals {
+		goal := &goals[i]
+		if goal.ProgressMode != model.GoalProgressTodos {
+			continue
+		}
+
+		current, target := todoGoalProgress(*goal, counts[goal.ID])
+		goal.CurrentTarget = current
+		goal.Done = target > 0 && current >= target
+	}
+
+	return goals, nil
+}
+
+// todoGoalProgress returns the done linked todos of a goal in todos mode and
+// the number to finish, TargetDays or all linked todos when it is 0
+func todoGoalProgress(goal model.Goals, count model.GoalTodoCount) (int, int) {
+	target := goal.TargetDays
+	if target <= 0 {
+		target = count.Total
+	}
+	return count.Done, target
+}
+
+// SyncProgress stores the progress of the goals in todos mode and done of
+// the objectives above the goals (or of the goals, when they are objectives).
+// Every change to a linked todo or a key result calls it, so filters on done
+// stay right while reads never write. Zero ids are skipped, failures are
+// logged and caught up by the next change
+// RevertGoal reverts the goal to a version of its history, the progress is
+// computed again against the reverted targets
+func (s *goalService) RevertGoal(ctx context.Context, actor Actor, goalId string, version int) (model.Revision, error) {
+	revision, err := s.revisions.Revert(ctx, actor, model.EntityGoal, goalId, version)
+	if err != nil {
+		return model.Revision{}, err
+	}
+
+	s.SyncProgress(ctx, []primitive.ObjectID{revision.EntityId})
+	return revision, nil
+}
+
+func (s *goalService) SyncProgress(ctx context.Context, goalIds []primitive.ObjectID) {
+	if err := s.syncProgress(ctx, goalIds); err != nil {
+		log.Println("Failed to update goal progress:", err)
+	}
+}
+
+func (s *goalService) syncProgress(ctx context.Context, goalIds []primitive.ObjectID) error {
+	var ids []primitive.ObjectID
+	for _, id := range goalIds {
+		if !id.IsZero() {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	goals, err := s.repo.GetGoalsByIds(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	var todoGoalIds []primitive.ObjectID
+	for _, goal := range goals {
+		if goal.ProgressMode == model.GoalProgressTodos {
+			todoGoalIds = append(todoGoalIds, goal.ID)
+		}
+	}
+	if len(todoGoalIds) > 0 {
+		counts, err := s.todoRepo.CountGoalTodos(ctx, todoGoalIds)
+		if err != nil {
+			return err
+		}
+		for _, goal := range goals {
+			if goal.ProgressMode != model.GoalProgressTodos {
+				continue
+			}
+			current, target := todoGoalProgress(goal, counts[goal.ID])
+			if goal.CurrentTarget == current && goal.Done == (target > 0 && current >= target) {
+				continue
+			}
+			if _, err := s.repo.SetFields(ctx, goal.ID.Hex(), progressFields(goal, model.GoalProgressTodos, current, target)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// the goals that are objectives and every objective above the goals
+	var objectives []model.Goals
+	seen := make(map[primitive.ObjectID]bool)
+	for _, goal := range goals {
+		for depth := 0; depth <= maxOKRDepth && !seen[goal.ID]; depth++ {
+			seen[goal.ID] = true
+			if goalType(goal) == model.GoalTypeObjective {
+				objectives = append(objectives, goal)
+			}
+			if goal.ParentId.IsZero() {
+				break
+			}
+			if goal, err = s.repo.GetGoalById(ctx, goal.ParentId.Hex()); err != nil {
+				return err
+			}
+			if goal.DeletedAt != nil {
+				break
+			}
+		}
+	}
+
+	// the progress of an objective is rolled up in its owner's timezone
+	for _, objective := range objectives {
+		rolled, err := s.oneWithProgress(ctx, objective)
+		if err != nil {
+			return err
+		}
+		if rolled.Done == objective.Done {
+			continue
+		}
+		if _, err := s.repo.SetFields(ctx, objective.ID.Hex(), doneFields(objective, rolled.Done)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetDeadline sets the last day to reach the goal, an empty deadline removes it
+func (s *goalService) SetDeadline(ctx context.Context, actor Actor, goalId string, deadline string) (model.Goals, error) {
+	before, err := s.ownGoal(ctx, actor, goalId)
+	if err != nil {
+		return model.Goals{}, err
+	}
+
+	after := before
+	after.Deadline = deadline
+	if err := validateGoal(&after); err != nil {
+		return model.Goals{}, err
+	}
+	if deadline != "" {
+		if err := s.checkDeadline(ctx, before.UserId.Hex(), deadline); err != nil {
+			return model.Goals{}, err
+		}
+	}
+
+	updated, err := s.repo.SetFields(ctx, goalId, map[string]any{"deadline": deadline})
+	if err != nil {
+		return model.Goals{}, err
+	}
+
+	if err := s.revisions.Record(ctx, actor, model.EntityGoal, before.ID, goalSnapshot(before), goalSnapshot(updated)); err != nil {
+		log.Println("Failed to record goal revision:", err)
+	}
+	s.activities.Record(ctx, actor, goalUpdateActivity(before, updated.Title))
+
+	return s.oneWithProgress(ctx, updated)
+}
+
+// GoalsBehind lists the live goals of the user in every workspace that are
+// at risk or off track, nearest deadline first
+func (s *goalService) GoalsBehind(ctx context.Context, actor Actor, userId string) ([]model.Goals, error) {
+	if userId == "" {
+		return nil, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return nil, err
+	}
+
+	goals, err := s.repo.GetAllUserGoals(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	goals, err = s.FillProgress(ctx, userId, goals)
+	if err != nil {
+		return nil, err
+	}
+
+	behind := []model.Goals{}
+	for _, goal := range goals {
+		if goal.Forecast != nil && goal.Forecast.Status != model.GoalOnTrack {
+			behind = append(behind, goal)
+		}
+	}
+	sort.SliceStable(behind, func(i, j int) bool { return behind[i].Deadline < behind[j].Deadline })
+
+	return behind, nil
+}
+
+// checkDeadline rejects deadlines before the user's today
+func (s *goalService) checkDeadline(ctx context.Context, userId string, deadline string) error {
+	today, err := s.userToday(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if deadline < today.Format("2006-01-02") {
+		return errors.New("deadline can not be in the past")
+	}
+	return nil
+}
+
+// CategoryRollups sums up the live goals of the user per category. Every
+// category is listed, goals without category come last
+func (s *goalService) CategoryRollups(ctx context.Context, actor Actor, userId string) ([]model.CategoryRollup, error) {
+	if userId == "" {
+		return nil, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return nil, err
+	}
+
+	categories, err := s.categoryRepo.GetUserCategories(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	goals, err := s.repo.GetAllUserGoals(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.rollup(ctx, userId, categories, goals)
+}
+
+// CategoryRollup sums up the live goals of one category
+func (s *goalService) CategoryRollup(ctx context.Context, actor Actor, categoryId string) (model.CategoryRollup, error) {
+	category, err := s.categoryRepo.GetCategoryById(ctx, categoryId)
+	if err != nil {
+		return model.CategoryRollup{}, err
+	}
+	if category.UserId.Hex() != actor.UserId {
+		return model.CategoryRollup{}, errors.New("category not found")
+	}
+
+	goals, err := s.repo.GetAllUserGoals(ctx, actor.UserId)
+	if err != nil {
+		return model.CategoryRollup{}, err
+	}
+
+	var inCategory []model.Goals
+	for _, goal := range goals {
+		if goal.CategoryId == category.ID {
+			inCategory = append(inCategory, goal)
+		}
+	}
+
+	rollups, err := s.rollup(ctx, actor.UserId, []model.GoalCategory{category}, inCategory)
+	if err != nil {
+		return model.CategoryRollup{}, err
+	}
+	return rollups[0], nil
+}
+
+func (s *goalService) rollup(ctx context.Context, userId string, categories []model.GoalCategory, goals []model.Goals) ([]model.CategoryRollup, error) {
+	goals, err := s.FillProgress(ctx, userId, goals)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := userLocation(ctx, s.userRepo, userId, "")
+	if err != nil {
+		return nil, err
+	}
+	today := time.Now().In(loc).Format("2006-01-02")
+
+	rollups := make([]model.CategoryRollup, 0, len(categories)+1)
+	index := make(map[primitive.ObjectID]int, len(categories))
+	for _, category := range categories {
+		index[category.ID] = len(rollups)
+		rollups = append(rollups, model.CategoryRollup{Category: category})
+	}
+
+	progress := make([]float64, len(rollups))
+	for _, goal := range goals {
+		i, ok := index[goal.CategoryId]
+		if !ok {
+			// goals without category (or of a category deleted meanwhile)
+			index[goal.CategoryId] = len(rollups)
+			i = len(rollups)
+			rollups = append(rollups, model.CategoryRollup{})
+			progress = append(progress, 0)
+		}
+
+		rollup := &rollups[i]
+		rollup.TotalGoals++
+		if goal.Done {
+			rollup.DoneGoals++
+		} else {
+			rollup.ActiveGoals++
+		}
+		progress[i] += goal.Progress
+
+		if streak := runningStreak(goal, today); streak > 0 {
+			rollup.RunningStreaks++
+			rollup.BestStreak = max(rollup.BestStreak, streak)
+		}
+		rollup.LongestStreak = max(rollup.LongestStreak, goal.LongestStreak)
+	}
+
+	for i := range rollups {
+		if rollups[i].TotalGoals > 0 {
+			rollups[i].CompletionRate = float64(rollups[i].DoneGoals) / float64(rollups[i].TotalGoals)
+			rollups[i].AverageProgress = progress[i] / float64(rollups[i].TotalGoals)
+		}
+	}
+
+	return rollups, nil
+}
+
+// resolveCategory finds the user's category with this name, it is created
+// when there is none yet. An empty name is no category
+func (s *goalService) resolveCategory(ctx context.Context, userId primitive.ObjectID, name string) (model.GoalCategory, error) {
+	category := model.GoalCategory{UserId: userId, Name: name}
+	normalizeCategoryName(&category)
+	if category.Key == "" {
+		return model.GoalCategory{}, nil
+	}
+	if err := validateCategory(&category); err != nil {
+		return model.GoalCategory{}, err
+	}
+
+	return s.categoryRepo.FindOrCreateCategory(ctx, category)
+}
+
+// runningStreak is the stored streak while it still runs, it ends once the
+// period after the one of the last check-in is over
+func runningStreak(goal model.Goals, today string) int {
+	if goal.CurrentStreak == 0 || goal.LastCheckIn == "" {
+		return 0
+	}
+
+	last, err := time.Parse("2006-01-02", goal.LastCheckIn)
+	if err != nil {
+		return 0
+	}
+	todayDay, err := time.Parse("2006-01-02", today)
+	if err != nil {
+		return 0
+	}
+
+	frequency := goalFrequency(goal)
+	lastPeriod := periodStart(last, frequency.Period)
+	current := periodStart(todayDay, frequency.Period)
+	if !lastPeriod.Equal(current) && !nextPeriod(lastPeriod, frequency.Period).Equal(current) {
+		return 0
+	}
+	return goal.CurrentStreak
+}
+
+// FillProgress counts the progress of todos mode goals and fills
+// Progress and Forecast of the goals
+func (s *goalService) FillProgress(ctx context.Context, userId string, goals []model.Goals) ([]model.Goals, error) {
+	goals, err := s.withTodoProgress(ctx, goals)
+	if err != nil {
+		return nil, err
+	}
+	return s.withProgress(ctx, userId, goals)
+}
+
+// withProgress fills Progress and Forecast of the goals, the user's today is
+// only looked up when a habit goal, an objective or a deadline needs it
+func (s *goalService) withProgress(ctx context.Context, userId string, goals []model.Goals) ([]model.Goals, error) {
+	needsToday := false
+	var objectiveIds []primitive.ObjectID
+	for _, goal := range goals {
+		kind := goalType(goal)
+		if kind == model.GoalTypeObjective {
+			objectiveIds = append(objectiveIds, goal.ID)
+		}
+		needsToday = needsToday || kind == model.GoalTypeHabit || kind == model.GoalTypeObjective || goal.Deadline != ""
+	}
+
+	var today time.Time
+	day := ""
+	if needsToday {
+		var err error
+		if today, err = s.userToday(ctx, userId); err != nil {
+			return nil, err
+		}
+		day = today.Format("2006-01-02")
+	}
+
+	for i := range goals {
+		goals[i].Progress = goalProgressValue(goals[i], day)
+	}
+
+	if len(objectiveIds) > 0 {
+		if err := s.withObjectiveProgress(ctx, goals, objectiveIds, day); err != nil {
+			return nil, err
+		}
+	}
+
+	if !today.IsZero() {
+		for i := range goals {
+			goals[i].Forecast = goalForecast(goals[i], today)
+		}
+	}
+	return goals, nil
+}
+
+// withObjectiveProgress rolls the progress of the key results up into the
+// objectives, an objective is done while its progress is full. Nothing is
+// stored, SyncProgress keeps done of the objectives up to date
+func (s *goalService) withObjectiveProgress(ctx context.Context, goals []model.Goals, objectiveIds []primitive.ObjectID, today string) error {
+	tree, err := s.repo.GetGoalTree(ctx, objectiveIds)
+	if err != nil {
+		return err
+	}
+	tree, err = s.withTodoProgress(ctx, tree)
+	if err != nil {
+		return err
+	}
+	for i := range tree {
+		tree[i].Progress = goalProgressValue(tree[i], today)
+	}
+	rollUpProgress(tree)
+
+	progress := make(map[primitive.ObjectID]float64, len(tree))
+	for _, goal := range tree {
+		progress[goal.ID] = goal.Progress
+	}
+
+	for i := range goals {
+		goal := &goals[i]
+		if goalType(*goal) != model.GoalTypeObjective {
+			continue
+		}
+
+		goal.Progress = progress[goal.ID]
+		goal.Done = goal.Progress >= 1
+	}
+	return nil
+}
+
+// rollUpProgress sets Progress of the objectives to the weighted progress of
+// their key results, goals must hold whole subtrees and the Progress of the
+// other goals. An objective without key results has no progress
+func rollUpProgress(goals []model.Goals) {
+	children := make(map[primitive.ObjectID][]int)
+	for i, goal := range goals {
+		if !goal.ParentId.IsZero() {
+			children[goal.ParentId] = append(children[goal.ParentId], i)
+		}
+	}
+
+	rolled := make(map[int]bool)
+	var visit func(i int, depth int) float64
+	visit = func(i int, depth int) float64 {
+		goal := &goals[i]
+		if goalType(*goal) != model.GoalTypeObjective || rolled[i] || depth > maxOKRDepth {
+			return goal.Progress
+		}
+		rolled[i] = true
+
+		weighted, total := 0.0, 0.0
+		for _, child := range children[goal.ID] {
+			weight := goalWeight(goals[child])
+			weighted += visit(child, depth+1) * weight
+			total += weight
+		}
+		goal.Progress = progressRatio(weighted, total)
+		return goal.Progress
+	}
+
+	for i := range goals {
+		visit(i, 0)
+	}
+}
+
+// goalWeight is the share of a key result in its objective
+func goalWeight(goal model.Goals) float64 {
+	if goal.Weight <= 0 {
+		return 1
+	}
+	return goal.Weight
+}
+
+// userToday is midnight of the current day in the user's timezone
+func (s *goalService) userToday(ctx context.Context, userId string) (time.Time, error) {
+	loc, err := userLocation(ctx, s.userRepo, userId, "")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return startOfDay(time.Now().In(loc)), nil
+}
+
+func (s *goalService) oneWithProgress(ctx context.Context, goal model.Goals) (model.Goals, error) {
+	goals, err := s.withProgress(ctx, goal.UserId.Hex(), []model.Goals{goal})
+	if err != nil {
+		return model.Goals{}, err
+	}
+	return goals[0], nil
+}
+
+// progressFields sets the mode and progress of a goal, done follows the
+// progress in both directions
+func progressFields(goal model.Goals, mode string, current int, target int) map[string]any {
+	fields := doneFields(goal, target > 0 && current >= target)
+	fields["progressMode"] = mode
+	fields["currentTarget"] = current
+	return fields
+}
+
+// doneFields sets done, completedAt keeps the first completion
+func doneFields(goal model.Goals, done bool) map[string]any {
+	fields := map[string]any{"done": done}
+	switch {
+	case done && !goal.Done:
+		fields["completedAt"] = time.Now()
+	case !done:
+		fields["completedAt"] = nil
+	}
+	return fields
+}
+
+// goalType is the type of the goal, goals from before the types are days goals
+func goalType(goal model.Goals) string {
+	if goal.Type == "" {
+		return model.GoalTypeDays
+	}
+	return goal.Type
+}
+
+// validateGoal checks the fields of the goal's type and clears the fields
+// that belong to other types
+func validateGoal(goal *model.Goals) error {
+	goal.Title = strings.TrimSpace(goal.Title)
+	if goal.Title == "" {
+		return errors.New("goal name is empty")
+	}
+
+	goal.Type = goalType(*goal)
+	if goal.Type != model.GoalTypeDays {
+		goal.TargetDays = 0
+	}
+	if goal.Type != model.GoalTypeHabit {
+		goal.Frequency = nil
+	}
+	if goal.Type != model.GoalTypeNumeric {
+		goal.Unit, goal.TargetValue, goal.CurrentValue = "", 0, 0
+	}
+	if goal.Type != model.GoalTypeMilestone {
+		goal.Milestones = nil
+	}
+
+	switch goal.Type {
+	case model.GoalTypeDays:
+		if goal.TargetDays < 1 {
+			return errors.New("targetDays must be at least 1")
+		}
+	case model.GoalTypeHabit:
+		if goal.Frequency == nil {
+			return errors.New("habit goals need a frequency")
+		}
+		limit, ok := map[string]int{"day": 1, "week": 7, "month": 28}[goal.Frequency.Period]
+		if !ok {
+			return errors.New("frequency period must be day, week or month")
+		}
+		if goal.Frequency.Times < 1 || goal.Frequency.Times > limit {
+			return fmt.Errorf("frequency times must be between 1 and %d per %s", limit, goal.Frequency.Period)
+		}
+	case model.GoalTypeNumeric:
+		goal.Unit = strings.TrimSpace(goal.Unit)
+		if goal.Unit == "" || len(goal.Unit) > maxGoalUnitLength {
+			return errors.New("unit must have 1 to 30 characters")
+		}
+		if goal.TargetValue <= 0 {
+			return errors.New("targetValue must be greater than 0")
+		}
+		if goal.CurrentValue < 0 {
+			return errors.New("currentValue can not be negative")
+		}
+	case model.GoalTypeObjective:
+		// the progress comes from the key results
+	case model.GoalTypeMilestone:
+		if len(goal.Milestones) == 0 || len(goal.Milestones) > maxMilestones {
+			return errors.New("milestone goals need 1 to 50 milestones")
+		}
+		for i := range goal.Milestones {
+			milestone := &goal.Milestones[i]
+			milestone.Title = strings.TrimSpace(milestone.Title)
+			if milestone.Title == "" || len(milestone.Title) > maxMilestoneTitleLen {
+				return errors.New("milestone titles must have 1 to 200 characters")
+			}
+			if milestone.ID.IsZero() {
+				milestone.ID = primitive.NewObjectID()
+			}
+		}
+	default:
+		return errors.New("type must be days, habit, numeric, milestone or objective")
+	}
+
+	if goal.Deadline != "" {
+		if goal.Type == model.GoalTypeHabit {
+			return errors.New("habit goals have no deadline")
+		}
+		if _, err := time.Parse("2006-01-02", goal.Deadline); err != nil {
+			return errors.New("deadline must look like 2006-01-02")
+		}
+	}
+
+	return nil
+}
+
+// goalProgressValue is the progress of the goal from 0 to 1. For habits it
+// is the share of the current period's check-ins, today is 2006-01-02 in the
+// user's timezone
+func goalProgressValue(goal model.Goals, today string) float64 {
+	if goal.Done {
+		return 1
+	}
+
+	switch goalType(goal) {
+	case model.GoalTypeHabit:
+		if goal.Frequency == nil || today == "" {
+			return 0
+		}
+		day, err := time.Parse("2006-01-02", today)
+		if err != nil || goal.PeriodStart != periodStart(day, goal.Frequency.Period).Format("2006-01-02") {
+			return 0
+		}
+		return progressRatio(float64(goal.PeriodCheckIns), float64(goal.Frequency.Times))
+	case model.GoalTypeNumeric:
+		return progressRatio(goal.CurrentValue, goal.TargetValue)
+	case model.GoalTypeMilestone:
+		return progressRatio(float64(doneMilestones(goal)), float64(len(goal.Milestones)))
+	default:
+		return progressRatio(float64(goal.CurrentTarget), float64(goal.TargetDays))
+	}
+}
+
+func doneMilestones(goal model.Goals) int {
+	done := 0
+	for _, milestone := range goal.Milestones {
+		if milestone.Done {
+			done++
+		}
+	}
+	return done
+}
+
+func progressRatio(current float64, target float64) float64 {
+	if target <= 0 {
+		return 0
+	}
+	return min(current/target, 1)
+}
+
+// goalActivity is the feed entry of a change to the goal
+func goalActivity(goal model.Goals, activityType string) model.Activity {
+	return model.Activity{
+		WorkspaceId: goal.WorkspaceId,
+		Type:        activityType,
+		EntityType:  model.EntityGoal,
+		EntityId:    goal.ID,
+		Title:       goal.Title,
+	}
+}
+
+// goalUpdateActivity keeps the old title when the goal was renamed
+func goalUpdateActivity(before model.Goals, title string) model.Activity {
+	activity := goalActivity(before, model.ActivityGoalUpdated)
+	activity.Title = title
+	if title != before.Title {
+		activity.Details = map[string]any{"from": before.Title}
+	}
+	return activity
+}
+
+func (s *goalService) ownGoal(ctx context.Context, actor Actor, goalId string) (model.Goals, error) {
+	return goalAccess(ctx, s.repo, s.workspaceRepo, actor, goalId, model.RoleEditor)
+}
+
+func NewGoalService(repo repository.GoalRepository, todoRepo repository.TodoRepository, checkInRepo repository.CheckInRepository, categoryRepo repository.CategoryRepository, workspaceRepo repository.WorkSpaceRepository, userRepo repository.UserRepository, revisions RevisionService, activities ActivityService) GoalService {
+	return &goalService{
+		repo:          repo,
+		todoRepo:      todoRepo,
+		checkInRepo:   checkInRepo,
+		categoryRepo:  categoryRepo,
+		workspaceRepo: workspaceRepo,
+		userRepo:      userRepo,
+		revisions:     revisions,
+		activities:    activities,
+	}
+}
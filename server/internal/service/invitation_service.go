@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// limits of an invitation link
+const (
+	maxInvitationUses = 100
+	maxInvitationTTL  = 30 * 24 * time.Hour
+)
+
+// InvitationService lets owners invite people into a workspace by email or
+// with a shareable link. Invitations expire, can be revoked and are accepted
+// by signed in users or right at signup
+type InvitationService interface {
+	CreateInvitation(ctx context.Context, actor Actor, workspaceId string, invitation model.Invitation, expiresIn time.Duration) (model.Invitation, error)
+	GetPendingInvitations(ctx context.Context, actor Actor, workspaceId string) ([]model.Invitation, error)
+	RevokeInvitation(ctx context.Context, actor Actor, invitationId string) (model.Invitation, error)
+	GetMyInvitations(ctx context.Context, actor Actor, userId string) ([]model.Invitation, error)
+	AcceptInvitation(ctx context.Context, actor Actor, token string) (model.Workspace, error)
+	AcceptInvitationById(ctx context.Context, actor Actor, invitationId string) (model.Workspace, error)
+	CheckInvitation(ctx context.Context, token string, email string) error
+}
+
+type invitationService struct {
+	repo          repository.InvitationRepository
+	workspaceRepo repository.WorkSpaceRepository
+	userRepo      repository.UserRepository
+	notifications NotificationService
+	transactor    repository.Transactor
+	activities    ActivityService
+	ttl           time.Duration // default lifetime of an invitation
+}
+
+// CreateInvitation creates an invitation with the role of invitation. With an
+// email it is for that person only and used once, without it is a link for
+// up to MaxUses people (0 is 1). expiresIn 0 is the default lifetime.
+// The token is only returned here
+func (s *invitationService) CreateInvitation(ctx context.Context, actor Actor, workspaceId string, invitation model.Invitation, expiresIn time.Duration) (model.Invitation, error) {
+	workspace, err := workspaceRoleAccess(ctx, s.workspaceRepo, actor, workspaceId, model.RoleOwner)
+	if err != nil {
+		return model.Invitation{}, err
+	}
+	inviterOid, err := primitive.ObjectIDFromHex(actor.UserId)
+	if err != nil {
+		return model.Invitation{}, err
+	}
+
+	if !model.ValidRole(invitation.Role) {
+		return model.Invitation{}, errors.New("role must be owner, editor or viewer")
+	}
+
+	if expiresIn == 0 {
+		expiresIn = s.ttl
+	}
+	if expiresIn < 0 || expiresIn > maxInvitationTTL {
+		return model.Invitation{}, errors.New("an invitation can be valid for at most 30 days")
+	}
+
+	// someone invited by email who is already a member
+	var invitee *model.User
+	invitation.Email = strings.ToLower(strings.TrimSpace(invitation.Email))
+	if invitation.Email != "" {
+		if !strings.Contains(invitation.Email, "@") {
+			return model.Invitation{}, errors.New("email is invalid")
+		}
+		invitation.MaxUses = 1
+
+		users, err := s.userRepo.FindUsersByHandles(ctx, []string{invitation.Email})
+		if err != nil {
+			return model.Invitation{}, err
+		}
+		if len(users) > 0 {
+			invitee = &users[0]
+			if workspace.RoleOf(invitee.ID.Hex()) != "" {
+				return model.Invitation{}, errors.New("user is already a member of this workspace")
+			}
+		}
+	}
+	if invitation.MaxUses == 0 {
+		invitation.MaxUses = 1
+	}
+	if invitation.MaxUses < 0 || invitation.MaxUses > maxInvitationUses {
+		return model.Invitation{}, fmt.Errorf("maxUses must be between 1 and %d", maxInvitationUses)
+	}
+
+	token, err := newInvitationToken()
+	if err != nil {
+		return model.Invitation{}, err
+	}
+
+	created, err := s.repo.CreateInvitation(ctx, model.Invitation{
+		WorkspaceId: workspace.ID,
+		InvitedBy:   inviterOid,
+		Email:       invitation.Email,
+		Role:        invitation.Role,
+		TokenHash:   hashInvitationToken(token),
+		MaxUses:     invitation.MaxUses,
+		ExpiresAt:   time.Now().Add(expiresIn),
+	})
+	if err != nil {
+		return model.Invitation{}, err
+	}
+	created.Token = token
+
+	// people with an account see the invitation in their notifications
+	if invitee != nil {
+		_, err := s.notifications.Notify(ctx, model.Notification{
+			UserId:       invitee.ID,
+			Type:         model.NotificationWorkspaceInvite,
+			Message:      fmt.Sprintf("You are invited to %q as %s", workspace.WorkspaceName, created.Role),
+			InvitationId: created.ID,
+		})
+		if err != nil {
+			log.Println("Failed to notify invited user:", err)
+		}
+	}
+
+	return created, nil
+}
+
+// GetPendingInvitations lists the invitations of the workspace that can still
+// be accepted, for its owners
+func (s *invitationService) GetPendingInvitations(ctx context.Context, actor Actor, workspaceId string) ([]model.Invitation, error) {
+	workspace, err := workspaceRoleAccess(ctx, s.workspaceRepo, actor, workspaceId, model.RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPendingInvitations(ctx, workspace.ID, time.Now())
+}
+
+// RevokeInvitation makes the invitation unusable, people that joined with it
+// stay members
+func (s *invitationService) RevokeInvitation(ctx context.Context, actor Actor, invitationId string) (model.Invitation, error) {
+	if invitationId == "" {
+		return model.Invitation{}, errors.New("InvitationId is Empty in Service")
+	}
+
+	invitation, err := s.repo.GetInvitationById(ctx, invitationId)
+	if err != nil {
+		return model.Invitation{}, err
+	}
+	if _, err := workspaceRoleAccess(ctx, s.workspaceRepo, actor, invitation.WorkspaceId.Hex(), model.RoleOwner); err != nil {
+		return model.Invitation{}, err
+	}
+
+	return s.repo.RevokeInvitation(ctx, invitation.ID)
+}
+
+// GetMyInvitations lists the pending invitations sent to the user's email
+func (s *invitationService) GetMyInvitations(ctx context.Context, actor Actor, userId string) ([]model.Invitation, error) {
+	if err := checkCaller(actor, userId); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetPendingInvitationsForEmail(ctx, strings.ToLower(user.Email), time.Now())
+}
+
+// AcceptInvitation joins the workspace of the invitation with the token
+func (s *invitationService) AcceptInvitation(ctx context.Context, actor Actor, token string) (model.Workspace, error) {
+	if token == "" {
+		return model.Workspace{}, errors.New("token is empty")
+	}
+
+	invitation, err := s.repo.GetInvitationByTokenHash(ctx, hashInvitationToken(token))
+	if err != nil {
+		return model.Workspace{}, err
+	}
+
+	return s.accept(ctx, actor, invitation)
+}
+
+// AcceptInvitationById joins the workspace of an invitation sent to the
+// caller's email, the email is the proof so no token is needed
+func (s *invitationService) AcceptInvitationById(ctx context.Context, actor Actor, invitationId string) (model.Workspace, error) {
+	if invitationId == "" {
+		return model.Workspace{}, errors.New("InvitationId is Empty in Service")
+	}
+
+	invitation, err := s.repo.GetInvitationById(ctx, invitationId)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	if invitation.Email == "" {
+		return model.Workspace{}, errors.New("invitation links are accepted with their token")
+	}
+
+	return s.accept(ctx, actor, invitation)
+}
+
+// CheckInvitation tells if the person with email can accept the invitation
+// with the token, used at signup before the user exists
+func (s *invitationService) CheckInvitation(ctx context.Context, token string, email string) error {
+	invitation, err := s.repo.GetInvitationByTokenHash(ctx, hashInvitationToken(token))
+	if err != nil {
+		return err
+	}
+	return checkInvitee(invitation, email, time.Now())
+}
+
+// accept adds the caller as member with the invitation's role and counts the
+// use in one transaction
+func (s *invitationService) accept(ctx context.Context, actor Actor, invitation model.Invitation) (model.Workspace, error) {
+	if actor.UserId == "" {
+		return model.Workspace{}, errors.New("caller is unknown")
+	}
+
+	user, err := s.userRepo.GetUserById(ctx, actor.UserId)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	now := time.Now()
+	if err := checkInvitee(invitation, user.Email, now); err != nil {
+		return model.Workspace{}, err
+	}
+
+	workspace, err := s.workspaceRepo.GetWorkspaceById(ctx, invitation.WorkspaceId.Hex())
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	if workspace.DeletedAt != nil {
+		return model.Workspace{}, errors.New("workspace not found")
+	}
+	if workspace.RoleOf(actor.UserId) != "" {
+		return model.Workspace{}, errors.New("you are already a member of this workspace")
+	}
+
+	err = s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.repo.UseInvitation(ctx, invitation.ID, user.ID, now); err != nil {
+			return err
+		}
+		workspace, err = s.workspaceRepo.AddMember(ctx, invitation.WorkspaceId.Hex(), model.WorkspaceMember{
+			UserId:   user.ID,
+			Role:     invitation.Role,
+			JoinedAt: now,
+		})
+		return err
+	})
+	if err != nil {
+		return model.Workspace{}, err
+	}
+
+	s.activities.Record(ctx, actor, memberActivity(workspace.ID, model.ActivityMemberJoined, user, invitation.Role))
+
+	workspace.Role = invitation.Role
+	return workspace, nil
+}
+
+// checkInvitee makes sure the invitation is pending and, when it was sent to
+// an email, that email is the one of the person accepting it
+func checkInvitee(invitation model.Invitation, email string, now time.Time) error {
+	if invitation.RevokedAt != nil || !now.Before(invitation.ExpiresAt) || invitation.Uses >= invitation.MaxUses {
+		return repository.ErrInvitationUnavailable
+	}
+	if invitation.Email != "" && !strings.EqualFold(invitation.Email, strings.TrimSpace(email)) {
+		return errors.New("this invitation is for another email")
+	}
+	return nil
+}
+
+// newInvitationToken returns 32 random bytes, url safe
+func newInvitationToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewInvitationService(repo repository.InvitationRepository, workspaceRepo repository.WorkSpaceRepository, userRepo repository.UserRepository, notifications NotificationService, transactor repository.Transactor, activities ActivityService, ttl time.Duration) InvitationService {
+	return &invitationService{
+		repo:          repo,
+		workspaceRepo: workspaceRepo,
+		userRepo:      userRepo,
+		notifications: notifications,
+		transactor:    transactor,
+		activities:    activities,
+		ttl:           ttl,
+	}
+}
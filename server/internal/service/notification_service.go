@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+)
+
+// number of notifications returned by one listing
+const notificationLimit = 50
+
+type NotificationService interface {
+	Notify(ctx context.Context, notification model.Notification) (model.Notification, error)
+	GetNotifications(ctx context.Context, actor Actor, userId string, unreadOnly bool) ([]model.Notification, error)
+	MarkRead(ctx context.Context, actor Actor, notificationId string) (model.Notification, error)
+}
+
+type notificationService struct {
+	repo repository.NotificationRepository
+}
+
+func (s *notificationService) Notify(ctx context.Context, notification model.Notification) (model.Notification, error) {
+	if notification.Type == "" || notification.Message == "" {
+		return model.Notification{}, errors.New("notification type / message is empty")
+	}
+	return s.repo.CreateNotification(ctx, notification)
+}
+
+func (s *notificationService) GetNotifications(ctx context.Context, actor Actor, userId string, unreadOnly bool) ([]model.Notification, error) {
+	if userId == "" {
+		return nil, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return nil, err
+	}
+	return s.repo.GetUserNotifications(ctx, userId, unreadOnly, notificationLimit)
+}
+
+// MarkRead only works on the caller's own notifications
+func (s *notificationService) MarkRead(ctx context.Context, actor Actor, notificationId string) (model.Notification, error) {
+	if notificationId == "" {
+		return model.Notification{}, errors.New("NotificationId is Empty in Service")
+	}
+
+	notification, err := s.repo.GetNotificationById(ctx, notificationId)
+	if err != nil {
+		return model.Notification{}, err
+	}
+	if notification.UserId.Hex() != actor.UserId {
+		return model.Notification{}, errors.New("notification not found")
+	}
+
+	return s.repo.MarkRead(ctx, notificationId)
+}
+
+func NewNotificationService(repo repository.NotificationRepository) NotificationService {
+	return &notificationService{
+		repo: repo,
+	}
+}
@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// limits of the OKR tree, depth counts the levels from the top objective
+const (
+	maxOKRDepth  = 5
+	maxOKRWeight = 100
+)
+
+var okrPeriodPattern = regexp.MustCompile(`^(\d{4})(?:-(Q[1-4]|H[12]|0[1-9]|1[0-2]))?$`)
+
+// OKRService nests goals into objectives with weighted key results and
+// scores objectives, a final score is taken once the period is over
+type OKRService interface {
+	SetOKR(ctx context.Context, actor Actor, goalId string, parentId string, weight float64, period string) (model.Goals, error)
+	GetOKRTree(ctx context.Context, actor Actor, workspaceId string, period string) ([]model.OKRNode, error)
+	ScoreObjective(ctx context.Context, actor Actor, goalId string) (model.OKRScore, error)
+	GetScores(ctx context.Context, actor Actor, goalId string) ([]model.OKRScore, error)
+	ScoreEndedPeriods(ctx context.Context) error
+	RunOKRScoring(ctx context.Context, interval time.Duration)
+}
+
+type okrService struct {
+	goalRepo      repository.GoalRepository
+	scoreRepo     repository.OKRScoreRepository
+	workspaceRepo repository.WorkSpaceRepository
+	userRepo      repository.UserRepository
+	goals         GoalService
+	activities    ActivityService
+}
+
+// SetOKR puts the goal under the objective parentId (empty makes it a top
+// level goal) with a weight (0 is 1) and a period (empty keeps the current
+// one). Key results take the period of their objective, a new period is
+// passed down to all of them
+func (s *okrService) SetOKR(ctx context.Context, actor Actor, goalId string, parentId string, weight float64, period string) (model.Goals, error) {
+	goal, err := s.ownGoal(ctx, actor, goalId)
+	if err != nil {
+		return model.Goals{}, err
+	}
+
+	if weight == 0 {
+		weight = 1
+	}
+	if weight < 0 || weight > maxOKRWeight {
+		return model.Goals{}, errors.New("weight must be between 0 and 100")
+	}
+	if period != "" {
+		if _, _, err := okrPeriodRange(period, time.UTC); err != nil {
+			return model.Goals{}, err
+		}
+	}
+
+	fields := map[string]any{"weight": weight, "parentId": nil}
+	if parentId != "" {
+		parent, err := s.ownGoal(ctx, actor, parentId)
+		if err != nil {
+			return model.Goals{}, err
+		}
+		if err := s.checkParent(ctx, goal, parent); err != nil {
+			return model.Goals{}, err
+		}
+
+		if parent.Period != "" {
+			if period != "" && period != parent.Period {
+				return model.Goals{}, errors.New("key results share the period of their objective")
+			}
+			period = parent.Period
+		}
+		fields["parentId"] = parent.ID
+	}
+	if period != "" {
+		fields["period"] = period
+	}
+
+	updated, err := s.goalRepo.SetFields(ctx, goalId, fields)
+	if err != nil {
+		return model.Goals{}, err
+	}
+
+	if period != "" {
+		tree, err := s.goalRepo.GetGoalTree(ctx, []primitive.ObjectID{updated.ID})
+		if err != nil {
+			return model.Goals{}, err
+		}
+		var keyResultIds []primitive.ObjectID
+		for _, keyResult := range tree {
+			if keyResult.ID != updated.ID {
+				keyResultIds = append(keyResultIds, keyResult.ID)
+			}
+		}
+		if err := s.goalRepo.SetPeriod(ctx, keyResultIds, period); err != nil {
+			return model.Goals{}, err
+		}
+	}
+
+	// the old and the new objective roll up other key results now
+	s.goals.SyncProgress(ctx, []primitive.ObjectID{goal.ParentId, updated.ID})
+
+	s.activities.Record(ctx, actor, goalActivity(updated, model.ActivityGoalUpdated))
+
+	goals, err := s.goals.FillProgress(ctx, actor.UserId, []model.Goals{updated})
+	if err != nil {
+		return model.Goals{}, err
+	}
+	return goals[0], nil
+}
+
+// GetOKRTree returns the top level objectives of the workspace with their
+// key results, period filters the objectives (empty is all)
+func (s *okrService) GetOKRTree(ctx context.Context, actor Actor, workspaceId string, period string) ([]model.OKRNode, error) {
+	if _, err := workspaceView(ctx, s.workspaceRepo, actor, workspaceId); err != nil {
+		return nil, err
+	}
+
+	goals, err := s.goalRepo.GetWorkspaceGoals(ctx, workspaceId)
+	if err != nil {
+		return nil, err
+	}
+	goals, err = s.goals.FillProgress(ctx, actor.UserId, goals)
+	if err != nil {
+		return nil, err
+	}
+
+	present := make(map[primitive.ObjectID]bool, len(goals))
+	children := make(map[primitive.ObjectID][]model.Goals)
+	for _, goal := range goals {
+		present[goal.ID] = true
+		if !goal.ParentId.IsZero() {
+			children[goal.ParentId] = append(children[goal.ParentId], goal)
+		}
+	}
+
+	var build func(goal model.Goals, depth int) model.OKRNode
+	build = func(goal model.Goals, depth int) model.OKRNode {
+		node := model.OKRNode{Goal: goal, Children: []model.OKRNode{}}
+		if depth < maxOKRDepth {
+			for _, child := range children[goal.ID] {
+				node.Children = append(node.Children, build(child, depth+1))
+			}
+		}
+		return node
+	}
+
+	tree := []model.OKRNode{}
+	for _, goal := range goals {
+		isRoot := goal.ParentId.IsZero() || !present[goal.ParentId]
+		if !isRoot || goalType(goal) != model.GoalTypeObjective || (period != "" && goal.Period != period) {
+			continue
+		}
+		tree = append(tree, build(goal, 1))
+	}
+
+	return tree, nil
+}
+
+// ScoreObjective keeps the current score of the objective in its history
+func (s *okrService) ScoreObjective(ctx context.Context, actor Actor, goalId string) (model.OKRScore, error) {
+	goal, err := s.ownGoal(ctx, actor, goalId)
+	if err != nil {
+		return model.OKRScore{}, err
+	}
+	if goalType(goal) != model.GoalTypeObjective {
+		return model.OKRScore{}, errors.New("only objectives are scored")
+	}
+
+	return s.score(ctx, goal, false)
+}
+
+// GetScores returns the score history of the objective, newest first
+func (s *okrService) GetScores(ctx context.Context, actor Actor, goalId string) ([]model.OKRScore, error) {
+	goal, err := goalAccess(ctx, s.goalRepo, s.workspaceRepo, actor, goalId, model.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.scoreRepo.GetGoalScores(ctx, goal.ID)
+}
+
+// ScoreEndedPeriods takes the final score of every objective whose period
+// is over in its owner's timezone
+func (s *okrService) ScoreEndedPeriods(ctx context.Context) error {
+	objectives, err := s.goalRepo.GetUnscoredObjectives(ctx)
+	if err != nil {
+		return err
+	}
+
+	locations := make(map[primitive.ObjectID]*time.Location)
+	for _, objective := range objectives {
+		loc, ok := locations[objective.UserId]
+		if !ok {
+			if loc, err = userLocation(ctx, s.userRepo, objective.UserId.Hex(), ""); err != nil {
+				log.Println("Skipping objective, owner's timezone is unknown:", objective.ID.Hex(), err)
+				continue
+			}
+			locations[objective.UserId] = loc
+		}
+
+		_, end, err := okrPeriodRange(objective.Period, loc)
+		if err != nil {
+			log.Println("Skipping objective with invalid period:", objective.ID.Hex(), err)
+			continue
+		}
+		if time.Now().Before(end) {
+			continue
+		}
+
+		// one failing objective must not hold back the others, it is
+		// retried on the next run
+		if _, err := s.score(ctx, objective, true); err != nil {
+			log.Println("Failed to take final OKR score:", objective.ID.Hex(), err)
+		}
+	}
+
+	return nil
+}
+
+// RunOKRScoring takes final scores every interval until ctx is done
+func (s *okrService) RunOKRScoring(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ScoreEndedPeriods(ctx); err != nil {
+				log.Println("OKR scoring failed:", err)
+			}
+		}
+	}
+}
+
+// score stores the rolled up progress of the objective and of its direct key
+// results, a final score is also kept on the goal
+func (s *okrService) score(ctx context.Context, objective model.Goals, final bool) (model.OKRScore, error) {
+	tree, err := s.goalRepo.GetGoalTree(ctx, []primitive.ObjectID{objective.ID})
+	if err != nil {
+		return model.OKRScore{}, err
+	}
+	tree, err = s.goals.FillProgress(ctx, objective.UserId.Hex(), tree)
+	if err != nil {
+		return model.OKRScore{}, err
+	}
+
+	score := model.OKRScore{
+		GoalId:      objective.ID,
+		UserId:      objective.UserId,
+		WorkspaceId: objective.WorkspaceId,
+		Period:      objective.Period,
+		KeyResults:  []model.KeyResultScore{},
+		Final:       final,
+	}
+	for _, goal := range tree {
+		switch {
+		case goal.ID == objective.ID:
+			score.Score = roundScore(goal.Progress)
+		case goal.ParentId == objective.ID:
+			score.KeyResults = append(score.KeyResults, model.KeyResultScore{
+				GoalId: goal.ID,
+				Title:  goal.Title,
+				Weight: goalWeight(goal),
+				Score:  roundScore(goal.Progress),
+			})
+		}
+	}
+
+	created, err := s.scoreRepo.CreateScore(ctx, score)
+	if err != nil {
+		if !final || !errors.Is(err, repository.ErrAlreadyScored) {
+			return model.OKRScore{}, err
+		}
+
+		// scored meanwhile, keep the stored final score on the goal
+		if created, err = s.finalScore(ctx, objective.ID); err != nil {
+			return model.OKRScore{}, err
+		}
+	}
+
+	if final {
+		if _, err := s.goalRepo.SetFields(ctx, objective.ID.Hex(), map[string]any{"score": created.Score}); err != nil {
+			return model.OKRScore{}, err
+		}
+	}
+
+	return created, nil
+}
+
+func (s *okrService) finalScore(ctx context.Context, goalId primitive.ObjectID) (model.OKRScore, error) {
+	scores, err := s.scoreRepo.GetGoalScores(ctx, goalId)
+	if err != nil {
+		return model.OKRScore{}, err
+	}
+	for _, score := range scores {
+		if score.Final {
+			return score, nil
+		}
+	}
+	return model.OKRScore{}, errors.New("final score not found")
+}
+
+// checkParent makes sure parent can hold the goal: an objective of the same
+// workspace that is not below the goal, and the tree stays within maxOKRDepth
+func (s *okrService) checkParent(ctx context.Context, goal model.Goals, parent model.Goals) error {
+	if parent.ID == goal.ID {
+		return errors.New("a goal can not be its own objective")
+	}
+	if goalType(parent) != model.GoalTypeObjective {
+		return errors.New("key results can only be added to objectives")
+	}
+	if parent.WorkspaceId != goal.WorkspaceId {
+		return errors.New("objective and key result must be in the same workspace")
+	}
+
+	// the goal with its key results, levels below the goal
+	subtree, err := s.goalRepo.GetGoalTree(ctx, []primitive.ObjectID{goal.ID})
+	if err != nil {
+		return err
+	}
+	depth := map[primitive.ObjectID]int{goal.ID: 0}
+	height := 0
+	for _, child := range subtree {
+		if child.ID == parent.ID {
+			return errors.New("the objective is a key result of this goal")
+		}
+		if child.ID != goal.ID {
+			depth[child.ID] = depth[child.ParentId] + 1
+			height = max(height, depth[child.ID])
+		}
+	}
+
+	// levels from the top objective down to the parent
+	levels := 1
+	for ancestor := parent; !ancestor.ParentId.IsZero() && levels <= maxOKRDepth; levels++ {
+		if ancestor, err = s.goalRepo.GetGoalById(ctx, ancestor.ParentId.Hex()); err != nil {
+			return err
+		}
+	}
+
+	if levels+1+height > maxOKRDepth {
+		return errors.New("objectives can be nested at most 5 levels deep")
+	}
+	return nil
+}
+
+func (s *okrService) ownGoal(ctx context.Context, actor Actor, goalId string) (model.Goals, error) {
+	return goalAccess(ctx, s.goalRepo, s.workspaceRepo, actor, goalId, model.RoleEditor)
+}
+
+// okrPeriodRange returns [start, end) of a period: a year (2026), a half
+// (2026-H1), a quarter (2026-Q4) or a month (2026-10)
+func okrPeriodRange(period string, loc *time.Location) (time.Time, time.Time, error) {
+	match := okrPeriodPattern.FindStringSubmatch(period)
+	if match == nil {
+		return time.Time{}, time.Time{}, errors.New("period must look like 2026, 2026-H1, 2026-Q4 or 2026-10")
+	}
+
+	year, _ := strconv.Atoi(match[1])
+	month, months := 1, 12
+	switch part := match[2]; {
+	case part == "":
+	case part[0] == 'Q':
+		month, months = (int(part[1]-'0')-1)*3+1, 3
+	case part[0] == 'H':
+		month, months = (int(part[1]-'0')-1)*6+1, 6
+	default:
+		month, _ = strconv.Atoi(part)
+		months = 1
+	}
+
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, months, 0), nil
+}
+
+// roundScore keeps two decimals, the usual OKR grading of 0.0 to 1.0
+func roundScore(progress float64) float64 {
+	return math.Round(progress*100) / 100
+}
+
+func NewOKRService(goalRepo repository.GoalRepository, scoreRepo repository.OKRScoreRepository, workspaceRepo repository.WorkSpaceRepository, userRepo repository.UserRepository, goals GoalService, activities ActivityService) OKRService {
+	return &okrService{
+		goalRepo:      goalRepo,
+		scoreRepo:     scoreRepo,
+		workspaceRepo: workspaceRepo,
+		userRepo:      userRepo,
+		goals:         goals,
+		activities:    activities,
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"github.com/ndk123-web/fast-todo/pkg/nquickadd"
+)
+
+type QuickAddService interface {
+	QuickAdd(ctx context.Context, actor Actor, userId string, workspaceId string, text string, timezone string, preview bool) (model.Todo, error)
+}
+
+type quickAddService struct {
+	todoService TodoService
+	userRepo    repository.UserRepository
+}
+
+// QuickAdd parses text like "Pay rent tomorrow 9am !high #finance every month"
+// and creates the todo, preview only returns the parsed todo.
+// dates are read in timezone, or the user's stored timezone when it is empty
+func (s *quickAddService) QuickAdd(ctx context.Context, actor Actor, userId string, workspaceId string, text string, timezone string, preview bool) (model.Todo, error) {
+	if userId == "" || workspaceId == "" {
+		return model.Todo{}, errors.New("UserId / WorkspaceId is Empty in Service")
+	}
+	if text == "" {
+		return model.Todo{}, errors.New("text is empty")
+	}
+
+	loc, err := userLocation(ctx, s.userRepo, userId, timezone)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	parsed := nquickadd.Parse(text, time.Now().In(loc))
+	if parsed.Task == "" {
+		return model.Todo{}, errors.New("Task is Invalid / Empty")
+	}
+
+	// a missing !priority is medium, same as CreateTodo
+	priority, _ := model.NormalizePriority(parsed.Priority)
+
+	todo := model.Todo{
+		Task:     parsed.Task,
+		Priority: priority,
+		DueAt:    parsed.DueAt,
+		AllDay:   parsed.DueAt != nil && !parsed.HasTime,
+		Labels:   parsed.Labels,
+	}
+	if parsed.Recurrence != nil {
+		todo.Recurrence = &model.Recurrence{
+			Frequency: parsed.Recurrence.Frequency,
+			Interval:  parsed.Recurrence.Interval,
+			Weekday:   parsed.Recurrence.Weekday,
+		}
+	}
+
+	if preview {
+		return todo, nil
+	}
+
+	return s.todoService.CreateTodo(ctx, actor, todo, workspaceId, userId)
+}
+
+// userLocation loads timezone, or the user's stored timezone when it is
+// empty, UTC when the user has none
+func userLocation(ctx context.Context, userRepo repository.UserRepository, userId string, timezone string) (*time.Location, error) {
+	if timezone == "" {
+		user, err := userRepo.GetUserById(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+		timezone = user.Timezone
+	}
+	if timezone == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, errors.New("invalid timezone " + timezone)
+	}
+	return loc, nil
+}
+
+func NewQuickAddService(todoService TodoService, userRepo repository.UserRepository) QuickAddService {
+	return &quickAddService{
+		todoService: todoService,
+		userRepo:    userRepo,
+	}
+}
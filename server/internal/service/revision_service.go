@@ -0,0 +1,329 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Actor is the user making a change, taken from the auth token by the handler
+type Actor struct {
+	UserId string
+	Email  string
+}
+
+type RevisionService interface {
+	Record(ctx context.Context, actor Actor, entityType string, entityId primitive.ObjectID, before map[string]any, after map[string]any) error
+	GetHistory(ctx context.Context, actor Actor, entityType string, entityId string) ([]model.Revision, error)
+	Revert(ctx context.Context, actor Actor, entityType string, entityId string, version int) (model.Revision, error)
+}
+
+type revisionService struct {
+	repo          repository.RevisionRepository
+	todoRepo      repository.TodoRepository
+	goalRepo      repository.GoalRepository
+	workspaceRepo repository.WorkSpaceRepository
+	activities    ActivityService
+}
+
+// fields of every entity that are tracked in the history (bson names)
+func todoSnapshot(todo model.Todo) map[string]any {
+	return map[string]any{
+		"task":      todo.Task,
+		"priority":  todo.Priority,
+		"urgent":    optionalBool(todo.Urgent),
+		"important": optionalBool(todo.Important),
+	}
+}
+
+// optionalBool keeps nil apart from false, a *bool would print as an address
+func optionalBool(value *bool) any {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+func goalSnapshot(goal model.Goals) map[string]any {
+	return map[string]any{
+		"title":       goal.Title,
+		"targetDays":  goal.TargetDays,
+		"category":    goal.Category,
+		"categoryId":  goal.CategoryId,
+		"frequency":   optionalFrequency(goal.Frequency),
+		"unit":        goal.Unit,
+		"targetValue": goal.TargetValue,
+		"deadline":    goal.Deadline,
+	}
+}
+
+// optionalFrequency stores the frequency as a plain document, nil stays nil
+func optionalFrequency(frequency *model.GoalFrequency) any {
+	if frequency == nil {
+		return nil
+	}
+	return map[string]any{"times": frequency.Times, "period": frequency.Period}
+}
+
+func workspaceSnapshot(workspace model.Workspace) map[string]any {
+	return map[string]any{
+		"workspaceName": workspace.WorkspaceName,
+	}
+}
+
+// Record appends a revision with the fields that differ between before and
+// after, nothing is written when no field changed
+func (s *revisionService) Record(ctx context.Context, actor Actor, entityType string, entityId primitive.ObjectID, before map[string]any, after map[string]any) error {
+	changes := diffFields(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	_, err := s.repo.CreateRevision(ctx, newRevision(actor, entityType, entityId, "update", changes))
+	return err
+}
+
+// GetHistory returns the revisions to any member of the entity's workspace
+func (s *revisionService) GetHistory(ctx context.Context, actor Actor, entityType string, entityId string) ([]model.Revision, error) {
+	if err := validEntityType(entityType); err != nil {
+		return nil, err
+	}
+	if entityId == "" {
+		return nil, errors.New("EntityId is Empty in Service")
+	}
+	if err := s.access(ctx, actor, entityType, entityId, model.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetHistory(ctx, entityType, entityId)
+}
+
+// Revert brings the tracked fields back to the state right after the given
+// version, version 0 is the state before the first revision. The revert
+// itself is recorded as a new revision. Editors revert todos and goals, only
+// owners revert a workspace. Goals are reverted through GoalService.RevertGoal
+// so their progress follows the reverted targets
+func (s *revisionService) Revert(ctx context.Context, actor Actor, entityType string, entityId string, version int) (model.Revision, error) {
+	if err := validEntityType(entityType); err != nil {
+		return model.Revision{}, err
+	}
+	need := model.RoleEditor
+	if entityType == model.EntityWorkspace {
+		need = model.RoleOwner
+	}
+	if err := s.access(ctx, actor, entityType, entityId, need); err != nil {
+		return model.Revision{}, err
+	}
+
+	revisions, err := s.repo.GetHistory(ctx, entityType, entityId)
+	if err != nil {
+		return model.Revision{}, err
+	}
+	if version < 0 || version > len(revisions) {
+		return model.Revision{}, fmt.Errorf("revision %d not found", version)
+	}
+
+	// walk forward from the target, the first old value of a field is the
+	// value it had at the target version
+	target := make(map[string]any)
+	for _, revision := range revisions {
+		if revision.Version <= version {
+			continue
+		}
+		for _, change := range revision.Changes {
+			if _, ok := target[change.Field]; !ok {
+				target[change.Field] = change.Old
+			}
+		}
+	}
+	if len(target) == 0 {
+		return model.Revision{}, errors.New("entity is already at this revision")
+	}
+
+	current, err := s.currentSnapshot(ctx, entityType, entityId)
+	if err != nil {
+		return model.Revision{}, err
+	}
+
+	after := make(map[string]any)
+	for field, value := range current {
+		after[field] = value
+	}
+	for field, value := range target {
+		after[field] = value
+	}
+
+	changes := diffFields(current, after)
+	if len(changes) == 0 {
+		return model.Revision{}, errors.New("entity is already at this revision")
+	}
+
+	fields := make(map[string]any)
+	for _, change := range changes {
+		fields[change.Field] = change.New
+	}
+
+	activity, err := s.applyFields(ctx, entityType, entityId, fields)
+	if err != nil {
+		return model.Revision{}, err
+	}
+
+	revision := newRevision(actor, entityType, activity.EntityId, "revert", changes)
+	revision.RevertedTo = &version
+	created, err := s.repo.CreateRevision(ctx, revision)
+	if err != nil {
+		return model.Revision{}, err
+	}
+
+	activity.Details = map[string]any{"version": version}
+	s.activities.Record(ctx, actor, activity)
+
+	return created, nil
+}
+
+// access checks that the caller has at least the role need in the workspace
+// of the entity
+func (s *revisionService) access(ctx context.Context, actor Actor, entityType string, entityId string, need string) error {
+	var err error
+	switch entityType {
+	case model.EntityTodo:
+		_, err = todoAccess(ctx, s.todoRepo, s.workspaceRepo, actor, entityId, need)
+	case model.EntityGoal:
+		_, err = goalAccess(ctx, s.goalRepo, s.workspaceRepo, actor, entityId, need)
+	default:
+		_, err = workspaceRoleAccess(ctx, s.workspaceRepo, actor, entityId, need)
+	}
+	return err
+}
+
+func (s *revisionService) currentSnapshot(ctx context.Context, entityType string, entityId string) (map[string]any, error) {
+	switch entityType {
+	case model.EntityTodo:
+		todo, err := s.todoRepo.GetTodoById(ctx, entityId)
+		if err != nil {
+			return nil, err
+		}
+		return todoSnapshot(todo), nil
+	case model.EntityGoal:
+		goal, err := s.goalRepo.GetGoalById(ctx, entityId)
+		if err != nil {
+			return nil, err
+		}
+		return goalSnapshot(goal), nil
+	default:
+		workspace, err := s.workspaceRepo.GetWorkspaceById(ctx, entityId)
+		if err != nil {
+			return nil, err
+		}
+		return workspaceSnapshot(workspace), nil
+	}
+}
+
+// applyFields writes the reverted fields and returns the feed entry of the
+// revert, without its details
+func (s *revisionService) applyFields(ctx context.Context, entityType string, entityId string, fields map[string]any) (model.Activity, error) {
+	switch entityType {
+	case model.EntityTodo:
+		// the weight follows the priority
+		if priority, ok := fields["priority"].(string); ok {
+			fields["priorityWeight"] = model.PriorityWeight(priority)
+		}
+		todo, err := s.todoRepo.SetFields(ctx, entityId, fields)
+		return todoActivity(todo, model.ActivityTodoReverted), err
+	case model.EntityGoal:
+		if err := s.validateGoalFields(ctx, entityId, fields); err != nil {
+			return model.Activity{}, err
+		}
+		goal, err := s.goalRepo.SetFields(ctx, entityId, fields)
+		return goalActivity(goal, model.ActivityGoalReverted), err
+	default:
+		// workspace names are unique per user
+		if name, ok := fields["workspaceName"].(string); ok {
+			workspace, err := s.workspaceRepo.GetWorkspaceById(ctx, entityId)
+			if err != nil {
+				return model.Activity{}, err
+			}
+			if _, err := s.workspaceRepo.GetWorkspaceByName(ctx, workspace.UserId.Hex(), name); err == nil {
+				return model.Activity{}, errors.New("workspace already exists for this user")
+			}
+		}
+		workspace, err := s.workspaceRepo.SetFields(ctx, entityId, fields)
+		return workspaceActivity(workspace, model.ActivityWorkspaceReverted), err
+	}
+}
+
+// validateGoalFields checks the goal as it would be with the reverted fields,
+// an old target may not fit the goal's type any more
+func (s *revisionService) validateGoalFields(ctx context.Context, goalId string, fields map[string]any) error {
+	goal, err := s.goalRepo.GetGoalById(ctx, goalId)
+	if err != nil {
+		return err
+	}
+
+	// the fields carry bson names, decoding them over the goal sets the
+	// matching struct fields
+	raw, err := bson.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	if err := bson.Unmarshal(raw, &goal); err != nil {
+		return err
+	}
+
+	return validateGoal(&goal)
+}
+
+func newRevision(actor Actor, entityType string, entityId primitive.ObjectID, action string, changes []model.FieldChange) model.Revision {
+	revision := model.Revision{
+		EntityType: entityType,
+		EntityId:   entityId,
+		Action:     action,
+		ActorEmail: actor.Email,
+		Changes:    changes,
+	}
+	if oid, err := primitive.ObjectIDFromHex(actor.UserId); err == nil {
+		revision.ActorId = oid
+	}
+	return revision
+}
+
+// diffFields lists the fields of after that differ from before, sorted by name.
+// values are compared by their printed form because values read back from
+// bson may have another go type (int32 vs int)
+func diffFields(before map[string]any, after map[string]any) []model.FieldChange {
+	var changes []model.FieldChange
+	for field, newValue := range after {
+		oldValue := before[field]
+		if fmt.Sprint(oldValue) != fmt.Sprint(newValue) {
+			changes = append(changes, model.FieldChange{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func validEntityType(entityType string) error {
+	switch entityType {
+	case model.EntityTodo, model.EntityGoal, model.EntityWorkspace:
+		return nil
+	default:
+		return errors.New("entityType must be todo, goal or workspace")
+	}
+}
+
+func NewRevisionService(repo repository.RevisionRepository, todoRepo repository.TodoRepository, goalRepo repository.GoalRepository, workspaceRepo repository.WorkSpaceRepository, activities ActivityService) RevisionService {
+	return &revisionService{
+		repo:          repo,
+		todoRepo:      todoRepo,
+		goalRepo:      goalRepo,
+		workspaceRepo: workspaceRepo,
+		activities:    activities,
+	}
+}
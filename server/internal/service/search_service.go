@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"github.com/ndk123-web/fast-todo/pkg/nsearch"
+)
+
+// search result limits
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+)
+
+type SearchService interface {
+	Search(ctx context.Context, actor Actor, userId string, q string, limit int) ([]model.SearchResult, error)
+}
+
+type searchService struct {
+	repo repository.SearchRepository
+}
+
+// Search runs a query like `"release notes" priority:high is:open ws:Work`
+// over the todos, goals and workspaces of the user
+func (s *searchService) Search(ctx context.Context, actor Actor, userId string, q string, limit int) ([]model.SearchResult, error) {
+	if userId == "" {
+		return nil, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return nil, err
+	}
+
+	query := nsearch.Parse(q)
+	if !query.HasText() && len(query.Filters) == 0 {
+		return nil, errors.New("search query is empty")
+	}
+	if err := validSearchFilters(query.Filters); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	return s.repo.Search(ctx, userId, query, limit)
+}
+
+// validSearchFilters only allows the filters the repository understands
+func validSearchFilters(filters map[string][]string) error {
+	for key, values := range filters {
+		for _, value := range values {
+			switch key {
+			case "ws":
+			case "priority":
+				if _, err := model.NormalizePriority(value); err != nil {
+					return err
+				}
+			case "is":
+				if value != "open" && value != "done" {
+					return fmt.Errorf("is:%s is not supported, use is:open or is:done", value)
+				}
+			case "type":
+				if value != model.EntityTodo && value != model.EntityGoal && value != model.EntityWorkspace {
+					return fmt.Errorf("type:%s is not supported, use todo, goal or workspace", value)
+				}
+			default:
+				return fmt.Errorf("unknown search filter %s", key)
+			}
+		}
+	}
+	return nil
+}
+
+func NewSearchService(repo repository.SearchRepository) SearchService {
+	return &searchService{
+		repo: repo,
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"github.com/ndk123-web/fast-todo/pkg/nquickadd"
+)
+
+// SnoozeService hides todos until a date, snoozed todos are left out of the
+// workspace listings and the matrix until the scheduler wakes them
+type SnoozeService interface {
+	SnoozeTodo(ctx context.Context, actor Actor, todoId string, until string, notify bool) (model.Todo, error)
+	UnsnoozeTodo(ctx context.Context, actor Actor, todoId string) (model.Todo, error)
+	GetSnoozedTodos(ctx context.Context, actor Actor, userId string) ([]model.Todo, error)
+	WakeTodos(ctx context.Context) error
+	RunSnoozeScheduler(ctx context.Context, interval time.Duration)
+}
+
+type snoozeService struct {
+	todoRepo      repository.TodoRepository
+	workspaceRepo repository.WorkSpaceRepository
+	userRepo      repository.UserRepository
+	notifications NotificationService
+}
+
+// SnoozeTodo reads until as RFC3339 ("2026-11-02T09:00:00Z") or like quick-add
+// ("monday", "tomorrow 9am", "2026-11-02") in the caller's timezone. A day
+// without a time wakes at its midnight
+func (s *snoozeService) SnoozeTodo(ctx context.Context, actor Actor, todoId string, until string, notify bool) (model.Todo, error) {
+	if _, err := s.ownTodo(ctx, actor, todoId); err != nil {
+		return model.Todo{}, err
+	}
+	if until == "" {
+		return model.Todo{}, errors.New("snooze time is empty")
+	}
+
+	wakeAt, err := time.Parse(time.RFC3339, until)
+	if err != nil {
+		loc, err := userLocation(ctx, s.userRepo, actor.UserId, "")
+		if err != nil {
+			return model.Todo{}, err
+		}
+
+		parsed := nquickadd.Parse(until, time.Now().In(loc))
+		if parsed.DueAt == nil || parsed.Task != "" || parsed.Recurrence != nil {
+			return model.Todo{}, errors.New("snooze time is not a date / time")
+		}
+		wakeAt = *parsed.DueAt
+	}
+
+	if !wakeAt.After(time.Now()) {
+		return model.Todo{}, errors.New("snooze time must be in the future")
+	}
+
+	return s.todoRepo.SetSnooze(ctx, todoId, &wakeAt, notify)
+}
+
+// UnsnoozeTodo puts the todo back right away, without a notification
+func (s *snoozeService) UnsnoozeTodo(ctx context.Context, actor Actor, todoId string) (model.Todo, error) {
+	todo, err := s.ownTodo(ctx, actor, todoId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	if todo.SnoozedUntil == nil {
+		return model.Todo{}, errors.New("todo is not snoozed")
+	}
+
+	return s.todoRepo.SetSnooze(ctx, todoId, nil, false)
+}
+
+func (s *snoozeService) GetSnoozedTodos(ctx context.Context, actor Actor, userId string) ([]model.Todo, error) {
+	if userId == "" {
+		return nil, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return nil, err
+	}
+	return s.todoRepo.GetSnoozedTodos(ctx, userId)
+}
+
+// WakeTodos returns every todo whose snooze is over to its list and
+// notifies the owners who asked for it
+func (s *snoozeService) WakeTodos(ctx context.Context) error {
+	woken, err := s.todoRepo.WakeSnoozedTodos(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, todo := range woken {
+		if !todo.SnoozeNotify || todo.DeletedAt != nil {
+			continue
+		}
+
+		_, err := s.notifications.Notify(ctx, model.Notification{
+			UserId:  todo.UserId,
+			Type:    model.NotificationSnoozeWoke,
+			Message: fmt.Sprintf("%q is back in your list", todo.Task),
+			TodoId:  todo.ID,
+		})
+		// the todo is already awake, one failed notification must not
+		// cost the other owners theirs
+		if err != nil {
+			log.Println("Failed to notify snooze wake-up:", todo.ID.Hex(), err)
+		}
+	}
+
+	return nil
+}
+
+// RunSnoozeScheduler wakes snoozed todos every interval until ctx is done
+func (s *snoozeService) RunSnoozeScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.WakeTodos(ctx); err != nil {
+				log.Println("Snooze wake-up failed:", err)
+			}
+		}
+	}
+}
+
+// ownTodo returns the live todo when the caller has access to its workspace
+func (s *snoozeService) ownTodo(ctx context.Context, actor Actor, todoId string) (model.Todo, error) {
+	return todoAccess(ctx, s.todoRepo, s.workspaceRepo, actor, todoId, model.RoleEditor)
+}
+
+func NewSnoozeService(todoRepo repository.TodoRepository, workspaceRepo repository.WorkSpaceRepository, userRepo repository.UserRepository, notifications NotificationService) SnoozeService {
+	return &snoozeService{
+		todoRepo:      todoRepo,
+		workspaceRepo: workspaceRepo,
+		userRepo:      userRepo,
+		notifications: notifications,
+	}
+}
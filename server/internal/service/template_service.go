@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"github.com/ndk123-web/fast-todo/pkg/nrank"
+	"github.com/ndk123-web/fast-todo/pkg/ntemplate"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maximum number of todos in a template, subtasks included
+const maxTemplateItems = 100
+
+type TemplateService interface {
+	CreateTemplate(ctx context.Context, actor Actor, userId string, template model.Template) (model.Template, error)
+	CreateTemplateFromTodos(ctx context.Context, actor Actor, userId string, name string, todoIds []string) (model.Template, error)
+	GetTemplates(ctx context.Context, actor Actor, userId string) ([]model.Template, error)
+	DeleteTemplate(ctx context.Context, actor Actor, templateId string) error
+	Instantiate(ctx context.Context, actor Actor, templateId string, workspaceId string, variables map[string]string, startDate string) ([]model.Todo, error)
+}
+
+type templateService struct {
+	templateRepo  repository.TemplateRepository
+	todoRepo      repository.TodoRepository
+	workspaceRepo repository.WorkSpaceRepository
+	userRepo      repository.UserRepository
+	transactor    repository.Transactor
+	activities    ActivityService
+}
+
+func (s *templateService) CreateTemplate(ctx context.Context, actor Actor, userId string, template model.Template) (model.Template, error) {
+	if err := checkCaller(actor, userId); err != nil {
+		return model.Template{}, err
+	}
+
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.Template{}, err
+	}
+
+	template.Name = strings.TrimSpace(template.Name)
+	if template.Name == "" {
+		return model.Template{}, errors.New("template name is empty")
+	}
+	if len(template.Items) == 0 {
+		return model.Template{}, errors.New("template has no todos")
+	}
+
+	count := 0
+	texts := []string{}
+	if err := normalizeTemplateItems(template.Items, &count, &texts); err != nil {
+		return model.Template{}, err
+	}
+
+	template.UserId = userOid
+	template.Variables = ntemplate.Variables(texts...)
+
+	return s.templateRepo.CreateTemplate(ctx, template)
+}
+
+// CreateTemplateFromTodos saves existing todos with their subtasks as a
+// template, due dates become offsets from today in the user's timezone
+func (s *templateService) CreateTemplateFromTodos(ctx context.Context, actor Actor, userId string, name string, todoIds []string) (model.Template, error) {
+	if err := checkCaller(actor, userId); err != nil {
+		return model.Template{}, err
+	}
+
+	ids, err := parseTransferIds(todoIds)
+	if err != nil {
+		return model.Template{}, err
+	}
+
+	todos, err := s.todoRepo.GetTodoTree(ctx, ids)
+	if err != nil {
+		return model.Template{}, err
+	}
+
+	workspaces := make(map[primitive.ObjectID]bool)
+	for _, todo := range todos {
+		if !workspaces[todo.WorkspaceId] {
+			if _, err := workspaceView(ctx, s.workspaceRepo, actor, todo.WorkspaceId.Hex()); err != nil {
+				return model.Template{}, err
+			}
+			workspaces[todo.WorkspaceId] = true
+		}
+	}
+
+	loc, err := userLocation(ctx, s.userRepo, actor.UserId, "")
+	if err != nil {
+		return model.Template{}, err
+	}
+	today := startOfDay(time.Now().In(loc))
+
+	sort.SliceStable(todos, func(i, j int) bool { return todos[i].Rank < todos[j].Rank })
+
+	selected := make(map[primitive.ObjectID]bool)
+	children := make(map[primitive.ObjectID][]model.Todo)
+	for _, todo := range todos {
+		selected[todo.ID] = true
+	}
+
+	var roots []model.Todo
+	for _, todo := range todos {
+		if selected[todo.ParentId] {
+			children[todo.ParentId] = append(children[todo.ParentId], todo)
+		} else {
+			roots = append(roots, todo)
+		}
+	}
+
+	var build func(todo model.Todo) model.TemplateItem
+	build = func(todo model.Todo) model.TemplateItem {
+		item := model.TemplateItem{
+			Task:            todo.Task,
+			Priority:        todo.Priority,
+			Labels:          todo.Labels,
+			EstimateMinutes: todo.EstimateMinutes,
+		}
+		if todo.DueAt != nil {
+			due := todo.DueAt.In(loc)
+			offset := daysBetween(today, due)
+			item.DueOffsetDays = &offset
+			if !todo.AllDay {
+				item.DueTime = due.Format("15:04")
+			}
+		}
+		for _, child := range children[todo.ID] {
+			item.Subtasks = append(item.Subtasks, build(child))
+		}
+		return item
+	}
+
+	template := model.Template{Name: name}
+	for _, root := range roots {
+		template.Items = append(template.Items, build(root))
+	}
+
+	return s.CreateTemplate(ctx, actor, actor.UserId, template)
+}
+
+func (s *templateService) GetTemplates(ctx context.Context, actor Actor, userId string) ([]model.Template, error) {
+	if userId == "" {
+		return nil, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return nil, err
+	}
+	return s.templateRepo.GetUserTemplates(ctx, userId)
+}
+
+func (s *templateService) DeleteTemplate(ctx context.Context, actor Actor, templateId string) error {
+	if _, err := s.ownTemplate(ctx, actor, templateId); err != nil {
+		return err
+	}
+	return s.templateRepo.DeleteTemplate(ctx, templateId)
+}
+
+// Instantiate creates the todos of the template at the end of the workspace.
+// Every {{variable}} needs a value, due offsets count from startDate
+// (YYYY-MM-DD) or today, both in the user's timezone
+func (s *templateService) Instantiate(ctx context.Context, actor Actor, templateId string, workspaceId string, variables map[string]string, startDate string) ([]model.Todo, error) {
+	template, err := s.ownTemplate(ctx, actor, templateId)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace, err := workspaceAccess(ctx, s.workspaceRepo, actor, workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := []string{}
+	for _, name := range template.Variables {
+		if _, ok := variables[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing template variables: %s", strings.Join(missing, ", "))
+	}
+
+	loc, err := userLocation(ctx, s.userRepo, actor.UserId, "")
+	if err != nil {
+		return nil, err
+	}
+
+	start := startOfDay(time.Now().In(loc))
+	if startDate != "" {
+		if start, err = time.ParseInLocation("2006-01-02", startDate, loc); err != nil {
+			return nil, errors.New("startDate must look like 2006-01-02")
+		}
+	}
+
+	todos := []model.Todo{}
+	var add func(items []model.TemplateItem, parentId primitive.ObjectID) error
+	add = func(items []model.TemplateItem, parentId primitive.ObjectID) error {
+		for _, item := range items {
+			todo, err := renderTemplateItem(item, variables, start, loc)
+			if err != nil {
+				return err
+			}
+
+			todo.ID = primitive.NewObjectID()
+			todo.UserId = template.UserId
+			todo.WorkspaceId = workspace.ID
+			todo.ParentId = parentId
+			todos = append(todos, todo)
+
+			if err := add(item.Subtasks, todo.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := add(template.Items, primitive.NilObjectID); err != nil {
+		return nil, err
+	}
+
+	// the todos go after the last rank of the workspace in template order,
+	// new ranks are handed out when a concurrent create took one of them
+	err = retryOnRankTaken(func(attempt int) error {
+		rank, err := s.todoRepo.GetLastRank(ctx, workspace.ID.Hex())
+		if err != nil {
+			return err
+		}
+		for i := range todos {
+			if rank, err = nrank.Between(rank, ""); err != nil {
+				return err
+			}
+			todos[i].Rank = rank
+		}
+
+		return s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			return s.todoRepo.InsertTodos(ctx, todos)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the feed shows the top level todos, subtasks come with them
+	for _, todo := range todos {
+		if todo.ParentId.IsZero() {
+			activity := todoActivity(todo, model.ActivityTodoCreated)
+			activity.Details = map[string]any{"template": template.Name}
+			s.activities.Record(ctx, actor, activity)
+		}
+	}
+
+	return todos, nil
+}
+
+func (s *templateService) ownTemplate(ctx context.Context, actor Actor, templateId string) (model.Template, error) {
+	if templateId == "" {
+		return model.Template{}, errors.New("TemplateId is Empty in Service")
+	}
+
+	template, err := s.templateRepo.GetTemplateById(ctx, templateId)
+	if err != nil {
+		return model.Template{}, err
+	}
+	if template.UserId.Hex() != actor.UserId {
+		return model.Template{}, errors.New("no access to template")
+	}
+
+	return template, nil
+}
+
+// normalizeTemplateItems validates the items in place and collects the texts
+// that may hold variables
+func normalizeTemplateItems(items []model.TemplateItem, count *int, texts *[]string) error {
+	for i := range items {
+		item := &items[i]
+
+		if *count++; *count > maxTemplateItems {
+			return fmt.Errorf("a template can have at most %d todos", maxTemplateItems)
+		}
+
+		item.Task = strings.TrimSpace(item.Task)
+		if item.Task == "" {
+			return errors.New("Task is Invalid / Empty")
+		}
+
+		priority, err := model.NormalizePriority(item.Priority)
+		if err != nil {
+			return err
+		}
+		item.Priority = priority
+
+		if item.EstimateMinutes < 0 {
+			return errors.New("estimateMinutes can not be negative")
+		}
+		if item.DueTime != "" {
+			if item.DueOffsetDays == nil {
+				return errors.New("dueTime needs dueOffsetDays")
+			}
+			if _, err := time.Parse("15:04", item.DueTime); err != nil {
+				return errors.New("dueTime must look like 17:00")
+			}
+		}
+
+		*texts = append(*texts, item.Task)
+		*texts = append(*texts, item.Labels...)
+
+		if err := normalizeTemplateItems(item.Subtasks, count, texts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderTemplateItem fills the variables and resolves the due offset, the
+// subtasks are left to the caller
+func renderTemplateItem(item model.TemplateItem, variables map[string]string, start time.Time, loc *time.Location) (model.Todo, error) {
+	task, err := ntemplate.Render(item.Task, variables)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	var labels []string
+	for _, label := range item.Labels {
+		label, err := ntemplate.Render(label, variables)
+		if err != nil {
+			return model.Todo{}, err
+		}
+		labels = append(labels, label)
+	}
+
+	todo := model.Todo{
+		Task:            task,
+		Priority:        item.Priority,
+		PriorityWeight:  model.PriorityWeight(item.Priority),
+		Labels:          labels,
+		EstimateMinutes: item.EstimateMinutes,
+	}
+
+	if item.DueOffsetDays != nil {
+		due := start.AddDate(0, 0, *item.DueOffsetDays)
+		todo.AllDay = true
+		if item.DueTime != "" {
+			clock, _ := time.Parse("15:04", item.DueTime)
+			due = time.Date(due.Year(), due.Month(), due.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+			todo.AllDay = false
+		}
+		todo.DueAt = &due
+	}
+
+	return todo, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// daysBetween counts calendar days from one date to another, DST safe
+func daysBetween(from time.Time, to time.Time) int {
+	fromDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDay := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDay.Sub(fromDay).Hours() / 24)
+}
+
+func NewTemplateService(templateRepo repository.TemplateRepository, todoRepo repository.TodoRepository, workspaceRepo repository.WorkSpaceRepository, userRepo repository.UserRepository, transactor repository.Transactor, activities ActivityService) TemplateService {
+	return &templateService{
+		templateRepo:  templateRepo,
+		todoRepo:      todoRepo,
+		workspaceRepo: workspaceRepo,
+		userRepo:      userRepo,
+		transactor:    transactor,
+		activities:    activities,
+	}
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// a single manual entry can not be longer than a day
+const maxManualEntryMinutes = 24 * 60
+
+type TimeService interface {
+	StartTimer(ctx context.Context, actor Actor, userId string, todoId string, note string) (model.TimeEntry, error)
+	StopTimer(ctx context.Context, actor Actor, userId string) (model.TimeEntry, error)
+	GetRunningTimer(ctx context.Context, actor Actor, userId string) (model.TimeEntry, error)
+	AddTimeEntry(ctx context.Context, actor Actor, userId string, todoId string, startedAt time.Time, minutes int, note string) (model.TimeEntry, error)
+	GetTodoTimeEntries(ctx context.Context, actor Actor, todoId string) ([]model.TimeEntry, error)
+	DeleteTimeEntry(ctx context.Context, actor Actor, userId string, entryId string) error
+	TimeReport(ctx context.Context, actor Actor, userId string, groupBy string, from time.Time, to time.Time, timezone string) ([]model.TimeReportRow, error)
+	EstimateReport(ctx context.Context, actor Actor, userId string, workspaceId string) ([]model.EstimateReportRow, error)
+}
+
+type timeService struct {
+	repo          repository.TimeEntryRepository
+	todoRepo      repository.TodoRepository
+	workspaceRepo repository.WorkSpaceRepository
+}
+
+// StartTimer starts tracking the caller's time on a todo they may edit
+func (s *timeService) StartTimer(ctx context.Context, actor Actor, userId string, todoId string, note string) (model.TimeEntry, error) {
+	if userId == "" || todoId == "" {
+		return model.TimeEntry{}, errors.New("UserId / TodoId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return model.TimeEntry{}, err
+	}
+
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+
+	todo, err := todoAccess(ctx, s.todoRepo, s.workspaceRepo, actor, todoId, model.RoleEditor)
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+
+	// the unique index rejects a second running timer
+	return s.repo.StartTimer(ctx, model.TimeEntry{
+		UserId:      userOid,
+		TodoId:      todo.ID,
+		WorkspaceId: todo.WorkspaceId,
+		StartedAt:   time.Now(),
+		Note:        note,
+	})
+}
+
+func (s *timeService) StopTimer(ctx context.Context, actor Actor, userId string) (model.TimeEntry, error) {
+	if userId == "" {
+		return model.TimeEntry{}, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return model.TimeEntry{}, err
+	}
+
+	running, err := s.repo.GetRunningTimer(ctx, userId)
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+
+	endedAt := time.Now()
+	duration := int64(endedAt.Sub(running.StartedAt).Seconds())
+
+	return s.repo.StopTimer(ctx, running.ID, endedAt, duration)
+}
+
+func (s *timeService) GetRunningTimer(ctx context.Context, actor Actor, userId string) (model.TimeEntry, error) {
+	if userId == "" {
+		return model.TimeEntry{}, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return model.TimeEntry{}, err
+	}
+
+	return s.repo.GetRunningTimer(ctx, userId)
+}
+
+func (s *timeService) AddTimeEntry(ctx context.Context, actor Actor, userId string, todoId string, startedAt time.Time, minutes int, note string) (model.TimeEntry, error) {
+	if userId == "" || todoId == "" {
+		return model.TimeEntry{}, errors.New("UserId / TodoId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return model.TimeEntry{}, err
+	}
+	if minutes <= 0 || minutes > maxManualEntryMinutes {
+		return model.TimeEntry{}, errors.New("minutes must be between 1 and 1440")
+	}
+	if startedAt.IsZero() {
+		return model.TimeEntry{}, errors.New("startedAt is empty")
+	}
+
+	endedAt := startedAt.Add(time.Duration(minutes) * time.Minute)
+	if endedAt.After(time.Now()) {
+		return model.TimeEntry{}, errors.New("time entry can not end in the future")
+	}
+
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+
+	todo, err := todoAccess(ctx, s.todoRepo, s.workspaceRepo, actor, todoId, model.RoleEditor)
+	if err != nil {
+		return model.TimeEntry{}, err
+	}
+
+	return s.repo.CreateEntry(ctx, model.TimeEntry{
+		UserId:          userOid,
+		TodoId:          todo.ID,
+		WorkspaceId:     todo.WorkspaceId,
+		StartedAt:       startedAt,
+		EndedAt:         &endedAt,
+		DurationSeconds: int64(minutes) * 60,
+		Manual:          true,
+		Note:            note,
+	})
+}
+
+func (s *timeService) GetTodoTimeEntries(ctx context.Context, actor Actor, todoId string) ([]model.TimeEntry, error) {
+	if todoId == "" {
+		return nil, errors.New("TodoId is Empty in Service")
+	}
+	if _, err := todoAccess(ctx, s.todoRepo, s.workspaceRepo, actor, todoId, model.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetTodoEntries(ctx, todoId)
+}
+
+func (s *timeService) DeleteTimeEntry(ctx context.Context, actor Actor, userId string, entryId string) error {
+	if userId == "" || entryId == "" {
+		return errors.New("UserId / EntryId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return err
+	}
+
+	return s.repo.DeleteEntry(ctx, userId, entryId)
+}
+
+// TimeReport totals tracked time by "workspace", "priority" or "day"
+func (s *timeService) TimeReport(ctx context.Context, actor Actor, userId string, groupBy string, from time.Time, to time.Time, timezone string) ([]model.TimeReportRow, error) {
+	if userId == "" {
+		return nil, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return nil, err
+	}
+	if !from.Before(to) {
+		return nil, errors.New("from must be before to")
+	}
+
+	switch groupBy {
+	case "workspace":
+		return s.repo.ReportByWorkspace(ctx, userId, from, to)
+	case "priority":
+		return s.repo.ReportByPriority(ctx, userId, from, to)
+	case "day":
+		return s.repo.ReportByDay(ctx, userId, from, to, timezone)
+	default:
+		return nil, errors.New("groupBy must be workspace, priority or day")
+	}
+}
+
+// EstimateReport compares the caller's tracked time with the estimates, in
+// all workspaces or in workspaceId when the caller is a member
+func (s *timeService) EstimateReport(ctx context.Context, actor Actor, userId string, workspaceId string) ([]model.EstimateReportRow, error) {
+	if userId == "" {
+		return nil, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return nil, err
+	}
+	if workspaceId != "" {
+		if _, err := workspaceView(ctx, s.workspaceRepo, actor, workspaceId); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.repo.EstimateReport(ctx, userId, workspaceId)
+}
+
+func NewTimeService(repo repository.TimeEntryRepository, todoRepo repository.TodoRepository, workspaceRepo repository.WorkSpaceRepository) TimeService {
+	return &timeService{
+		repo:          repo,
+		todoRepo:      todoRepo,
+		workspaceRepo: workspaceRepo,
+	}
+}
@@ -1,74 +1,1027 @@
-// Package service contains business logic for todo operations
-package service
-
-import (
-	"context"
-	"errors"
-
-	"github.com/ndk123-web/fast-todo/internal/model"
-	"github.com/ndk123-web/fast-todo/internal/repository"
-)
-
-// TodoService defines the interface for todo business logic operations
-type TodoService interface {
-	GetTodos(ctx context.Context) ([]model.Todo, error)
-	CreateTodo(ctx context.Context, todo model.Todo, workspaceId string, userId string) (model.Todo, error)
-	UpdateTodo(ctx context.Context, todoId string, updatedTask string, priority string) (model.Todo, error)
-	DeleteTodo(ctx context.Context, todoId string) (bool, error)
-	GetSpecificTodo(ctx context.Context, workspaceId string, userId string) ([]model.Todo, error)
-	ToggleTodo(ctx context.Context, todoId string, toggle string, userId string) (bool, error)
-}
-
-// todoService implements TodoService with a repository layer dependency
-type todoService struct {
-	repo repository.TodoRepository // Repository for data access
-}
-
-// NewTodoService creates a new instance of TodoService with the provided repository
-func NewTodoService(repo repository.TodoRepository) TodoService {
-	return &todoService{repo: repo}
-}
-
-// GetTodos retrieves all todo items from the repository
-func (s *todoService) GetTodos(ctx context.Context) ([]model.Todo, error) {
-	return s.repo.GetAll(ctx)
-}
-
-func (s *todoService) ToggleTodo(ctx context.Context, todoId string, toggle string, userId string) (bool, error) {
-	if todoId == "" || toggle == "" || userId == "" {
-		return false, errors.New("Something is missing from userId,todoId,toggle in service")
-	}
-	// Delegate to repository to actually update the DB
-	return s.repo.ToggleTodo(ctx, todoId, toggle, userId)
-}
-
-// CreateTodo adds a new todo item through the repository
-func (s *todoService) CreateTodo(ctx context.Context, todo model.Todo, workspaceId string, userId string) (model.Todo, error) {
-	return s.repo.CreateTodo(ctx, todo, workspaceId, userId)
-}
-
-// UpdateTodo modifies an existing todo's task through the repository
-func (s *todoService) UpdateTodo(ctx context.Context, todoId string, updatedTask string, priority string) (model.Todo, error) {
-	return s.repo.UpdateTodo(ctx, todoId, updatedTask,priority)
-}
-
-// DeleteTodo removes a todo item by ID through the repository
-// Returns true if deletion was successful, false otherwise
-func (s *todoService) DeleteTodo(ctx context.Context, todoId string) (bool, error) {
-	return s.repo.DeleteTodo(ctx, todoId)
-}
-
-func (s *todoService) GetSpecificTodo(ctx context.Context, workspaceId string, userId string) ([]model.Todo, error) {
-	if workspaceId == "" || userId == "" {
-		return nil, errors.New("Workspace ID / UserId is empty in service")
-	}
-
-	var todos []model.Todo
-	todos, err := s.repo.GetSpecificTodo(ctx, workspaceId, userId)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return todos, nil
-}
+// Package service contains business logic for todo operations
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"github.com/ndk123-web/fast-todo/pkg/nrank"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TodoService defines the interface for todo business logic operations
+type TodoService interface {
+	GetTodos(ctx context.Context, actor Actor) ([]model.Todo, error)
+	CreateTodo(ctx context.Context, actor Actor, todo model.Todo, workspaceId string, userId string) (model.Todo, error)
+	UpdateTodo(ctx context.Context, actor Actor, todoId string, update model.TodoUpdate) (model.Todo, error)
+	DeleteTodo(ctx context.Context, actor Actor, todoId string) (bool, error)
+	GetSpecificTodo(ctx context.Context, actor Actor, workspaceId string, userId string, sortBy string) ([]model.Todo, error)
+	ToggleTodo(ctx context.Context, actor Actor, todoId string, toggle string) (bool, error)
+	MoveTodo(ctx context.Context, actor Actor, todoId string, afterId string, beforeId string, status string) (model.Todo, error)
+	RebalanceRanks(ctx context.Context, maxRankLength int) error
+	RunRankRebalancer(ctx context.Context, interval time.Duration, maxRankLength int)
+	SetEstimate(ctx context.Context, actor Actor, todoId string, estimateMinutes int) (model.Todo, error)
+	BatchTodos(ctx context.Context, actor Actor, userId string, mode string, ops []model.BatchOp) (model.BatchResponse, error)
+	GetMatrix(ctx context.Context, actor Actor, workspaceId string, userId string) (model.Matrix, error)
+	LinkGoal(ctx context.Context, actor Actor, todoId string, goalId string) (model.Todo, error)
+	AssignTodo(ctx context.Context, actor Actor, todoId string, assigneeIds []string) (model.Todo, error)
+	GetAssignedTodos(ctx context.Context, actor Actor, userId string) ([]model.Todo, error)
+}
+
+// maximum number of items in one batch request
+const maxBatchOps = 100
+
+// times a write picks new ranks after another todo took one of them
+const maxRankAttempts = 5
+
+// todos due within this window count as urgent in the matrix
+const urgentWithin = 48 * time.Hour
+
+// todoService implements TodoService with a repository layer dependency
+type todoService struct {
+	repo          repository.TodoRepository      // Repository for data access
+	commentRepo   repository.CommentRepository   // comment counts
+	goalRepo      repository.GoalRepository      // goals todos contribute to
+	workspaceRepo repository.WorkSpaceRepository // access checks
+	revisions     RevisionService                // change history
+	activities    ActivityService                // workspace feed
+	goals         GoalService                    // progress of linked goals
+}
+
+// NewTodoService creates a new instance of TodoService with the provided repository
+func NewTodoService(repo repository.TodoRepository, commentRepo repository.CommentRepository, goalRepo repository.GoalRepository, workspaceRepo repository.WorkSpaceRepository, revisions RevisionService, activities ActivityService, goals GoalService) TodoService {
+	return &todoService{repo: repo, commentRepo: commentRepo, goalRepo: goalRepo, workspaceRepo: workspaceRepo, revisions: revisions, activities: activities, goals: goals}
+}
+
+// GetTodos retrieves the todos of every workspace the caller owns or is a
+// member of
+func (s *todoService) GetTodos(ctx context.Context, actor Actor) ([]model.Todo, error) {
+	workspaces, err := s.workspaceRepo.GetAllUserWorkspace(ctx, actor.UserId)
+	if err != nil {
+		return nil, err
+	}
+	workspaceIds := make([]primitive.ObjectID, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		workspaceIds = append(workspaceIds, workspace.ID)
+	}
+
+	return s.repo.GetAll(ctx, workspaceIds)
+}
+
+func (s *todoService) ToggleTodo(ctx context.Context, actor Actor, todoId string, toggle string) (bool, error) {
+	if todoId == "" || toggle == "" {
+		return false, errors.New("Something is missing from todoId,toggle in service")
+	}
+	todo, err := todoAccess(ctx, s.repo, s.workspaceRepo, actor, todoId, model.RoleEditor)
+	if err != nil {
+		return false, err
+	}
+	// Delegate to repository to actually update the DB
+	ok, err := s.repo.ToggleTodo(ctx, todoId, toggle)
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	if done := toggle == "completed"; done != todo.Done {
+		s.goals.SyncProgress(ctx, []primitive.ObjectID{todo.GoalId})
+		s.activities.Record(ctx, actor, todoActivity(todo, doneActivity(done)))
+	}
+	return true, nil
+}
+
+// CreateTodo adds a new todo item through the repository
+// New todos always go to the end of the workspace list
+func (s *todoService) CreateTodo(ctx context.Context, actor Actor, todo model.Todo, workspaceId string, userId string) (model.Todo, error) {
+	if err := checkCaller(actor, userId); err != nil {
+		return model.Todo{}, err
+	}
+	workspace, err := workspaceAccess(ctx, s.workspaceRepo, actor, workspaceId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	todo.AssigneeIds, err = checkAssignees(workspace, todo.AssigneeIds)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	priority, err := model.NormalizePriority(todo.Priority)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	todo.Priority = priority
+	todo.PriorityWeight = model.PriorityWeight(priority)
+
+	// a subtask must live next to its parent
+	if !todo.ParentId.IsZero() {
+		parent, err := s.repo.GetTodoById(ctx, todo.ParentId.Hex())
+		if err != nil {
+			return model.Todo{}, err
+		}
+		if parent.WorkspaceId.Hex() != workspaceId {
+			return model.Todo{}, errors.New("parent todo must be in the same workspace")
+		}
+	}
+
+	if !todo.GoalId.IsZero() {
+		if err := s.checkGoal(ctx, todo.GoalId, workspaceId); err != nil {
+			return model.Todo{}, err
+		}
+	}
+
+	// concurrent creates can read the same last rank, the unique rank index
+	// lets one of them in and the others go again with the new last rank
+	var created model.Todo
+	err = retryOnRankTaken(func(attempt int) error {
+		lastRank, err := s.repo.GetLastRank(ctx, workspaceId)
+		if err != nil {
+			return err
+		}
+
+		todo.Rank, err = nrank.Between(lastRank, "")
+		if err != nil {
+			return err
+		}
+
+		created, err = s.repo.CreateTodo(ctx, todo, workspaceId, userId)
+		return err
+	})
+	if err != nil {
+		return model.Todo{}, err
+	}
+	s.goals.SyncProgress(ctx, []primitive.ObjectID{created.GoalId})
+
+	s.activities.Record(ctx, actor, todoActivity(created, model.ActivityTodoCreated))
+	return created, nil
+}
+
+// UpdateTodo changes only the fields set in update through the repository
+// and records the changed fields in the history
+func (s *todoService) UpdateTodo(ctx context.Context, actor Actor, todoId string, update model.TodoUpdate) (model.Todo, error) {
+	if todoId == "" {
+		return model.Todo{}, errors.New("Todo Id is Empty")
+	}
+
+	fields := make(map[string]any)
+	if update.Task != nil {
+		if *update.Task == "" {
+			return model.Todo{}, errors.New("Task is Invalid / Empty")
+		}
+		fields["task"] = *update.Task
+	}
+	if update.Priority != nil {
+		priority, err := model.NormalizePriority(*update.Priority)
+		if err != nil {
+			return model.Todo{}, err
+		}
+		fields["priority"] = priority
+		fields["priorityWeight"] = model.PriorityWeight(priority)
+	}
+	if update.Urgent != nil {
+		fields["urgent"] = *update.Urgent
+	}
+	if update.Important != nil {
+		fields["important"] = *update.Important
+	}
+	if len(fields) == 0 {
+		return model.Todo{}, errors.New("nothing to update")
+	}
+
+	before, err := todoAccess(ctx, s.repo, s.workspaceRepo, actor, todoId, model.RoleEditor)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	updated, err := s.repo.SetFields(ctx, todoId, fields)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	// the update is already written, a missing revision should not fail it
+	if err := s.revisions.Record(ctx, actor, model.EntityTodo, before.ID, todoSnapshot(before), todoSnapshot(updated)); err != nil {
+		log.Println("Failed to record todo revision:", err)
+	}
+	s.activities.Record(ctx, actor, todoUpdateActivity(before, updated.Task))
+
+	return updated, nil
+}
+
+// SetEstimate stores the planned minutes of a todo (0 clears it)
+func (s *todoService) SetEstimate(ctx context.Context, actor Actor, todoId string, estimateMinutes int) (model.Todo, error) {
+	if todoId == "" {
+		return model.Todo{}, errors.New("TodoId is empty in service")
+	}
+	if estimateMinutes < 0 {
+		return model.Todo{}, errors.New("estimateMinutes can not be negative")
+	}
+	if _, err := todoAccess(ctx, s.repo, s.workspaceRepo, actor, todoId, model.RoleEditor); err != nil {
+		return model.Todo{}, err
+	}
+
+	return s.repo.SetEstimate(ctx, todoId, estimateMinutes)
+}
+
+// DeleteTodo moves a todo item and its subtasks to the trash through the repository
+// Returns true if deletion was successful, false otherwise
+// comments and files stay until the trash purge removes the todo for good
+func (s *todoService) DeleteTodo(ctx context.Context, actor Actor, todoId string) (bool, error) {
+	todo, err := todoAccess(ctx, s.repo, s.workspaceRepo, actor, todoId, model.RoleEditor)
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := s.repo.DeleteTodo(ctx, todoId)
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	// the subtasks go to the trash with their parent
+	subtasks, err := s.repo.TrashSubtasks(ctx, []primitive.ObjectID{todo.ID})
+	if err != nil {
+		return false, err
+	}
+	goalIds := []primitive.ObjectID{todo.GoalId}
+	for _, subtask := range subtasks {
+		goalIds = append(goalIds, subtask.GoalId)
+	}
+	s.goals.SyncProgress(ctx, goalIds)
+
+	s.activities.Record(ctx, actor, todoActivity(todo, model.ActivityTodoDeleted))
+	return true, nil
+}
+
+// GetSpecificTodo returns the todos of every member of the workspace
+func (s *todoService) GetSpecificTodo(ctx context.Context, actor Actor, workspaceId string, userId string, sortBy string) ([]model.Todo, error) {
+	if workspaceId == "" || userId == "" {
+		return nil, errors.New("Workspace ID / UserId is empty in service")
+	}
+	if sortBy != "" && sortBy != "rank" && sortBy != "priority" {
+		return nil, errors.New("sort must be rank or priority")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return nil, err
+	}
+	if _, err := workspaceView(ctx, s.workspaceRepo, actor, workspaceId); err != nil {
+		return nil, err
+	}
+
+	var todos []model.Todo
+	todos, err := s.repo.GetSpecificTodo(ctx, workspaceId, sortBy)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// attach comment counts with one query for the whole list
+	todoIds := make([]primitive.ObjectID, 0, len(todos))
+	for _, todo := range todos {
+		todoIds = append(todoIds, todo.ID)
+	}
+	counts, err := s.commentRepo.CountByTodoIds(ctx, todoIds)
+	if err != nil {
+		return nil, err
+	}
+	for i := range todos {
+		todos[i].CommentCount = counts[todos[i].ID]
+	}
+
+	return todos, nil
+}
+
+// MoveTodo places the todo between two neighbours, afterId is the todo that
+// should end up right above it and beforeId the one right below it.
+// One of them may be empty to move to the start / end of the list.
+// status ("completed" / "not-started") moves the todo into another column.
+func (s *todoService) MoveTodo(ctx context.Context, actor Actor, todoId string, afterId string, beforeId string, status string) (model.Todo, error) {
+	if todoId == "" {
+		return model.Todo{}, errors.New("TodoId is empty in service")
+	}
+	if afterId == todoId || beforeId == todoId {
+		return model.Todo{}, errors.New("todo can not be its own neighbour")
+	}
+
+	var done *bool
+	switch status {
+	case "":
+	case "completed":
+		value := true
+		done = &value
+	case "not-started":
+		value := false
+		done = &value
+	default:
+		return model.Todo{}, errors.New("invalid status value")
+	}
+
+	todo, err := todoAccess(ctx, s.repo, s.workspaceRepo, actor, todoId, model.RoleEditor)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	workspaceId := todo.WorkspaceId.Hex()
+
+	// a concurrent move can take the key between the same neighbours, the
+	// next attempt goes between afterId and the todo that got there first
+	var moved model.Todo
+	err = retryOnRankTaken(func(attempt int) error {
+		afterRank, beforeRank, err := s.neighbourRanks(ctx, workspaceId, afterId, beforeId)
+		if err != nil {
+			return err
+		}
+		if attempt > 1 {
+			if beforeRank, err = s.repo.GetNeighbourRank(ctx, workspaceId, afterRank, true); err != nil {
+				return err
+			}
+		}
+
+		rank, err := nrank.Between(afterRank, beforeRank)
+		if err != nil {
+			return errors.New("after todo must be ranked above the before todo")
+		}
+
+		moved, err = s.repo.MoveTodo(ctx, todoId, rank, done)
+		return err
+	})
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	// only a move into another column shows in the feed
+	if done != nil && *done != todo.Done {
+		s.goals.SyncProgress(ctx, []primitive.ObjectID{todo.GoalId})
+		s.activities.Record(ctx, actor, todoActivity(moved, doneActivity(*done)))
+	}
+	return moved, nil
+}
+
+// LinkGoal makes the todo count towards a goal of its workspace, an empty
+// goalId removes the link
+func (s *todoService) LinkGoal(ctx context.Context, actor Actor, todoId string, goalId string) (model.Todo, error) {
+	if todoId == "" {
+		return model.Todo{}, errors.New("TodoId is empty in service")
+	}
+
+	todo, err := todoAccess(ctx, s.repo, s.workspaceRepo, actor, todoId, model.RoleEditor)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	goalOid := primitive.NilObjectID
+	if goalId != "" {
+		if goalOid, err = primitive.ObjectIDFromHex(goalId); err != nil {
+			return model.Todo{}, err
+		}
+		if err := s.checkGoal(ctx, goalOid, todo.WorkspaceId.Hex()); err != nil {
+			return model.Todo{}, err
+		}
+	}
+
+	linked, err := s.repo.SetGoal(ctx, todoId, goalOid)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	// the old goal loses the todo, the new one gains it
+	s.goals.SyncProgress(ctx, []primitive.ObjectID{todo.GoalId, goalOid})
+	return linked, nil
+}
+
+// AssignTodo replaces the assignees of the todo, every assignee must be a
+// member of the todo's workspace. An empty list unassigns everyone
+func (s *todoService) AssignTodo(ctx context.Context, actor Actor, todoId string, assigneeIds []string) (model.Todo, error) {
+	todo, err := todoAccess(ctx, s.repo, s.workspaceRepo, actor, todoId, model.RoleEditor)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	workspace, err := s.workspaceRepo.GetWorkspaceById(ctx, todo.WorkspaceId.Hex())
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	oids := make([]primitive.ObjectID, 0, len(assigneeIds))
+	for _, assigneeId := range assigneeIds {
+		oid, err := primitive.ObjectIDFromHex(assigneeId)
+		if err != nil {
+			return model.Todo{}, fmt.Errorf("assignee %q is not a valid id", assigneeId)
+		}
+		oids = append(oids, oid)
+	}
+	oids, err = checkAssignees(workspace, oids)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	assigned, err := s.repo.SetAssignees(ctx, todoId, oids)
+	if err != nil {
+		return model.Todo{}, err
+	}
+
+	activity := todoActivity(assigned, model.ActivityTodoAssigned)
+	activity.Details = map[string]any{"assigneeIds": oids}
+	s.activities.Record(ctx, actor, activity)
+	return assigned, nil
+}
+
+// GetAssignedTodos returns the open todos assigned to the user across all of
+// the user's workspaces
+func (s *todoService) GetAssignedTodos(ctx context.Context, actor Actor, userId string) ([]model.Todo, error) {
+	if userId == "" {
+		return nil, errors.New("UserId is empty in service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return nil, err
+	}
+	userOid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	workspaces, err := s.workspaceRepo.GetAllUserWorkspace(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	workspaceIds := make([]primitive.ObjectID, 0, len(workspaces))
+	for _, workspace := range workspaces {
+		workspaceIds = append(workspaceIds, workspace.ID)
+	}
+
+	todos, err := s.repo.GetAssignedTodos(ctx, userOid, workspaceIds)
+	if err != nil {
+		return nil, err
+	}
+
+	todoIds := make([]primitive.ObjectID, 0, len(todos))
+	for _, todo := range todos {
+		todoIds = append(todoIds, todo.ID)
+	}
+	counts, err := s.commentRepo.CountByTodoIds(ctx, todoIds)
+	if err != nil {
+		return nil, err
+	}
+	for i := range todos {
+		todos[i].CommentCount = counts[todos[i].ID]
+	}
+
+	return todos, nil
+}
+
+// checkAssignees drops duplicates and makes sure every assignee is a member
+// of the workspace
+func checkAssignees(workspace model.Workspace, assigneeIds []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	seen := make(map[primitive.ObjectID]bool)
+	checked := make([]primitive.ObjectID, 0, len(assigneeIds))
+	for _, assigneeId := range assigneeIds {
+		if seen[assigneeId] {
+			continue
+		}
+		if workspace.RoleOf(assigneeId.Hex()) == "" {
+			return nil, fmt.Errorf("assignee %s is not a member of this workspace", assigneeId.Hex())
+		}
+		seen[assigneeId] = true
+		checked = append(checked, assigneeId)
+	}
+	return checked, nil
+}
+
+// memberAssignees keeps the assignees that are members of the workspace, used
+// when todos go to another workspace
+func memberAssignees(workspace model.Workspace, assigneeIds []primitive.ObjectID) []primitive.ObjectID {
+	var kept []primitive.ObjectID
+	for _, assigneeId := range assigneeIds {
+		if workspace.RoleOf(assigneeId.Hex()) != "" {
+			kept = append(kept, assigneeId)
+		}
+	}
+	return kept
+}
+
+// checkGoal makes sure a todo of the workspace can link to the goal
+func (s *todoService) checkGoal(ctx context.Context, goalId primitive.ObjectID, workspaceId string) error {
+	goal, err := s.goalRepo.GetGoalById(ctx, goalId.Hex())
+	if err != nil {
+		return err
+	}
+	if goal.DeletedAt != nil || goal.WorkspaceId.Hex() != workspaceId {
+		return errors.New("goal must be in the same workspace as the todo")
+	}
+	return nil
+}
+
+// neighbourRanks resolves the ranks the moved todo has to fit between
+func (s *todoService) neighbourRanks(ctx context.Context, workspaceId string, afterId string, beforeId string) (string, string, error) {
+	var after, before model.Todo
+	var err error
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if afterId != "" {
+			if after, err = s.neighbour(ctx, workspaceId, afterId); err != nil {
+				return "", "", err
+			}
+		}
+		if beforeId != "" {
+			if before, err = s.neighbour(ctx, workspaceId, beforeId); err != nil {
+				return "", "", err
+			}
+		}
+
+		// old todos have no rank yet, give the workspace fresh keys and retry
+		if (afterId != "" && after.Rank == "") || (beforeId != "" && before.Rank == "") {
+			if err := s.rebalanceWorkspace(ctx, workspaceId); err != nil {
+				return "", "", err
+			}
+			continue
+		}
+
+		afterRank, beforeRank := after.Rank, before.Rank
+
+		// only one neighbour given, the other side is whatever is next to it
+		switch {
+		case afterId != "" && beforeId == "":
+			beforeRank, err = s.repo.GetNeighbourRank(ctx, workspaceId, afterRank, true)
+		case afterId == "" && beforeId != "":
+			afterRank, err = s.repo.GetNeighbourRank(ctx, workspaceId, beforeRank, false)
+		}
+		if err != nil {
+			return "", "", err
+		}
+
+		return afterRank, beforeRank, nil
+	}
+
+	return "", "", errors.New("could not rank neighbours, try again")
+}
+
+func (s *todoService) neighbour(ctx context.Context, workspaceId string, todoId string) (model.Todo, error) {
+	todo, err := s.repo.GetTodoById(ctx, todoId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	if todo.WorkspaceId.Hex() != workspaceId {
+		return model.Todo{}, errors.New("neighbour todo belongs to another workspace")
+	}
+	return todo, nil
+}
+
+// RebalanceRanks rewrites the rank keys of every workspace that has keys
+// longer than maxRankLength (or todos without a rank)
+func (s *todoService) RebalanceRanks(ctx context.Context, maxRankLength int) error {
+	workspaceIds, err := s.repo.GetWorkspacesToRebalance(ctx, maxRankLength)
+	if err != nil {
+		return err
+	}
+
+	for _, workspaceId := range workspaceIds {
+		if err := s.rebalanceWorkspace(ctx, workspaceId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rebalanceWorkspace spreads short keys over the workspace, keeping the order
+func (s *todoService) rebalanceWorkspace(ctx context.Context, workspaceId string) error {
+	todos, err := s.repo.GetWorkspaceTodosByRank(ctx, workspaceId)
+	if err != nil {
+		return err
+	}
+
+	keys := nrank.Spread(len(todos))
+	ranks := make(map[primitive.ObjectID]string)
+	for i, todo := range todos {
+		if todo.Rank != keys[i] {
+			ranks[todo.ID] = keys[i]
+		}
+	}
+
+	return s.repo.UpdateRanks(ctx, ranks)
+}
+
+// retryOnRankTaken runs write until it no longer loses a rank to a concurrent
+// write, at most maxRankAttempts times. write must read the ranks it hands out
+// again on every attempt
+func retryOnRankTaken(write func(attempt int) error) error {
+	for attempt := 1; ; attempt++ {
+		err := write(attempt)
+		if errors.Is(err, repository.ErrRankTaken) && attempt < maxRankAttempts {
+			continue
+		}
+		return err
+	}
+}
+
+// RunRankRebalancer checks for long rank keys every interval until ctx is done
+func (s *todoService) RunRankRebalancer(ctx context.Context, interval time.Duration, maxRankLength int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.RebalanceRanks(ctx, maxRankLength); err != nil {
+				log.Println("Rank rebalance failed:", err)
+			}
+		}
+	}
+}
+
+// BatchTodos runs a list of create / update / toggle / delete items of the user
+// with one BulkWrite. every item is checked first, in atomic mode a single bad
+// item rejects the batch before anything is written
+func (s *todoService) BatchTodos(ctx context.Context, actor Actor, userId string, mode string, ops []model.BatchOp) (model.BatchResponse, error) {
+	if userId == "" {
+		return model.BatchResponse{}, errors.New("UserId is empty in service")
+	}
+	if mode != model.BatchAtomic && mode != model.BatchBestEffort {
+		return model.BatchResponse{}, errors.New("mode must be atomic or best-effort")
+	}
+	if len(ops) == 0 || len(ops) > maxBatchOps {
+		return model.BatchResponse{}, fmt.Errorf("batch must have between 1 and %d items", maxBatchOps)
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return model.BatchResponse{}, err
+	}
+
+	// every workspace the batch writes to is checked once
+	access := make(map[string]error)
+	canEdit := func(workspaceId string) error {
+		if err, ok := access[workspaceId]; ok {
+			return err
+		}
+		_, err := workspaceAccess(ctx, s.workspaceRepo, actor, workspaceId)
+		access[workspaceId] = err
+		return err
+	}
+
+	// load every todo the batch touches in one query
+	var todoIds []primitive.ObjectID
+	for _, op := range ops {
+		if oid, err := primitive.ObjectIDFromHex(op.TodoId); err == nil {
+			todoIds = append(todoIds, oid)
+		}
+	}
+	existing := make(map[string]model.Todo)
+	if len(todoIds) > 0 {
+		todos, err := s.repo.GetTodosByIds(ctx, todoIds)
+		if err != nil {
+			return model.BatchResponse{}, err
+		}
+		for _, todo := range todos {
+			existing[todo.ID.Hex()] = todo
+		}
+	}
+
+	response := model.BatchResponse{Mode: mode, Results: make([]model.BatchResult, len(ops))}
+	var valid []model.BatchOp
+	var validIndex []int // index in ops of every valid item
+	lastRanks := make(map[string]string)
+	deleted := make(map[string]bool)
+
+	for i, op := range ops {
+		response.Results[i] = model.BatchResult{Index: i, Op: op.Op, TodoId: op.TodoId}
+
+		if err := s.checkBatchOp(ctx, &op, existing, deleted, lastRanks, canEdit); err != nil {
+			response.Results[i].Status = "failed"
+			response.Results[i].Error = err.Error()
+			continue
+		}
+
+		response.Results[i].TodoId = op.TodoId
+		valid = append(valid, op)
+		validIndex = append(validIndex, i)
+	}
+
+	if mode == model.BatchAtomic && len(valid) < len(ops) {
+		for _, i := range validIndex {
+			response.Results[i].Status = "skipped"
+		}
+		response.Failed = len(ops) - len(valid)
+		return response, nil
+	}
+
+	failed, err := s.writeBatch(ctx, userId, valid, mode == model.BatchAtomic)
+	if err != nil && !errors.Is(err, repository.ErrBatchAborted) {
+		return model.BatchResponse{}, err
+	}
+	aborted := err != nil
+
+	for j, i := range validIndex {
+		result := &response.Results[i]
+		writeErr, writeFailed := failed[j]
+		switch {
+		case writeFailed:
+			result.Status = "failed"
+			result.Error = writeErr.Error()
+		case aborted:
+			result.Status = "skipped"
+		default:
+			result.Status = "ok"
+			response.Applied++
+		}
+	}
+
+	for _, result := range response.Results {
+		if result.Status == "failed" {
+			response.Failed++
+		}
+	}
+
+	// same history, feed and goal progress as single changes, the batch is
+	// already written
+	var goalIds, deletedIds []primitive.ObjectID
+	for j, i := range validIndex {
+		op := valid[j]
+		if response.Results[i].Status != "ok" {
+			continue
+		}
+
+		before := existing[op.TodoId]
+		switch op.Op {
+		case "create":
+			workspaceOid, _ := primitive.ObjectIDFromHex(op.WorkspaceId)
+			todoOid, _ := primitive.ObjectIDFromHex(op.TodoId)
+			created := model.Todo{ID: todoOid, WorkspaceId: workspaceOid, Task: op.Task}
+			s.activities.Record(ctx, actor, todoActivity(created, model.ActivityTodoCreated))
+		case "update":
+			after := before
+			if op.Task != "" {
+				after.Task = op.Task
+			}
+			if op.Priority != "" {
+				after.Priority = op.Priority
+			}
+			if err := s.revisions.Record(ctx, actor, model.EntityTodo, before.ID, todoSnapshot(before), todoSnapshot(after)); err != nil {
+				log.Println("Failed to record todo revision:", err)
+			}
+			s.activities.Record(ctx, actor, todoUpdateActivity(before, after.Task))
+		case "toggle":
+			if done := op.Toggle == "completed"; done != before.Done {
+				goalIds = append(goalIds, before.GoalId)
+				s.activities.Record(ctx, actor, todoActivity(before, doneActivity(done)))
+			}
+		case "delete":
+			goalIds = append(goalIds, before.GoalId)
+			deletedIds = append(deletedIds, before.ID)
+			s.activities.Record(ctx, actor, todoActivity(before, model.ActivityTodoDeleted))
+		}
+	}
+	if len(deletedIds) > 0 {
+		subtasks, err := s.repo.TrashSubtasks(ctx, deletedIds)
+		if err != nil {
+			log.Println("Failed to trash subtasks:", err)
+		}
+		for _, subtask := range subtasks {
+			goalIds = append(goalIds, subtask.GoalId)
+		}
+	}
+	s.goals.SyncProgress(ctx, goalIds)
+
+	return response, nil
+}
+
+// writeBatch writes the checked items, creates that lost their rank to a
+// concurrent create get new ranks after the current last one and go again.
+// An atomic batch is written again as a whole, a best effort one only
+// repeats the creates that failed
+func (s *todoService) writeBatch(ctx context.Context, userId string, ops []model.BatchOp, atomic bool) (map[int]error, error) {
+	failed := make(map[int]error)
+	pending := make([]int, len(ops)) // index in ops of every item to write
+	for i := range ops {
+		pending[i] = i
+	}
+
+	for attempt := 1; ; attempt++ {
+		batch := make([]model.BatchOp, len(pending))
+		for k, i := range pending {
+			batch[k] = ops[i]
+		}
+
+		batchFailed, err := s.repo.BulkWrite(ctx, userId, batch, atomic)
+		if err != nil && !errors.Is(err, repository.ErrBatchAborted) {
+			return nil, err
+		}
+
+		var rankTaken []int
+		for k, i := range pending {
+			writeErr, ok := batchFailed[k]
+			if !ok {
+				delete(failed, i)
+				continue
+			}
+			failed[i] = writeErr
+			if errors.Is(writeErr, repository.ErrRankTaken) {
+				rankTaken = append(rankTaken, i)
+			}
+		}
+		if len(rankTaken) == 0 || attempt == maxRankAttempts {
+			return failed, err
+		}
+
+		if !atomic {
+			pending = rankTaken
+		}
+		if err := s.rankBatchCreates(ctx, ops, pending); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// rankBatchCreates hands out new ranks after the last one of their workspace
+// to the creates among ops at the indexes, keeping their order
+func (s *todoService) rankBatchCreates(ctx context.Context, ops []model.BatchOp, indexes []int) error {
+	lastRanks := make(map[string]string)
+	for _, i := range indexes {
+		op := &ops[i]
+		if op.Op != "create" {
+			continue
+		}
+
+		lastRank, ok := lastRanks[op.WorkspaceId]
+		if !ok {
+			var err error
+			if lastRank, err = s.repo.GetLastRank(ctx, op.WorkspaceId); err != nil {
+				return err
+			}
+		}
+
+		rank, err := nrank.Between(lastRank, "")
+		if err != nil {
+			return err
+		}
+		lastRanks[op.WorkspaceId] = rank
+		op.Rank = rank
+	}
+	return nil
+}
+
+// todoActivity is the feed entry of a change to the todo
+func todoActivity(todo model.Todo, activityType string) model.Activity {
+	return model.Activity{
+		WorkspaceId: todo.WorkspaceId,
+		Type:        activityType,
+		EntityType:  model.EntityTodo,
+		EntityId:    todo.ID,
+		Title:       todo.Task,
+	}
+}
+
+// todoUpdateActivity keeps the old task when the todo was renamed
+func todoUpdateActivity(before model.Todo, task string) model.Activity {
+	activity := todoActivity(before, model.ActivityTodoUpdated)
+	activity.Title = task
+	if task != before.Task {
+		activity.Details = map[string]any{"from": before.Task}
+	}
+	return activity
+}
+
+// doneActivity is the feed type of a todo that is completed or reopened
+func doneActivity(done bool) string {
+	if done {
+		return model.ActivityTodoCompleted
+	}
+	return model.ActivityTodoReopened
+}
+
+// checkBatchOp validates one batch item against the todos of the user, creates
+// get a new id and a rank after the ones already handed out in this batch.
+// canEdit checks the caller's role in the workspace of the item
+func (s *todoService) checkBatchOp(ctx context.Context, op *model.BatchOp, existing map[string]model.Todo, deleted map[string]bool, lastRanks map[string]string, canEdit func(workspaceId string) error) error {
+	if op.Op == "create" {
+		if op.TodoId != "" {
+			return errors.New("create must not have a todoId")
+		}
+		if op.Task == "" {
+			return errors.New("Task is Invalid / Empty")
+		}
+		priority, err := model.NormalizePriority(op.Priority)
+		if err != nil {
+			return err
+		}
+		op.Priority = priority
+		if _, err := primitive.ObjectIDFromHex(op.WorkspaceId); err != nil {
+			return errors.New("invalid workspaceId")
+		}
+		if err := canEdit(op.WorkspaceId); err != nil {
+			return err
+		}
+
+		lastRank, ok := lastRanks[op.WorkspaceId]
+		if !ok {
+			var err error
+			if lastRank, err = s.repo.GetLastRank(ctx, op.WorkspaceId); err != nil {
+				return err
+			}
+		}
+
+		rank, err := nrank.Between(lastRank, "")
+		if err != nil {
+			return err
+		}
+		lastRanks[op.WorkspaceId] = rank
+
+		op.TodoId = primitive.NewObjectID().Hex()
+		op.Rank = rank
+		return nil
+	}
+
+	todo, ok := existing[op.TodoId]
+	if !ok {
+		return errors.New("todo not found")
+	}
+	if err := canEdit(todo.WorkspaceId.Hex()); err != nil {
+		return err
+	}
+	if deleted[op.TodoId] {
+		return errors.New("todo is deleted earlier in this batch")
+	}
+
+	switch op.Op {
+	case "update":
+		if op.Task == "" && op.Priority == "" {
+			return errors.New("update needs a task or priority")
+		}
+		if op.Priority != "" {
+			priority, err := model.NormalizePriority(op.Priority)
+			if err != nil {
+				return err
+			}
+			op.Priority = priority
+		}
+	case "toggle":
+		if op.Toggle != "completed" && op.Toggle != "not-started" {
+			return errors.New("invalid toggle value")
+		}
+	case "delete":
+		deleted[op.TodoId] = true
+	default:
+		return errors.New("op must be create, update, toggle or delete")
+	}
+
+	return nil
+}
+
+// GetMatrix sorts the open todos of a workspace into the Eisenhower quadrants.
+// a todo without an explicit flag is urgent when it is due within
+// urgentWithin and important when its priority is high
+func (s *todoService) GetMatrix(ctx context.Context, actor Actor, workspaceId string, userId string) (model.Matrix, error) {
+	if workspaceId == "" || userId == "" {
+		return model.Matrix{}, errors.New("Workspace ID / UserId is empty in service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return model.Matrix{}, err
+	}
+	if _, err := workspaceView(ctx, s.workspaceRepo, actor, workspaceId); err != nil {
+		return model.Matrix{}, err
+	}
+
+	todos, err := s.repo.GetOpenTodos(ctx, workspaceId)
+	if err != nil {
+		return model.Matrix{}, err
+	}
+
+	// highest priority first, then the earliest due date (repo order breaks ties)
+	sort.SliceStable(todos, func(i, j int) bool {
+		if todos[i].PriorityWeight != todos[j].PriorityWeight {
+			return todos[i].PriorityWeight > todos[j].PriorityWeight
+		}
+		if todos[i].DueAt == nil || todos[j].DueAt == nil {
+			return todos[i].DueAt != nil && todos[j].DueAt == nil
+		}
+		return todos[i].DueAt.Before(*todos[j].DueAt)
+	})
+
+	matrix := model.Matrix{DoFirst: []model.Todo{}, Schedule: []model.Todo{}, Delegate: []model.Todo{}, Eliminate: []model.Todo{}}
+	urgentBefore := time.Now().Add(urgentWithin)
+
+	for _, todo := range todos {
+		urgent := todo.DueAt != nil && todo.DueAt.Before(urgentBefore)
+		if todo.Urgent != nil {
+			urgent = *todo.Urgent
+		}
+		important := todo.PriorityWeight >= model.PriorityWeight(model.PriorityHigh)
+		if todo.Important != nil {
+			important = *todo.Important
+		}
+
+		switch {
+		case urgent && important:
+			matrix.DoFirst = append(matrix.DoFirst, todo)
+		case important:
+			matrix.Schedule = append(matrix.Schedule, todo)
+		case urgent:
+			matrix.Delegate = append(matrix.Delegate, todo)
+		default:
+			matrix.Eliminate = append(matrix.Eliminate, todo)
+		}
+	}
+
+	return matrix, nil
+}
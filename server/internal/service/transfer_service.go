@@ -0,0 +1,412 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"github.com/ndk123-web/fast-todo/pkg/nrank"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maximum number of todos / goals selected in one move or copy
+const maxTransferItems = 100
+
+// TransferService moves and copies todos and goals between workspaces.
+// Subtasks always go with their parent, every write runs in one transaction.
+// Parents, labels and goals are re-linked. Dependencies between todos are not:
+// the todo model has no dependency links yet, they need to be added there
+// before a move can carry them
+type TransferService interface {
+	MoveTodos(ctx context.Context, actor Actor, todoIds []string, workspaceId string) ([]model.Todo, error)
+	CopyTodos(ctx context.Context, actor Actor, todoIds []string, workspaceId string) ([]model.Todo, error)
+	MoveGoals(ctx context.Context, actor Actor, goalIds []string, workspaceId string) ([]model.Goals, error)
+	CopyGoals(ctx context.Context, actor Actor, goalIds []string, workspaceId string) ([]model.Goals, error)
+}
+
+type transferService struct {
+	todoRepo      repository.TodoRepository
+	goalRepo      repository.GoalRepository
+	workspaceRepo repository.WorkSpaceRepository
+	timeEntryRepo repository.TimeEntryRepository
+	focusRepo     repository.FocusRepository
+	transactor    repository.Transactor
+	activities    ActivityService
+	goals         GoalService
+}
+
+// MoveTodos keeps the ids, so comments, attachments, time entries and focus
+// sessions stay linked, the entries and sessions count for the target. A subtask whose parent is not moved becomes a top level todo and
+// assignees that are not members of the target are dropped
+func (s *transferService) MoveTodos(ctx context.Context, actor Actor, todoIds []string, workspaceId string) ([]model.Todo, error) {
+	target, sources, tree, err := s.loadTodos(ctx, actor, todoIds, workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	moved := make(map[primitive.ObjectID]bool)
+	movedIds := make([]primitive.ObjectID, 0, len(tree))
+	var goalIds []primitive.ObjectID
+	for _, todo := range tree {
+		if todo.WorkspaceId == target.ID {
+			return nil, errors.New("todo is already in this workspace")
+		}
+		moved[todo.ID] = true
+		movedIds = append(movedIds, todo.ID)
+		goalIds = append(goalIds, todo.GoalId)
+	}
+
+	// both feeds show the selected todos, subtasks go with them silently
+	var activities []model.Activity
+	for _, todo := range tree {
+		if todo.ParentId.IsZero() || !moved[todo.ParentId] {
+			source := sources[todo.WorkspaceId]
+			activities = append(activities,
+				transferActivity(todoActivity(todo, model.ActivityTodoMoved), source.ID, "to", target),
+				transferActivity(todoActivity(todo, model.ActivityTodoMoved), target.ID, "from", source))
+		}
+	}
+
+	for i := range tree {
+		tree[i].WorkspaceId = target.ID
+		if !tree[i].ParentId.IsZero() && !moved[tree[i].ParentId] {
+			tree[i].ParentId = primitive.NilObjectID
+		}
+		// the goal stays in the old workspace
+		tree[i].GoalId = primitive.NilObjectID
+		tree[i].AssigneeIds = memberAssignees(target, tree[i].AssigneeIds)
+	}
+
+	// concurrent creates can take the new ranks, the move goes again after
+	// the new last rank
+	err = retryOnRankTaken(func(attempt int) error {
+		if err := s.appendRanks(ctx, target, tree); err != nil {
+			return err
+		}
+		return s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			if err := s.todoRepo.RelocateTodos(ctx, tree); err != nil {
+				return err
+			}
+			if err := s.timeEntryRepo.MoveTodoEntries(ctx, movedIds, target.ID); err != nil {
+				return err
+			}
+			return s.focusRepo.MoveTodoSessions(ctx, movedIds, target.ID)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.goals.SyncProgress(ctx, goalIds)
+
+	for _, activity := range activities {
+		s.activities.Record(ctx, actor, activity)
+	}
+
+	return tree, nil
+}
+
+// CopyTodos creates new todos with the same task, priority, labels, due date
+// and recurrence, assignees only when they are members of the target.
+// Subtasks point to the copy of their parent, comments, attachments, tracked
+// time and focus sessions are not copied
+func (s *transferService) CopyTodos(ctx context.Context, actor Actor, todoIds []string, workspaceId string) ([]model.Todo, error) {
+	target, sources, tree, err := s.loadTodos(ctx, actor, todoIds, workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	userOid, err := primitive.ObjectIDFromHex(actor.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	copyIds := make(map[primitive.ObjectID]primitive.ObjectID)
+	for _, todo := range tree {
+		copyIds[todo.ID] = primitive.NewObjectID()
+	}
+
+	var activities []model.Activity
+	var goalIds []primitive.ObjectID
+	for i := range tree {
+		source := sources[tree[i].WorkspaceId]
+		tree[i].ParentId = copyIds[tree[i].ParentId] // zero when the parent is not copied
+		tree[i].ID = copyIds[tree[i].ID]
+		tree[i].UserId = userOid
+		if tree[i].WorkspaceId != target.ID {
+			tree[i].GoalId = primitive.NilObjectID
+		}
+		goalIds = append(goalIds, tree[i].GoalId)
+		tree[i].WorkspaceId = target.ID
+		tree[i].AssigneeIds = memberAssignees(target, tree[i].AssigneeIds)
+		tree[i].CommentCount = 0
+
+		if tree[i].ParentId.IsZero() {
+			activities = append(activities, transferActivity(todoActivity(tree[i], model.ActivityTodoCopied), target.ID, "from", source))
+		}
+	}
+
+	err = retryOnRankTaken(func(attempt int) error {
+		if err := s.appendRanks(ctx, target, tree); err != nil {
+			return err
+		}
+		return s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			return s.todoRepo.InsertTodos(ctx, tree)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	// a copy inside the goal's workspace counts towards it
+	s.goals.SyncProgress(ctx, goalIds)
+
+	for _, activity := range activities {
+		s.activities.Record(ctx, actor, activity)
+	}
+
+	return tree, nil
+}
+
+func (s *transferService) MoveGoals(ctx context.Context, actor Actor, goalIds []string, workspaceId string) ([]model.Goals, error) {
+	target, sources, goals, err := s.loadGoals(ctx, actor, goalIds, workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(goals))
+	moved := make(map[primitive.ObjectID]bool, len(goals))
+	var activities []model.Activity
+	for i := range goals {
+		if goals[i].WorkspaceId == target.ID {
+			return nil, errors.New("goal is already in this workspace")
+		}
+		source := sources[goals[i].WorkspaceId]
+		activities = append(activities,
+			transferActivity(goalActivity(goals[i], model.ActivityGoalMoved), source.ID, "to", target),
+			transferActivity(goalActivity(goals[i], model.ActivityGoalMoved), target.ID, "from", source))
+
+		ids = append(ids, goals[i].ID)
+		moved[goals[i].ID] = true
+		goals[i].WorkspaceId = target.ID
+	}
+
+	// objectives and key results that are split up become top level goals,
+	// the objectives left behind and the moved goals are counted again after
+	var detached []primitive.ObjectID
+	synced := append([]primitive.ObjectID{}, ids...)
+	for i := range goals {
+		if !goals[i].ParentId.IsZero() && !moved[goals[i].ParentId] {
+			detached = append(detached, goals[i].ID)
+			synced = append(synced, goals[i].ParentId)
+			goals[i].ParentId = primitive.NilObjectID
+		}
+	}
+
+	// todos of the goals stay behind and lose the link
+	err = s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.goalRepo.MoveGoals(ctx, ids, target.ID); err != nil {
+			return err
+		}
+		if err := s.goalRepo.DetachGoals(ctx, detached); err != nil {
+			return err
+		}
+		if err := s.goalRepo.DetachChildren(ctx, ids, ids); err != nil {
+			return err
+		}
+		return s.todoRepo.UnlinkGoals(ctx, ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.goals.SyncProgress(ctx, synced)
+
+	for _, activity := range activities {
+		s.activities.Record(ctx, actor, activity)
+	}
+
+	return goals, nil
+}
+
+// CopyGoals copies title, target and progress into new goals, key results
+// stay under their objective when it is copied as well
+func (s *transferService) CopyGoals(ctx context.Context, actor Actor, goalIds []string, workspaceId string) ([]model.Goals, error) {
+	target, sources, goals, err := s.loadGoals(ctx, actor, goalIds, workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	userOid, err := primitive.ObjectIDFromHex(actor.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	copyIds := make(map[primitive.ObjectID]primitive.ObjectID, len(goals))
+	for i := range goals {
+		copyIds[goals[i].ID] = primitive.NewObjectID()
+	}
+	activities := make([]model.Activity, 0, len(goals))
+	for i := range goals {
+		source := sources[goals[i].WorkspaceId]
+		goals[i].ID = copyIds[goals[i].ID]
+		goals[i].ParentId = copyIds[goals[i].ParentId] // zero when the objective is not copied
+		goals[i].UserId = userOid
+		goals[i].WorkspaceId = target.ID
+		activities = append(activities, transferActivity(goalActivity(goals[i], model.ActivityGoalCopied), target.ID, "from", source))
+	}
+
+	err = s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		return s.goalRepo.InsertGoals(ctx, goals)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, activity := range activities {
+		s.activities.Record(ctx, actor, activity)
+	}
+
+	return goals, nil
+}
+
+// loadTodos checks access to the target and the source workspaces and returns
+// them with the selected todos and all their subtasks, in their current order
+func (s *transferService) loadTodos(ctx context.Context, actor Actor, todoIds []string, workspaceId string) (model.Workspace, map[primitive.ObjectID]model.Workspace, []model.Todo, error) {
+	target, err := workspaceAccess(ctx, s.workspaceRepo, actor, workspaceId)
+	if err != nil {
+		return model.Workspace{}, nil, nil, err
+	}
+
+	ids, err := parseTransferIds(todoIds)
+	if err != nil {
+		return model.Workspace{}, nil, nil, err
+	}
+
+	tree, err := s.todoRepo.GetTodoTree(ctx, ids)
+	if err != nil {
+		return model.Workspace{}, nil, nil, err
+	}
+
+	found := make(map[primitive.ObjectID]bool)
+	sourceIds := make(map[primitive.ObjectID]bool)
+	for _, todo := range tree {
+		found[todo.ID] = true
+		sourceIds[todo.WorkspaceId] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return model.Workspace{}, nil, nil, fmt.Errorf("todo %s not found", id.Hex())
+		}
+	}
+	sources, err := s.checkSources(ctx, actor, sourceIds)
+	if err != nil {
+		return model.Workspace{}, nil, nil, err
+	}
+
+	sort.SliceStable(tree, func(i, j int) bool { return tree[i].Rank < tree[j].Rank })
+	return target, sources, tree, nil
+}
+
+func (s *transferService) loadGoals(ctx context.Context, actor Actor, goalIds []string, workspaceId string) (model.Workspace, map[primitive.ObjectID]model.Workspace, []model.Goals, error) {
+	target, err := workspaceAccess(ctx, s.workspaceRepo, actor, workspaceId)
+	if err != nil {
+		return model.Workspace{}, nil, nil, err
+	}
+
+	ids, err := parseTransferIds(goalIds)
+	if err != nil {
+		return model.Workspace{}, nil, nil, err
+	}
+
+	goals, err := s.goalRepo.GetGoalsByIds(ctx, ids)
+	if err != nil {
+		return model.Workspace{}, nil, nil, err
+	}
+	if len(goals) != len(ids) {
+		return model.Workspace{}, nil, nil, errors.New("GoalId Document Not Found")
+	}
+
+	sourceIds := make(map[primitive.ObjectID]bool)
+	for _, goal := range goals {
+		sourceIds[goal.WorkspaceId] = true
+	}
+	sources, err := s.checkSources(ctx, actor, sourceIds)
+	if err != nil {
+		return model.Workspace{}, nil, nil, err
+	}
+
+	return target, sources, goals, nil
+}
+
+// appendRanks gives the todos new ranks at the end of the target workspace,
+// keeping their order
+func (s *transferService) appendRanks(ctx context.Context, target model.Workspace, todos []model.Todo) error {
+	rank, err := s.todoRepo.GetLastRank(ctx, target.ID.Hex())
+	if err != nil {
+		return err
+	}
+
+	for i := range todos {
+		if rank, err = nrank.Between(rank, ""); err != nil {
+			return err
+		}
+		todos[i].Rank = rank
+	}
+	return nil
+}
+
+// checkSources returns the source workspaces by id when the caller may take
+// items out of all of them
+func (s *transferService) checkSources(ctx context.Context, actor Actor, workspaceIds map[primitive.ObjectID]bool) (map[primitive.ObjectID]model.Workspace, error) {
+	sources := make(map[primitive.ObjectID]model.Workspace, len(workspaceIds))
+	for workspaceId := range workspaceIds {
+		workspace, err := workspaceAccess(ctx, s.workspaceRepo, actor, workspaceId.Hex())
+		if err != nil {
+			return nil, err
+		}
+		sources[workspaceId] = workspace
+	}
+	return sources, nil
+}
+
+// transferActivity puts the activity in the feed of workspaceId, naming the
+// other workspace of the move or copy under direction ("to" / "from")
+func transferActivity(activity model.Activity, workspaceId primitive.ObjectID, direction string, other model.Workspace) model.Activity {
+	activity.WorkspaceId = workspaceId
+	activity.Details = map[string]any{direction: other.WorkspaceName}
+	return activity
+}
+
+func parseTransferIds(values []string) ([]primitive.ObjectID, error) {
+	if len(values) == 0 || len(values) > maxTransferItems {
+		return nil, fmt.Errorf("select between 1 and %d items", maxTransferItems)
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(values))
+	seen := make(map[primitive.ObjectID]bool)
+	for _, value := range values {
+		oid, err := primitive.ObjectIDFromHex(value)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[oid] {
+			seen[oid] = true
+			ids = append(ids, oid)
+		}
+	}
+	return ids, nil
+}
+
+func NewTransferService(todoRepo repository.TodoRepository, goalRepo repository.GoalRepository, workspaceRepo repository.WorkSpaceRepository, timeEntryRepo repository.TimeEntryRepository, focusRepo repository.FocusRepository, transactor repository.Transactor, activities ActivityService, goals GoalService) TransferService {
+	return &transferService{
+		todoRepo:      todoRepo,
+		goalRepo:      goalRepo,
+		workspaceRepo: workspaceRepo,
+		timeEntryRepo: timeEntryRepo,
+		focusRepo:     focusRepo,
+		transactor:    transactor,
+		activities:    activities,
+		goals:         goals,
+	}
+}
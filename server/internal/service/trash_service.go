@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TrashService interface {
+	GetTrash(ctx context.Context, actor Actor, userId string) (model.Trash, error)
+	RestoreTodo(ctx context.Context, actor Actor, todoId string) (model.Todo, error)
+	RestoreGoal(ctx context.Context, actor Actor, goalId string) (model.Goals, error)
+	RestoreWorkspace(ctx context.Context, actor Actor, workspaceId string) (model.Workspace, error)
+	Purge(ctx context.Context, retention time.Duration) error
+	RunTrashPurger(ctx context.Context, interval time.Duration, retention time.Duration)
+}
+
+type trashService struct {
+	todoRepo      repository.TodoRepository
+	goalRepo      repository.GoalRepository
+	workspaceRepo repository.WorkSpaceRepository
+	commentRepo   repository.CommentRepository
+	activityRepo  repository.ActivityRepository
+	timeEntryRepo repository.TimeEntryRepository
+	focusRepo     repository.FocusRepository
+	revisionRepo  repository.RevisionRepository
+	checkInRepo   repository.CheckInRepository
+	attachments   AttachmentService
+	activities    ActivityService
+	goals         GoalService
+}
+
+// GetTrash lists the deleted todos and goals of every workspace the caller
+// can restore them in, and the deleted workspaces the caller owns
+func (s *trashService) GetTrash(ctx context.Context, actor Actor, userId string) (model.Trash, error) {
+	if userId == "" {
+		return model.Trash{}, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return model.Trash{}, err
+	}
+
+	live, err := s.workspaceRepo.GetAllUserWorkspace(ctx, userId)
+	if err != nil {
+		return model.Trash{}, err
+	}
+	workspaceIds := []primitive.ObjectID{}
+	for _, workspace := range live {
+		if model.RoleAllows(workspace.RoleOf(userId), model.RoleEditor) {
+			workspaceIds = append(workspaceIds, workspace.ID)
+		}
+	}
+
+	todos, err := s.todoRepo.GetDeletedTodos(ctx, workspaceIds)
+	if err != nil {
+		return model.Trash{}, err
+	}
+
+	goals, err := s.goalRepo.GetDeletedGoals(ctx, workspaceIds)
+	if err != nil {
+		return model.Trash{}, err
+	}
+
+	workspaces, err := s.workspaceRepo.GetDeletedWorkspaces(ctx, userId)
+	if err != nil {
+		return model.Trash{}, err
+	}
+
+	return model.Trash{Todos: todos, Goals: goals, Workspaces: workspaces}, nil
+}
+
+// RestoreTodo takes a todo out of the trash for an editor of its workspace,
+// the workspace itself must not be in the trash. Subtasks that went to the
+// trash with it come back too
+func (s *trashService) RestoreTodo(ctx context.Context, actor Actor, todoId string) (model.Todo, error) {
+	if todoId == "" {
+		return model.Todo{}, errors.New("TodoId is Empty in Service")
+	}
+
+	todo, err := s.todoRepo.GetDeletedTodoById(ctx, todoId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	if _, err := workspaceAccess(ctx, s.workspaceRepo, actor, todo.WorkspaceId.Hex()); err != nil {
+		return model.Todo{}, err
+	}
+
+	restored, err := s.todoRepo.RestoreTodo(ctx, todoId)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	subtasks, err := s.todoRepo.RestoreSubtasks(ctx, restored.ID, *todo.DeletedAt)
+	if err != nil {
+		return model.Todo{}, err
+	}
+	goalIds := []primitive.ObjectID{restored.GoalId}
+	for _, subtask := range subtasks {
+		goalIds = append(goalIds, subtask.GoalId)
+	}
+	s.goals.SyncProgress(ctx, goalIds)
+
+	s.activities.Record(ctx, actor, todoActivity(restored, model.ActivityTodoRestored))
+	return restored, nil
+}
+
+// RestoreGoal takes a goal out of the trash for an editor of its workspace,
+// the workspace itself must not be in the trash
+func (s *trashService) RestoreGoal(ctx context.Context, actor Actor, goalId string) (model.Goals, error) {
+	if goalId == "" {
+		return model.Goals{}, errors.New("GoalId is Empty in Service")
+	}
+
+	goal, err := s.goalRepo.GetGoalById(ctx, goalId)
+	if err != nil {
+		return model.Goals{}, err
+	}
+	if _, err := workspaceAccess(ctx, s.workspaceRepo, actor, goal.WorkspaceId.Hex()); err != nil {
+		return model.Goals{}, err
+	}
+
+	restored, err := s.goalRepo.RestoreGoal(ctx, goalId)
+	if err != nil {
+		return model.Goals{}, err
+	}
+	// the objective counts the key result again
+	s.goals.SyncProgress(ctx, []primitive.ObjectID{restored.ID})
+
+	s.activities.Record(ctx, actor, goalActivity(restored, model.ActivityGoalRestored))
+	return restored, nil
+}
+
+// RestoreWorkspace takes a workspace out of the trash for one of its owners,
+// it fails when a live workspace already has the same name
+func (s *trashService) RestoreWorkspace(ctx context.Context, actor Actor, workspaceId string) (model.Workspace, error) {
+	if workspaceId == "" {
+		return model.Workspace{}, errors.New("WorkspaceId is Empty in Service")
+	}
+	if actor.UserId == "" {
+		return model.Workspace{}, errors.New("caller is unknown")
+	}
+
+	// workspaceRoleAccess treats trashed workspaces as not found, the role is
+	// checked here instead
+	workspace, err := s.workspaceRepo.GetWorkspaceById(ctx, workspaceId)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	role := workspace.RoleOf(actor.UserId)
+	if role == "" {
+		return model.Workspace{}, errors.New("no access to workspace")
+	}
+	if !model.RoleAllows(role, model.RoleOwner) {
+		return model.Workspace{}, errors.New("your role in this workspace does not allow this")
+	}
+
+	restored, err := s.workspaceRepo.RestoreWorkspace(ctx, workspaceId)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+
+	s.activities.Record(ctx, actor, workspaceActivity(restored, model.ActivityWorkspaceRestored))
+
+	restored.Role = role
+	return restored, nil
+}
+
+// Purge hard deletes everything that is in the trash for longer than
+// retention, together with the subtasks of purged todos and everything
+// recorded about the purged todos / goals / workspaces.
+// todos / goals and the feed of a purged workspace go with it, even when they are not
+// in the trash themselves
+func (s *trashService) Purge(ctx context.Context, retention time.Duration) error {
+	deletedBefore := time.Now().Add(-retention)
+
+	todoIds, err := s.todoRepo.PurgeDeletedTodos(ctx, deletedBefore)
+	if err != nil {
+		return err
+	}
+	if err := s.cleanupTodos(ctx, todoIds); err != nil {
+		return err
+	}
+
+	goalIds, err := s.goalRepo.PurgeDeletedGoals(ctx, deletedBefore)
+	if err != nil {
+		return err
+	}
+	if err := s.cleanupGoals(ctx, goalIds); err != nil {
+		return err
+	}
+
+	workspaceIds, err := s.workspaceRepo.PurgeDeletedWorkspaces(ctx, deletedBefore)
+	if err != nil {
+		return err
+	}
+
+	for _, workspaceId := range workspaceIds {
+		todoIds, err := s.todoRepo.PurgeWorkspaceTodos(ctx, workspaceId)
+		if err != nil {
+			return err
+		}
+		if err := s.cleanupTodos(ctx, todoIds); err != nil {
+			return err
+		}
+
+		goalIds, err := s.goalRepo.PurgeWorkspaceGoals(ctx, workspaceId)
+		if err != nil {
+			return err
+		}
+		if err := s.cleanupGoals(ctx, goalIds); err != nil {
+			return err
+		}
+
+		if err := s.activityRepo.PurgeWorkspaceActivities(ctx, workspaceId); err != nil {
+			return err
+		}
+		if err := s.revisionRepo.DeleteEntityRevisions(ctx, model.EntityWorkspace, objectIds([]string{workspaceId})); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cleanupTodos purges the subtasks of purged todos and removes the comment
+// threads, files, tracked time, focus sessions and history of all of them
+func (s *trashService) cleanupTodos(ctx context.Context, todoIds []string) error {
+	if len(todoIds) == 0 {
+		return nil
+	}
+
+	subtaskIds, err := s.todoRepo.PurgeSubtasks(ctx, todoIds)
+	if err != nil {
+		return err
+	}
+	todoIds = append(todoIds, subtaskIds...)
+
+	for _, todoId := range todoIds {
+		if err := s.commentRepo.DeleteTodoComments(ctx, todoId); err != nil {
+			return err
+		}
+		if err := s.attachments.DeleteTodoAttachments(ctx, todoId); err != nil {
+			return err
+		}
+	}
+
+	oids := objectIds(todoIds)
+	if err := s.timeEntryRepo.DeleteTodoEntries(ctx, oids); err != nil {
+		return err
+	}
+	if err := s.focusRepo.DeleteTodoSessions(ctx, oids); err != nil {
+		return err
+	}
+	return s.revisionRepo.DeleteEntityRevisions(ctx, model.EntityTodo, oids)
+}
+
+// cleanupGoals removes the check-ins and history of purged goals
+func (s *trashService) cleanupGoals(ctx context.Context, goalIds []string) error {
+	if len(goalIds) == 0 {
+		return nil
+	}
+
+	oids := objectIds(goalIds)
+	if err := s.checkInRepo.DeleteGoalCheckIns(ctx, oids); err != nil {
+		return err
+	}
+	return s.revisionRepo.DeleteEntityRevisions(ctx, model.EntityGoal, oids)
+}
+
+// objectIds parses the ids handed back by a purge, invalid ones are skipped
+func objectIds(ids []string) []primitive.ObjectID {
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			oids = append(oids, oid)
+		}
+	}
+	return oids
+}
+
+// RunTrashPurger empties old trash every interval until ctx is done
+func (s *trashService) RunTrashPurger(ctx context.Context, interval time.Duration, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Purge(ctx, retention); err != nil {
+				log.Println("Trash purge failed:", err)
+			}
+		}
+	}
+}
+
+func NewTrashService(todoRepo repository.TodoRepository, goalRepo repository.GoalRepository, workspaceRepo repository.WorkSpaceRepository, commentRepo repository.CommentRepository, activityRepo repository.ActivityRepository, timeEntryRepo repository.TimeEntryRepository, focusRepo repository.FocusRepository, revisionRepo repository.RevisionRepository, checkInRepo repository.CheckInRepository, attachments AttachmentService, activities ActivityService, goals GoalService) TrashService {
+	return &trashService{
+		todoRepo:      todoRepo,
+		goalRepo:      goalRepo,
+		workspaceRepo: workspaceRepo,
+		commentRepo:   commentRepo,
+		activityRepo:  activityRepo,
+		timeEntryRepo: timeEntryRepo,
+		focusRepo:     focusRepo,
+		revisionRepo:  revisionRepo,
+		checkInRepo:   checkInRepo,
+		attachments:   attachments,
+		activities:    activities,
+		goals:         goals,
+	}
+}
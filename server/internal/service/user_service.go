@@ -1,89 +1,128 @@
-package service
-
-import (
-	"context"
-	"fmt"
-	"github.com/ndk123-web/fast-todo/internal/model"
-	"github.com/ndk123-web/fast-todo/internal/repository"
-	"github.com/ndk123-web/fast-todo/pkg/njwt"
-	"golang.org/x/crypto/bcrypt"
-	"os"
-)
-
-var JWTSECRET = []byte(os.Getenv("JWT_SECRET"))
-
-type UserService interface {
-	GetUserTodos(ctx context.Context, userId string) ([]model.Todo, error)
-	SignUpUser(ctx context.Context, email string, password string, fullName string) (*repository.SignUpResponse, error)
-	SignInUser(ctx context.Context, email string, password string) (*repository.SignUpResponse, error)
-}
-
-type userService struct {
-	repo repository.UserRepository
-}
-
-func (s *userService) GetUserTodos(ctx context.Context, userId string) ([]model.Todo, error) {
-	return s.repo.GetUserTodos(ctx, userId)
-}
-
-func (s *userService) SignUpUser(ctx context.Context, email string, password string, fullName string) (*repository.SignUpResponse, error) {
-
-	// bcrypt the password
-	hashedPassword, err := BcryptForPassword(password)
-	if err != nil {
-		return nil, err
-	}
-
-	response, err := s.repo.SignUpUser(ctx, email, hashedPassword,fullName)
-	if err != nil {
-		return nil, err
-	}
-
-	accessString, refreshString, err := njwt.CreateAccessAndRefreshToken(email)
-	if err != nil {
-		return nil, err
-	}
-	// inject tokens with response
-	response.AccessToken = accessString
-	response.RefreshToken = refreshString
-
-	return response, nil
-}
-
-func (s *userService) SignInUser(ctx context.Context, email string, password string) (*repository.SignUpResponse, error) {
-
-	// if response is all right then
-	response, err := s.repo.SignInUser(ctx, email, password)
-	if err != nil {
-		return nil, err
-	}
-
-	// get the accessToken and Refresh token
-	accessString, refreshString, err := njwt.CreateAccessAndRefreshToken(response.Email)
-	if err != nil {
-		return nil, err
-	}
-
-	// inject access and refresh token to the response and send it to the handler
-	response.AccessToken = accessString
-	response.RefreshToken = refreshString
-
-	return response, nil
-}
-
-func BcryptForPassword(password string) (string, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	fmt.Println("Hashed password:", string(hashed))
-
-	hashedString := string(hashed)
-	return hashedString, nil
-}
-
-func NewUserService(repo repository.UserRepository) UserService {
-	return &userService{
-		repo: repo,
-	}
-}
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"github.com/ndk123-web/fast-todo/pkg/njwt"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"os"
+	"time"
+)
+
+var JWTSECRET = []byte(os.Getenv("JWT_SECRET"))
+
+type UserService interface {
+	GetUserTodos(ctx context.Context, userId string) ([]model.Todo, error)
+	SignUpUser(ctx context.Context, email string, password string, fullName string, inviteToken string) (*repository.SignUpResponse, error)
+	SignInUser(ctx context.Context, email string, password string) (*repository.SignUpResponse, error)
+	SetTimezone(ctx context.Context, actor Actor, userId string, timezone string) (model.User, error)
+}
+
+type userService struct {
+	repo        repository.UserRepository
+	invitations InvitationService
+}
+
+func (s *userService) GetUserTodos(ctx context.Context, userId string) ([]model.Todo, error) {
+	return s.repo.GetUserTodos(ctx, userId)
+}
+
+// SignUpUser creates the user, with an inviteToken the user also joins the
+// workspace of the invitation
+func (s *userService) SignUpUser(ctx context.Context, email string, password string, fullName string, inviteToken string) (*repository.SignUpResponse, error) {
+
+	// a bad invitation fails the signup before the user exists
+	if inviteToken != "" {
+		if err := s.invitations.CheckInvitation(ctx, inviteToken, email); err != nil {
+			return nil, err
+		}
+	}
+
+	// bcrypt the password
+	hashedPassword, err := BcryptForPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.repo.SignUpUser(ctx, email, hashedPassword,fullName)
+	if err != nil {
+		return nil, err
+	}
+
+	accessString, refreshString, err := njwt.CreateAccessAndRefreshToken(email, response.UserId)
+	if err != nil {
+		return nil, err
+	}
+	// inject tokens with response
+	response.AccessToken = accessString
+	response.RefreshToken = refreshString
+
+	if inviteToken != "" {
+		workspace, err := s.invitations.AcceptInvitation(ctx, Actor{UserId: response.UserId, Email: email}, inviteToken)
+		if err != nil {
+			log.Println("Failed to accept invitation at signup:", err)
+		} else {
+			response.Workspace = &workspace
+		}
+	}
+
+	return response, nil
+}
+
+func (s *userService) SignInUser(ctx context.Context, email string, password string) (*repository.SignUpResponse, error) {
+
+	// if response is all right then
+	response, err := s.repo.SignInUser(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// get the accessToken and Refresh token
+	accessString, refreshString, err := njwt.CreateAccessAndRefreshToken(response.Email, response.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	// inject access and refresh token to the response and send it to the handler
+	response.AccessToken = accessString
+	response.RefreshToken = refreshString
+
+	return response, nil
+}
+
+// SetTimezone validates the IANA name before storing it
+func (s *userService) SetTimezone(ctx context.Context, actor Actor, userId string, timezone string) (model.User, error) {
+	if userId == "" {
+		return model.User{}, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return model.User{}, err
+	}
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "" {
+		return model.User{}, errors.New("invalid timezone " + timezone)
+	}
+
+	return s.repo.SetTimezone(ctx, userId, timezone)
+}
+
+func BcryptForPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	fmt.Println("Hashed password:", string(hashed))
+
+	hashedString := string(hashed)
+	return hashedString, nil
+}
+
+func NewUserService(repo repository.UserRepository, invitations InvitationService) UserService {
+	return &userService{
+		repo:        repo,
+		invitations: invitations,
+	}
+}
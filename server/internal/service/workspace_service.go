@@ -1,73 +1,310 @@
-package service
-
-import (
-	"context"
-	"errors"
-
-	"github.com/ndk123-web/fast-todo/internal/model"
-	"github.com/ndk123-web/fast-todo/internal/repository"
-)
-
-// WorkspaceService interface
-type WorkspaceService interface {
-	GetAllUserWorkspace(ctx context.Context, userId string) ([]model.Workspace, error)
-	CreateWorkspace(ctx context.Context, userId string, workspaceName string) (string,error)
-	UpdatedWorkspace(ctx context.Context, userId string, workspaceName string, updatedWorkspace string) error
-	DeleteWorkspace(ctx context.Context, userId string, workspaceName string) error
-}
-
-// workspaceService struct
-type workspaceService struct {
-	repo repository.WorkSpaceRepository
-}
-
-func (s *workspaceService) GetAllUserWorkspace(ctx context.Context, userId string) ([]model.Workspace, error) {
-	if userId == "" {
-		return nil, errors.New("UserId is Empty in Service")
-	}
-
-	// call the repo method
-	return s.repo.GetAllUserWorkspace(ctx, userId)
-}
-
-func (s *workspaceService) CreateWorkspace(ctx context.Context, userId string, workspaceName string) (string,error) {
-	if userId == "" || workspaceName == "" {
-		return "",errors.New("UserEmail or workspaceName is Empty")
-	}
-
-	// call the repo create method
-	return s.repo.CreateWorkspace(ctx, userId, workspaceName)
-}
-
-func (s *workspaceService) UpdatedWorkspace(ctx context.Context, userId string, workspaceName string, updatedWorkspace string) error {
-	if userId == "" || workspaceName == "" {
-		return errors.New("UserId / workspace name empty in Service")
-	}
-
-	// call the repo update method
-	if err := s.repo.UpdatedWorkspace(ctx, userId, workspaceName, updatedWorkspace); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-
-func (s *workspaceService) DeleteWorkspace(ctx context.Context, userId string, workspaceName string) error {
-	if userId == "" || workspaceName == "" {
-		return errors.New("UserId / workspace name empty in Service")
-	}
-
-	// call the repo delete method
-	if err := s.repo.DeleteWorkspace(ctx, userId, workspaceName); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func NewWorkSpaceService(repo repository.WorkSpaceRepository) WorkspaceService {
-	return &workspaceService{
-		repo: repo,
-	}
-}
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ndk123-web/fast-todo/internal/model"
+	"github.com/ndk123-web/fast-todo/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WorkspaceService interface
+type WorkspaceService interface {
+	GetAllUserWorkspace(ctx context.Context, actor Actor, userId string) ([]model.Workspace, error)
+	CreateWorkspace(ctx context.Context, actor Actor, userId string, workspaceName string) (string,error)
+	UpdatedWorkspace(ctx context.Context, actor Actor, userId string, workspaceName string, updatedWorkspace string) error
+	DeleteWorkspace(ctx context.Context, actor Actor, userId string, workspaceName string) error
+	GetMembers(ctx context.Context, actor Actor, workspaceId string) ([]model.WorkspaceMember, error)
+	AddMember(ctx context.Context, actor Actor, workspaceId string, email string, role string) (model.Workspace, error)
+	SetMemberRole(ctx context.Context, actor Actor, workspaceId string, memberId string, role string) (model.Workspace, error)
+	RemoveMember(ctx context.Context, actor Actor, workspaceId string, memberId string) error
+}
+
+// workspaceService struct
+type workspaceService struct {
+	repo       repository.WorkSpaceRepository
+	userRepo   repository.UserRepository
+	todoRepo   repository.TodoRepository
+	revisions  RevisionService
+	activities ActivityService
+	transactor repository.Transactor
+}
+
+// GetAllUserWorkspace returns the owned and the shared workspaces of the
+// user, each with the user's role
+func (s *workspaceService) GetAllUserWorkspace(ctx context.Context, actor Actor, userId string) ([]model.Workspace, error) {
+	if userId == "" {
+		return nil, errors.New("UserId is Empty in Service")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return nil, err
+	}
+
+	// call the repo method
+	workspaces, err := s.repo.GetAllUserWorkspace(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range workspaces {
+		workspaces[i].Role = workspaces[i].RoleOf(userId)
+	}
+	return workspaces, nil
+}
+
+func (s *workspaceService) CreateWorkspace(ctx context.Context, actor Actor, userId string, workspaceName string) (string,error) {
+	if userId == "" || workspaceName == "" {
+		return "",errors.New("UserEmail or workspaceName is Empty")
+	}
+	if err := checkCaller(actor, userId); err != nil {
+		return "", err
+	}
+
+	// call the repo create method
+	workspaceId, err := s.repo.CreateWorkspace(ctx, userId, workspaceName)
+	if err != nil {
+		return "", err
+	}
+
+	if workspaceOid, err := primitive.ObjectIDFromHex(workspaceId); err == nil {
+		s.activities.Record(ctx, actor, workspaceActivity(model.Workspace{ID: workspaceOid, WorkspaceName: workspaceName}, model.ActivityWorkspaceCreated))
+	}
+	return workspaceId, nil
+}
+
+func (s *workspaceService) UpdatedWorkspace(ctx context.Context, actor Actor, userId string, workspaceName string, updatedWorkspace string) error {
+	if userId == "" || workspaceName == "" {
+		return errors.New("UserId / workspace name empty in Service")
+	}
+
+	// the workspace before the update, for the history. userId is the
+	// owner, members rename a shared workspace by its owner and name
+	before, err := s.repo.GetWorkspaceByName(ctx, userId, workspaceName)
+	if err != nil {
+		return err
+	}
+	if _, err := workspaceAccess(ctx, s.repo, actor, before.ID.Hex()); err != nil {
+		return err
+	}
+
+	// call the repo update method
+	if err := s.repo.UpdatedWorkspace(ctx, userId, workspaceName, updatedWorkspace); err != nil {
+		return err
+	}
+
+	after := before
+	after.WorkspaceName = updatedWorkspace
+	if err := s.revisions.Record(ctx, actor, model.EntityWorkspace, before.ID, workspaceSnapshot(before), workspaceSnapshot(after)); err != nil {
+		log.Println("Failed to record workspace revision:", err)
+	}
+
+	activity := workspaceActivity(after, model.ActivityWorkspaceRenamed)
+	activity.Details = map[string]any{"from": before.WorkspaceName}
+	s.activities.Record(ctx, actor, activity)
+
+	return nil
+}
+
+
+// DeleteWorkspace moves the workspace to the trash, only owners may do it
+func (s *workspaceService) DeleteWorkspace(ctx context.Context, actor Actor, userId string, workspaceName string) error {
+	if userId == "" || workspaceName == "" {
+		return errors.New("UserId / workspace name empty in Service")
+	}
+
+	workspace, err := s.repo.GetWorkspaceByName(ctx, userId, workspaceName)
+	if err != nil {
+		return err
+	}
+	if _, err := workspaceRoleAccess(ctx, s.repo, actor, workspace.ID.Hex(), model.RoleOwner); err != nil {
+		return err
+	}
+
+	// call the repo delete method
+	if err := s.repo.DeleteWorkspace(ctx, userId, workspaceName); err != nil {
+		return err
+	}
+
+	s.activities.Record(ctx, actor, workspaceActivity(workspace, model.ActivityWorkspaceDeleted))
+	return nil
+}
+
+// GetMembers lists the creator of the workspace and everyone it is shared with
+func (s *workspaceService) GetMembers(ctx context.Context, actor Actor, workspaceId string) ([]model.WorkspaceMember, error) {
+	workspace, err := workspaceView(ctx, s.repo, actor, workspaceId)
+	if err != nil {
+		return nil, err
+	}
+
+	members := append([]model.WorkspaceMember{{UserId: workspace.UserId, Role: model.RoleOwner, JoinedAt: workspace.CreatedAt}}, workspace.Members...)
+
+	userIds := make([]primitive.ObjectID, 0, len(members))
+	for _, member := range members {
+		userIds = append(userIds, member.UserId)
+	}
+	users, err := s.userRepo.GetUsersByIds(ctx, userIds)
+	if err != nil {
+		return nil, err
+	}
+	emails := make(map[primitive.ObjectID]string, len(users))
+	for _, user := range users {
+		emails[user.ID] = user.Email
+	}
+	for i := range members {
+		members[i].Email = emails[members[i].UserId]
+	}
+
+	return members, nil
+}
+
+// AddMember shares the workspace with the user that has the email, only
+// owners may do it
+func (s *workspaceService) AddMember(ctx context.Context, actor Actor, workspaceId string, email string, role string) (model.Workspace, error) {
+	if _, err := workspaceRoleAccess(ctx, s.repo, actor, workspaceId, model.RoleOwner); err != nil {
+		return model.Workspace{}, err
+	}
+	if !model.ValidRole(role) {
+		return model.Workspace{}, errors.New("role must be owner, editor or viewer")
+	}
+
+	email = strings.TrimSpace(email)
+	if email == "" || !strings.Contains(email, "@") {
+		return model.Workspace{}, errors.New("email is invalid")
+	}
+	users, err := s.userRepo.FindUsersByHandles(ctx, []string{email})
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	if len(users) == 0 {
+		return model.Workspace{}, errors.New("no user with this email")
+	}
+
+	workspace, err := s.repo.AddMember(ctx, workspaceId, model.WorkspaceMember{
+		UserId:   users[0].ID,
+		Role:     role,
+		JoinedAt: time.Now(),
+	})
+	if err != nil {
+		return model.Workspace{}, err
+	}
+
+	s.activities.Record(ctx, actor, memberActivity(workspace.ID, model.ActivityMemberAdded, users[0], role))
+
+	workspace.Role = model.RoleOwner
+	return workspace, nil
+}
+
+// SetMemberRole changes the role of a member, the creator always stays owner
+func (s *workspaceService) SetMemberRole(ctx context.Context, actor Actor, workspaceId string, memberId string, role string) (model.Workspace, error) {
+	workspace, err := workspaceRoleAccess(ctx, s.repo, actor, workspaceId, model.RoleOwner)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	if !model.ValidRole(role) {
+		return model.Workspace{}, errors.New("role must be owner, editor or viewer")
+	}
+
+	memberOid, err := primitive.ObjectIDFromHex(memberId)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+	if memberOid == workspace.UserId {
+		return model.Workspace{}, errors.New("the creator of a workspace is always an owner")
+	}
+
+	updated, err := s.repo.SetMemberRole(ctx, workspaceId, memberOid, role)
+	if err != nil {
+		return model.Workspace{}, err
+	}
+
+	if member, err := s.userRepo.GetUserById(ctx, memberId); err == nil {
+		s.activities.Record(ctx, actor, memberActivity(workspace.ID, model.ActivityMemberRoleChanged, member, role))
+	}
+
+	updated.Role = workspace.Role
+	return updated, nil
+}
+
+// RemoveMember takes the workspace away from a member, owners remove anyone
+// and members may leave on their own. The creator can not leave.
+// The member is unassigned from every todo of the workspace
+func (s *workspaceService) RemoveMember(ctx context.Context, actor Actor, workspaceId string, memberId string) error {
+	need := model.RoleOwner
+	if memberId == actor.UserId {
+		need = model.RoleViewer
+	}
+	workspace, err := workspaceRoleAccess(ctx, s.repo, actor, workspaceId, need)
+	if err != nil {
+		return err
+	}
+
+	memberOid, err := primitive.ObjectIDFromHex(memberId)
+	if err != nil {
+		return err
+	}
+	if memberOid == workspace.UserId {
+		return errors.New("the creator of a workspace can not be removed")
+	}
+
+	// the member's todos in the workspace stay, only the assignments go
+	err = s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.repo.RemoveMember(ctx, workspaceId, memberOid); err != nil {
+			return err
+		}
+		return s.todoRepo.UnassignUser(ctx, workspace.ID, memberOid)
+	})
+	if err != nil {
+		return err
+	}
+
+	activityType := model.ActivityMemberRemoved
+	if memberId == actor.UserId {
+		activityType = model.ActivityMemberLeft
+	}
+	if member, err := s.userRepo.GetUserById(ctx, memberId); err == nil {
+		s.activities.Record(ctx, actor, memberActivity(workspace.ID, activityType, member, ""))
+	}
+	return nil
+}
+
+// workspaceActivity is the feed entry of a change to the workspace itself
+func workspaceActivity(workspace model.Workspace, activityType string) model.Activity {
+	return model.Activity{
+		WorkspaceId: workspace.ID,
+		Type:        activityType,
+		EntityType:  model.EntityWorkspace,
+		EntityId:    workspace.ID,
+		Title:       workspace.WorkspaceName,
+	}
+}
+
+// memberActivity is the feed entry of a change to the members, role is the
+// new role of the member if it has one
+func memberActivity(workspaceId primitive.ObjectID, activityType string, member model.User, role string) model.Activity {
+	activity := model.Activity{
+		WorkspaceId: workspaceId,
+		Type:        activityType,
+		EntityType:  model.EntityMember,
+		EntityId:    member.ID,
+		Title:       displayName(member),
+	}
+	if role != "" {
+		activity.Details = map[string]any{"role": role}
+	}
+	return activity
+}
+
+func NewWorkSpaceService(repo repository.WorkSpaceRepository, userRepo repository.UserRepository, todoRepo repository.TodoRepository, revisions RevisionService, activities ActivityService, transactor repository.Transactor) WorkspaceService {
+	return &workspaceService{
+		repo:       repo,
+		userRepo:   userRepo,
+		todoRepo:   todoRepo,
+		revisions:  revisions,
+		activities: activities,
+		transactor: transactor,
+	}
+}
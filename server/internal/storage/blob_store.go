@@ -0,0 +1,32 @@
+// Package storage holds the blob stores used for file attachments
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore saves and loads file content by key, keys look like
+// "attachments/<todoId>/<attachmentId>"
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey rejects keys that could escape the store root / bucket
+func validKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return errors.New("invalid blob key")
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return errors.New("invalid blob key")
+		}
+	}
+	return nil
+}
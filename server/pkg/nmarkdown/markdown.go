@@ -0,0 +1,184 @@
+// Package nmarkdown renders a small, safe subset of Markdown to HTML.
+// Raw HTML in the source is always escaped and only http(s) / mailto links
+// are kept, so the output can be stored and shown without another sanitizer.
+package nmarkdown
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	headingRe = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	bulletRe  = regexp.MustCompile(`^[-*+]\s+(.*)$`)
+	orderedRe = regexp.MustCompile(`^\d+[.)]\s+(.*)$`)
+	quoteRe   = regexp.MustCompile(`^>\s?(.*)$`)
+
+	codeSpanRe = regexp.MustCompile("`([^`]+)`")
+	linkRe     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	boldRe     = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	italicRe   = regexp.MustCompile(`\*([^*]+)\*`)
+	underRe    = regexp.MustCompile(`(^|\W)_([^_]+)_(\W|$)`)
+	strikeRe   = regexp.MustCompile(`~~([^~]+)~~`)
+	tokenRe    = regexp.MustCompile("\x00(\\d+)\x00")
+)
+
+// Render converts markdown source to sanitized HTML
+func Render(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+
+	var out strings.Builder
+	var paragraph, quote, items, code []string
+	var listTag string
+	inCode := false
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			out.WriteString("<p>" + inlineLines(paragraph) + "</p>\n")
+			paragraph = nil
+		}
+		if len(quote) > 0 {
+			out.WriteString("<blockquote><p>" + inlineLines(quote) + "</p></blockquote>\n")
+			quote = nil
+		}
+		if len(items) > 0 {
+			out.WriteString("<" + listTag + ">\n")
+			for _, item := range items {
+				out.WriteString("<li>" + inline(item) + "</li>\n")
+			}
+			out.WriteString("</" + listTag + ">\n")
+			items, listTag = nil, ""
+		}
+	}
+
+	for _, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		// fenced code blocks are copied as escaped text
+		if inCode {
+			if strings.HasPrefix(trimmed, "```") {
+				out.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+				code, inCode = nil, false
+				continue
+			}
+			code = append(code, line)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") {
+			flush()
+			inCode = true
+			continue
+		}
+
+		if trimmed == "" {
+			flush()
+			continue
+		}
+
+		if m := headingRe.FindStringSubmatch(trimmed); m != nil {
+			flush()
+			level := len(m[1])
+			out.WriteString(fmt.Sprintf("<h%d>%s</h%d>\n", level, inline(m[2]), level))
+			continue
+		}
+
+		if m := bulletRe.FindStringSubmatch(trimmed); m != nil {
+			if listTag != "ul" {
+				flush()
+				listTag = "ul"
+			}
+			items = append(items, m[1])
+			continue
+		}
+
+		if m := orderedRe.FindStringSubmatch(trimmed); m != nil {
+			if listTag != "ol" {
+				flush()
+				listTag = "ol"
+			}
+			items = append(items, m[1])
+			continue
+		}
+
+		if m := quoteRe.FindStringSubmatch(trimmed); m != nil {
+			if len(quote) == 0 {
+				flush()
+			}
+			quote = append(quote, m[1])
+			continue
+		}
+
+		// plain text ends a list or quote and starts / continues a paragraph
+		if len(items) > 0 || len(quote) > 0 {
+			flush()
+		}
+		paragraph = append(paragraph, trimmed)
+	}
+
+	// unterminated code block still gets rendered
+	if inCode {
+		out.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+	}
+	flush()
+
+	return strings.TrimSpace(out.String())
+}
+
+// inlineLines renders lines of one block, soft line breaks become <br>
+func inlineLines(lines []string) string {
+	rendered := make([]string, 0, len(lines))
+	for _, line := range lines {
+		rendered = append(rendered, inline(line))
+	}
+	return strings.Join(rendered, "<br>\n")
+}
+
+// inline renders code spans, links and emphasis of a single line
+func inline(text string) string {
+	// code spans and links are swapped for tokens, so emphasis rules can not
+	// reach into their content (e.g. "_" inside an URL)
+	var tokens []string
+	stash := func(rendered string) string {
+		tokens = append(tokens, rendered)
+		return fmt.Sprintf("\x00%d\x00", len(tokens)-1)
+	}
+
+	// NUL is never valid in a comment, drop it so tokens can not be forged
+	text = strings.ReplaceAll(text, "\x00", "")
+
+	text = codeSpanRe.ReplaceAllStringFunc(text, func(match string) string {
+		content := codeSpanRe.FindStringSubmatch(match)[1]
+		return stash("<code>" + html.EscapeString(content) + "</code>")
+	})
+
+	text = linkRe.ReplaceAllStringFunc(text, func(match string) string {
+		m := linkRe.FindStringSubmatch(match)
+		label, url := m[1], m[2]
+		if !safeURL(url) {
+			return stash(html.EscapeString(label))
+		}
+		return stash(`<a href="` + html.EscapeString(url) + `" rel="nofollow noopener" target="_blank">` + html.EscapeString(label) + `</a>`)
+	})
+
+	text = html.EscapeString(text)
+	text = boldRe.ReplaceAllString(text, "<strong>$1</strong>")
+	text = italicRe.ReplaceAllString(text, "<em>$1</em>")
+	text = underRe.ReplaceAllString(text, "$1<em>$2</em>$3")
+	text = strikeRe.ReplaceAllString(text, "<del>$1</del>")
+
+	return tokenRe.ReplaceAllStringFunc(text, func(match string) string {
+		var index int
+		fmt.Sscanf(tokenRe.FindStringSubmatch(match)[1], "%d", &index)
+		return tokens[index]
+	})
+}
+
+// safeURL only allows links that can not run script in the browser
+func safeURL(url string) bool {
+	lower := strings.ToLower(url)
+	return strings.HasPrefix(lower, "http://") ||
+		strings.HasPrefix(lower, "https://") ||
+		strings.HasPrefix(lower, "mailto:")
+}
@@ -0,0 +1,67 @@
+package nmarkdown
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{name: "empty", src: "", want: ""},
+		{name: "paragraph", src: "hello world", want: "<p>hello world</p>"},
+		{name: "soft break", src: "one\r\ntwo", want: "<p>one<br>\ntwo</p>"},
+		{name: "two paragraphs", src: "one\n\ntwo", want: "<p>one</p>\n<p>two</p>"},
+		{name: "heading", src: "## Title *x*", want: "<h2>Title <em>x</em></h2>"},
+		{name: "bullets", src: "- a\n* b", want: "<ul>\n<li>a</li>\n<li>b</li>\n</ul>"},
+		{name: "ordered", src: "1. a\n2) b", want: "<ol>\n<li>a</li>\n<li>b</li>\n</ol>"},
+		{name: "list then text", src: "- a\ntext", want: "<ul>\n<li>a</li>\n</ul>\n<p>text</p>"},
+		{name: "quote", src: "> a\n> b", want: "<blockquote><p>a<br>\nb</p></blockquote>"},
+		{name: "code block", src: "```\n<b>x</b>\n  y\n```", want: "<pre><code>&lt;b&gt;x&lt;/b&gt;\n  y</code></pre>"},
+		{name: "unterminated code block", src: "```\nx", want: "<pre><code>x</code></pre>"},
+		{name: "raw html", src: "<script>alert(1)</script>", want: "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.src); got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInline(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "bold and italic", text: "**b** *i* _u_", want: "<strong>b</strong> <em>i</em> <em>u</em>"},
+		{name: "strike", text: "~~gone~~", want: "<del>gone</del>"},
+		{name: "snake case stays", text: "snake_case_name", want: "snake_case_name"},
+		{name: "code span", text: "`**not bold** <b>`", want: "<code>**not bold** &lt;b&gt;</code>"},
+		{
+			name: "link",
+			text: "[docs](https://example.com/a_b_c)",
+			want: `<a href="https://example.com/a_b_c" rel="nofollow noopener" target="_blank">docs</a>`,
+		},
+		{
+			name: "mailto link",
+			text: "[mail](mailto:a@b.c)",
+			want: `<a href="mailto:a@b.c" rel="nofollow noopener" target="_blank">mail</a>`,
+		},
+		{name: "unsafe link", text: "[click](javascript:alert(1))", want: "click)"},
+		{name: "unsafe link keeps label only", text: "[x](data:text/html)", want: "x"},
+		{name: "forged token", text: "\x000\x00 text", want: "0 text"},
+		{name: "escapes quotes", text: `"a" & 'b'`, want: "&#34;a&#34; &amp; &#39;b&#39;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inline(tt.text); got != tt.want {
+				t.Errorf("inline(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,462 @@
+// Package nquickadd turns free text like
+// "Pay rent tomorrow 9am !high #finance every month" into a structured todo.
+// Whatever is not recognised stays in the task text.
+package nquickadd
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence repeats a todo every Interval units of Frequency
+type Recurrence struct {
+	Frequency string // daily / weekly / monthly / yearly
+	Interval  int
+	Weekday   string // lower case weekday for "every monday", else empty
+}
+
+type Result struct {
+	Task       string
+	DueAt      *time.Time // in the location of now
+	HasTime    bool       // false when only a day was given
+	Priority   string     // low / medium / high, empty when not given
+	Labels     []string
+	Recurrence *Recurrence
+}
+
+var priorities = map[string]string{
+	"!high": "high", "!h": "high", "!1": "high", "!!!": "high",
+	"!medium": "medium", "!med": "medium", "!m": "medium", "!2": "medium", "!!": "medium",
+	"!low": "low", "!l": "low", "!3": "low",
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September, "sept": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+// units of "every 2 weeks" / "in 3 days"
+var units = map[string]string{
+	"day": "daily", "days": "daily",
+	"week": "weekly", "weeks": "weekly",
+	"month": "monthly", "months": "monthly",
+	"year": "yearly", "years": "yearly",
+}
+
+// words that may stand in front of a date / time ("due friday", "at 9am", "until monday")
+var connectors = map[string]bool{"on": true, "at": true, "by": true, "due": true, "until": true}
+
+var (
+	clockPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)$`)
+	hourPattern  = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?$`)
+	h24Pattern   = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+	isoPattern   = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	dayPattern   = regexp.MustCompile(`^(\d{1,2})(st|nd|rd|th)?$`)
+	labelPattern = regexp.MustCompile(`^#[\p{L}\p{N}_\-/]+$`)
+	issuePattern = regexp.MustCompile(`^#\d+$`)
+)
+
+// Parse reads input relative to now, dates and times are in now's location
+func Parse(input string, now time.Time) Result {
+	var result Result
+
+	words := strings.Fields(input)
+	used := make([]bool, len(words))
+
+	today := midnight(now)
+	var day time.Time
+	var hasDay bool
+	var hour, minute int
+
+	for i := 0; i < len(words); i++ {
+		word := clean(words[i])
+
+		if p, ok := priorities[word]; ok && result.Priority == "" {
+			result.Priority = p
+			used[i] = true
+			continue
+		}
+
+		// "#123" points at an issue, it is not a label
+		if labelPattern.MatchString(word) && !issuePattern.MatchString(word) {
+			label := strings.TrimPrefix(word, "#")
+			if !containsString(result.Labels, label) {
+				result.Labels = append(result.Labels, label)
+			}
+			used[i] = true
+			continue
+		}
+
+		if result.Recurrence == nil {
+			if n, recurrence := matchRecurrence(words, i); n > 0 {
+				result.Recurrence = recurrence
+				markUsed(used, i, n)
+				i += n - 1
+				continue
+			}
+		}
+
+		// "due friday" / "at 9am", the connector only goes when something follows
+		start := i
+		if connectors[word] && i+1 < len(words) {
+			start = i + 1
+		}
+
+		if !hasDay {
+			if n, d := matchDate(words, start, today); n > 0 {
+				day, hasDay = d, true
+				markUsed(used, i, start-i+n)
+				i = start + n - 1
+				continue
+			}
+		}
+
+		if !result.HasTime {
+			if n, h, m := matchTime(words, start); n > 0 {
+				hour, minute, result.HasTime = h, m, true
+				markUsed(used, i, start-i+n)
+				i = start + n - 1
+				continue
+			}
+		}
+	}
+
+	// "every monday" without a date starts on the next monday, today only
+	// when its time has not passed yet
+	if !hasDay && result.Recurrence != nil && result.Recurrence.Weekday != "" {
+		day, hasDay = nextWeekday(today, weekdays[result.Recurrence.Weekday], false), true
+		if day.Equal(today) && (!result.HasTime || hour*60+minute < now.Hour()*60+now.Minute()) {
+			day = day.AddDate(0, 0, 7)
+		}
+	}
+
+	switch {
+	case hasDay && result.HasTime:
+		due := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, now.Location())
+		result.DueAt = &due
+	case hasDay:
+		result.DueAt = &day
+	case result.HasTime:
+		// only a time, the next time the clock shows it
+		due := time.Date(today.Year(), today.Month(), today.Day(), hour, minute, 0, 0, now.Location())
+		if due.Before(now) {
+			due = due.AddDate(0, 0, 1)
+		}
+		result.DueAt = &due
+	}
+
+	var task []string
+	for i, word := range words {
+		if !used[i] {
+			task = append(task, word)
+		}
+	}
+	result.Task = strings.Join(task, " ")
+
+	return result
+}
+
+// matchRecurrence reads "daily" / "weekly" / "every month" / "every 2 weeks" /
+// "every other day" / "every monday" at i and returns the words it used
+func matchRecurrence(words []string, i int) (int, *Recurrence) {
+	switch clean(words[i]) {
+	case "daily":
+		return 1, &Recurrence{Frequency: "daily", Interval: 1}
+	case "weekly":
+		return 1, &Recurrence{Frequency: "weekly", Interval: 1}
+	case "monthly":
+		return 1, &Recurrence{Frequency: "monthly", Interval: 1}
+	case "yearly", "annually":
+		return 1, &Recurrence{Frequency: "yearly", Interval: 1}
+	case "every":
+	default:
+		return 0, nil
+	}
+
+	if i+1 >= len(words) {
+		return 0, nil
+	}
+	next := clean(words[i+1])
+
+	if frequency, ok := units[next]; ok {
+		return 2, &Recurrence{Frequency: frequency, Interval: 1}
+	}
+	if _, ok := weekdays[next]; ok {
+		return 2, &Recurrence{Frequency: "weekly", Interval: 1, Weekday: weekdayName(next)}
+	}
+
+	// every other week / every 3 days
+	interval := 0
+	if next == "other" {
+		interval = 2
+	} else if n, err := strconv.Atoi(next); err == nil && n > 0 {
+		interval = n
+	}
+	if interval > 0 && i+2 < len(words) {
+		if frequency, ok := units[clean(words[i+2])]; ok {
+			return 3, &Recurrence{Frequency: frequency, Interval: interval}
+		}
+	}
+
+	return 0, nil
+}
+
+// matchDate reads a day at i and returns the words it used and the day at midnight
+func matchDate(words []string, i int, today time.Time) (int, time.Time) {
+	if i >= len(words) {
+		return 0, time.Time{}
+	}
+	word := clean(words[i])
+
+	switch word {
+	case "today", "tonight":
+		return 1, today
+	case "tomorrow", "tmrw", "tmr":
+		return 1, today.AddDate(0, 0, 1)
+	}
+
+	if weekday, ok := weekdays[word]; ok {
+		return 1, nextWeekday(today, weekday, false)
+	}
+
+	if word == "next" && i+1 < len(words) {
+		if weekday, ok := weekdays[clean(words[i+1])]; ok {
+			return 2, nextWeekday(today, weekday, true)
+		}
+	}
+
+	// in 3 days / in a week
+	if word == "in" && i+2 < len(words) {
+		amount := clean(words[i+1])
+		n, err := strconv.Atoi(amount)
+		if amount == "a" || amount == "an" {
+			n, err = 1, nil
+		}
+		if err == nil && n > 0 {
+			switch units[clean(words[i+2])] {
+			case "daily":
+				return 3, today.AddDate(0, 0, n)
+			case "weekly":
+				return 3, today.AddDate(0, 0, 7*n)
+			case "monthly":
+				return 3, today.AddDate(0, n, 0)
+			case "yearly":
+				return 3, today.AddDate(n, 0, 0)
+			}
+		}
+	}
+
+	if isoPattern.MatchString(word) {
+		if day, err := time.ParseInLocation("2006-01-02", word, today.Location()); err == nil {
+			return 1, day
+		}
+	}
+
+	// jan 5 / 5 jan, a day that already passed this year means next year
+	if i+1 < len(words) {
+		next := clean(words[i+1])
+		if month, ok := months[word]; ok {
+			if dayOfMonth, ok := parseDayOfMonth(next); ok {
+				if date, ok := upcomingDate(today, month, dayOfMonth); ok {
+					return 2, date
+				}
+			}
+		}
+		if month, ok := months[next]; ok {
+			if dayOfMonth, ok := parseDayOfMonth(word); ok {
+				if date, ok := upcomingDate(today, month, dayOfMonth); ok {
+					return 2, date
+				}
+			}
+		}
+	}
+
+	// 31st / the 1st without a month, only with the suffix so plain numbers
+	// stay in the task
+	n := 1
+	if word == "the" && i+1 < len(words) {
+		word = clean(words[i+1])
+		n = 2
+	}
+	if m := dayPattern.FindStringSubmatch(word); m != nil && m[2] != "" {
+		if dayOfMonth, ok := parseDayOfMonth(word); ok {
+			return n, upcomingDayOfMonth(today, dayOfMonth)
+		}
+	}
+
+	return 0, time.Time{}
+}
+
+// matchTime reads "9am" / "9:30 pm" / "21:00" / "noon" at i
+func matchTime(words []string, i int) (int, int, int) {
+	if i >= len(words) {
+		return 0, 0, 0
+	}
+	word := clean(words[i])
+
+	switch word {
+	case "noon":
+		return 1, 12, 0
+	case "midnight":
+		return 1, 0, 0
+	}
+
+	if m := clockPattern.FindStringSubmatch(word); m != nil {
+		if hour, minute, ok := clock(m[1], m[2], m[3]); ok {
+			return 1, hour, minute
+		}
+	}
+
+	// "9 am" as two words
+	if m := hourPattern.FindStringSubmatch(word); m != nil && i+1 < len(words) {
+		if suffix := clean(words[i+1]); suffix == "am" || suffix == "pm" {
+			if hour, minute, ok := clock(m[1], m[2], suffix); ok {
+				return 2, hour, minute
+			}
+		}
+	}
+
+	if m := h24Pattern.FindStringSubmatch(word); m != nil {
+		if hour, minute, ok := clock(m[1], m[2], ""); ok {
+			return 1, hour, minute
+		}
+	}
+
+	return 0, 0, 0
+}
+
+// clock validates hour / minute and applies am / pm
+func clock(hourValue string, minuteValue string, suffix string) (int, int, bool) {
+	hour, _ := strconv.Atoi(hourValue)
+	minute := 0
+	if minuteValue != "" {
+		minute, _ = strconv.Atoi(minuteValue)
+	}
+	if minute > 59 {
+		return 0, 0, false
+	}
+
+	switch suffix {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		hour %= 12
+		if suffix == "pm" {
+			hour += 12
+		}
+	default:
+		if hour > 23 {
+			return 0, 0, false
+		}
+	}
+
+	return hour, minute, true
+}
+
+// nextWeekday returns the next day with the weekday, today counts unless
+// strict is set ("next friday" on a friday is a week away)
+func nextWeekday(today time.Time, weekday time.Weekday, strict bool) time.Time {
+	days := (int(weekday) - int(today.Weekday()) + 7) % 7
+	if days == 0 && strict {
+		days = 7
+	}
+	return today.AddDate(0, 0, days)
+}
+
+// upcomingDate returns the next month / day from today on, false when the
+// month never has the day ("feb 30"). feb 29 waits for the next leap year
+func upcomingDate(today time.Time, month time.Month, day int) (time.Time, bool) {
+	// 2000 is a leap year, so every day that can exist fits
+	if day > daysIn(month, 2000) {
+		return time.Time{}, false
+	}
+
+	for year := today.Year(); ; year++ {
+		if day > daysIn(month, year) {
+			continue
+		}
+		date := time.Date(year, month, day, 0, 0, 0, 0, today.Location())
+		if !date.Before(today) {
+			return date, true
+		}
+	}
+}
+
+// upcomingDayOfMonth returns the day in this month, or in the next month
+// that has it once it passed ("31st" skips the short months)
+func upcomingDayOfMonth(today time.Time, day int) time.Time {
+	for months := 0; ; months++ {
+		first := time.Date(today.Year(), today.Month()+time.Month(months), 1, 0, 0, 0, 0, today.Location())
+		if day > daysIn(first.Month(), first.Year()) {
+			continue
+		}
+		date := first.AddDate(0, 0, day-1)
+		if !date.Before(today) {
+			return date
+		}
+	}
+}
+
+func daysIn(month time.Month, year int) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func parseDayOfMonth(word string) (int, bool) {
+	m := dayPattern.FindStringSubmatch(word)
+	if m == nil {
+		return 0, false
+	}
+	day, _ := strconv.Atoi(m[1])
+	return day, day >= 1 && day <= 31
+}
+
+func weekdayName(word string) string {
+	return strings.ToLower(weekdays[word].String())
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// clean lower cases a word and drops trailing punctuation
+func clean(word string) string {
+	return strings.TrimRight(strings.ToLower(word), ",.;")
+}
+
+func markUsed(used []bool, start int, n int) {
+	for i := start; i < start+n && i < len(used); i++ {
+		used[i] = true
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package nquickadd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// wednesday
+var now = time.Date(2026, time.March, 4, 10, 30, 0, 0, time.UTC)
+
+func day(year int, month time.Month, d int, hour int, minute int) *time.Time {
+	t := time.Date(year, month, d, hour, minute, 0, 0, time.UTC)
+	return &t
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Result
+	}{
+		{
+			input: "Pay rent tomorrow 9am !high #finance every month",
+			want: Result{
+				Task:       "Pay rent",
+				DueAt:      day(2026, time.March, 5, 9, 0),
+				HasTime:    true,
+				Priority:   "high",
+				Labels:     []string{"finance"},
+				Recurrence: &Recurrence{Frequency: "monthly", Interval: 1},
+			},
+		},
+		{
+			input: "call mom",
+			want:  Result{Task: "call mom"},
+		},
+		{
+			input: "report due friday",
+			want:  Result{Task: "report", DueAt: day(2026, time.March, 6, 0, 0)},
+		},
+		{
+			input: "standup next wednesday at 9:15 am",
+			want:  Result{Task: "standup", DueAt: day(2026, time.March, 11, 9, 15), HasTime: true},
+		},
+		{
+			input: "water plants in 3 days",
+			want:  Result{Task: "water plants", DueAt: day(2026, time.March, 7, 0, 0)},
+		},
+		{
+			input: "taxes 2026-04-15",
+			want:  Result{Task: "taxes", DueAt: day(2026, time.April, 15, 0, 0)},
+		},
+		{
+			input: "party jan 5",
+			want:  Result{Task: "party", DueAt: day(2027, time.January, 5, 0, 0)},
+		},
+		{
+			input: "party 31st dec",
+			want:  Result{Task: "party", DueAt: day(2026, time.December, 31, 0, 0)},
+		},
+		{
+			input: "leap day feb 29",
+			want:  Result{Task: "leap day", DueAt: day(2028, time.February, 29, 0, 0)},
+		},
+		{
+			input: "nonsense feb 30",
+			want:  Result{Task: "nonsense feb 30"},
+		},
+		{
+			input: "Pay rent every month on the 1st",
+			want: Result{
+				Task:       "Pay rent",
+				DueAt:      day(2026, time.April, 1, 0, 0),
+				Recurrence: &Recurrence{Frequency: "monthly", Interval: 1},
+			},
+		},
+		{
+			input: "Meet on 31st",
+			want:  Result{Task: "Meet", DueAt: day(2026, time.March, 31, 0, 0)},
+		},
+		{
+			input: "invoice by the 4th",
+			want:  Result{Task: "invoice", DueAt: day(2026, time.March, 4, 0, 0)},
+		},
+		{
+			input: "read chapter 5",
+			want:  Result{Task: "read chapter 5"},
+		},
+		{
+			input: "Fix issue #123 #bug",
+			want:  Result{Task: "Fix issue #123", Labels: []string{"bug"}},
+		},
+		{
+			input: "gym every monday 7am #health #health",
+			want: Result{
+				Task:       "gym",
+				DueAt:      day(2026, time.March, 9, 7, 0),
+				HasTime:    true,
+				Labels:     []string{"health"},
+				Recurrence: &Recurrence{Frequency: "weekly", Interval: 1, Weekday: "monday"},
+			},
+		},
+		{
+			input: "backup every 2 weeks",
+			want:  Result{Task: "backup", Recurrence: &Recurrence{Frequency: "weekly", Interval: 2}},
+		},
+		{
+			input: "lunch noon",
+			want:  Result{Task: "lunch", DueAt: day(2026, time.March, 4, 12, 0), HasTime: true},
+		},
+		{
+			input: "wake up 7:00",
+			want:  Result{Task: "wake up", DueAt: day(2026, time.March, 5, 7, 0), HasTime: true},
+		},
+		{
+			input: "meet at 25:00 !low",
+			want:  Result{Task: "meet at 25:00", Priority: "low"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Parse(tt.input, now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpcomingDate(t *testing.T) {
+	tests := []struct {
+		name  string
+		month time.Month
+		day   int
+		want  time.Time
+		ok    bool
+	}{
+		{"later this year", time.June, 30, time.Date(2026, time.June, 30, 0, 0, 0, 0, time.UTC), true},
+		{"today", time.March, 4, time.Date(2026, time.March, 4, 0, 0, 0, 0, time.UTC), true},
+		{"passed", time.March, 3, time.Date(2027, time.March, 3, 0, 0, 0, 0, time.UTC), true},
+		{"leap day", time.February, 29, time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC), true},
+		{"no such day", time.February, 30, time.Time{}, false},
+		{"april 31", time.April, 31, time.Time{}, false},
+	}
+
+	today := midnight(now)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := upcomingDate(today, tt.month, tt.day)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("upcomingDate(%v, %d) = %v, %v, want %v, %v", tt.month, tt.day, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestUpcomingDayOfMonth(t *testing.T) {
+	tests := []struct {
+		name  string
+		today time.Time
+		day   int
+		want  time.Time
+	}{
+		{"this month", now, 31, time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC)},
+		{"today", now, 4, time.Date(2026, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{"passed", now, 1, time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"short month", time.Date(2026, time.April, 2, 0, 0, 0, 0, time.UTC), 31, time.Date(2026, time.May, 31, 0, 0, 0, 0, time.UTC)},
+		{"next year", time.Date(2026, time.December, 31, 0, 0, 0, 0, time.UTC), 30, time.Date(2027, time.January, 30, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := upcomingDayOfMonth(midnight(tt.today), tt.day); !got.Equal(tt.want) {
+				t.Errorf("upcomingDayOfMonth(%v, %d) = %v, want %v", tt.today, tt.day, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Package nrank generates fractional rank keys for manual ordering.
+// Keys are base62 strings compared byte by byte, so a key can always be
+// created between two neighbours without touching any other document.
+package nrank
+
+import (
+	"errors"
+	"strings"
+)
+
+// digits are in ascending ASCII order so Mongo's binary string sort matches
+const digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+var ErrInvalidRange = errors.New("rank: before key must sort after the after key")
+
+// Between returns a key that sorts strictly between after and before.
+// An empty after means "start of list", an empty before means "end of list".
+func Between(after string, before string) (string, error) {
+	if before != "" && after >= before {
+		return "", ErrInvalidRange
+	}
+
+	// keys never end with the smallest digit, otherwise nothing fits in front
+	if strings.HasSuffix(after, digits[:1]) || strings.HasSuffix(before, digits[:1]) {
+		return "", errors.New("rank: key has a trailing zero digit")
+	}
+
+	return midpoint(after, before), nil
+}
+
+func midpoint(a string, b string) string {
+	if b != "" {
+		// copy the common prefix, then work on the rest
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + midpoint(rest, b[n:])
+		}
+	}
+
+	digitA := 0
+	if a != "" {
+		digitA = strings.IndexByte(digits, a[0])
+	}
+	digitB := len(digits)
+	if b != "" {
+		digitB = strings.IndexByte(digits, b[0])
+	}
+
+	// there is room for a single digit in between
+	if digitB-digitA > 1 {
+		return string(digits[(digitA+digitB+1)/2])
+	}
+
+	// digits are consecutive, so we have to go one level deeper
+	if len(b) > 1 {
+		return b[:1]
+	}
+
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(digits[digitA]) + midpoint(rest, "")
+}
+
+func digitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return digits[0]
+}
+
+// Spread returns n keys in ascending order that are evenly spaced over the
+// whole key space. Used by the rebalancer to shorten keys that grew too long.
+func Spread(n int) []string {
+	if n <= 0 {
+		return nil
+	}
+
+	// pick the smallest width that leaves at least one free slot between keys
+	width, space := 1, len(digits)
+	for space < 2*(n+1) {
+		width++
+		space *= len(digits)
+	}
+	step := space / (n + 1)
+
+	keys := make([]string, 0, n)
+	for i := 1; i <= n; i++ {
+		keys = append(keys, encode(i*step, width))
+	}
+	return keys
+}
+
+// encode writes value as a fixed width base62 number without trailing zeros
+func encode(value int, width int) string {
+	buf := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		buf[i] = digits[value%len(digits)]
+		value /= len(digits)
+	}
+	return strings.TrimRight(string(buf), digits[:1])
+}
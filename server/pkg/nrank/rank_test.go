@@ -0,0 +1,92 @@
+package nrank
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name    string
+		after   string
+		before  string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty list", want: "V"},
+		{name: "start of list", before: "V", want: "G"},
+		{name: "end of list", after: "V", want: "l"},
+		{name: "room for a digit", after: "A", before: "C", want: "B"},
+		{name: "consecutive digits", after: "A", before: "B", want: "AV"},
+		{name: "common prefix", after: "AB", before: "AD", want: "AC"},
+		{name: "shorter after", after: "A", before: "AC", want: "A6"},
+		{name: "last digit", after: "z", want: "zV"},
+		{name: "same keys", after: "B", before: "B", wantErr: true},
+		{name: "swapped keys", after: "C", before: "B", wantErr: true},
+		{name: "trailing zero", after: "A0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Between(tt.after, tt.before)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Between(%q, %q) error = %v, wantErr %v", tt.after, tt.before, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Between(%q, %q) = %q, want %q", tt.after, tt.before, got, tt.want)
+			}
+			if got <= tt.after || (tt.before != "" && got >= tt.before) {
+				t.Errorf("Between(%q, %q) = %q does not sort in between", tt.after, tt.before, got)
+			}
+		})
+	}
+}
+
+// inserting again and again at the same spot must keep finding room
+func TestBetweenRepeated(t *testing.T) {
+	after, before := "A", "B"
+	for i := 0; i < 200; i++ {
+		key, err := Between(after, before)
+		if err != nil {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+		if key <= after || key >= before || strings.HasSuffix(key, "0") {
+			t.Fatalf("insert %d: %q is not a valid key between %q and %q", i, key, after, before)
+		}
+		before = key
+	}
+}
+
+func TestSpread(t *testing.T) {
+	tests := []struct {
+		n         int
+		wantWidth int
+	}{
+		{n: 0},
+		{n: 1, wantWidth: 1},
+		{n: 30, wantWidth: 1},
+		{n: 31, wantWidth: 2},
+		{n: 1000, wantWidth: 2},
+	}
+
+	for _, tt := range tests {
+		keys := Spread(tt.n)
+		if len(keys) != tt.n {
+			t.Fatalf("Spread(%d) returned %d keys", tt.n, len(keys))
+		}
+		for i, key := range keys {
+			if len(key) > tt.wantWidth {
+				t.Errorf("Spread(%d) key %q is longer than %d", tt.n, key, tt.wantWidth)
+			}
+			if strings.HasSuffix(key, "0") {
+				t.Errorf("Spread(%d) key %q has a trailing zero", tt.n, key)
+			}
+			if i > 0 && key <= keys[i-1] {
+				t.Errorf("Spread(%d) keys %q and %q are not ascending", tt.n, keys[i-1], key)
+			}
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// Package nsearch parses search queries like
+// `"release notes" priority:high is:open ws:Work` and has a small in-process
+// inverted index for stores that can not run a Mongo text search.
+// Matching follows Mongo's $text rules: any word can match, every quoted
+// phrase must match.
+package nsearch
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed search string, everything is lower case
+type Query struct {
+	Terms   []string            // free words
+	Phrases []string            // "quoted text"
+	Filters map[string][]string // key:value pairs, a key may repeat
+}
+
+// Parse splits the input into words, quoted phrases and key:value filters.
+// A filter value can be quoted too: ws:"Side Project"
+func Parse(input string) Query {
+	query := Query{Filters: make(map[string][]string)}
+
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		// "phrase"
+		if runes[i] == '"' {
+			value, next := readQuoted(runes, i)
+			if words := Tokenize(value); len(words) > 0 {
+				query.Phrases = append(query.Phrases, strings.Join(words, " "))
+			}
+			i = next
+			continue
+		}
+
+		start := i
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != ':' && runes[i] != '"' {
+			i++
+		}
+
+		// key:value or key:"value"
+		if i < len(runes) && runes[i] == ':' && i > start && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			key := strings.ToLower(string(runes[start:i]))
+
+			var value string
+			if runes[i+1] == '"' {
+				value, i = readQuoted(runes, i+1)
+			} else {
+				valueStart := i + 1
+				for i = valueStart; i < len(runes) && !unicode.IsSpace(runes[i]); i++ {
+				}
+				value = string(runes[valueStart:i])
+			}
+
+			if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+				query.Filters[key] = append(query.Filters[key], value)
+			}
+			continue
+		}
+
+		// plain word, skip to the end of it
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' {
+			i++
+		}
+		query.Terms = append(query.Terms, Tokenize(string(runes[start:i]))...)
+	}
+
+	return query
+}
+
+// readQuoted reads from the opening quote at start up to the closing quote
+// (or the end of input) and returns the text and the index after it
+func readQuoted(runes []rune, start int) (string, int) {
+	end := start + 1
+	for end < len(runes) && runes[end] != '"' {
+		end++
+	}
+	value := string(runes[start+1 : end])
+	if end < len(runes) {
+		end++
+	}
+	return value, end
+}
+
+// HasText reports whether the query has words or phrases (not only filters)
+func (q Query) HasText() bool {
+	return len(q.Terms) > 0 || len(q.Phrases) > 0
+}
+
+// Text builds the $search string for a Mongo text query
+func (q Query) Text() string {
+	parts := append([]string{}, q.Terms...)
+	for _, phrase := range q.Phrases {
+		parts = append(parts, `"`+phrase+`"`)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Tokenize lower cases the text and splits it into words of letters / digits
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Hit is a matching document of an index search
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// Index is an inverted index over short texts, it is not safe for
+// concurrent writes
+type Index struct {
+	words    map[string][]string       // id -> words of the document
+	postings map[string]map[string]int // word -> id -> count
+}
+
+func NewIndex() *Index {
+	return &Index{
+		words:    make(map[string][]string),
+		postings: make(map[string]map[string]int),
+	}
+}
+
+// Add indexes text under id, adding the same id again replaces it
+func (ix *Index) Add(id string, text string) {
+	ix.Remove(id)
+
+	words := Tokenize(text)
+	ix.words[id] = words
+	for _, word := range words {
+		if ix.postings[word] == nil {
+			ix.postings[word] = make(map[string]int)
+		}
+		ix.postings[word][id]++
+	}
+}
+
+func (ix *Index) Remove(id string) {
+	for _, word := range ix.words[id] {
+		delete(ix.postings[word], id)
+		if len(ix.postings[word]) == 0 {
+			delete(ix.postings, word)
+		}
+	}
+	delete(ix.words, id)
+}
+
+// Search returns the documents matching the words / phrases of the query,
+// best first. Words are weighted by how rare they are (tf-idf) and scores
+// are normalised by document length so short titles rank above long ones
+func (ix *Index) Search(q Query) []Hit {
+	if !q.HasText() {
+		return nil
+	}
+
+	total := float64(len(ix.words))
+	scores := make(map[string]float64)
+
+	for _, term := range q.Terms {
+		postings := ix.postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		idf := math.Log(1 + total/float64(len(postings)))
+		for id, count := range postings {
+			scores[id] += float64(count) * idf
+		}
+	}
+
+	// phrases are required, words only add to the score then
+	if len(q.Phrases) > 0 {
+		candidates := scores
+
+		scores = make(map[string]float64)
+		for id := range ix.words {
+			score := candidates[id]
+			text := " " + strings.Join(ix.words[id], " ") + " "
+			matched := true
+			for _, phrase := range q.Phrases {
+				if !strings.Contains(text, " "+phrase+" ") {
+					matched = false
+					break
+				}
+				score += float64(len(strings.Fields(phrase)))
+			}
+			if matched {
+				scores[id] = score
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		if score <= 0 {
+			continue
+		}
+		hits = append(hits, Hit{ID: id, Score: score / math.Sqrt(float64(len(ix.words[id])))})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits
+}
+
+// Highlight escapes text for HTML and wraps the words of the query in <mark>.
+// A word also matches longer words it is the start of (note -> notes), close
+// to what Mongo's stemming finds
+func Highlight(text string, q Query) string {
+	marks := make(map[string]bool)
+	for _, term := range q.Terms {
+		marks[term] = true
+	}
+	for _, phrase := range q.Phrases {
+		for _, word := range strings.Fields(phrase) {
+			marks[word] = true
+		}
+	}
+
+	var out strings.Builder
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !isWordRune(runes[i]) {
+			out.WriteString(html.EscapeString(string(runes[i])))
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(runes) && isWordRune(runes[i]) {
+			i++
+		}
+		word := string(runes[start:i])
+
+		if matchesMark(strings.ToLower(word), marks) {
+			out.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+		} else {
+			out.WriteString(html.EscapeString(word))
+		}
+	}
+
+	return out.String()
+}
+
+func matchesMark(word string, marks map[string]bool) bool {
+	if marks[word] {
+		return true
+	}
+	for mark := range marks {
+		if len(mark) >= 3 && strings.HasPrefix(word, mark) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,138 @@
+package nsearch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Query
+	}{
+		{
+			input: "",
+			want:  Query{Filters: map[string][]string{}},
+		},
+		{
+			input: "Release Notes",
+			want:  Query{Terms: []string{"release", "notes"}, Filters: map[string][]string{}},
+		},
+		{
+			input: `"release notes" priority:high is:open ws:Work`,
+			want: Query{
+				Phrases: []string{"release notes"},
+				Filters: map[string][]string{"priority": {"high"}, "is": {"open"}, "ws": {"work"}},
+			},
+		},
+		{
+			input: `ws:"Side Project" label:a label:b deploy`,
+			want: Query{
+				Terms:   []string{"deploy"},
+				Filters: map[string][]string{"ws": {"side project"}, "label": {"a", "b"}},
+			},
+		},
+		{
+			input: `"unterminated phrase`,
+			want:  Query{Phrases: []string{"unterminated phrase"}, Filters: map[string][]string{}},
+		},
+		{
+			input: `key: value :x`,
+			want:  Query{Terms: []string{"key", "value", "x"}, Filters: map[string][]string{}},
+		},
+		{
+			input: `e-mail "  "`,
+			want:  Query{Terms: []string{"e", "mail"}, Filters: map[string][]string{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Parse(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryText(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		hasText bool
+	}{
+		{input: "fix login", want: "fix login", hasText: true},
+		{input: `deploy "release notes"`, want: `deploy "release notes"`, hasText: true},
+		{input: "is:open", want: "", hasText: false},
+	}
+
+	for _, tt := range tests {
+		q := Parse(tt.input)
+		if got := q.Text(); got != tt.want {
+			t.Errorf("Parse(%q).Text() = %q, want %q", tt.input, got, tt.want)
+		}
+		if got := q.HasText(); got != tt.hasText {
+			t.Errorf("Parse(%q).HasText() = %v, want %v", tt.input, got, tt.hasText)
+		}
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	ix := NewIndex()
+	ix.Add("1", "Write release notes")
+	ix.Add("2", "Release the new version to production")
+	ix.Add("3", "Buy milk")
+	ix.Add("4", "notes")
+	ix.Add("5", "temporary")
+	ix.Remove("5")
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{input: "milk", want: []string{"3"}},
+		{input: "notes", want: []string{"4", "1"}},
+		{input: "release", want: []string{"1", "2"}},
+		{input: `"release notes"`, want: []string{"1"}},
+		{input: `"notes release"`, want: []string{}},
+		{input: "temporary", want: []string{}},
+		{input: "is:open", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			hits := ix.Search(Parse(tt.input))
+			var got []string
+			if hits != nil {
+				got = []string{}
+			}
+			for _, hit := range hits {
+				got = append(got, hit.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text  string
+		query string
+		want  string
+	}{
+		{text: "Write release notes", query: "notes", want: "Write release <mark>notes</mark>"},
+		{text: "Write release notes", query: "note", want: "Write release <mark>notes</mark>"},
+		{text: "Go is fun", query: "go", want: "<mark>Go</mark> is fun"},
+		{text: "Gopher", query: "go", want: "Gopher"},
+		{text: "<b>notes</b>", query: "notes", want: "&lt;b&gt;<mark>notes</mark>&lt;/b&gt;"},
+		{text: "release notes", query: `"release notes"`, want: "<mark>release</mark> <mark>notes</mark>"},
+	}
+
+	for _, tt := range tests {
+		if got := Highlight(tt.text, Parse(tt.query)); got != tt.want {
+			t.Errorf("Highlight(%q, %q) = %q, want %q", tt.text, tt.query, got, tt.want)
+		}
+	}
+}
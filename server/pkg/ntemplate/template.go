@@ -0,0 +1,52 @@
+// Package ntemplate fills {{variables}} in template texts.
+// A variable name is made of letters, digits, '_', '-' and '.', spaces
+// inside the braces are ignored: {{ version }} is the same as {{version}}.
+package ntemplate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.\-]+)\s*\}\}`)
+
+// Variables returns the distinct variable names used in the texts, sorted
+func Variables(texts ...string) []string {
+	seen := make(map[string]bool)
+	names := []string{}
+	for _, text := range texts {
+		for _, match := range placeholder.FindAllStringSubmatch(text, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				names = append(names, match[1])
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render replaces every variable of text with its value.
+// All variables must have a value, otherwise an error names the missing ones
+func Render(text string, values map[string]string) (string, error) {
+	if missing := Missing(values, text); len(missing) > 0 {
+		return "", fmt.Errorf("missing template variables: %s", strings.Join(missing, ", "))
+	}
+
+	return placeholder.ReplaceAllStringFunc(text, func(match string) string {
+		return values[placeholder.FindStringSubmatch(match)[1]]
+	}), nil
+}
+
+// Missing returns the variables of the texts without a value, sorted
+func Missing(values map[string]string, texts ...string) []string {
+	missing := []string{}
+	for _, name := range Variables(texts...) {
+		if _, ok := values[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,93 @@
+package ntemplate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVariables(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		want  []string
+	}{
+		{name: "none", texts: []string{"plain text"}, want: []string{}},
+		{name: "sorted and distinct", texts: []string{"{{b}} {{a}}", "{{ b }}"}, want: []string{"a", "b"}},
+		{name: "allowed characters", texts: []string{"{{release.version-2_x}}"}, want: []string{"release.version-2_x"}},
+		{name: "invalid names", texts: []string{"{{two words}} {{}} {single}"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Variables(tt.texts...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Variables(%q) = %q, want %q", tt.texts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		values  map[string]string
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "fills every variable",
+			text:   "Release {{ version }} to {{env}}",
+			values: map[string]string{"version": "1.2", "env": "prod"},
+			want:   "Release 1.2 to prod",
+		},
+		{
+			name:   "repeated variable",
+			text:   "{{x}}+{{x}}",
+			values: map[string]string{"x": "1"},
+			want:   "1+1",
+		},
+		{
+			name:   "empty value",
+			text:   "[{{x}}]",
+			values: map[string]string{"x": ""},
+			want:   "[]",
+		},
+		{
+			name:   "no variables",
+			text:   "plain",
+			values: nil,
+			want:   "plain",
+		},
+		{
+			name:    "missing variables",
+			text:    "{{b}} {{a}} {{c}}",
+			values:  map[string]string{"c": "3"},
+			wantErr: "missing template variables: a, b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.text, tt.values)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Render(%q) error = %v, want %q", tt.text, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render(%q) error = %v", tt.text, err)
+			}
+			if got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMissing(t *testing.T) {
+	got := Missing(map[string]string{"a": "1"}, "{{a}} {{b}}", "{{c}} {{b}}")
+	if want := []string{"b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Missing() = %q, want %q", got, want)
+	}
+}